@@ -15,6 +15,48 @@ type Neo4jBackupValues struct {
 	Resources                Neo4jBackupResources     `yaml:"resources,omitempty"`
 	Tolerations              []Toleration             `yaml:"tolerations,omitempty"`
 	Affinity                 Affinity                 `yaml:"affinity,omitempty"`
+	SSL                      BackupSSL                `yaml:"ssl,omitempty"`
+	Daemon                   BackupDaemon             `yaml:"daemon,omitempty"`
+	Doctor                   BackupDoctor             `yaml:"doctor,omitempty"`
+	KubernetesReporting      KubernetesReporting      `yaml:"kubernetesReporting,omitempty"`
+	Tracing                  Tracing                  `yaml:"tracing,omitempty"`
+	Logging                  BackupLogging            `yaml:"logging,omitempty"`
+}
+
+type BackupDoctor struct {
+	Enabled bool `yaml:"enabled" default:"true"`
+}
+
+type BackupDaemon struct {
+	Enabled         bool   `yaml:"enabled,omitempty"`
+	Schedule        string `yaml:"schedule,omitempty"`
+	Interval        string `yaml:"interval,omitempty"`
+	Jitter          string `yaml:"jitter,omitempty"`
+	MissedRunPolicy string `yaml:"missedRunPolicy,omitempty"`
+	Port            int    `yaml:"port,omitempty"`
+}
+
+type BackupSSL struct {
+	Backup BackupSSLPolicy `yaml:"backup,omitempty"`
+}
+
+type BackupSSLPolicy struct {
+	PrivateKey        BackupSSLSecret  `yaml:"privateKey,omitempty"`
+	PublicCertificate BackupSSLSecret  `yaml:"publicCertificate,omitempty"`
+	TrustedCerts      BackupSSLSources `yaml:"trustedCerts,omitempty"`
+	RevokedCerts      BackupSSLSources `yaml:"revokedCerts,omitempty"`
+	ClientAuth        string           `yaml:"clientAuth,omitempty"`
+	VerifyHostname    bool             `yaml:"verifyHostname,omitempty"`
+	MinValidityDays   int              `yaml:"minValidityDays,omitempty"`
+}
+
+type BackupSSLSecret struct {
+	SecretName string `yaml:"secretName,omitempty"`
+	SubPath    string `yaml:"subPath,omitempty"`
+}
+
+type BackupSSLSources struct {
+	Sources []interface{} `yaml:"sources,omitempty"`
 }
 
 type Neo4jBackupResources struct {
@@ -66,29 +108,193 @@ type Neo4jBackupNeo4j struct {
 }
 
 type Backup struct {
-	BucketName               string          `yaml:"bucketName,omitempty"`
-	DatabaseAdminServiceName string          `yaml:"databaseAdminServiceName,omitempty"`
-	DatabaseAdminServiceIP   string          `yaml:"databaseAdminServiceIP,omitempty"`
-	DatabaseNamespace        string          `yaml:"databaseNamespace,omitempty" default:"default"`
-	DatabaseBackupPort       string          `yaml:"databaseBackupPort,omitempty" default:"6362"`
-	DatabaseClusterDomain    string          `yaml:"databaseClusterDomain,omitempty" default:"cluster.local"`
-	DatabaseBackupEndpoints  string          `yaml:"databaseBackupEndpoints,omitempty"`
-	Database                 string          `yaml:"database,omitempty"`
-	AzureStorageAccountName  string          `yaml:"azureStorageAccountName,omitempty"`
-	CloudProvider            string          `yaml:"cloudProvider,omitempty"`
-	MinioEndpoint            string          `yaml:"minioEndpoint,omitempty"`
-	SecretName               string          `yaml:"secretName,omitempty"`
-	SecretKeyName            string          `yaml:"secretKeyName,omitempty"`
-	PageCache                string          `yaml:"pageCache,omitempty"`
-	HeapSize                 string          `yaml:"heapSize,omitempty"`
-	FallbackToFull           bool            `yaml:"fallbackToFull" default:"true"`
-	IncludeMetadata          string          `yaml:"includeMetadata,omitempty"`
-	Type                     string          `yaml:"type,omitempty"`
-	KeepFailed               bool            `yaml:"keepFailed" default:"false"`
-	ParallelRecovery         bool            `yaml:"parallelRecovery" default:"false"`
-	KeepBackupFiles          bool            `yaml:"keepBackupFiles" default:"true"`
-	Verbose                  bool            `yaml:"verbose" default:"true"`
-	AggregateBackup          AggregateBackup `yaml:"aggregate,omitempty"`
+	Mode                     string                `yaml:"mode,omitempty"`
+	BucketName               string                `yaml:"bucketName,omitempty"`
+	DatabaseAdminServiceName string                `yaml:"databaseAdminServiceName,omitempty"`
+	DatabaseAdminServiceIP   string                `yaml:"databaseAdminServiceIP,omitempty"`
+	DatabaseNamespace        string                `yaml:"databaseNamespace,omitempty" default:"default"`
+	DatabaseBackupPort       string                `yaml:"databaseBackupPort,omitempty" default:"6362"`
+	DatabaseClusterDomain    string                `yaml:"databaseClusterDomain,omitempty" default:"cluster.local"`
+	DatabaseBackupEndpoints  string                `yaml:"databaseBackupEndpoints,omitempty"`
+	Database                 string                `yaml:"database,omitempty"`
+	AzureStorageAccountName  string                `yaml:"azureStorageAccountName,omitempty"`
+	CloudProvider            string                `yaml:"cloudProvider,omitempty"`
+	MinioEndpoint            string                `yaml:"minioEndpoint,omitempty"`
+	SecretName               string                `yaml:"secretName,omitempty"`
+	SecretKeyName            string                `yaml:"secretKeyName,omitempty"`
+	PageCache                string                `yaml:"pageCache,omitempty"`
+	HeapSize                 string                `yaml:"heapSize,omitempty"`
+	FallbackToFull           bool                  `yaml:"fallbackToFull" default:"true"`
+	IncludeMetadata          string                `yaml:"includeMetadata,omitempty"`
+	Type                     string                `yaml:"type,omitempty"`
+	KeepFailed               bool                  `yaml:"keepFailed" default:"false"`
+	ParallelRecovery         bool                  `yaml:"parallelRecovery" default:"false"`
+	KeepBackupFiles          bool                  `yaml:"keepBackupFiles" default:"true"`
+	Verbose                  bool                  `yaml:"verbose" default:"true"`
+	AggregateBackup          AggregateBackup       `yaml:"aggregate,omitempty"`
+	FullBackupPolicy         FullBackupPolicy      `yaml:"fullBackupPolicy,omitempty"`
+	ObjectTags               map[string]string     `yaml:"objectTags,omitempty"`
+	ObjectMetadata           map[string]string     `yaml:"objectMetadata,omitempty"`
+	AWS                      BackupAWS             `yaml:"aws,omitempty"`
+	GCP                      BackupGCP             `yaml:"gcp,omitempty"`
+	Azure                    BackupAzure           `yaml:"azure,omitempty"`
+	KeyLayout                string                `yaml:"keyLayout,omitempty"`
+	Destinations             []BackupDestination   `yaml:"destinations,omitempty"`
+	DestinationFailurePolicy string                `yaml:"destinationFailurePolicy,omitempty"`
+	DestinationInit          BackupDestinationInit `yaml:"destinationInit,omitempty"`
+	UploadRateLimit          int64                 `yaml:"uploadRateLimit,omitempty"`
+	UploadProgressInterval   int                   `yaml:"uploadProgressInterval,omitempty"`
+	ResumableUploads         bool                  `yaml:"resumableUploads,omitempty"`
+	CapacityCheck            CapacityCheck         `yaml:"capacityCheck,omitempty"`
+	Targets                  []BackupTarget        `yaml:"targets,omitempty"`
+	TargetParallelism        int                   `yaml:"targetParallelism,omitempty"`
+	Dump                     Dump                  `yaml:"dump,omitempty"`
+	Import                   BackupImport          `yaml:"import,omitempty"`
+	Maintenance              BackupMaintenance     `yaml:"maintenance,omitempty"`
+	Snapshot                 BackupSnapshot        `yaml:"snapshot,omitempty"`
+	Hooks                    []BackupHook          `yaml:"hooks,omitempty"`
+	HooksSecretName          string                `yaml:"hooksSecretName,omitempty"`
+}
+
+type BackupHook struct {
+	Name          string            `yaml:"name,omitempty"`
+	Event         string            `yaml:"event,omitempty"`
+	Command       []string          `yaml:"command,omitempty"`
+	URL           string            `yaml:"url,omitempty"`
+	Method        string            `yaml:"method,omitempty"`
+	Headers       map[string]string `yaml:"headers,omitempty"`
+	Timeout       string            `yaml:"timeout,omitempty"`
+	FailurePolicy string            `yaml:"failurePolicy,omitempty"`
+}
+
+type Dump struct {
+	DataVolume    map[string]interface{} `yaml:"dataVolume,omitempty"`
+	DataDirectory string                 `yaml:"dataDirectory,omitempty"`
+}
+
+type BackupImport struct {
+	Type                 string                 `yaml:"type,omitempty"`
+	Nodes                []BackupImportFiles    `yaml:"nodes,omitempty"`
+	Relationships        []BackupImportFiles    `yaml:"relationships,omitempty"`
+	OverwriteDestination bool                   `yaml:"overwriteDestination" default:"false"`
+	AdditionalFlags      []string               `yaml:"additionalFlags,omitempty"`
+	DataVolume           map[string]interface{} `yaml:"dataVolume,omitempty"`
+	DataDirectory        string                 `yaml:"dataDirectory,omitempty"`
+}
+
+type BackupSnapshot struct {
+	Release      string                `yaml:"release,omitempty"`
+	Volumes      []string              `yaml:"volumes,omitempty"`
+	ClassName    string                `yaml:"className,omitempty"`
+	ReadyTimeout string                `yaml:"readyTimeout,omitempty"`
+	Keep         int                   `yaml:"keep,omitempty"`
+	Quiesce      BackupSnapshotQuiesce `yaml:"quiesce,omitempty"`
+	RBAC         BackupSnapshotRBAC    `yaml:"rbac,omitempty"`
+}
+
+type BackupSnapshotQuiesce struct {
+	Enabled            bool   `yaml:"enabled" default:"false"`
+	Endpoint           string `yaml:"endpoint,omitempty"`
+	PasswordFromSecret string `yaml:"passwordFromSecret,omitempty"`
+}
+
+type BackupSnapshotRBAC struct {
+	Create bool `yaml:"create" default:"true"`
+}
+
+type BackupMaintenance struct {
+	ToFormat                       string   `yaml:"toFormat,omitempty"`
+	CopyOnlyNodesWithLabels        []string `yaml:"copyOnlyNodesWithLabels,omitempty"`
+	IgnoreNodesWithLabels          []string `yaml:"ignoreNodesWithLabels,omitempty"`
+	CopyOnlyRelationshipsWithTypes []string `yaml:"copyOnlyRelationshipsWithTypes,omitempty"`
+	IgnoreRelationshipsWithTypes   []string `yaml:"ignoreRelationshipsWithTypes,omitempty"`
+	SkipLabels                     []string `yaml:"skipLabels,omitempty"`
+	SkipProperties                 []string `yaml:"skipProperties,omitempty"`
+	CompactNodeStore               bool     `yaml:"compactNodeStore" default:"false"`
+	AdditionalFlags                []string `yaml:"additionalFlags,omitempty"`
+}
+
+type BackupImportFiles struct {
+	Labels string   `yaml:"labels,omitempty"`
+	Type   string   `yaml:"type,omitempty"`
+	Files  []string `yaml:"files"`
+}
+
+type BackupTarget struct {
+	Name             string                        `yaml:"name"`
+	Endpoints        string                        `yaml:"endpoints,omitempty"`
+	ServiceName      string                        `yaml:"serviceName,omitempty"`
+	ServiceIP        string                        `yaml:"serviceIP,omitempty"`
+	Namespace        string                        `yaml:"namespace,omitempty"`
+	BackupPort       string                        `yaml:"backupPort,omitempty"`
+	Database         string                        `yaml:"database,omitempty"`
+	KeyPrefix        string                        `yaml:"keyPrefix,omitempty"`
+	ConsistencyCheck *BackupTargetConsistencyCheck `yaml:"consistencyCheck,omitempty"`
+}
+
+type BackupTargetConsistencyCheck struct {
+	Enable              *bool  `yaml:"enable,omitempty"`
+	Database            string `yaml:"database,omitempty"`
+	CheckIndexes        *bool  `yaml:"checkIndexes,omitempty"`
+	CheckGraph          *bool  `yaml:"checkGraph,omitempty"`
+	CheckCounts         *bool  `yaml:"checkCounts,omitempty"`
+	CheckPropertyOwners *bool  `yaml:"checkPropertyOwners,omitempty"`
+	MaxOffHeapMemory    string `yaml:"maxOffHeapMemory,omitempty"`
+	Threads             string `yaml:"threads,omitempty"`
+	Verbose             *bool  `yaml:"verbose,omitempty"`
+}
+
+type BackupDestination struct {
+	Name          string             `yaml:"name"`
+	CloudProvider string             `yaml:"cloudProvider"`
+	BucketName    string             `yaml:"bucketName"`
+	SecretName    string             `yaml:"secretName,omitempty"`
+	SecretKeyName string             `yaml:"secretKeyName,omitempty"`
+	KeyLayout     string             `yaml:"keyLayout,omitempty"`
+	AssumeRoles   []BackupAssumeRole `yaml:"assumeRoles,omitempty"`
+}
+
+type BackupAWS struct {
+	StorageClass string             `yaml:"storageClass,omitempty"`
+	SseKmsKeyId  string             `yaml:"sseKmsKeyId,omitempty"`
+	ObjectLock   BackupObjectLock   `yaml:"objectLock,omitempty"`
+	AssumeRoles  []BackupAssumeRole `yaml:"assumeRoles,omitempty"`
+}
+
+type BackupAssumeRole struct {
+	RoleArn     string `yaml:"roleArn"`
+	ExternalId  string `yaml:"externalId,omitempty"`
+	SessionName string `yaml:"sessionName,omitempty"`
+	Duration    string `yaml:"duration,omitempty"`
+}
+
+type BackupObjectLock struct {
+	Mode       string `yaml:"mode,omitempty"`
+	RetainDays string `yaml:"retainDays,omitempty"`
+}
+
+type BackupGCP struct {
+	StorageClass   string `yaml:"storageClass,omitempty"`
+	KmsKeyName     string `yaml:"kmsKeyName,omitempty"`
+	ProjectId      string `yaml:"projectId,omitempty"`
+	BucketLocation string `yaml:"bucketLocation,omitempty"`
+}
+
+type BackupDestinationInit struct {
+	CreateBucket bool                           `yaml:"createBucket" default:"false"`
+	WriteProbe   bool                           `yaml:"writeProbe" default:"false"`
+	Lifecycle    BackupDestinationInitLifecycle `yaml:"lifecycle,omitempty"`
+}
+
+type BackupDestinationInitLifecycle struct {
+	ExpirationDays            int  `yaml:"expirationDays,omitempty"`
+	ExpireWholeBucket         bool `yaml:"expireWholeBucket" default:"false"`
+	AbortIncompleteUploadDays int  `yaml:"abortIncompleteUploadDays,omitempty"`
+}
+
+type BackupAzure struct {
+	AccessTier      string `yaml:"accessTier,omitempty"`
+	EncryptionScope string `yaml:"encryptionScope,omitempty"`
 }
 
 type AggregateBackup struct {
@@ -100,16 +306,57 @@ type AggregateBackup struct {
 	Database         string `yaml:"database"`
 }
 
+type CapacityCheck struct {
+	Enabled         bool `yaml:"enabled" default:"true"`
+	HeadroomPercent int  `yaml:"headroomPercent,omitempty"`
+}
+
+type KubernetesReporting struct {
+	Events          bool                    `yaml:"events,omitempty"`
+	StatusConfigMap bool                    `yaml:"statusConfigMap,omitempty"`
+	RBAC            KubernetesReportingRBAC `yaml:"rbac,omitempty"`
+}
+
+type KubernetesReportingRBAC struct {
+	Create bool `yaml:"create" default:"true"`
+}
+
+type BackupLogging struct {
+	Format string `yaml:"format,omitempty"`
+	Level  string `yaml:"level,omitempty"`
+}
+
+type Tracing struct {
+	Enabled       bool                 `yaml:"enabled,omitempty"`
+	Endpoint      string               `yaml:"endpoint,omitempty"`
+	Insecure      bool                 `yaml:"insecure,omitempty"`
+	ServiceName   string               `yaml:"serviceName,omitempty"`
+	SampleRatio   float64              `yaml:"sampleRatio,omitempty"`
+	HeadersSecret TracingHeadersSecret `yaml:"headersSecret,omitempty"`
+}
+
+type TracingHeadersSecret struct {
+	Name string `yaml:"name,omitempty"`
+	Key  string `yaml:"key,omitempty"`
+}
+
+type FullBackupPolicy struct {
+	MaxDifferentials            int `yaml:"maxDifferentials,omitempty"`
+	MaxDays                     int `yaml:"maxDays,omitempty"`
+	AggregateAfterDifferentials int `yaml:"aggregateAfterDifferentials,omitempty"`
+}
+
 type ConsistencyCheck struct {
-	Enable              bool   `yaml:"enable" default:"false"`
-	Database            string `yaml:"database,omitempty"`
-	CheckIndexes        bool   `yaml:"checkIndexes" default:"true"`
-	CheckGraph          bool   `yaml:"checkGraph" default:"true"`
-	CheckCounts         bool   `yaml:"checkCounts" default:"true"`
-	CheckPropertyOwners bool   `yaml:"checkPropertyOwners" default:"true"`
-	MaxOffHeapMemory    string `yaml:"maxOffHeapMemory,omitempty"`
-	Threads             string `yaml:"threads,omitempty"`
-	Verbose             bool   `yaml:"verbose" default:"true"`
+	Enable                bool   `yaml:"enable" default:"false"`
+	Database              string `yaml:"database,omitempty"`
+	CheckIndexes          bool   `yaml:"checkIndexes" default:"true"`
+	CheckGraph            bool   `yaml:"checkGraph" default:"true"`
+	CheckCounts           bool   `yaml:"checkCounts" default:"true"`
+	CheckPropertyOwners   bool   `yaml:"checkPropertyOwners" default:"true"`
+	MaxOffHeapMemory      string `yaml:"maxOffHeapMemory,omitempty"`
+	Threads               string `yaml:"threads,omitempty"`
+	Verbose               bool   `yaml:"verbose" default:"true"`
+	FailOnInconsistencies bool   `yaml:"failOnInconsistencies" default:"true"`
 }
 
 type Toleration struct {
@@ -38,6 +38,12 @@ type Operations struct {
 	Image        string            `yaml:"image"`
 	Protocol     string            `yaml:"protocol"`
 	Labels       map[string]string `yaml:"labels"`
+	Logging      OperationsLogging `yaml:"logging,omitempty"`
+}
+
+type OperationsLogging struct {
+	Format string `yaml:"format,omitempty"`
+	Level  string `yaml:"level,omitempty"`
 }
 
 type Analytics struct {
@@ -7,11 +7,17 @@ type Neo4jReverseProxyValues struct {
 }
 
 type ReverseProxy struct {
-	Image       string  `yaml:"image,omitempty"`
-	ServiceName string  `yaml:"serviceName,omitempty"`
-	Namespace   string  `yaml:"namespace,omitempty"`
-	Domain      string  `yaml:"domain,omitempty"`
-	Ingress     Ingress `yaml:"ingress,omitempty"`
+	Image       string              `yaml:"image,omitempty"`
+	ServiceName string              `yaml:"serviceName,omitempty"`
+	Namespace   string              `yaml:"namespace,omitempty"`
+	Domain      string              `yaml:"domain,omitempty"`
+	Ingress     Ingress             `yaml:"ingress,omitempty"`
+	Logging     ReverseProxyLogging `yaml:"logging,omitempty"`
+}
+
+type ReverseProxyLogging struct {
+	Format string `yaml:"format,omitempty"`
+	Level  string `yaml:"level,omitempty"`
 }
 
 type Ingress struct {
@@ -0,0 +1,3 @@
+module github.com/neo4j/helm-charts/logging
+
+go 1.22
@@ -0,0 +1,225 @@
+// Package logging writes the logs of the binary as structured records via log/slog
+// The standard log package is redirected to the same handler , hence every log.Printf becomes a record of level INFO
+// (WARN when the message starts with Warning) carrying the service , the run ID and with the secrets redacted
+// It is a module of its own without dependencies , shared by the backup , reverse proxy and neo4j-operations binaries
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	// RunIDEnv carries the run ID to the child processes , ex: the targets of a multi target job and the hooks
+	RunIDEnv = "LOG_RUN_ID"
+	// TargetEnv is the name of the target logged by the run of a single target of a multi target job
+	TargetEnv = "LOG_TARGET"
+
+	redacted = "[REDACTED]"
+)
+
+var (
+	lock   sync.Mutex
+	base   slog.Handler
+	format = FormatText
+	runID  string
+
+	// secretPatterns are replaced in the messages and the string attributes , the first group (if any) is kept
+	secretPatterns = []struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`), redacted},
+		{regexp.MustCompile(`(?i)\b((?:aws_secret_access_key|aws_session_token|azure_storage_account_key|accountkey|client_secret|private_key|neo4j_auth|password|passwd|secret|token|sig)"?\s*[=:]\s*"?)[^\s"',;&]+`), "${1}" + redacted},
+		{regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`), redacted},
+		{regexp.MustCompile(`(?i)\b((?:bearer|basic)\s+)[A-Za-z0-9._~+/=-]{8,}`), "${1}" + redacted},
+		{regexp.MustCompile(`(://[^:/@\s]+:)[^@/\s]+@`), "${1}" + redacted + "@"},
+	}
+	// sensitiveKey matches the keys of the attributes whose value is never logged
+	sensitiveKey = regexp.MustCompile(`(?i)(password|passwd|secret|token|authorization|(access|account|private|api)_?key)`)
+)
+
+// Setup makes the handler configured by LOG_FORMAT (text or json , default text) and LOG_LEVEL (debug , info , warn or error ,
+// default info) the default logger of slog and of the standard log package
+// The run ID is read from LOG_RUN_ID , a new one is generated when it is not set
+func Setup(service string) error {
+	logFormat := strings.ToLower(strings.TrimSpace(os.Getenv("LOG_FORMAT")))
+	if logFormat == "" {
+		logFormat = FormatText
+	}
+	level, err := ParseLevel(os.Getenv("LOG_LEVEL"))
+	if err != nil {
+		return err
+	}
+	handler, err := NewHandler(os.Stderr, logFormat, level)
+	if err != nil {
+		return err
+	}
+	attrs := []slog.Attr{slog.String("service", service)}
+	if target := strings.TrimSpace(os.Getenv(TargetEnv)); target != "" {
+		attrs = append(attrs, slog.String("target", target))
+	}
+
+	lock.Lock()
+	base = handler.WithAttrs(attrs)
+	format = logFormat
+	lock.Unlock()
+	if id := strings.TrimSpace(os.Getenv(RunIDEnv)); id != "" {
+		setRunID(id)
+		return nil
+	}
+	setRunID(NewRunID())
+	return nil
+}
+
+// ParseLevel parses debug , info , warn (or warning) and error , an empty value is info
+func ParseLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "", "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid LOG_LEVEL %s. Supported values are debug , info , warn and error", value)
+	}
+}
+
+// Format returns the configured output format , text unless LOG_FORMAT is json
+func Format() string {
+	lock.Lock()
+	defer lock.Unlock()
+	return format
+}
+
+// NewRunID returns a random correlation ID
+func NewRunID() string {
+	id := make([]byte, 8)
+	if _, err := rand.Read(id); err != nil {
+		return "0000000000000000"
+	}
+	return hex.EncodeToString(id)
+}
+
+// RunID returns the correlation ID of the current run , empty when Setup was not called
+func RunID() string {
+	lock.Lock()
+	defer lock.Unlock()
+	return runID
+}
+
+// StartRun assigns a new correlation ID to the records of the following run and to the child processes , ex: every run of the daemon
+func StartRun() string {
+	lock.Lock()
+	configured := base != nil
+	lock.Unlock()
+	if !configured {
+		return ""
+	}
+	id := NewRunID()
+	setRunID(id)
+	return id
+}
+
+func setRunID(id string) {
+	lock.Lock()
+	runID = id
+	logger := slog.New(base.WithAttrs([]slog.Attr{slog.String("run_id", id)}))
+	lock.Unlock()
+	os.Setenv(RunIDEnv, id)
+	slog.SetDefault(logger)
+}
+
+// Redact replaces the secrets present in the value , ex: AWS access keys , passwords and bearer tokens
+func Redact(value string) string {
+	for _, secret := range secretPatterns {
+		value = secret.pattern.ReplaceAllString(value, secret.replacement)
+	}
+	return value
+}
+
+// NewHandler returns the handler writing the records of the given level (or above) to w in the given format (text or json)
+// The messages and the attributes are redacted , the messages starting with Warning are of level WARN
+func NewHandler(w io.Writer, logFormat string, level slog.Leveler) (slog.Handler, error) {
+	// the records are filtered by redactingHandler once their level is known
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	switch logFormat {
+	case FormatJSON:
+		return &redactingHandler{next: slog.NewJSONHandler(w, options), level: level}, nil
+	case FormatText:
+		return &redactingHandler{next: slog.NewTextHandler(w, options), level: level}, nil
+	default:
+		return nil, fmt.Errorf("invalid LOG_FORMAT %s. Supported values are text and json", logFormat)
+	}
+}
+
+type redactingHandler struct {
+	next  slog.Handler
+	level slog.Leveler
+}
+
+func (h *redactingHandler) Enabled(_ context.Context, level slog.Level) bool {
+	// the records of the standard log package are of level INFO until their message is read
+	return level >= h.level.Level() || level == slog.LevelInfo
+}
+
+func (h *redactingHandler) Handle(ctx context.Context, r slog.Record) error {
+	level := r.Level
+	if level == slog.LevelInfo && strings.HasPrefix(r.Message, "Warning") {
+		level = slog.LevelWarn
+	}
+	if level < h.level.Level() {
+		return nil
+	}
+	record := slog.NewRecord(r.Time, level, Redact(r.Message), r.PC)
+	r.Attrs(func(a slog.Attr) bool {
+		record.AddAttrs(redactAttr(a))
+		return true
+	})
+	return h.next.Handle(ctx, record)
+}
+
+func (h *redactingHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	redactedAttrs := make([]slog.Attr, 0, len(attrs))
+	for _, a := range attrs {
+		redactedAttrs = append(redactedAttrs, redactAttr(a))
+	}
+	return &redactingHandler{next: h.next.WithAttrs(redactedAttrs), level: h.level}
+}
+
+func (h *redactingHandler) WithGroup(name string) slog.Handler {
+	return &redactingHandler{next: h.next.WithGroup(name), level: h.level}
+}
+
+func redactAttr(a slog.Attr) slog.Attr {
+	a.Value = a.Value.Resolve()
+	switch {
+	case a.Value.Kind() == slog.KindGroup:
+		var group []any
+		for _, member := range a.Value.Group() {
+			group = append(group, redactAttr(member))
+		}
+		return slog.Group(a.Key, group...)
+	case sensitiveKey.MatchString(a.Key):
+		return slog.String(a.Key, redacted)
+	case a.Value.Kind() == slog.KindString:
+		return slog.String(a.Key, Redact(a.Value.String()))
+	}
+	if err, ok := a.Value.Any().(error); ok {
+		return slog.String(a.Key, Redact(err.Error()))
+	}
+	return a
+}
@@ -0,0 +1,85 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials/stscreds"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+)
+
+const (
+	defaultRoleSessionName = "neo4j-backup"
+	// stsRegion is used to reach sts when the region is not configured , sts is a global service
+	stsRegion          = "us-east-1"
+	minSessionDuration = 15 * time.Minute
+	maxSessionDuration = 12 * time.Hour
+)
+
+// assumeRole describes a single role of the chain of roles assumed on top of the base credentials
+type assumeRole struct {
+	RoleArn     string `json:"roleArn"`
+	ExternalID  string `json:"externalId"`
+	SessionName string `json:"sessionName"`
+	// Duration of the role session ex: 1h , sts caps the sessions of a chained role to 1h
+	Duration string `json:"duration"`
+
+	duration time.Duration
+}
+
+// parseAssumeRoles parses the json list of roles of a destination , AWS_ASSUME_ROLES for the primary destination
+// Ex: [{"roleArn":"arn:aws:iam::111111111111:role/backup","externalId":"neo4j","sessionName":"nightly","duration":"1h"}]
+func parseAssumeRoles(value string) ([]assumeRole, error) {
+	if value = strings.TrimSpace(value); value == "" || value == "null" {
+		return nil, nil
+	}
+	var roles []assumeRole
+	if err := json.Unmarshal([]byte(value), &roles); err != nil {
+		return nil, fmt.Errorf("unable to parse the roles to assume \n %v", err)
+	}
+	for i := range roles {
+		role := &roles[i]
+		if !strings.HasPrefix(role.RoleArn, "arn:") {
+			return nil, fmt.Errorf("invalid role arn %q in the roles to assume", role.RoleArn)
+		}
+		if role.SessionName == "" {
+			role.SessionName = defaultRoleSessionName
+		}
+		if role.Duration != "" {
+			duration, err := time.ParseDuration(role.Duration)
+			if err != nil || duration < minSessionDuration || duration > maxSessionDuration {
+				return nil, fmt.Errorf("invalid duration %s of role %s. It must be between 15m and 12h", role.Duration, role.RoleArn)
+			}
+			role.duration = duration
+		}
+	}
+	return roles, nil
+}
+
+// assumeRoleChain replaces the credentials of the config with the credentials of the last role of the chain
+// Every role is assumed using the credentials of the previous one , the first one using the base credentials
+// The role sessions are refreshed automatically when they expire
+func assumeRoleChain(cfg *aws.Config, roles []assumeRole) {
+	for _, role := range roles {
+		stsConfig := cfg.Copy()
+		if stsConfig.Region == "" {
+			stsConfig.Region = stsRegion
+		}
+		role := role
+		provider := stscreds.NewAssumeRoleProvider(sts.NewFromConfig(stsConfig), role.RoleArn, func(options *stscreds.AssumeRoleOptions) {
+			options.RoleSessionName = role.SessionName
+			if role.ExternalID != "" {
+				options.ExternalID = aws.String(role.ExternalID)
+			}
+			if role.duration != 0 {
+				options.Duration = role.duration
+			}
+		})
+		log.Printf("Using role %s with session name %s", role.RoleArn, role.SessionName)
+		cfg.Credentials = aws.NewCredentialsCache(provider)
+	}
+}
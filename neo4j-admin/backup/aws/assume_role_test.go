@@ -0,0 +1,117 @@
+package aws
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAssumeRoles(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    []assumeRole
+		wantErr bool
+	}{
+		{name: "not configured", value: ""},
+		{name: "null", value: "null"},
+		{
+			name:  "defaults",
+			value: `[{"roleArn":"arn:aws:iam::111111111111:role/backup"}]`,
+			want:  []assumeRole{{RoleArn: "arn:aws:iam::111111111111:role/backup", SessionName: defaultRoleSessionName}},
+		},
+		{
+			name:  "chain",
+			value: `[{"roleArn":"arn:aws:iam::111111111111:role/hop"},{"roleArn":"arn:aws:iam::222222222222:role/backup","externalId":"neo4j","sessionName":"nightly","duration":"30m"}]`,
+			want: []assumeRole{
+				{RoleArn: "arn:aws:iam::111111111111:role/hop", SessionName: defaultRoleSessionName},
+				{RoleArn: "arn:aws:iam::222222222222:role/backup", ExternalID: "neo4j", SessionName: "nightly", Duration: "30m", duration: 30 * time.Minute},
+			},
+		},
+		{name: "invalid json", value: `{`, wantErr: true},
+		{name: "invalid arn", value: `[{"roleArn":"backup"}]`, wantErr: true},
+		{name: "duration too short", value: `[{"roleArn":"arn:aws:iam::111111111111:role/backup","duration":"5m"}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			roles, err := parseAssumeRoles(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, roles)
+		})
+	}
+}
+
+// TestAssumeRoleChain checks that every role of the chain is assumed with the credentials of the previous one
+func TestAssumeRoleChain(t *testing.T) {
+	t.Parallel()
+
+	var lock sync.Mutex
+	var requests []url.Values
+	var signedBy []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.NoError(t, r.ParseForm())
+		lock.Lock()
+		index := len(requests)
+		requests = append(requests, r.PostForm)
+		// Authorization: AWS4-HMAC-SHA256 Credential=<access key id>/<date>/<region>/sts/aws4_request, ...
+		credential := strings.SplitN(strings.TrimPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential="), "/", 2)[0]
+		signedBy = append(signedBy, credential)
+		lock.Unlock()
+		w.Header().Set("Content-Type", "text/xml")
+		fmt.Fprintf(w, `<AssumeRoleResponse xmlns="https://sts.amazonaws.com/doc/2011-06-15/">
+  <AssumeRoleResult>
+    <Credentials>
+      <AccessKeyId>ROLE%d</AccessKeyId>
+      <SecretAccessKey>secret%d</SecretAccessKey>
+      <SessionToken>token%d</SessionToken>
+      <Expiration>%s</Expiration>
+    </Credentials>
+    <AssumedRoleUser>
+      <Arn>%s</Arn>
+      <AssumedRoleId>id:%d</AssumedRoleId>
+    </AssumedRoleUser>
+  </AssumeRoleResult>
+</AssumeRoleResponse>`, index+1, index+1, index+1, time.Now().Add(time.Hour).UTC().Format(time.RFC3339), r.PostForm.Get("RoleArn"), index+1)
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Credentials:  credentials.NewStaticCredentialsProvider("BASE", "base-secret", ""),
+		BaseEndpoint: aws.String(server.URL),
+	}
+	assumeRoleChain(&cfg, []assumeRole{
+		{RoleArn: "arn:aws:iam::111111111111:role/hop", SessionName: "first"},
+		{RoleArn: "arn:aws:iam::222222222222:role/backup", ExternalID: "neo4j", SessionName: "second", duration: 30 * time.Minute},
+	})
+
+	creds, err := cfg.Credentials.Retrieve(context.TODO())
+	assert.NoError(t, err)
+	assert.Equal(t, "ROLE2", creds.AccessKeyID)
+	assert.Equal(t, "token2", creds.SessionToken)
+
+	if assert.Len(t, requests, 2) {
+		assert.Equal(t, "arn:aws:iam::111111111111:role/hop", requests[0].Get("RoleArn"))
+		assert.Equal(t, "first", requests[0].Get("RoleSessionName"))
+		assert.Empty(t, requests[0].Get("ExternalId"))
+		assert.Equal(t, "arn:aws:iam::222222222222:role/backup", requests[1].Get("RoleArn"))
+		assert.Equal(t, "neo4j", requests[1].Get("ExternalId"))
+		assert.Equal(t, "1800", requests[1].Get("DurationSeconds"))
+	}
+	assert.Equal(t, []string{"BASE", "ROLE1"}, signedBy)
+}
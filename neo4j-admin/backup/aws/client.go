@@ -9,10 +9,13 @@ import (
 )
 
 type awsClient struct {
-	cfg *aws.Config
+	cfg           *aws.Config
+	uploadOptions *uploadOptions
 }
 
-func NewAwsClient(credentialPath string) (*awsClient, error) {
+// NewAwsClient returns a client using the credentials file at credentialPath , the web identity when it is /credentials/
+// The roles of assumeRoles (a json list , empty when none) are assumed on top of them
+func NewAwsClient(credentialPath string, assumeRoles string) (*awsClient, error) {
 	var cfg aws.Config
 	var err error
 	if credentialPath == "/credentials/" {
@@ -39,7 +42,20 @@ func NewAwsClient(credentialPath string) (*awsClient, error) {
 
 	}
 
+	// the roles of the destination (if any) are assumed on top of the credentials file or the web identity
+	roles, err := parseAssumeRoles(assumeRoles)
+	if err != nil {
+		return nil, err
+	}
+	assumeRoleChain(&cfg, roles)
+
+	options, err := getUploadOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	return &awsClient{
-		cfg: &cfg,
+		cfg:           &cfg,
+		uploadOptions: options,
 	}, nil
 }
@@ -0,0 +1,50 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	profilePattern         = regexp.MustCompile(`(?m)^\s*\[\s*([^\]]+?)\s*\]`)
+	accessKeyIDPattern     = regexp.MustCompile(`(?m)^\s*aws_access_key_id\s*=\s*\S+`)
+	secretAccessKeyPattern = regexp.MustCompile(`(?m)^\s*aws_secret_access_key\s*=\s*\S+`)
+)
+
+// CheckCredentials checks the format of the shared credentials file , or the web identity token when the service account is used
+// It returns a description of the credentials without connecting to aws
+func CheckCredentials(credentialPath string) (string, error) {
+	if credentialPath == "/credentials/" {
+		tokenFile, present := os.LookupEnv("AWS_WEB_IDENTITY_TOKEN_FILE")
+		if !present {
+			return "", fmt.Errorf("missing AWS_WEB_IDENTITY_TOKEN_FILE , the service account is not annotated with an IAM role (eks.amazonaws.com/role-arn)")
+		}
+		if _, err := os.Stat(tokenFile); err != nil {
+			return "", fmt.Errorf("unable to read the web identity token \n %v", err)
+		}
+		return fmt.Sprintf("web identity of role %s", os.Getenv("AWS_ROLE_ARN")), nil
+	}
+	data, err := os.ReadFile(credentialPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to open aws credential file \n %v", err)
+	}
+	profile := strings.TrimSpace(os.Getenv("AWS_PROFILE"))
+	if profile == "" {
+		profile = "default"
+	}
+	var present bool
+	for _, match := range profilePattern.FindAllStringSubmatch(string(data), -1) {
+		present = present || match[1] == profile
+	}
+	switch {
+	case !present:
+		return "", fmt.Errorf("missing profile [%s] in aws credential file %s", profile, credentialPath)
+	case !accessKeyIDPattern.Match(data):
+		return "", fmt.Errorf("missing aws_access_key_id in aws credential file %s", credentialPath)
+	case !secretAccessKeyPattern.Match(data):
+		return "", fmt.Errorf("missing aws_secret_access_key in aws credential file %s", credentialPath)
+	}
+	return fmt.Sprintf("profile %s of credential file %s", profile, credentialPath), nil
+}
@@ -0,0 +1,54 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	directory := t.TempDir()
+	tokenFile := filepath.Join(directory, "token")
+	assert.NoError(t, os.WriteFile(tokenFile, []byte("token"), 0600))
+	t.Setenv("AWS_PROFILE", "")
+	t.Setenv("AWS_ROLE_ARN", "arn:aws:iam::111111111111:role/backup")
+
+	tests := []struct {
+		name           string
+		credentials    string
+		credentialPath string
+		tokenFile      string
+		wantErr        string
+	}{
+		{name: "valid file", credentials: "[default]\naws_access_key_id = AKIA\naws_secret_access_key = secret\n"},
+		{name: "missing profile", credentials: "[backup]\naws_access_key_id = AKIA\naws_secret_access_key = secret\n", wantErr: "missing profile [default]"},
+		{name: "missing secret", credentials: "[default]\naws_access_key_id = AKIA\naws_secret_access_key =\n", wantErr: "missing aws_secret_access_key"},
+		{name: "missing file", credentialPath: filepath.Join(directory, "missing"), wantErr: "unable to open aws credential file"},
+		{name: "web identity", credentialPath: "/credentials/", tokenFile: tokenFile},
+		{name: "missing web identity", credentialPath: "/credentials/", wantErr: "missing AWS_WEB_IDENTITY_TOKEN_FILE"},
+		{name: "missing token", credentialPath: "/credentials/", tokenFile: filepath.Join(directory, "missing"), wantErr: "unable to read the web identity token"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentialPath := tt.credentialPath
+			if credentialPath == "" {
+				credentialPath = filepath.Join(t.TempDir(), "credentials")
+				assert.NoError(t, os.WriteFile(credentialPath, []byte(tt.credentials), 0600))
+			}
+			if tt.tokenFile != "" {
+				t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", tt.tokenFile)
+			} else {
+				t.Setenv("AWS_WEB_IDENTITY_TOKEN_FILE", "")
+				os.Unsetenv("AWS_WEB_IDENTITY_TOKEN_FILE")
+			}
+			_, err := CheckCredentials(credentialPath)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
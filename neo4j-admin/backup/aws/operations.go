@@ -44,10 +44,9 @@ func (a *awsClient) CheckBucketAccess(bucketName string) error {
 	if err != nil {
 		return fmt.Errorf("Unable to connect to s3 bucket %s \n Here's why: %v\n", bucketName, err)
 	}
-	if strings.Contains(bucketName, "/") {
-		if len(objects.Contents) == 0 {
-			return fmt.Errorf("s3 Bucket %s does not exist", bucketName)
-		}
+	// an empty prefix is valid , it is created by the first upload
+	if strings.Contains(bucketName, "/") && len(objects.Contents) == 0 {
+		log.Printf("Prefix of s3 bucket %s is empty", bucketName)
 	}
 	log.Printf("Connectivity with S3 Bucket '%s' established", bucketName)
 
@@ -83,19 +82,23 @@ func (a *awsClient) UploadFile(fileNames []string, bucketName string) error {
 			continue
 		}
 
-		file, err := os.Open(filePath)
+		file, err := common.OpenUploadFile(filePath, bucketName)
 		if err != nil {
-			return fmt.Errorf("Couldn't open file %v to upload. Here's why: %v\n", filePath, err)
+			return err
 		}
 
 		log.Printf("Starting upload of file %s", filePath)
 		log.Printf("KeyName := %s", generateKeyName(bucketName, fileName))
-		_, err = s3Client.PutObject(context.TODO(), &s3.PutObjectInput{
-			Bucket: aws.String(parentBucketName),
-			Key:    aws.String(generateKeyName(bucketName, fileName)),
-			Body:   file,
-		})
+		input := &s3.PutObjectInput{
+			Bucket:        aws.String(parentBucketName),
+			Key:           aws.String(generateKeyName(bucketName, fileName)),
+			Body:          file,
+			ContentLength: aws.Int64(file.Size()),
+		}
+		a.uploadOptions.apply(input, fileName)
+		_, err = s3Client.PutObject(context.TODO(), input)
 		if err != nil {
+			file.Close()
 			return fmt.Errorf("Couldn't upload file %v to %v:%v. Here's why: %v\n", filePath, bucketName, fileName, err)
 		}
 		file.Close()
@@ -114,20 +117,26 @@ func (a *awsClient) UploadLargeObject(fileName string, location string, bucketNa
 		u.PartSize = partGiBs * 1024 * 1024 * 1024
 	})
 
-	file, err := os.Open(filePath)
+	file, err := common.OpenUploadFile(filePath, bucketName)
 	if err != nil {
-		return fmt.Errorf("Couldn't open large file %v to upload. Here's why: %v\n", filePath, err)
+		return err
 	}
 
 	defer file.Close()
 
 	log.Printf("Starting upload of file %s", filePath)
 	log.Printf("KeyName := %s", generateKeyName(bucketName, fileName))
-	_, err = uploader.Upload(context.TODO(), &s3.PutObjectInput{
+	input := &s3.PutObjectInput{
 		Bucket: aws.String(parentBucketName),
 		Key:    aws.String(generateKeyName(bucketName, fileName)),
 		Body:   file,
-	})
+	}
+	a.uploadOptions.apply(input, fileName)
+	if common.ResumableUploadsEnabled() {
+		err = a.resumableUpload(s3Client, file, input, filePath)
+	} else {
+		_, err = uploader.Upload(context.TODO(), input)
+	}
 	if err != nil {
 		return fmt.Errorf("Couldn't upload large file %v to %v:%v. Here's why: %v\n", filePath, bucketName, fileName, err)
 	}
@@ -135,13 +144,23 @@ func (a *awsClient) UploadLargeObject(fileName string, location string, bucketNa
 	return err
 }
 
-// GenerateEnvVariablesFromCredentials sets AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY
+// GenerateEnvVariablesFromCredentials sets AWS_ACCESS_KEY_ID , AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN
 // This is required in the case when aggregate backup is to be performed but service account (role based creds) is not used
+// or when roles are assumed on top of it
 func (a *awsClient) GenerateEnvVariablesFromCredentials() error {
 	creds, err := a.cfg.Credentials.Retrieve(context.TODO())
 	if err != nil {
 		return err
 	}
+	// the credentials of an assumed role are temporary and only valid along with the session token
+	if creds.SessionToken != "" {
+		err = os.Setenv("AWS_SESSION_TOKEN", creds.SessionToken)
+	} else {
+		err = os.Unsetenv("AWS_SESSION_TOKEN")
+	}
+	if err != nil {
+		return err
+	}
 	err = os.Setenv("AWS_ACCESS_KEY_ID", creds.AccessKeyID)
 	if err != nil {
 		return err
@@ -180,3 +199,80 @@ func (a *awsClient) getS3Client() *s3.Client {
 	}
 	return client
 }
+
+// ListObjects returns all the objects present below the given bucket name and prefix along with the metadata of the backup artifacts
+func (a *awsClient) ListObjects(bucketName string) ([]common.ObjectInfo, error) {
+	s3Client := a.getS3Client()
+	parentBucketName, prefix := common.SplitBucketName(bucketName)
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(parentBucketName),
+	}
+	if prefix != "" {
+		input.Prefix = aws.String(fmt.Sprintf("%s/", strings.TrimSuffix(prefix, "/")))
+	}
+
+	var objects []common.ObjectInfo
+	paginator := s3.NewListObjectsV2Paginator(s3Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("Unable to list objects of s3 bucket %s \n Here's why: %v\n", bucketName, err)
+		}
+		for _, object := range page.Contents {
+			objectInfo := common.ObjectInfo{
+				Key:          aws.ToString(object.Key),
+				Size:         aws.ToInt64(object.Size),
+				LastModified: aws.ToTime(object.LastModified),
+			}
+			// list objects does not return the user metadata hence it is retrieved only for backup artifacts
+			if strings.HasSuffix(objectInfo.Key, ".backup") {
+				head, err := s3Client.HeadObject(context.TODO(), &s3.HeadObjectInput{
+					Bucket: aws.String(parentBucketName),
+					Key:    object.Key,
+				})
+				if err != nil {
+					return nil, fmt.Errorf("Unable to retrieve metadata of object %s in s3 bucket %s \n Here's why: %v\n", objectInfo.Key, bucketName, err)
+				}
+				objectInfo.Metadata = head.Metadata
+			}
+			objects = append(objects, objectInfo)
+		}
+	}
+	return objects, nil
+}
+
+// DownloadFile downloads the object with the given key from the s3 bucket to the given file path
+func (a *awsClient) DownloadFile(bucketName string, key string, filePath string) error {
+	parentBucketName, _ := common.SplitBucketName(bucketName)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Couldn't create file %v to download. Here's why: %v\n", filePath, err)
+	}
+	defer file.Close()
+
+	log.Printf("Starting download of object %s from s3 bucket %s", key, parentBucketName)
+	downloader := manager.NewDownloader(a.getS3Client())
+	_, err = downloader.Download(context.TODO(), file, &s3.GetObjectInput{
+		Bucket: aws.String(parentBucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't download object %v from %v. Here's why: %v\n", key, parentBucketName, err)
+	}
+	log.Printf("Object %s downloaded to %s !!", key, filePath)
+	return nil
+}
+
+// DeleteObject deletes the object with the given key from the s3 bucket
+func (a *awsClient) DeleteObject(bucketName string, key string) error {
+	parentBucketName, _ := common.SplitBucketName(bucketName)
+	_, err := a.getS3Client().DeleteObject(context.TODO(), &s3.DeleteObjectInput{
+		Bucket: aws.String(parentBucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't delete object %v from %v. Here's why: %v\n", key, parentBucketName, err)
+	}
+	log.Printf("Object %s deleted from s3 bucket %s !!", key, parentBucketName)
+	return nil
+}
@@ -9,7 +9,7 @@ import (
 
 func TestCheckBucketAccessForAWS(t *testing.T) {
 	t.Parallel()
-	client, err := NewAwsClient(os.Getenv("AWS_CREDENTIAL_PATH"))
+	client, err := NewAwsClient(os.Getenv("AWS_CREDENTIAL_PATH"), os.Getenv("AWS_ASSUME_ROLES"))
 	assert.NoError(t, err)
 
 	tests := []struct {
@@ -38,9 +38,14 @@ func TestCheckBucketAccessForAWS(t *testing.T) {
 			bucketName: "does-not-exist-bucket",
 		},
 		{
-			name:       "invalid subdirectory",
+			name:       "invalid bucket with subdirectory",
 			wantErr:    true,
-			bucketName: "helm-backup-test/invalid",
+			bucketName: "does-not-exist-bucket/test",
+		},
+		{
+			name:       "empty subdirectory",
+			wantErr:    false,
+			bucketName: "helm-backup-test/empty",
 		},
 	}
 	for _, tt := range tests {
@@ -54,7 +59,7 @@ func TestCheckBucketAccessForAWS(t *testing.T) {
 
 func TestUploadFileForAWS(t *testing.T) {
 	t.Parallel()
-	client, err := NewAwsClient(os.Getenv("AWS_CREDENTIAL_PATH"))
+	client, err := NewAwsClient(os.Getenv("AWS_CREDENTIAL_PATH"), os.Getenv("AWS_ASSUME_ROLES"))
 	assert.NoError(t, err)
 
 	currentDirectory, err := os.Getwd()
@@ -0,0 +1,100 @@
+package aws
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// uploadOptions contains the s3 specific options applied to every uploaded object
+type uploadOptions struct {
+	storageClass         types.StorageClass
+	sseKmsKeyId          string
+	tags                 map[string]string
+	metadata             map[string]string
+	objectLockMode       types.ObjectLockMode
+	objectLockRetainDays int
+}
+
+// getUploadOptions reads the s3 upload options from the below env variables
+// AWS_STORAGE_CLASS , AWS_SSE_KMS_KEY_ID , OBJECT_TAGS , OBJECT_METADATA , AWS_OBJECT_LOCK_MODE , AWS_OBJECT_LOCK_RETAIN_DAYS
+func getUploadOptions() (*uploadOptions, error) {
+	options := &uploadOptions{
+		storageClass:   types.StorageClass(strings.ToUpper(strings.TrimSpace(os.Getenv("AWS_STORAGE_CLASS")))),
+		sseKmsKeyId:    strings.TrimSpace(os.Getenv("AWS_SSE_KMS_KEY_ID")),
+		objectLockMode: types.ObjectLockMode(strings.ToUpper(strings.TrimSpace(os.Getenv("AWS_OBJECT_LOCK_MODE")))),
+	}
+
+	if options.storageClass != "" && !isValidStorageClass(options.storageClass) {
+		return nil, fmt.Errorf("invalid s3 storage class %s. Supported values are %v", options.storageClass, options.storageClass.Values())
+	}
+
+	if options.objectLockMode != "" {
+		if options.objectLockMode != types.ObjectLockModeGovernance && options.objectLockMode != types.ObjectLockModeCompliance {
+			return nil, fmt.Errorf("invalid s3 object lock mode %s. Supported values are GOVERNANCE and COMPLIANCE", options.objectLockMode)
+		}
+		days, err := strconv.Atoi(strings.TrimSpace(os.Getenv("AWS_OBJECT_LOCK_RETAIN_DAYS")))
+		if err != nil || days <= 0 {
+			return nil, fmt.Errorf("AWS_OBJECT_LOCK_RETAIN_DAYS must be a positive number when AWS_OBJECT_LOCK_MODE is set")
+		}
+		options.objectLockRetainDays = days
+	}
+
+	var err error
+	options.tags, err = common.ParseKeyValuePairs(os.Getenv("OBJECT_TAGS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid object tags \n %v", err)
+	}
+	options.metadata, err = common.ParseKeyValuePairs(os.Getenv("OBJECT_METADATA"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid object metadata \n %v", err)
+	}
+	return options, nil
+}
+
+// apply sets the storage class , encryption , tags , metadata and object lock retention on the given PutObjectInput
+func (o *uploadOptions) apply(input *s3.PutObjectInput, fileName string) {
+	input.Metadata = common.MergeMetadata(o.metadata, common.ArtifactMetadata(fileName))
+	if o.storageClass != "" {
+		input.StorageClass = o.storageClass
+	}
+	if o.sseKmsKeyId != "" {
+		input.ServerSideEncryption = types.ServerSideEncryptionAwsKms
+		input.SSEKMSKeyId = aws.String(o.sseKmsKeyId)
+	}
+	if len(o.tags) != 0 {
+		input.Tagging = aws.String(encodeTags(o.tags))
+	}
+	if o.objectLockMode != "" {
+		input.ObjectLockMode = o.objectLockMode
+		input.ObjectLockRetainUntilDate = aws.Time(time.Now().UTC().AddDate(0, 0, o.objectLockRetainDays))
+		// s3 requires a checksum for any object uploaded with object lock retention
+		input.ChecksumAlgorithm = types.ChecksumAlgorithmSha256
+	}
+}
+
+// encodeTags returns the tags in the url encoded query format expected by s3 . Ex: env=prod&team=graph
+func encodeTags(tags map[string]string) string {
+	values := url.Values{}
+	for key, value := range tags {
+		values.Set(key, value)
+	}
+	return values.Encode()
+}
+
+func isValidStorageClass(storageClass types.StorageClass) bool {
+	for _, value := range storageClass.Values() {
+		if value == storageClass {
+			return true
+		}
+	}
+	return false
+}
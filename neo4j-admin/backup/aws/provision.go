@@ -0,0 +1,96 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// CreateBucket creates the s3 bucket of the given bucket name in the region of the client if it does not exist
+func (a *awsClient) CreateBucket(bucketName string) (bool, error) {
+	parentBucketName, _ := common.SplitBucketName(bucketName)
+	client := a.getS3Client()
+	_, err := client.HeadBucket(context.TODO(), &s3.HeadBucketInput{Bucket: aws.String(parentBucketName)})
+	if err == nil {
+		return false, nil
+	}
+	var notFound *types.NotFound
+	if !errors.As(err, &notFound) {
+		return false, fmt.Errorf("Unable to check if s3 bucket %s exists \n Here's why: %v\n", parentBucketName, err)
+	}
+	input := &s3.CreateBucketInput{Bucket: aws.String(parentBucketName)}
+	// us-east-1 is the default location and must not be provided as location constraint
+	if region := a.cfg.Region; region != "" && region != "us-east-1" {
+		input.CreateBucketConfiguration = &types.CreateBucketConfiguration{LocationConstraint: types.BucketLocationConstraint(region)}
+	}
+	_, err = client.CreateBucket(context.TODO(), input)
+	var owned *types.BucketAlreadyOwnedByYou
+	if errors.As(err, &owned) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Unable to create s3 bucket %s \n Here's why: %v\n", parentBucketName, err)
+	}
+	log.Printf("S3 bucket %s created in region %s !!", parentBucketName, a.cfg.Region)
+	return true, nil
+}
+
+// ApplyLifecycle applies the expiration and the abort of incomplete multipart uploads to the prefix of the given bucket name
+// The lifecycle configuration of s3 is replaced as a whole hence the rules of other prefixes are read and kept
+func (a *awsClient) ApplyLifecycle(bucketName string, policy common.LifecyclePolicy) error {
+	parentBucketName, prefix := common.SplitBucketName(bucketName)
+	client := a.getS3Client()
+	id := common.LifecycleRuleID(bucketName)
+
+	var rules []types.LifecycleRule
+	existing, err := client.GetBucketLifecycleConfiguration(context.TODO(), &s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(parentBucketName)})
+	var apiError smithy.APIError
+	switch {
+	case err == nil:
+		for _, rule := range existing.Rules {
+			if aws.ToString(rule.ID) != id {
+				rules = append(rules, rule)
+			}
+		}
+	case errors.As(err, &apiError) && apiError.ErrorCode() == "NoSuchLifecycleConfiguration":
+	default:
+		return fmt.Errorf("Unable to read the lifecycle configuration of s3 bucket %s \n Here's why: %v\n", parentBucketName, err)
+	}
+
+	rule := types.LifecycleRule{
+		ID:     aws.String(id),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilterMemberPrefix{Value: lifecyclePrefix(prefix)},
+	}
+	if policy.ExpirationDays > 0 {
+		rule.Expiration = &types.LifecycleExpiration{Days: aws.Int32(int32(policy.ExpirationDays))}
+	}
+	if policy.AbortIncompleteUploadDays > 0 {
+		rule.AbortIncompleteMultipartUpload = &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(int32(policy.AbortIncompleteUploadDays))}
+	}
+	_, err = client.PutBucketLifecycleConfiguration(context.TODO(), &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(parentBucketName),
+		LifecycleConfiguration: &types.BucketLifecycleConfiguration{Rules: append(rules, rule)},
+	})
+	if err != nil {
+		return fmt.Errorf("Unable to apply the lifecycle rule %s to s3 bucket %s \n Here's why: %v\n", id, parentBucketName, err)
+	}
+	log.Printf("Lifecycle rule %s applied to s3 bucket %s !!", id, parentBucketName)
+	return nil
+}
+
+// lifecyclePrefix returns the prefix matched by a lifecycle rule , ending with / so that sibling prefixes are not matched
+func lifecyclePrefix(prefix string) string {
+	if prefix = strings.Trim(prefix, "/"); prefix == "" {
+		return ""
+	}
+	return prefix + "/"
+}
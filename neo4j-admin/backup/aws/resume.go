@@ -0,0 +1,147 @@
+package aws
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// resumablePartSize is the part size of the multipart uploads which can be resumed
+const resumablePartSize = 1024 * 1024 * 1024
+
+// resumableUpload uploads the file using a multipart upload whose upload id is persisted in the upload state
+// A retried run lists the parts already uploaded with the same upload id and uploads only the remaining parts
+func (a *awsClient) resumableUpload(s3Client *s3.Client, file *common.UploadReader, input *s3.PutObjectInput, filePath string) error {
+	ctx := context.TODO()
+	checksum, err := common.FileChecksum(filePath)
+	if err != nil {
+		return err
+	}
+	bucket, key := aws.ToString(input.Bucket), aws.ToString(input.Key)
+
+	uploaded := make(map[int32]types.Part)
+	session, found := common.FindUploadSession("aws", bucket, key, checksum)
+	if found {
+		uploaded, err = listUploadedParts(ctx, s3Client, bucket, key, session.ID)
+		if err != nil {
+			log.Printf("Unable to resume multipart upload %s of %s , starting a new upload: %v", session.ID, key, err)
+			found = false
+			uploaded = make(map[int32]types.Part)
+		} else {
+			log.Printf("Resuming multipart upload %s of %s , %d part(s) already uploaded", session.ID, key, len(uploaded))
+		}
+	}
+	if !found {
+		output, err := s3Client.CreateMultipartUpload(ctx, createMultipartUploadInput(input))
+		if err != nil {
+			return fmt.Errorf("Couldn't create multipart upload for %v. Here's why: %v\n", key, err)
+		}
+		session = common.UploadSession{
+			CloudProvider: "aws",
+			BucketName:    bucket,
+			Key:           key,
+			Checksum:      checksum,
+			ID:            aws.ToString(output.UploadId),
+			PartSize:      resumablePartSize,
+			Created:       time.Now().UTC(),
+		}
+		if err = common.SaveUploadSession(session); err != nil {
+			return err
+		}
+	}
+
+	var completedParts []types.CompletedPart
+	size := file.Size()
+	for partNumber, offset := int32(1), int64(0); offset < size; partNumber, offset = partNumber+1, offset+session.PartSize {
+		length := session.PartSize
+		if offset+length > size {
+			length = size - offset
+		}
+		if part, present := uploaded[partNumber]; present && aws.ToInt64(part.Size) == length {
+			file.Resumed(length)
+			completedParts = append(completedParts, types.CompletedPart{
+				PartNumber:     aws.Int32(partNumber),
+				ETag:           part.ETag,
+				ChecksumSHA256: part.ChecksumSHA256,
+			})
+			continue
+		}
+		output, err := s3Client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:            input.Bucket,
+			Key:               input.Key,
+			UploadId:          aws.String(session.ID),
+			PartNumber:        aws.Int32(partNumber),
+			Body:              io.NewSectionReader(file, offset, length),
+			ContentLength:     aws.Int64(length),
+			ChecksumAlgorithm: input.ChecksumAlgorithm,
+		}, s3.WithAPIOptions(v4.SwapComputePayloadSHA256ForUnsignedPayloadMiddleware))
+		if err != nil {
+			return fmt.Errorf("Couldn't upload part %d of %v. Here's why: %v\n", partNumber, key, err)
+		}
+		completedParts = append(completedParts, types.CompletedPart{
+			PartNumber:     aws.Int32(partNumber),
+			ETag:           output.ETag,
+			ChecksumSHA256: output.ChecksumSHA256,
+		})
+	}
+
+	_, err = s3Client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          input.Bucket,
+		Key:             input.Key,
+		UploadId:        aws.String(session.ID),
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: completedParts},
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't complete multipart upload of %v. Here's why: %v\n", key, err)
+	}
+	return common.DeleteUploadSession("aws", bucket, key)
+}
+
+// listUploadedParts returns the parts already uploaded for the given upload id by part number
+func listUploadedParts(ctx context.Context, s3Client *s3.Client, bucket string, key string, uploadId string) (map[int32]types.Part, error) {
+	parts := make(map[int32]types.Part)
+	paginator := s3.NewListPartsPaginator(s3Client, &s3.ListPartsInput{
+		Bucket:   aws.String(bucket),
+		Key:      aws.String(key),
+		UploadId: aws.String(uploadId),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			var noSuchUpload *types.NoSuchUpload
+			if errors.As(err, &noSuchUpload) {
+				return nil, fmt.Errorf("multipart upload %s does not exist anymore", uploadId)
+			}
+			return nil, err
+		}
+		for _, part := range page.Parts {
+			parts[aws.ToInt32(part.PartNumber)] = part
+		}
+	}
+	return parts, nil
+}
+
+// createMultipartUploadInput returns the CreateMultipartUploadInput carrying the same storage options as the PutObjectInput
+func createMultipartUploadInput(input *s3.PutObjectInput) *s3.CreateMultipartUploadInput {
+	return &s3.CreateMultipartUploadInput{
+		Bucket:                    input.Bucket,
+		Key:                       input.Key,
+		Metadata:                  input.Metadata,
+		StorageClass:              input.StorageClass,
+		ServerSideEncryption:      input.ServerSideEncryption,
+		SSEKMSKeyId:               input.SSEKMSKeyId,
+		Tagging:                   input.Tagging,
+		ObjectLockMode:            input.ObjectLockMode,
+		ObjectLockRetainUntilDate: input.ObjectLockRetainUntilDate,
+		ChecksumAlgorithm:         input.ChecksumAlgorithm,
+	}
+}
@@ -11,7 +11,8 @@ import (
 )
 
 type azureClient struct {
-	client *azblob.Client
+	client        *azblob.Client
+	uploadOptions *uploadOptions
 }
 
 func NewAzureClient(credentialPath string) (*azureClient, error) {
@@ -57,8 +58,14 @@ func NewAzureClient(credentialPath string) (*azureClient, error) {
 		}
 	}
 
+	options, err := getUploadOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	return &azureClient{
-		client: client,
+		client:        client,
+		uploadOptions: options,
 	}, nil
 }
 
@@ -0,0 +1,37 @@
+package azure
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// CheckCredentials checks the format of the credential file , or the storage account and the workload identity when the service account is used
+// It returns a description of the credentials without connecting to azure
+func CheckCredentials(credentialPath string) (string, error) {
+	if credentialPath == "/credentials/" {
+		storageAccountName := strings.TrimSpace(os.Getenv("AZURE_STORAGE_ACCOUNT_NAME"))
+		if storageAccountName == "" {
+			return "", fmt.Errorf("missing AZURE_STORAGE_ACCOUNT_NAME required along with the service account")
+		}
+		if tokenFile := os.Getenv("AZURE_FEDERATED_TOKEN_FILE"); tokenFile != "" {
+			if _, err := os.Stat(tokenFile); err != nil {
+				return "", fmt.Errorf("unable to read the federated token of the workload identity \n %v", err)
+			}
+			return fmt.Sprintf("workload identity of client %s for storage account %s", os.Getenv("AZURE_CLIENT_ID"), storageAccountName), nil
+		}
+		return fmt.Sprintf("default azure credential (managed identity) for storage account %s", storageAccountName), nil
+	}
+	data, err := os.ReadFile(credentialPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to open azure credential file \n %v", err)
+	}
+	storageAccountName, err := getStorageAccountName(string(data))
+	if err != nil {
+		return "", fmt.Errorf("invalid azure credential file %s , expected AZURE_STORAGE_ACCOUNT_NAME=<name> \n %v", credentialPath, err)
+	}
+	if _, err = getStorageAccountKey(string(data)); err != nil {
+		return "", fmt.Errorf("invalid azure credential file %s , expected AZURE_STORAGE_ACCOUNT_KEY=<key> \n %v", credentialPath, err)
+	}
+	return fmt.Sprintf("shared key of storage account %s", strings.TrimSpace(storageAccountName)), nil
+}
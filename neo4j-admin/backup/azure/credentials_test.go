@@ -0,0 +1,45 @@
+package azure
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	t.Setenv("AZURE_FEDERATED_TOKEN_FILE", "")
+
+	tests := []struct {
+		name               string
+		credentials        string
+		credentialPath     string
+		storageAccountName string
+		want               string
+		wantErr            string
+	}{
+		{name: "valid file", credentials: "AZURE_STORAGE_ACCOUNT_NAME=neo4jbackups\nAZURE_STORAGE_ACCOUNT_KEY=a2V5\n", want: "shared key of storage account neo4jbackups"},
+		{name: "missing key", credentials: "AZURE_STORAGE_ACCOUNT_NAME=neo4jbackups\n", wantErr: "expected AZURE_STORAGE_ACCOUNT_KEY=<key>"},
+		{name: "malformed name", credentials: "AZURE_STORAGE_ACCOUNT: neo4jbackups\nAZURE_STORAGE_ACCOUNT_KEY=a2V5\n", wantErr: "expected AZURE_STORAGE_ACCOUNT_NAME=<name>"},
+		{name: "workload identity", credentialPath: "/credentials/", storageAccountName: "neo4jbackups", want: "default azure credential (managed identity) for storage account neo4jbackups"},
+		{name: "missing storage account", credentialPath: "/credentials/", wantErr: "missing AZURE_STORAGE_ACCOUNT_NAME"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentialPath := tt.credentialPath
+			if credentialPath == "" {
+				credentialPath = filepath.Join(t.TempDir(), "credentials")
+				assert.NoError(t, os.WriteFile(credentialPath, []byte(tt.credentials), 0600))
+			}
+			t.Setenv("AZURE_STORAGE_ACCOUNT_NAME", tt.storageAccountName)
+			got, err := CheckCredentials(credentialPath)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
@@ -5,12 +5,19 @@ import (
 	"fmt"
 	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
 	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
 	"golang.org/x/net/context"
 	"log"
 	"os"
 	"strings"
 )
 
+// CheckBucketAccess checks if the given container name is accessible or not
+func (a *azureClient) CheckBucketAccess(containerName string) error {
+	return a.CheckContainerAccess(containerName)
+}
+
+// CheckContainerAccess checks if the given container name is accessible or not
 func (a *azureClient) CheckContainerAccess(containerName string) error {
 
 	prefix := ""
@@ -56,9 +63,9 @@ func (a *azureClient) UploadFile(fileNames []string, containerName string) error
 	for _, fileName := range fileNames {
 
 		filePath := fmt.Sprintf("%s/%s", location, fileName)
-		file, err := os.Open(filePath)
+		file, err := common.OpenUploadFile(filePath, containerName)
 		if err != nil {
-			return fmt.Errorf("Couldn't open file %v to upload. Here's why: %v\n", filePath, err)
+			return err
 		}
 
 		name := fileName
@@ -66,8 +73,14 @@ func (a *azureClient) UploadFile(fileNames []string, containerName string) error
 			name = fmt.Sprintf("%s/%s", prefix, fileName)
 		}
 		log.Printf("Starting upload of file %s", filePath)
-		_, err = a.client.UploadFile(context.TODO(), parentContainerName, name, file, nil)
+		// large files are staged as blocks which a retried run can continue
+		if common.ResumableUploadsEnabled() && file.Size() >= resumableThreshold {
+			err = a.resumableUpload(file, parentContainerName, name, fileName, filePath)
+		} else {
+			_, err = a.client.UploadStream(context.TODO(), parentContainerName, name, file, a.uploadOptions.uploadStreamOptions(fileName, file.Size()))
+		}
 		if err != nil {
+			file.Close()
 			return fmt.Errorf("Couldn't upload file %v to %v Here's why: %v\n", filePath, containerName, err)
 		}
 		log.Printf("File %s uploaded to azure container %s !!", fileName, containerName)
@@ -75,3 +88,67 @@ func (a *azureClient) UploadFile(fileNames []string, containerName string) error
 	}
 	return nil
 }
+
+// ListObjects returns all the blobs present below the given container name and prefix
+func (a *azureClient) ListObjects(containerName string) ([]common.ObjectInfo, error) {
+	parentContainerName, prefix := common.SplitBucketName(containerName)
+	options := &azblob.ListBlobsFlatOptions{
+		Include: azblob.ListBlobsInclude{Metadata: true},
+	}
+	if prefix != "" {
+		prefix = fmt.Sprintf("%s/", strings.TrimSuffix(prefix, "/"))
+		options.Prefix = &prefix
+	}
+
+	var objects []common.ObjectInfo
+	pager := a.client.NewListBlobsFlatPager(parentContainerName, options)
+	for pager.More() {
+		page, err := pager.NextPage(context.TODO())
+		if err != nil {
+			return nil, fmt.Errorf("Unable to list blobs of azure container %s \n Here's why: %v", containerName, err)
+		}
+		for _, item := range page.Segment.BlobItems {
+			objectInfo := common.ObjectInfo{
+				Key:      *item.Name,
+				Metadata: fromAzureMetadata(item.Metadata),
+			}
+			if item.Properties != nil {
+				if item.Properties.ContentLength != nil {
+					objectInfo.Size = *item.Properties.ContentLength
+				}
+				if item.Properties.LastModified != nil {
+					objectInfo.LastModified = *item.Properties.LastModified
+				}
+			}
+			objects = append(objects, objectInfo)
+		}
+	}
+	return objects, nil
+}
+
+// DownloadFile downloads the blob with the given key from the azure container to the given file path
+func (a *azureClient) DownloadFile(containerName string, key string, filePath string) error {
+	parentContainerName, _ := common.SplitBucketName(containerName)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Couldn't create file %v to download. Here's why: %v\n", filePath, err)
+	}
+	defer file.Close()
+
+	log.Printf("Starting download of blob %s from azure container %s", key, parentContainerName)
+	if _, err = a.client.DownloadFile(context.TODO(), parentContainerName, key, file, nil); err != nil {
+		return fmt.Errorf("Couldn't download blob %v from %v Here's why: %v\n", key, parentContainerName, err)
+	}
+	log.Printf("Blob %s downloaded to %s !!", key, filePath)
+	return nil
+}
+
+// DeleteObject deletes the blob with the given key from the azure container
+func (a *azureClient) DeleteObject(containerName string, key string) error {
+	parentContainerName, _ := common.SplitBucketName(containerName)
+	if _, err := a.client.DeleteBlob(context.TODO(), parentContainerName, key, nil); err != nil {
+		return fmt.Errorf("Couldn't delete blob %v from %v Here's why: %v\n", key, parentContainerName, err)
+	}
+	log.Printf("Blob %s deleted from azure container %s !!", key, parentContainerName)
+	return nil
+}
@@ -0,0 +1,108 @@
+package azure
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blob"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+const (
+	defaultBlockSize = 8 * 1024 * 1024
+	// maxBlocks is the maximum number of blocks of a block blob
+	maxBlocks         = 50000
+	uploadConcurrency = 4
+)
+
+// uploadOptions contains the azure specific options applied to every uploaded blob
+type uploadOptions struct {
+	accessTier      blob.AccessTier
+	encryptionScope string
+	tags            map[string]string
+	metadata        map[string]string
+}
+
+// getUploadOptions reads the azure upload options from the below env variables
+// AZURE_ACCESS_TIER , AZURE_ENCRYPTION_SCOPE , OBJECT_TAGS , OBJECT_METADATA
+func getUploadOptions() (*uploadOptions, error) {
+	options := &uploadOptions{
+		accessTier:      blob.AccessTier(strings.TrimSpace(os.Getenv("AZURE_ACCESS_TIER"))),
+		encryptionScope: strings.TrimSpace(os.Getenv("AZURE_ENCRYPTION_SCOPE")),
+	}
+	if options.accessTier != "" && !isValidAccessTier(options.accessTier) {
+		return nil, fmt.Errorf("invalid azure access tier %s. Supported values are Hot, Cool, Cold and Archive", options.accessTier)
+	}
+	var err error
+	options.tags, err = common.ParseKeyValuePairs(os.Getenv("OBJECT_TAGS"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob index tags \n %v", err)
+	}
+	if len(options.tags) > 10 {
+		return nil, fmt.Errorf("azure supports a maximum of 10 blob index tags , found %d", len(options.tags))
+	}
+	options.metadata, err = common.ParseKeyValuePairs(os.Getenv("OBJECT_METADATA"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid object metadata \n %v", err)
+	}
+	return options, nil
+}
+
+// uploadStreamOptions returns the UploadStreamOptions containing the access tier , blob index tags , encryption scope and metadata
+// The block size grows with the file size since a block blob can contain at most 50000 blocks
+func (o *uploadOptions) uploadStreamOptions(fileName string, fileSize int64) *azblob.UploadStreamOptions {
+	blockSize := int64(defaultBlockSize)
+	if minBlockSize := (fileSize + maxBlocks - 1) / maxBlocks; minBlockSize > blockSize {
+		blockSize = minBlockSize
+	}
+	streamOptions := &azblob.UploadStreamOptions{
+		BlockSize:   blockSize,
+		Concurrency: uploadConcurrency,
+		Metadata:    toAzureMetadata(common.MergeMetadata(o.metadata, common.ArtifactMetadata(fileName))),
+	}
+	if o.accessTier != "" {
+		streamOptions.AccessTier = &o.accessTier
+	}
+	if len(o.tags) != 0 {
+		streamOptions.Tags = o.tags
+	}
+	if o.encryptionScope != "" {
+		streamOptions.CPKScopeInfo = &blob.CPKScopeInfo{
+			EncryptionScope: &o.encryptionScope,
+		}
+	}
+	return streamOptions
+}
+
+// toAzureMetadata converts the metadata to the format expected by azure
+// azure metadata keys must be valid c# identifiers hence '-' is replaced with '_'
+func toAzureMetadata(metadata map[string]string) map[string]*string {
+	azureMetadata := make(map[string]*string, len(metadata))
+	for key, value := range metadata {
+		v := value
+		azureMetadata[strings.ReplaceAll(key, "-", "_")] = &v
+	}
+	return azureMetadata
+}
+
+func isValidAccessTier(accessTier blob.AccessTier) bool {
+	for _, value := range []blob.AccessTier{blob.AccessTierHot, blob.AccessTierCool, blob.AccessTierCold, blob.AccessTierArchive} {
+		if value == accessTier {
+			return true
+		}
+	}
+	return false
+}
+
+// fromAzureMetadata converts the azure metadata back to the format used while uploading
+func fromAzureMetadata(azureMetadata map[string]*string) map[string]string {
+	metadata := make(map[string]string, len(azureMetadata))
+	for key, value := range azureMetadata {
+		if value != nil {
+			metadata[strings.ReplaceAll(strings.ToLower(key), "_", "-")] = *value
+		}
+	}
+	return metadata
+}
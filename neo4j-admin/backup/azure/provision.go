@@ -0,0 +1,25 @@
+package azure
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/bloberror"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"golang.org/x/net/context"
+)
+
+// CreateBucket creates the azure container of the given container name if it does not exist
+// The lifecycle rules of azure are managed on the storage account hence the client does not apply them
+func (a *azureClient) CreateBucket(containerName string) (bool, error) {
+	parentContainerName, _ := common.SplitBucketName(containerName)
+	_, err := a.client.CreateContainer(context.TODO(), parentContainerName, nil)
+	if bloberror.HasCode(err, bloberror.ContainerAlreadyExists) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("Unable to create azure container %s \n Here's why: %v", parentContainerName, err)
+	}
+	log.Printf("Azure container %s created !!", parentContainerName)
+	return true, nil
+}
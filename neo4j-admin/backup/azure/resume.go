@@ -0,0 +1,97 @@
+package azure
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore/streaming"
+	"github.com/Azure/azure-sdk-for-go/sdk/storage/azblob/blockblob"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// resumableThreshold is the minimum file size uploaded via staged blocks which a retried run can continue
+const resumableThreshold = 1024 * 1024 * 1024
+
+// resumableUpload stages the file as blocks whose id prefix is persisted in the upload state and commits the block list at the end
+// A retried run lists the uncommitted blocks of the blob (kept by azure for 7 days) and stages only the missing blocks
+func (a *azureClient) resumableUpload(file *common.UploadReader, containerName string, name string, fileName string, filePath string) error {
+	ctx := context.TODO()
+	checksum, err := common.FileChecksum(filePath)
+	if err != nil {
+		return err
+	}
+	blobClient := a.client.ServiceClient().NewContainerClient(containerName).NewBlockBlobClient(name)
+	streamOptions := a.uploadOptions.uploadStreamOptions(fileName, file.Size())
+
+	staged := make(map[string]int64)
+	session, found := common.FindUploadSession("azure", containerName, name, checksum)
+	if found {
+		blockList, err := blobClient.GetBlockList(ctx, blockblob.BlockListTypeUncommitted, nil)
+		if err != nil {
+			log.Printf("Unable to list the uncommitted blocks of %s , starting a new upload: %v", name, err)
+			found = false
+		} else {
+			for _, block := range blockList.UncommittedBlocks {
+				if block.Name != nil && block.Size != nil {
+					staged[*block.Name] = *block.Size
+				}
+			}
+			log.Printf("Resuming upload of %s , %d block(s) already staged", name, len(staged))
+		}
+	}
+	if !found {
+		prefix := make([]byte, 8)
+		if _, err = rand.Read(prefix); err != nil {
+			return err
+		}
+		session = common.UploadSession{
+			CloudProvider: "azure",
+			BucketName:    containerName,
+			Key:           name,
+			Checksum:      checksum,
+			ID:            hex.EncodeToString(prefix),
+			PartSize:      streamOptions.BlockSize,
+			Created:       time.Now().UTC(),
+		}
+		if err = common.SaveUploadSession(session); err != nil {
+			return err
+		}
+	}
+
+	var blockIDs []string
+	size := file.Size()
+	for index, offset := 0, int64(0); offset < size; index, offset = index+1, offset+session.PartSize {
+		length := session.PartSize
+		if offset+length > size {
+			length = size - offset
+		}
+		// every block id of a blob must have the same length
+		blockID := base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s-%06d", session.ID, index)))
+		blockIDs = append(blockIDs, blockID)
+		if stagedSize, present := staged[blockID]; present && stagedSize == length {
+			file.Resumed(length)
+			continue
+		}
+		body := streaming.NopCloser(io.NewSectionReader(file, offset, length))
+		if _, err = blobClient.StageBlock(ctx, blockID, body, nil); err != nil {
+			return fmt.Errorf("Couldn't stage block %d of %v. Here's why: %v\n", index, name, err)
+		}
+	}
+
+	_, err = blobClient.CommitBlockList(ctx, blockIDs, &blockblob.CommitBlockListOptions{
+		Tags:         streamOptions.Tags,
+		Metadata:     streamOptions.Metadata,
+		Tier:         streamOptions.AccessTier,
+		CPKScopeInfo: streamOptions.CPKScopeInfo,
+	})
+	if err != nil {
+		return fmt.Errorf("Couldn't commit the block list of %v. Here's why: %v\n", name, err)
+	}
+	return common.DeleteUploadSession("azure", containerName, name)
+}
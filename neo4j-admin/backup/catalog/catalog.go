@@ -0,0 +1,228 @@
+package catalog
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+const (
+	TypeFull    = "FULL"
+	TypeDiff    = "DIFF"
+	TypeUnknown = "UNKNOWN"
+)
+
+// Artifact describes a single backup artifact present in the bucket
+type Artifact struct {
+	Key      string    `json:"key"`
+	FileName string    `json:"fileName"`
+	Database string    `json:"database"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Size     int64     `json:"size"`
+	// ConsistencyCheckReport is the key of the consistency check report generated for this artifact (if any)
+	ConsistencyCheckReport string `json:"consistencyCheckReport,omitempty"`
+}
+
+// Chain is a full backup followed by the differential backups taken on top of it
+// Full is nil when the differential backups of the chain do not have a full backup present in the bucket
+// Unknown contains the artifacts of unknown type taken during the chain (ex: uploaded without the backup type metadata) ,
+// they may be full or differential backups hence the chain cannot be restored
+type Chain struct {
+	Full          *Artifact  `json:"full,omitempty"`
+	Differentials []Artifact `json:"differentials"`
+	Unknown       []Artifact `json:"unknown,omitempty"`
+}
+
+// Database contains all the backup chains of a single database ordered from the oldest to the newest
+type Database struct {
+	Name   string  `json:"name"`
+	Chains []Chain `json:"chains"`
+}
+
+// Catalog contains the backup chains of every database present in the bucket
+type Catalog struct {
+	Databases []Database `json:"databases"`
+}
+
+// Build groups the backup artifacts present in the given list of objects by database into full/differential chains
+// Consistency check reports are linked to the latest backup artifact of the same database taken before the report was generated
+func Build(objects []common.ObjectInfo) *Catalog {
+	artifactsByDatabase := make(map[string][]Artifact)
+	var reports []common.ObjectInfo
+	for _, object := range objects {
+		fileName := path.Base(object.Key)
+		if common.ArtifactType(fileName) == common.ArtifactTypeConsistencyCheck {
+			reports = append(reports, object)
+			continue
+		}
+		if !strings.HasSuffix(fileName, ".backup") {
+			continue
+		}
+		artifact, ok := newArtifact(object)
+		if !ok {
+			continue
+		}
+		artifactsByDatabase[artifact.Database] = append(artifactsByDatabase[artifact.Database], artifact)
+	}
+
+	for database, artifacts := range artifactsByDatabase {
+		sort.SliceStable(artifacts, func(i, j int) bool {
+			return artifacts[i].Time.Before(artifacts[j].Time)
+		})
+		linkReports(artifacts, reports)
+		artifactsByDatabase[database] = artifacts
+	}
+
+	catalog := &Catalog{}
+	for _, name := range sortedDatabaseNames(artifactsByDatabase) {
+		catalog.Databases = append(catalog.Databases, Database{
+			Name:   name,
+			Chains: buildChains(artifactsByDatabase[name]),
+		})
+	}
+	return catalog
+}
+
+// FindDatabase returns the catalog entry of the given database
+func (c *Catalog) FindDatabase(name string) (Database, bool) {
+	for _, database := range c.Databases {
+		if database.Name == name {
+			return database, true
+		}
+	}
+	return Database{}, false
+}
+
+// Artifacts returns all the artifacts of the chain ordered from the oldest to the newest
+func (c Chain) Artifacts() []Artifact {
+	var artifacts []Artifact
+	if c.Full != nil {
+		artifacts = append(artifacts, *c.Full)
+	}
+	artifacts = append(artifacts, c.Differentials...)
+	if len(c.Unknown) == 0 {
+		return artifacts
+	}
+	artifacts = append(artifacts, c.Unknown...)
+	sort.SliceStable(artifacts, func(i, j int) bool {
+		return artifacts[i].Time.Before(artifacts[j].Time)
+	})
+	return artifacts
+}
+
+// Validate returns an error naming the artifact which prevents the chain from being restored ,
+// ex: a chain without a full backup or containing an artifact of unknown type
+func (c Chain) Validate() error {
+	if c.Full == nil || c.Full.Type != TypeFull {
+		oldest := c.Artifacts()[0]
+		return fmt.Errorf("the backup chain of database %s starting with %s does not start with a full backup", oldest.Database, oldest.Key)
+	}
+	if len(c.Unknown) > 0 {
+		return fmt.Errorf("the backup chain of database %s contains %s whose backup type is unknown. It may be a full or a differential backup and cannot be restored as part of the chain",
+			c.Full.Database, c.Unknown[0].Key)
+	}
+	return nil
+}
+
+// Size returns the total size of all the artifacts of the chain
+func (c Chain) Size() int64 {
+	var size int64
+	for _, artifact := range c.Artifacts() {
+		size += artifact.Size
+	}
+	return size
+}
+
+// Latest returns the newest artifact of the chain
+func (c Chain) Latest() Artifact {
+	artifacts := c.Artifacts()
+	return artifacts[len(artifacts)-1]
+}
+
+func newArtifact(object common.ObjectInfo) (Artifact, bool) {
+	fileName := path.Base(object.Key)
+	database, timeStamp, err := common.ParseArtifactName(fileName)
+	if err != nil {
+		return Artifact{}, false
+	}
+	if value := object.Metadata["database"]; value != "" {
+		database = value
+	}
+	artifactTime, err := time.Parse("2006-01-02T15-04-05", timeStamp)
+	if err != nil {
+		artifactTime = object.LastModified
+	}
+	backupType := strings.ToUpper(object.Metadata["backup-type"])
+	if backupType != TypeFull && backupType != TypeDiff {
+		backupType = TypeUnknown
+	}
+	return Artifact{
+		Key:      object.Key,
+		FileName: fileName,
+		Database: database,
+		Type:     backupType,
+		Time:     artifactTime,
+		Size:     object.Size,
+	}, true
+}
+
+// buildChains groups the time ordered artifacts into chains
+// A FULL backup starts a new chain , DIFF backups are added to the current chain
+// Artifacts with an unknown type are added to the current chain as well , since the following artifacts may depend on them
+// they are never promoted to the full backup of a chain
+func buildChains(artifacts []Artifact) []Chain {
+	var chains []Chain
+	current := -1
+	for i := range artifacts {
+		artifact := artifacts[i]
+		if artifact.Type == TypeFull {
+			chains = append(chains, Chain{Full: &artifact, Differentials: []Artifact{}})
+			current = len(chains) - 1
+			continue
+		}
+		if current == -1 {
+			chains = append(chains, Chain{Differentials: []Artifact{}})
+			current = len(chains) - 1
+		}
+		if artifact.Type == TypeDiff {
+			chains[current].Differentials = append(chains[current].Differentials, artifact)
+		} else {
+			chains[current].Unknown = append(chains[current].Unknown, artifact)
+		}
+	}
+	return chains
+}
+
+// linkReports links every consistency check report to the latest artifact of the same database taken before the report
+func linkReports(artifacts []Artifact, reports []common.ObjectInfo) {
+	for _, report := range reports {
+		database, timeStamp, err := common.ParseArtifactName(path.Base(report.Key))
+		if err != nil || len(artifacts) == 0 || artifacts[0].Database != database {
+			continue
+		}
+		reportTime, err := time.Parse("2006-01-02T15-04-05", timeStamp)
+		if err != nil {
+			continue
+		}
+		for i := len(artifacts) - 1; i >= 0; i-- {
+			if !artifacts[i].Time.After(reportTime) {
+				artifacts[i].ConsistencyCheckReport = report.Key
+				break
+			}
+		}
+	}
+}
+
+func sortedDatabaseNames(artifactsByDatabase map[string][]Artifact) []string {
+	names := make([]string, 0, len(artifactsByDatabase))
+	for name := range artifactsByDatabase {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,327 @@
+package catalog
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func testObjects() []common.ObjectInfo {
+	return []common.ObjectInfo{
+		{Key: "team/neo4j-2024-06-10T10-00-00.backup", Size: 1024, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/neo4j-2024-06-11T10-00-00.backup", Size: 10, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/neo4j-2024-06-12T10-00-00.backup", Size: 20, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/neo4j-2024-06-12T10-05-00.backup.report.tar.gz", Size: 5},
+		{Key: "team/neo4j-2024-06-13T10-00-00.backup", Size: 2048, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/system-2024-06-09T10-00-00.backup", Size: 30, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/other.yaml", Size: 1},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	t.Parallel()
+
+	backupCatalog := Build(testObjects())
+	assert.Len(t, backupCatalog.Databases, 2)
+
+	neo4j, present := backupCatalog.FindDatabase("neo4j")
+	assert.True(t, present)
+	assert.Len(t, neo4j.Chains, 2)
+	assert.Equal(t, "neo4j-2024-06-10T10-00-00.backup", neo4j.Chains[0].Full.FileName)
+	assert.Len(t, neo4j.Chains[0].Differentials, 2)
+	assert.Equal(t, int64(1054), neo4j.Chains[0].Size())
+	assert.Equal(t, "team/neo4j-2024-06-12T10-05-00.backup.report.tar.gz", neo4j.Chains[0].Latest().ConsistencyCheckReport)
+	assert.Len(t, neo4j.Chains[1].Differentials, 0)
+
+	system, present := backupCatalog.FindDatabase("system")
+	assert.True(t, present)
+	assert.Nil(t, system.Chains[0].Full, "differential without a full backup must not have a full artifact")
+}
+
+// unknownObjects mixes artifacts uploaded without the backup type metadata with full and differential backups
+func unknownObjects() []common.ObjectInfo {
+	return []common.ObjectInfo{
+		{Key: "team/legacy-2024-06-01T10-00-00.backup", Size: 100},
+		{Key: "team/legacy-2024-06-02T10-00-00.backup", Size: 10},
+		{Key: "team/neo4j-2024-06-10T10-00-00.backup", Size: 1024, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/neo4j-2024-06-11T10-00-00.backup", Size: 10},
+		{Key: "team/neo4j-2024-06-12T10-00-00.backup", Size: 20, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/neo4j-2024-06-13T10-00-00.backup", Size: 1024, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/neo4j-2024-06-14T10-00-00.backup", Size: 30, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/neo4j-2024-06-15T10-00-00.backup", Size: 40, Metadata: map[string]string{"backup-type": "unexpected"}},
+		{Key: "team/orders-2024-06-10T10-00-00.backup", Size: 10, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/orders-2024-06-11T10-00-00.backup", Size: 10},
+		{Key: "team/orders-2024-06-12T10-00-00.backup", Size: 10, Metadata: map[string]string{"backup-type": "DIFF"}},
+	}
+}
+
+func TestBuildUnknownArtifacts(t *testing.T) {
+	t.Parallel()
+
+	backupCatalog := Build(unknownObjects())
+	tests := []struct {
+		name       string
+		database   string
+		wantChains [][]string
+		wantFull   []string
+		wantErrors []string
+	}{
+		{
+			name:       "artifacts uploaded before the metadata existed",
+			database:   "legacy",
+			wantChains: [][]string{{"legacy-2024-06-01T10-00-00.backup", "legacy-2024-06-02T10-00-00.backup"}},
+			wantFull:   []string{""},
+			wantErrors: []string{"the backup chain of database legacy starting with team/legacy-2024-06-01T10-00-00.backup does not start with a full backup"},
+		},
+		{
+			name:     "unknown artifacts within chains",
+			database: "neo4j",
+			wantChains: [][]string{
+				{"neo4j-2024-06-10T10-00-00.backup", "neo4j-2024-06-11T10-00-00.backup", "neo4j-2024-06-12T10-00-00.backup"},
+				{"neo4j-2024-06-13T10-00-00.backup", "neo4j-2024-06-14T10-00-00.backup", "neo4j-2024-06-15T10-00-00.backup"},
+			},
+			wantFull: []string{"neo4j-2024-06-10T10-00-00.backup", "neo4j-2024-06-13T10-00-00.backup"},
+			wantErrors: []string{
+				"the backup chain of database neo4j contains team/neo4j-2024-06-11T10-00-00.backup whose backup type is unknown",
+				"the backup chain of database neo4j contains team/neo4j-2024-06-15T10-00-00.backup whose backup type is unknown",
+			},
+		},
+		{
+			name:       "unknown artifact between differential backups",
+			database:   "orders",
+			wantChains: [][]string{{"orders-2024-06-10T10-00-00.backup", "orders-2024-06-11T10-00-00.backup", "orders-2024-06-12T10-00-00.backup"}},
+			wantFull:   []string{""},
+			wantErrors: []string{"the backup chain of database orders starting with team/orders-2024-06-10T10-00-00.backup does not start with a full backup"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entry, present := backupCatalog.FindDatabase(tt.database)
+			assert.True(t, present)
+			var chains [][]string
+			var fulls []string
+			for i, chain := range entry.Chains {
+				var fileNames []string
+				for _, artifact := range chain.Artifacts() {
+					fileNames = append(fileNames, artifact.FileName)
+				}
+				chains = append(chains, fileNames)
+				full := ""
+				if chain.Full != nil {
+					full = chain.Full.FileName
+					assert.Equal(t, TypeFull, chain.Full.Type, "an artifact of unknown type is never the full backup of a chain")
+				}
+				fulls = append(fulls, full)
+				assert.ErrorContains(t, chain.Validate(), tt.wantErrors[i])
+			}
+			assert.Equal(t, tt.wantChains, chains)
+			assert.Equal(t, tt.wantFull, fulls)
+		})
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	now := time.Date(2024, 6, 14, 10, 0, 0, 0, time.UTC)
+	assert.NoError(t, Build(testObjects()).WriteTable(&buffer, now))
+	assert.Contains(t, buffer.String(), "team/neo4j-2024-06-13T10-00-00.backup")
+	assert.Contains(t, buffer.String(), "2.0KiB")
+	assert.Contains(t, buffer.String(), "1d0h")
+}
+
+func TestExpiredChains(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 14, 10, 0, 0, 0, time.UTC)
+	neo4j, _ := Build(testObjects()).FindDatabase("neo4j")
+
+	assert.Len(t, ExpiredChains(neo4j, 0, 0, now, false), 0, "no retention policy must not expire any chain")
+	assert.Len(t, ExpiredChains(neo4j, 1, 0, now, false), 1, "only the newest chain must be retained")
+	assert.Len(t, ExpiredChains(neo4j, 2, 0, now, false), 0)
+	assert.Len(t, ExpiredChains(neo4j, 0, 24*time.Hour, now, false), 1)
+	assert.Len(t, ExpiredChains(neo4j, 0, time.Hour, now, false), 1, "the newest chain must always be retained")
+
+	// FULL , DIFF | FULL , UNKNOWN , DIFF | FULL , UNKNOWN
+	objects := []common.ObjectInfo{
+		{Key: "team/neo4j-2024-06-08T10-00-00.backup", Size: 1024, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/neo4j-2024-06-09T10-00-00.backup", Size: 10, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/neo4j-2024-06-10T10-00-00.backup", Size: 1024, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/neo4j-2024-06-11T10-00-00.backup", Size: 10},
+		{Key: "team/neo4j-2024-06-12T10-00-00.backup", Size: 20, Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "team/neo4j-2024-06-13T10-00-00.backup", Size: 1024, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/neo4j-2024-06-13T12-00-00.backup", Size: 10},
+	}
+	neo4j, _ = Build(objects).FindDatabase("neo4j")
+	assert.Len(t, neo4j.Chains, 3)
+	expired := ExpiredChains(neo4j, 1, 0, now, false)
+	assert.Empty(t, expired, "the chains containing unknown artifacts do not count , the only restorable chain is retained")
+	expired = ExpiredChains(neo4j, 0, time.Hour, now, false)
+	if assert.Len(t, expired, 1, "the newest restorable chain is retained whatever its age") {
+		assert.Equal(t, "neo4j-2024-06-10T10-00-00.backup", expired[0].Full.FileName)
+	}
+
+	legacy, _ := Build(unknownObjects()).FindDatabase("legacy")
+	assert.Empty(t, ExpiredChains(legacy, 1, time.Hour, now, false), "the newest chain is retained")
+
+	// UNKNOWN , UNKNOWN | FULL , DIFF
+	objects = append(unknownObjects()[:2], []common.ObjectInfo{
+		{Key: "team/legacy-2024-06-10T10-00-00.backup", Size: 1024, Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "team/legacy-2024-06-11T10-00-00.backup", Size: 10, Metadata: map[string]string{"backup-type": "DIFF"}},
+	}...)
+	legacy, _ = Build(objects).FindDatabase("legacy")
+	assert.Len(t, legacy.Chains, 2)
+	assert.Empty(t, ExpiredChains(legacy, 1, time.Hour, now, false), "the chains of unknown artifacts are kept unless pruneUnknown is set")
+	expired = ExpiredChains(legacy, 1, time.Hour, now, true)
+	if assert.Len(t, expired, 1) {
+		assert.Nil(t, expired[0].Full)
+		assert.Len(t, expired[0].Unknown, 2)
+	}
+}
+
+func TestPlanAt(t *testing.T) {
+	t.Parallel()
+
+	backupCatalog := Build(testObjects())
+	tests := []struct {
+		name             string
+		database         string
+		at               string
+		wantArtifacts    []string
+		wantCoveredUntil string
+		wantError        string
+	}{
+		{
+			name:             "within a chain",
+			database:         "neo4j",
+			at:               "2024-06-11 14:00:00",
+			wantArtifacts:    []string{"neo4j-2024-06-10T10-00-00.backup", "neo4j-2024-06-11T10-00-00.backup"},
+			wantCoveredUntil: "2024-06-11T10:00:00Z",
+		},
+		{
+			name:             "at an artifact time",
+			database:         "neo4j",
+			at:               "2024-06-12T10:00:00Z",
+			wantArtifacts:    []string{"neo4j-2024-06-10T10-00-00.backup", "neo4j-2024-06-11T10-00-00.backup", "neo4j-2024-06-12T10-00-00.backup"},
+			wantCoveredUntil: "2024-06-12T10:00:00Z",
+		},
+		{
+			name:             "with a zone",
+			database:         "neo4j",
+			at:               "2024-06-13T11:00:00+02:00",
+			wantArtifacts:    []string{"neo4j-2024-06-10T10-00-00.backup", "neo4j-2024-06-11T10-00-00.backup", "neo4j-2024-06-12T10-00-00.backup"},
+			wantCoveredUntil: "2024-06-12T10:00:00Z",
+		},
+		{
+			name:             "latest chain",
+			database:         "neo4j",
+			at:               "2024-07-01",
+			wantArtifacts:    []string{"neo4j-2024-06-13T10-00-00.backup"},
+			wantCoveredUntil: "2024-06-13T10:00:00Z",
+		},
+		{name: "before the first backup", database: "neo4j", at: "2024-06-10 09:59:59", wantError: "the oldest backup of database neo4j was taken at 2024-06-10T10:00:00Z"},
+		{name: "missing full backup", database: "system", at: "2024-06-14", wantError: "without a full backup in the bucket"},
+		{name: "unknown database", database: "orders-archive", at: "2024-06-14", wantError: "no backup of database orders-archive found"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := ParseTime(tt.at)
+			assert.NoError(t, err)
+			plan, err := backupCatalog.PlanAt(tt.database, at)
+			if tt.wantError != "" {
+				assert.ErrorIs(t, err, ErrNoCoverage)
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			assert.NoError(t, err)
+			var fileNames []string
+			for _, artifact := range plan.Artifacts {
+				fileNames = append(fileNames, artifact.FileName)
+			}
+			assert.Equal(t, tt.wantArtifacts, fileNames)
+			assert.Equal(t, tt.wantCoveredUntil, plan.CoveredUntil.Format(time.RFC3339))
+		})
+	}
+
+	_, err := ParseTime("last tuesday")
+	assert.ErrorContains(t, err, "invalid time last tuesday")
+}
+
+func TestPlanAtUnknownArtifacts(t *testing.T) {
+	t.Parallel()
+
+	full := Artifact{Key: "team/neo4j-2024-06-10T10-00-00.backup", Database: "neo4j", Type: TypeUnknown, Time: time.Date(2024, 6, 10, 10, 0, 0, 0, time.UTC)}
+	tests := []struct {
+		name          string
+		catalog       *Catalog
+		at            string
+		wantArtifacts int
+		wantError     string
+	}{
+		{
+			name:      "unknown artifact as the full backup of a catalog file",
+			catalog:   &Catalog{Databases: []Database{{Name: "neo4j", Chains: []Chain{{Full: &full, Differentials: []Artifact{}}}}}},
+			at:        "2024-06-11",
+			wantError: "starts with team/neo4j-2024-06-10T10-00-00.backup of type UNKNOWN instead of a full backup",
+		},
+		{
+			name:      "unknown artifact taken before the time",
+			catalog:   Build(unknownObjects()),
+			at:        "2024-06-12 12:00:00",
+			wantError: "contains team/neo4j-2024-06-11T10-00-00.backup of type UNKNOWN which cannot be restored as a differential backup",
+		},
+		{
+			name:          "unknown artifact taken after the time",
+			catalog:       Build(unknownObjects()),
+			at:            "2024-06-10 12:00:00",
+			wantArtifacts: 1,
+		},
+		{
+			name:          "differential backup followed by an unknown artifact",
+			catalog:       Build(unknownObjects()),
+			at:            "2024-06-14 12:00:00",
+			wantArtifacts: 2,
+		},
+		{
+			name:      "unknown artifact ending the latest chain",
+			catalog:   Build(unknownObjects()),
+			at:        "2024-06-16",
+			wantError: "contains team/neo4j-2024-06-15T10-00-00.backup of type UNKNOWN",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			at, err := ParseTime(tt.at)
+			assert.NoError(t, err)
+			plan, err := tt.catalog.PlanAt("neo4j", at)
+			if tt.wantError != "" {
+				assert.ErrorIs(t, err, ErrNoCoverage)
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			if assert.NoError(t, err) {
+				assert.Len(t, plan.Artifacts, tt.wantArtifacts)
+			}
+		})
+	}
+}
+
+func TestReadCatalog(t *testing.T) {
+	t.Parallel()
+
+	var buffer bytes.Buffer
+	assert.NoError(t, Build(testObjects()).WriteJSON(&buffer))
+	backupCatalog, err := Read(&buffer)
+	assert.NoError(t, err)
+	plan, err := backupCatalog.PlanAt("neo4j", time.Date(2024, 6, 12, 0, 0, 0, 0, time.UTC))
+	assert.NoError(t, err)
+	assert.Len(t, plan.Artifacts, 2)
+	assert.Equal(t, int64(1034), plan.Size)
+
+	_, err = Read(bytes.NewBufferString("not json"))
+	assert.ErrorContains(t, err, "unable to read the backup catalog")
+}
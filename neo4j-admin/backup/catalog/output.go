@@ -0,0 +1,84 @@
+package catalog
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// WriteTable writes the catalog as a table containing one row per artifact
+func (c *Catalog) WriteTable(w io.Writer, now time.Time) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DATABASE\tCHAIN\tTYPE\tARTIFACT\tSIZE\tAGE\tCONSISTENCY REPORT")
+	for _, database := range c.Databases {
+		for i, chain := range database.Chains {
+			for _, artifact := range chain.Artifacts() {
+				report := "no"
+				if artifact.ConsistencyCheckReport != "" {
+					report = "yes"
+				}
+				fmt.Fprintf(tw, "%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+					database.Name,
+					i+1,
+					artifact.Type,
+					artifact.Key,
+					FormatBytes(artifact.Size),
+					FormatAge(now.Sub(artifact.Time)),
+					report)
+			}
+		}
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the catalog as an indented json document
+func (c *Catalog) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(c)
+}
+
+// FormatBytes returns the size in a human readable format. Ex: 1536 returns 1.5KiB
+func FormatBytes(size int64) string {
+	return common.FormatBytes(size)
+}
+
+// FormatAge returns the duration rounded to the most significant unit. Ex: 50h returns 2d2h
+func FormatAge(age time.Duration) string {
+	if age < 0 {
+		age = 0
+	}
+	days := int(age.Hours()) / 24
+	hours := int(age.Hours()) % 24
+	switch {
+	case days > 0:
+		return fmt.Sprintf("%dd%dh", days, hours)
+	case hours > 0:
+		return fmt.Sprintf("%dh%dm", hours, int(age.Minutes())%60)
+	default:
+		return fmt.Sprintf("%dm", int(age.Minutes()))
+	}
+}
+
+// WriteTable writes the plan as a table containing one row per artifact in restore order
+func (p *Plan) WriteTable(w io.Writer) error {
+	fmt.Fprintf(w, "Database %s as of %s is covered until %s by %d artifact(s) , %s to download\n\n",
+		p.Database, p.At.Format(time.RFC3339), p.CoveredUntil.Format(time.RFC3339), len(p.Artifacts), FormatBytes(p.Size))
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "STEP\tTYPE\tARTIFACT\tTIME\tSIZE")
+	for i, artifact := range p.Artifacts {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", i+1, artifact.Type, artifact.Key, artifact.Time.Format(time.RFC3339), FormatBytes(artifact.Size))
+	}
+	return tw.Flush()
+}
+
+// WriteJSON writes the plan as an indented json document
+func (p *Plan) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(p)
+}
@@ -0,0 +1,98 @@
+package catalog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrNoCoverage is returned when no restorable backup chain of the database was taken at or before the requested time
+var ErrNoCoverage = errors.New("no backup coverage")
+
+// timeLayouts are the accepted formats of a point in time , the formats without a zone are read as UTC
+var timeLayouts = []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02T15:04:05", "2006-01-02T15-04-05", "2006-01-02 15:04", "2006-01-02"}
+
+// Plan is the minimal chain of artifacts restoring a database as of a point in time
+type Plan struct {
+	Database string    `json:"database"`
+	At       time.Time `json:"at"`
+	// Artifacts are the full backup followed by the differential backups taken up to At , in restore order
+	Artifacts []Artifact `json:"artifacts"`
+	// CoveredUntil is the time of the newest artifact of the plan , the changes made between CoveredUntil and At are not restored
+	CoveredUntil time.Time `json:"coveredUntil"`
+	Size         int64     `json:"size"`
+}
+
+// ParseTime parses a point in time given as RFC3339 , yyyy-MM-dd HH:mm:ss , yyyy-MM-dd HH:mm or yyyy-MM-dd (UTC)
+func ParseTime(value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %s. Expected format is yyyy-MM-dd HH:mm:ss (UTC) or RFC3339 , ex: 2024-06-11T14:00:00+02:00", value)
+}
+
+// Read reads a catalog written by WriteJSON , ex: the output of the list command kept as a manifest of the bucket
+func Read(r io.Reader) (*Catalog, error) {
+	catalog := &Catalog{}
+	if err := json.NewDecoder(r).Decode(catalog); err != nil {
+		return nil, fmt.Errorf("unable to read the backup catalog \n %v", err)
+	}
+	return catalog, nil
+}
+
+// PlanAt returns the plan restoring the database as of the given time
+// The newest chain containing a full backup taken at or before the time is selected , its differential backups taken after the time are left out
+// Chains whose full backup is missing from the bucket cannot be restored and are skipped. ErrNoCoverage is returned when no chain qualifies
+// or when the selected chain does not start with a FULL artifact or contains artifacts of unknown type (ex: a catalog file written before they were tracked)
+func (c *Catalog) PlanAt(database string, at time.Time) (*Plan, error) {
+	entry, present := c.FindDatabase(database)
+	if !present || len(entry.Chains) == 0 {
+		return nil, fmt.Errorf("%w: no backup of database %s found", ErrNoCoverage, database)
+	}
+	skipped := 0
+	for i := len(entry.Chains) - 1; i >= 0; i-- {
+		chain := entry.Chains[i]
+		var artifacts []Artifact
+		for _, artifact := range chain.Artifacts() {
+			if artifact.Time.After(at) {
+				break
+			}
+			artifacts = append(artifacts, artifact)
+		}
+		if len(artifacts) == 0 {
+			continue
+		}
+		if chain.Full == nil {
+			skipped++
+			continue
+		}
+		if chain.Full.Type != TypeFull {
+			return nil, fmt.Errorf("%w: the chain of database %s covering %s starts with %s of type %s instead of a full backup",
+				ErrNoCoverage, database, at.Format(time.RFC3339), chain.Full.Key, chain.Full.Type)
+		}
+		for _, artifact := range artifacts[1:] {
+			if artifact.Type != TypeDiff {
+				return nil, fmt.Errorf("%w: the chain of database %s covering %s contains %s of type %s which cannot be restored as a differential backup",
+					ErrNoCoverage, database, at.Format(time.RFC3339), artifact.Key, artifact.Type)
+			}
+		}
+		plan := &Plan{Database: database, At: at, Artifacts: artifacts, CoveredUntil: artifacts[len(artifacts)-1].Time}
+		for _, artifact := range artifacts {
+			plan.Size += artifact.Size
+		}
+		return plan, nil
+	}
+	oldest := entry.Chains[0].Artifacts()[0]
+	if skipped > 0 {
+		return nil, fmt.Errorf("%w: database %s has %d chain(s) taken before %s without a full backup in the bucket",
+			ErrNoCoverage, database, skipped, at.Format(time.RFC3339))
+	}
+	return nil, fmt.Errorf("%w: the oldest backup of database %s was taken at %s , after %s",
+		ErrNoCoverage, database, oldest.Time.Format(time.RFC3339), at.Format(time.RFC3339))
+}
@@ -0,0 +1,41 @@
+package catalog
+
+import "time"
+
+// ExpiredChains returns the chains of the database which are not retained by the given retention policy
+// keepChains retains the newest N restorable chains (0 disables the check) and maxAge retains the chains whose newest artifact
+// is younger than maxAge (0 disables the check). The chains which cannot be restored (see Chain.Validate) do not count
+// toward keepChains. The newest chain and the newest restorable chain of a database are always retained.
+// The chains without a full backup containing artifacts of unknown type (ex: uploaded before the backup type metadata)
+// may hold the full backup the following artifacts depend on , they are only expired when pruneUnknown is set
+func ExpiredChains(database Database, keepChains int, maxAge time.Duration, now time.Time, pruneUnknown bool) []Chain {
+	var expired []Chain
+	if keepChains <= 0 && maxAge <= 0 {
+		return expired
+	}
+	last := len(database.Chains) - 1
+	newerChains := 0
+	retained := make([]bool, len(database.Chains))
+	for i := last; i >= 0; i-- {
+		chain := database.Chains[i]
+		restorable := chain.Validate() == nil
+		exceedsCount := keepChains > 0 && newerChains >= keepChains
+		exceedsAge := maxAge > 0 && now.Sub(chain.Latest().Time) > maxAge
+		retained[i] = i == last || (restorable && newerChains == 0) || !(exceedsCount || exceedsAge) ||
+			(!pruneUnknown && isUnknownChain(chain))
+		if restorable {
+			newerChains++
+		}
+	}
+	for i, chain := range database.Chains {
+		if !retained[i] {
+			expired = append(expired, chain)
+		}
+	}
+	return expired
+}
+
+// isUnknownChain returns true when the chain has no full backup and contains artifacts of unknown type
+func isUnknownChain(chain Chain) bool {
+	return chain.Full == nil && len(chain.Unknown) > 0
+}
@@ -0,0 +1,67 @@
+package common
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// outcome of a consistency check
+const (
+	ConsistencyCheckConsistent   = "consistent"
+	ConsistencyCheckInconsistent = "inconsistent"
+	// ConsistencyCheckFailed means the check did not complete , ex: neo4j-admin crashed or ran out of memory
+	ConsistencyCheckFailed = "failed"
+)
+
+// types of the inconsistencies counted in a consistency check summary
+const (
+	InconsistencyNodes          = "nodes"
+	InconsistencyRelationships  = "relationships"
+	InconsistencyIndexes        = "indexes"
+	InconsistencyCounts         = "counts"
+	InconsistencyPropertyOwners = "propertyOwners"
+	InconsistencyOther          = "other"
+)
+
+// ConsistencyCheckSummary is the structured summary of the report generated by the consistency check of a database
+// It is uploaded as <report>.json next to the report tar archive
+type ConsistencyCheckSummary struct {
+	Database string `json:"database"`
+	Status   string `json:"status"`
+	Errors   int    `json:"errors"`
+	Warnings int    `json:"warnings"`
+	// Inconsistencies contains the number of errors and warnings by type (nodes , relationships , indexes , counts , propertyOwners , other)
+	Inconsistencies map[string]int `json:"inconsistencies,omitempty"`
+	// Samples contains the ids of the first affected entities by type
+	Samples map[string][]int64 `json:"samples,omitempty"`
+	// Report is the name of the report tar archive
+	Report string `json:"report,omitempty"`
+	// Error contains the output of a check which did not complete
+	Error string `json:"error,omitempty"`
+}
+
+// ReadConsistencyCheckSummary reads the summary written to the given file path
+func ReadConsistencyCheckSummary(filePath string) (*ConsistencyCheckSummary, error) {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read consistency check summary %s \n %v", filePath, err)
+	}
+	summary := &ConsistencyCheckSummary{}
+	if err = json.Unmarshal(data, summary); err != nil {
+		return nil, fmt.Errorf("unable to parse consistency check summary %s \n %v", filePath, err)
+	}
+	return summary, nil
+}
+
+// Write writes the summary as an indented json document to the given file path
+func (s *ConsistencyCheckSummary) Write(filePath string) error {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal consistency check summary \n %v", err)
+	}
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("unable to write consistency check summary to %s \n %v", filePath, err)
+	}
+	return nil
+}
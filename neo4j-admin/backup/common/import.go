@@ -0,0 +1,34 @@
+package common
+
+// types of import performed by neo4j-admin database import
+const (
+	ImportTypeFull        = "full"
+	ImportTypeIncremental = "incremental"
+)
+
+// outcome of an import
+const (
+	ImportSucceeded = "succeeded"
+	ImportFailed    = "failed"
+)
+
+// ImportSummary is the structured summary of the output of neo4j-admin database import
+type ImportSummary struct {
+	Database string `json:"database"`
+	Type     string `json:"type"`
+	Status   string `json:"status"`
+	// Files contains the object keys of the CSV files imported
+	Files         []string `json:"files,omitempty"`
+	Nodes         int64    `json:"nodes"`
+	Relationships int64    `json:"relationships"`
+	Properties    int64    `json:"properties"`
+	// BadEntries is the number of entries skipped and logged into the report , ex: relationships referring to missing nodes
+	BadEntries int `json:"badEntries"`
+	// Duration is the duration of the import as printed by neo4j-admin , ex: 1m 2s 345ms
+	Duration   string `json:"duration,omitempty"`
+	PeakMemory string `json:"peakMemory,omitempty"`
+	// Report is the path of the report containing the bad entries
+	Report string `json:"report,omitempty"`
+	// Error contains the output of an import which did not complete
+	Error string `json:"error,omitempty"`
+}
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// KeyPrefix expands the given key layout for the provided artifact and returns the resulting key prefix
+// The below placeholders are supported
+// {database} , {year} , {month} , {day} , {backupType} , {artifactType}
+// Ex: layout {database}/{year}/{month} for neo4j-2023-05-04T17-21-27.backup returns neo4j/2023/05
+func KeyPrefix(layout string, fileName string) string {
+	layout = strings.Trim(strings.TrimSpace(layout), "/")
+	if layout == "" {
+		return ""
+	}
+	metadata := ArtifactMetadata(fileName)
+	backupTime, err := time.Parse("2006-01-02T15-04-05", metadata["backup-time"])
+	if err != nil {
+		backupTime = time.Now().UTC()
+	}
+	replacer := strings.NewReplacer(
+		"{database}", valueOrUnknown(metadata["database"]),
+		"{year}", backupTime.Format("2006"),
+		"{month}", backupTime.Format("01"),
+		"{day}", backupTime.Format("02"),
+		"{backupType}", valueOrUnknown(strings.ToLower(metadata["backup-type"])),
+		"{artifactType}", metadata["artifact-type"],
+	)
+	return replacer.Replace(layout)
+}
+
+// JoinBucketPath appends the given prefix to the bucket name
+// Ex: demo/test and neo4j/2023 returns demo/test/neo4j/2023
+func JoinBucketPath(bucketName string, prefix string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return bucketName
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(bucketName, "/"), prefix)
+}
+
+func valueOrUnknown(value string) string {
+	if value == "" {
+		return "unknown"
+	}
+	return value
+}
@@ -0,0 +1,55 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKeyPrefix(t *testing.T) {
+	SetBackupType("neo4j-2023-05-04T17-21-27.backup", "FULL")
+
+	tests := []struct {
+		name     string
+		layout   string
+		fileName string
+		want     string
+	}{
+		{
+			name:     "empty layout",
+			layout:   "",
+			fileName: "neo4j-2023-05-04T17-21-27.backup",
+			want:     "",
+		},
+		{
+			name:     "database and date",
+			layout:   "/{database}/{year}/{month}/{day}/",
+			fileName: "neo4j-2023-05-04T17-21-27.backup",
+			want:     "neo4j/2023/05/04",
+		},
+		{
+			name:     "backup type",
+			layout:   "{database}/{backupType}",
+			fileName: "neo4j-2023-05-04T17-21-27.backup",
+			want:     "neo4j/full",
+		},
+		{
+			name:     "consistency check report",
+			layout:   "{database}/{artifactType}",
+			fileName: "system-2023-05-04T17-21-27.backup.report.tar.gz",
+			want:     "system/consistency-check-report",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, KeyPrefix(tt.layout, tt.fileName))
+		})
+	}
+}
+
+func TestJoinBucketPath(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "demo", JoinBucketPath("demo", ""))
+	assert.Equal(t, "demo/test/neo4j", JoinBucketPath("demo/test", "/neo4j/"))
+}
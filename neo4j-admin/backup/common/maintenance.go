@@ -0,0 +1,29 @@
+package common
+
+// store maintenance operations performed on a restored backup
+const (
+	MaintenanceCopy    = "copy"
+	MaintenanceMigrate = "migrate"
+)
+
+// MaintenanceReport describes a store maintenance operation , it is uploaded as <artifact>.maintenance.json next to the resulting dump
+type MaintenanceReport struct {
+	// Operation is copy or migrate
+	Operation string `json:"operation"`
+	Database  string `json:"database"`
+	Status    string `json:"status"`
+	// SourceArtifacts contains the object keys of the backup chain the database was restored from
+	SourceArtifacts []string `json:"sourceArtifacts,omitempty"`
+	SourceBytes     int64    `json:"sourceBytes"`
+	// Artifact is the dump of the database once copied (or migrated)
+	Artifact      string `json:"artifact,omitempty"`
+	ArtifactBytes int64  `json:"artifactBytes"`
+	ToFormat      string `json:"toFormat,omitempty"`
+	// Filters contains the copy filters , ex: ignoreNodesWithLabels: [Temp]
+	Filters map[string][]string `json:"filters,omitempty"`
+	// Duration is the duration of the copy (or migrate) command
+	Duration string `json:"duration,omitempty"`
+	// Output contains the last lines of the output of the copy (or migrate) command
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,132 @@
+package common
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ArtifactTypeBackup                  = "backup"
+	ArtifactTypeConsistencyCheck        = "consistency-check-report"
+	ArtifactTypeConsistencyCheckSummary = "consistency-check-summary"
+	ArtifactTypeDump                    = "dump"
+	ArtifactTypeMaintenanceDump         = "maintenance-dump"
+	ArtifactTypeMaintenanceReport       = "maintenance-report"
+)
+
+var (
+	artifactNameRegex = regexp.MustCompile(`^(.*)-(\d{4}-\d{2}-\d{2}T\d{2}-\d{2}-\d{2})\.(?:backup|dump|(?:copy|migrate)\.dump)`)
+	backupTypes       = map[string]string{}
+	backupTypesLock   sync.RWMutex
+)
+
+// SetBackupType records the backup type (FULL or DIFF) of the given backup artifact
+// so that it can be attached as metadata when the artifact is uploaded
+func SetBackupType(fileName string, backupType string) {
+	backupTypesLock.Lock()
+	defer backupTypesLock.Unlock()
+	backupTypes[fileName] = backupType
+}
+
+// GetBackupType returns the backup type recorded for the given backup artifact
+// Falls back to the TYPE env variable when no type has been recorded
+func GetBackupType(fileName string) string {
+	backupTypesLock.RLock()
+	defer backupTypesLock.RUnlock()
+	if backupType, present := backupTypes[fileName]; present {
+		return backupType
+	}
+	return strings.ToUpper(strings.TrimSpace(os.Getenv("TYPE")))
+}
+
+// ParseArtifactName returns the database name and timestamp embedded in a backup artifact , dump or consistency check report name
+// Ex: neo4j-2023-05-04T17-21-27.backup returns neo4j and 2023-05-04T17-21-27
+func ParseArtifactName(fileName string) (string, string, error) {
+	matches := artifactNameRegex.FindStringSubmatch(fileName)
+	if len(matches) != 3 {
+		return "", "", fmt.Errorf("unable to retrieve database name from artifact name %s", fileName)
+	}
+	return matches[1], matches[2], nil
+}
+
+// ArtifactType returns whether the given file is a backup artifact , a dump , a consistency check report , its summary
+// or the dump (<database>-<timestamp>.<operation>.dump) and report of a store maintenance operation
+func ArtifactType(fileName string) string {
+	if strings.HasSuffix(fileName, ".maintenance.json") {
+		return ArtifactTypeMaintenanceReport
+	}
+	if strings.HasSuffix(fileName, "."+MaintenanceCopy+".dump") || strings.HasSuffix(fileName, "."+MaintenanceMigrate+".dump") {
+		return ArtifactTypeMaintenanceDump
+	}
+	if strings.HasSuffix(fileName, ".dump") {
+		return ArtifactTypeDump
+	}
+	if strings.HasSuffix(fileName, ".report.tar.gz") {
+		return ArtifactTypeConsistencyCheck
+	}
+	if strings.HasSuffix(fileName, ".report.json") {
+		return ArtifactTypeConsistencyCheckSummary
+	}
+	return ArtifactTypeBackup
+}
+
+// ArtifactMetadata returns the metadata describing the database and backup type which is attached to every uploaded artifact
+func ArtifactMetadata(fileName string) map[string]string {
+	metadata := map[string]string{
+		"artifact-type": ArtifactType(fileName),
+	}
+	if database, timeStamp, err := ParseArtifactName(fileName); err == nil {
+		metadata["database"] = database
+		metadata["backup-time"] = timeStamp
+	}
+	if backupType := GetBackupType(fileName); backupType != "" && metadata["artifact-type"] == ArtifactTypeBackup {
+		metadata["backup-type"] = backupType
+	}
+	return metadata
+}
+
+// ParseKeyValuePairs converts a comma separated list of key=value pairs into a map
+// Ex: team=graph,env=prod returns map[env:prod team:graph]
+func ParseKeyValuePairs(value string) (map[string]string, error) {
+	pairs := make(map[string]string)
+	if strings.TrimSpace(value) == "" {
+		return pairs, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		key, val, found := strings.Cut(pair, "=")
+		key = strings.TrimSpace(key)
+		if !found || key == "" {
+			return nil, fmt.Errorf("invalid key value pair '%s'. Expected format is key=value", pair)
+		}
+		pairs[key] = strings.TrimSpace(val)
+	}
+	return pairs, nil
+}
+
+// MergeMetadata merges the given maps into a new map. Values from later maps take precedence
+func MergeMetadata(maps ...map[string]string) map[string]string {
+	merged := make(map[string]string)
+	for _, m := range maps {
+		for key, value := range m {
+			merged[key] = value
+		}
+	}
+	return merged
+}
+
+// SortedKeys returns the keys of the given map in sorted order
+func SortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
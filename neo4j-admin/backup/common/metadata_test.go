@@ -0,0 +1,73 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseKeyValuePairs(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name    string
+		value   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name:  "empty value",
+			value: "",
+			want:  map[string]string{},
+		},
+		{
+			name:  "multiple pairs",
+			value: "team=graph, env=prod",
+			want:  map[string]string{"team": "graph", "env": "prod"},
+		},
+		{
+			name:    "missing separator",
+			value:   "team",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseKeyValuePairs(tt.value)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestArtifactMetadata(t *testing.T) {
+	SetBackupType("neo4j-2023-05-04T17-21-27.backup", "DIFF")
+
+	metadata := ArtifactMetadata("neo4j-2023-05-04T17-21-27.backup")
+	assert.Equal(t, "neo4j", metadata["database"])
+	assert.Equal(t, "DIFF", metadata["backup-type"])
+	assert.Equal(t, ArtifactTypeBackup, metadata["artifact-type"])
+
+	metadata = ArtifactMetadata("my-db-2023-05-04T17-21-27.backup.report.tar.gz")
+	assert.Equal(t, "my-db", metadata["database"])
+	assert.Equal(t, ArtifactTypeConsistencyCheck, metadata["artifact-type"])
+
+	metadata = ArtifactMetadata("orders-2023-05-04T17-21-27.dump")
+	assert.Equal(t, "orders", metadata["database"])
+	assert.Equal(t, "2023-05-04T17-21-27", metadata["backup-time"])
+	assert.Equal(t, ArtifactTypeDump, metadata["artifact-type"])
+	assert.Empty(t, metadata["backup-type"], "a dump is not part of a backup chain")
+
+	metadata = ArtifactMetadata("orders-2023-05-04T17-21-27.copy.dump")
+	assert.Equal(t, "orders", metadata["database"])
+	assert.Equal(t, "2023-05-04T17-21-27", metadata["backup-time"])
+	assert.Equal(t, ArtifactTypeMaintenanceDump, metadata["artifact-type"])
+
+	metadata = ArtifactMetadata("orders-2023-05-04T17-21-27.copy.dump.maintenance.json")
+	assert.Equal(t, "orders", metadata["database"])
+	assert.Equal(t, ArtifactTypeMaintenanceReport, metadata["artifact-type"])
+}
@@ -0,0 +1,235 @@
+package common
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	uploadState     *UploadState
+	uploadStatePath string
+	uploadStateLock sync.Mutex
+
+	checksums     = make(map[string]fileChecksum)
+	checksumsLock sync.Mutex
+)
+
+// UploadState is persisted in the UPLOAD_STATE_FILE so that a retried job resumes the uploads of the failed run
+// instead of taking a new backup and uploading it from the beginning
+type UploadState struct {
+	// BackupFiles and ConsistencyCheckReports are the artifacts of the run whose upload did not complete
+	BackupFiles             []string `json:"backupFiles,omitempty"`
+	ConsistencyCheckReports []string `json:"consistencyCheckReports,omitempty"`
+	// Checksums contains the sha256 checksum of every artifact at the time it was created
+	Checksums map[string]string `json:"checksums,omitempty"`
+	// Uploaded contains the artifacts already uploaded to every destination
+	Uploaded map[string][]string `json:"uploaded,omitempty"`
+	// Sessions contains the provider upload sessions which are not completed
+	Sessions []UploadSession `json:"sessions,omitempty"`
+}
+
+// UploadSession describes an upload which can be resumed by a later run
+type UploadSession struct {
+	CloudProvider string `json:"cloudProvider"`
+	BucketName    string `json:"bucketName"`
+	Key           string `json:"key"`
+	// Checksum is the sha256 checksum of the local file being uploaded , the session is resumed only if the checksum matches
+	Checksum string `json:"checksum"`
+	// ID is the s3 multipart upload id , the gcs resumable session uri or the prefix of the azure block ids
+	ID       string    `json:"id"`
+	PartSize int64     `json:"partSize"`
+	Created  time.Time `json:"created"`
+}
+
+type fileChecksum struct {
+	size     int64
+	modTime  time.Time
+	checksum string
+}
+
+// ResumableUploadsEnabled returns true when the UPLOAD_STATE_FILE is configured
+func ResumableUploadsEnabled() bool {
+	return uploadStateFile() != ""
+}
+
+func uploadStateFile() string {
+	return strings.TrimSpace(os.Getenv("UPLOAD_STATE_FILE"))
+}
+
+// UpdateUploadState applies the given function to the upload state and persists it
+func UpdateUploadState(update func(state *UploadState)) error {
+	uploadStateLock.Lock()
+	defer uploadStateLock.Unlock()
+	state, err := loadUploadState()
+	if err != nil {
+		return err
+	}
+	update(state)
+	return saveUploadState(state)
+}
+
+// GetUploadState returns a copy of the persisted upload state
+func GetUploadState() (UploadState, error) {
+	uploadStateLock.Lock()
+	defer uploadStateLock.Unlock()
+	state, err := loadUploadState()
+	if err != nil {
+		return UploadState{}, err
+	}
+	copied := *state
+	return copied, nil
+}
+
+// ClearUploadState deletes the upload state file , it is called once every upload of a run is completed
+func ClearUploadState() error {
+	uploadStateLock.Lock()
+	defer uploadStateLock.Unlock()
+	uploadState = nil
+	filePath := uploadStateFile()
+	if filePath == "" {
+		return nil
+	}
+	if err := os.Remove(filePath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("unable to delete upload state file %s \n %v", filePath, err)
+	}
+	return nil
+}
+
+// FindUploadSession returns the session of a previous upload of the same file to the same key
+// A session created for a file with a different checksum is discarded
+func FindUploadSession(cloudProvider string, bucketName string, key string, checksum string) (UploadSession, bool) {
+	var session UploadSession
+	var found bool
+	err := UpdateUploadState(func(state *UploadState) {
+		for i, s := range state.Sessions {
+			if s.CloudProvider != cloudProvider || s.BucketName != bucketName || s.Key != key {
+				continue
+			}
+			if s.Checksum != checksum {
+				log.Printf("Discarding upload session of %s/%s since the checksum of the local file changed", bucketName, key)
+				state.Sessions = append(state.Sessions[:i], state.Sessions[i+1:]...)
+				return
+			}
+			session, found = s, true
+			return
+		}
+	})
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return UploadSession{}, false
+	}
+	return session, found
+}
+
+// SaveUploadSession persists the upload session replacing any previous session of the same key
+func SaveUploadSession(session UploadSession) error {
+	return UpdateUploadState(func(state *UploadState) {
+		state.Sessions = removeSession(state.Sessions, session.CloudProvider, session.BucketName, session.Key)
+		state.Sessions = append(state.Sessions, session)
+	})
+}
+
+// DeleteUploadSession removes the upload session of the given key , it is called once the upload is completed or the session expired
+func DeleteUploadSession(cloudProvider string, bucketName string, key string) error {
+	return UpdateUploadState(func(state *UploadState) {
+		state.Sessions = removeSession(state.Sessions, cloudProvider, bucketName, key)
+	})
+}
+
+func removeSession(sessions []UploadSession, cloudProvider string, bucketName string, key string) []UploadSession {
+	var remaining []UploadSession
+	for _, s := range sessions {
+		if s.CloudProvider != cloudProvider || s.BucketName != bucketName || s.Key != key {
+			remaining = append(remaining, s)
+		}
+	}
+	return remaining
+}
+
+// loadUploadState must be called while holding the lock
+func loadUploadState() (*UploadState, error) {
+	filePath := uploadStateFile()
+	if uploadState != nil && uploadStatePath == filePath {
+		return uploadState, nil
+	}
+	state := &UploadState{}
+	if filePath != "" {
+		data, err := os.ReadFile(filePath)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("unable to read upload state file %s \n %v", filePath, err)
+		}
+		if len(data) != 0 {
+			if err = json.Unmarshal(data, state); err != nil {
+				log.Printf("Warning: ignoring corrupt upload state file %s: %v", filePath, err)
+				state = &UploadState{}
+			}
+		}
+	}
+	uploadState, uploadStatePath = state, filePath
+	return state, nil
+}
+
+// saveUploadState must be called while holding the lock
+// The state is written to a temporary file and renamed so that a pod killed while writing does not corrupt it
+func saveUploadState(state *UploadState) error {
+	filePath := uploadStateFile()
+	if filePath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal upload state \n %v", err)
+	}
+	if err = os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("unable to create directory for upload state file %s \n %v", filePath, err)
+	}
+	tempFile := filePath + ".tmp"
+	if err = os.WriteFile(tempFile, data, 0644); err != nil {
+		return fmt.Errorf("unable to write upload state file %s \n %v", tempFile, err)
+	}
+	if err = os.Rename(tempFile, filePath); err != nil {
+		return fmt.Errorf("unable to write upload state file %s \n %v", filePath, err)
+	}
+	return nil
+}
+
+// FileChecksum returns the hex encoded sha256 checksum of the file
+// The checksum is cached as long as the size and modification time of the file do not change
+func FileChecksum(filePath string) (string, error) {
+	fileInfo, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't get info of file %v. Here's why: %v\n", filePath, err)
+	}
+	checksumsLock.Lock()
+	cached, present := checksums[filePath]
+	checksumsLock.Unlock()
+	if present && cached.size == fileInfo.Size() && cached.modTime.Equal(fileInfo.ModTime()) {
+		return cached.checksum, nil
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't open file %v. Here's why: %v\n", filePath, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err = io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("Couldn't compute checksum of file %v. Here's why: %v\n", filePath, err)
+	}
+	checksum := hex.EncodeToString(hash.Sum(nil))
+
+	checksumsLock.Lock()
+	checksums[filePath] = fileChecksum{size: fileInfo.Size(), modTime: fileInfo.ModTime(), checksum: checksum}
+	checksumsLock.Unlock()
+	return checksum, nil
+}
@@ -0,0 +1,48 @@
+package common
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadSessions(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "upload-state.json")
+	t.Setenv("UPLOAD_STATE_FILE", stateFile)
+	assert.True(t, ResumableUploadsEnabled())
+
+	session := UploadSession{CloudProvider: "aws", BucketName: "demo", Key: "neo4j.backup", Checksum: "abc", ID: "upload-1"}
+	assert.NoError(t, SaveUploadSession(session))
+	assert.FileExists(t, stateFile)
+
+	found, ok := FindUploadSession("aws", "demo", "neo4j.backup", "abc")
+	assert.True(t, ok)
+	assert.Equal(t, "upload-1", found.ID)
+
+	// a session created for a different local file is discarded
+	_, ok = FindUploadSession("aws", "demo", "neo4j.backup", "def")
+	assert.False(t, ok)
+	_, ok = FindUploadSession("aws", "demo", "neo4j.backup", "abc")
+	assert.False(t, ok)
+
+	assert.NoError(t, SaveUploadSession(session))
+	assert.NoError(t, DeleteUploadSession("aws", "demo", "neo4j.backup"))
+	state, err := GetUploadState()
+	assert.NoError(t, err)
+	assert.Empty(t, state.Sessions)
+
+	assert.NoError(t, ClearUploadState())
+	assert.NoFileExists(t, stateFile)
+}
+
+func TestFileChecksum(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "neo4j.backup")
+	assert.NoError(t, os.WriteFile(filePath, []byte("neo4j"), 0644))
+	checksum, err := FileChecksum(filePath)
+	assert.NoError(t, err)
+	assert.Equal(t, "13fd9e770be366985fd7e0ca5026434c7c3e4e84e111f09039889277534b4114", checksum)
+}
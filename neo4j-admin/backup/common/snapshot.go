@@ -0,0 +1,15 @@
+package common
+
+// SnapshotSummary describes a CSI VolumeSnapshot of a data volume taken by the snapshot mode
+type SnapshotSummary struct {
+	Name string `json:"name"`
+	// Volume is the name of the snapshotted PersistentVolumeClaim
+	Volume string `json:"volume"`
+	Class  string `json:"class,omitempty"`
+	// CreationTime is the point in time of the snapshot as reported by the CSI driver
+	CreationTime string `json:"creationTime,omitempty"`
+	ReadyToUse   bool   `json:"readyToUse"`
+	// RestoreSize is the minimum size of a volume restored from the snapshot , ex: 10Gi
+	RestoreSize string `json:"restoreSize,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
@@ -0,0 +1,73 @@
+package common
+
+import (
+	"strings"
+	"time"
+)
+
+// ObjectInfo describes a single object present in a bucket (or container)
+type ObjectInfo struct {
+	// Key is the complete object key including the prefix , excluding the bucket name
+	Key          string            `json:"key"`
+	Size         int64             `json:"size"`
+	LastModified time.Time         `json:"lastModified"`
+	Metadata     map[string]string `json:"metadata,omitempty"`
+}
+
+// StorageClient is implemented by every cloud provider client used as a backup destination
+type StorageClient interface {
+	// CheckBucketAccess checks if the given bucket (or container) name is accessible or not
+	CheckBucketAccess(bucketName string) error
+	// UploadFile uploads the files present at LOCATION to the given bucket (or container)
+	UploadFile(fileNames []string, bucketName string) error
+	// ListObjects returns all the objects present below the given bucket (or container) name and prefix
+	ListObjects(bucketName string) ([]ObjectInfo, error)
+	// DownloadFile downloads the object with the given key (as returned by ListObjects) to the given file path
+	DownloadFile(bucketName string, key string, filePath string) error
+	// DeleteObject deletes the object with the given key (as returned by ListObjects)
+	DeleteObject(bucketName string, key string) error
+}
+
+// SplitBucketName splits the given bucket name into the parent bucket name and the prefix
+// Ex: demo/test/test2 returns demo and test/test2
+func SplitBucketName(bucketName string) (string, string) {
+	if index := strings.Index(bucketName, "/"); index != -1 {
+		return bucketName[:index], bucketName[index+1:]
+	}
+	return bucketName, ""
+}
+
+// LifecyclePolicy describes the lifecycle rules applied below the prefix of a destination , 0 disables a rule
+type LifecyclePolicy struct {
+	// ExpirationDays deletes the objects older than the given number of days
+	ExpirationDays int
+	// AbortIncompleteUploadDays aborts the multipart uploads left incomplete for the given number of days
+	AbortIncompleteUploadDays int
+}
+
+// Enabled returns true when at least one of the rules is enabled
+func (p LifecyclePolicy) Enabled() bool {
+	return p.ExpirationDays > 0 || p.AbortIncompleteUploadDays > 0
+}
+
+// LifecycleRuleID returns the id identifying the lifecycle rules applied to the prefix of the given bucket name
+// Ex: demo/team/neo4j returns neo4j-backup-team/neo4j
+func LifecycleRuleID(bucketName string) string {
+	_, prefix := SplitBucketName(bucketName)
+	if prefix = strings.Trim(prefix, "/"); prefix == "" {
+		return "neo4j-backup"
+	}
+	return "neo4j-backup-" + prefix
+}
+
+// BucketCreator is implemented by the storage clients able to create a missing bucket (or container)
+type BucketCreator interface {
+	// CreateBucket creates the parent bucket of the given bucket name if it does not exist and returns whether it was created
+	CreateBucket(bucketName string) (bool, error)
+}
+
+// LifecycleManager is implemented by the storage clients able to apply lifecycle rules to a bucket
+type LifecycleManager interface {
+	// ApplyLifecycle replaces the rules previously applied to the prefix of the given bucket name , the rules of other prefixes are kept
+	ApplyLifecycle(bucketName string, policy LifecyclePolicy) error
+}
@@ -0,0 +1,301 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	defaultProgressInterval = 30 * time.Second
+	// maxRateLimitBurst is the largest chunk read from the file before waiting on the rate limiter
+	maxRateLimitBurst = 1024 * 1024
+)
+
+var (
+	uploadLimiter     *rate.Limiter
+	uploadLimiterOnce sync.Once
+	uploadLimiterErr  error
+
+	transfers     []TransferProgress
+	transfersLock sync.Mutex
+)
+
+// TransferProgress describes the progress of a single file upload
+type TransferProgress struct {
+	FileName         string    `json:"fileName"`
+	BucketName       string    `json:"bucketName"`
+	TotalBytes       int64     `json:"totalBytes"`
+	BytesTransferred int64     `json:"bytesTransferred"`
+	StartTime        time.Time `json:"startTime"`
+	ElapsedSeconds   float64   `json:"elapsedSeconds"`
+	// Throughput is the average upload speed in bytes per second
+	Throughput float64 `json:"throughput"`
+}
+
+// Percent returns the percentage of bytes transferred
+func (p TransferProgress) Percent() float64 {
+	if p.TotalBytes == 0 {
+		return 100
+	}
+	return float64(p.BytesTransferred) * 100 / float64(p.TotalBytes)
+}
+
+// ETA returns the estimated remaining duration of the upload based on the average throughput
+func (p TransferProgress) ETA() time.Duration {
+	if p.Throughput <= 0 {
+		return 0
+	}
+	remaining := float64(p.TotalBytes - p.BytesTransferred)
+	return time.Duration(remaining / p.Throughput * float64(time.Second))
+}
+
+// String returns the progress in a human readable format. Ex: 512.0MiB/1.0GiB (50.0%) at 10.0MiB/s ETA 51s
+func (p TransferProgress) String() string {
+	return fmt.Sprintf("%s/%s (%.1f%%) at %s/s ETA %s",
+		FormatBytes(p.BytesTransferred),
+		FormatBytes(p.TotalBytes),
+		p.Percent(),
+		FormatBytes(int64(p.Throughput)),
+		p.ETA().Round(time.Second))
+}
+
+// UploadReader reads a file being uploaded honouring the upload rate limit and logs the upload progress periodically
+type UploadReader struct {
+	file     *os.File
+	limiter  *rate.Limiter
+	interval time.Duration
+	lastLog  time.Time
+	position int64
+	// readAt is the number of bytes read using ReadAt
+	readAt   int64
+	progress TransferProgress
+	now      func() time.Time
+	lock     sync.Mutex
+}
+
+// OpenUploadFile opens the file present at the given path for uploading it to the given bucket
+// The upload rate is limited to UPLOAD_RATE_LIMIT bytes per second (unlimited when empty or 0)
+// and the progress is logged every UPLOAD_PROGRESS_INTERVAL seconds (default 30)
+func OpenUploadFile(filePath string, bucketName string) (*UploadReader, error) {
+	limiter, err := getUploadLimiter()
+	if err != nil {
+		return nil, err
+	}
+	interval, err := getProgressInterval()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("Couldn't open file %v to upload. Here's why: %v\n", filePath, err)
+	}
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("Couldn't get info of file %v to upload. Here's why: %v\n", filePath, err)
+	}
+	return newUploadReader(file, fileInfo.Size(), bucketName, limiter, interval, time.Now), nil
+}
+
+func newUploadReader(file *os.File, size int64, bucketName string, limiter *rate.Limiter, interval time.Duration, now func() time.Time) *UploadReader {
+	start := now()
+	return &UploadReader{
+		file:     file,
+		limiter:  limiter,
+		interval: interval,
+		lastLog:  start,
+		now:      now,
+		progress: TransferProgress{
+			FileName:   filepath.Base(file.Name()),
+			BucketName: bucketName,
+			TotalBytes: size,
+			StartTime:  start.UTC(),
+		},
+	}
+}
+
+// Read reads from the file waiting on the rate limiter (if any) before returning the bytes read
+func (u *UploadReader) Read(p []byte) (int, error) {
+	if u.limiter != nil && len(p) > u.limiter.Burst() {
+		p = p[:u.limiter.Burst()]
+	}
+	n, err := u.file.Read(p)
+	if n > 0 && u.limiter != nil {
+		if waitErr := u.limiter.WaitN(context.Background(), n); waitErr != nil {
+			return n, waitErr
+		}
+	}
+	u.lock.Lock()
+	u.position += int64(n)
+	u.update(u.position)
+	u.lock.Unlock()
+	return n, err
+}
+
+// ReadAt reads from the file at the given offset waiting on the rate limiter (if any)
+// The s3 upload manager uses ReadAt to upload the parts of a large file without buffering them
+func (u *UploadReader) ReadAt(p []byte, offset int64) (int, error) {
+	var read int
+	for read < len(p) {
+		chunk := p[read:]
+		if u.limiter != nil && len(chunk) > u.limiter.Burst() {
+			chunk = chunk[:u.limiter.Burst()]
+		}
+		n, err := u.file.ReadAt(chunk, offset+int64(read))
+		if n > 0 && u.limiter != nil {
+			if waitErr := u.limiter.WaitN(context.Background(), n); waitErr != nil {
+				return read + n, waitErr
+			}
+		}
+		read += n
+		u.lock.Lock()
+		u.readAt += int64(n)
+		u.update(u.readAt)
+		u.lock.Unlock()
+		if err != nil {
+			return read, err
+		}
+	}
+	return read, nil
+}
+
+// Seek sets the offset of the next read , the sdk clients seek to rewind the file when retrying a request
+func (u *UploadReader) Seek(offset int64, whence int) (int64, error) {
+	position, err := u.file.Seek(offset, whence)
+	if err != nil {
+		return position, err
+	}
+	u.lock.Lock()
+	u.position = position
+	u.lock.Unlock()
+	return position, nil
+}
+
+// Resumed records the bytes uploaded by a previous run , these bytes are not read again
+func (u *UploadReader) Resumed(n int64) {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	u.readAt += n
+	u.update(u.readAt)
+}
+
+// Size returns the size of the file being uploaded
+func (u *UploadReader) Size() int64 {
+	return u.progress.TotalBytes
+}
+
+// Progress returns a snapshot of the upload progress
+func (u *UploadReader) Progress() TransferProgress {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	return u.snapshot()
+}
+
+// Close closes the file , logs the final progress and records it so that it can be reported in the run status
+func (u *UploadReader) Close() error {
+	progress := u.Progress()
+	log.Printf("Upload of file %s to %s ended at %s", progress.FileName, progress.BucketName, progress)
+	transfersLock.Lock()
+	transfers = append(transfers, progress)
+	transfersLock.Unlock()
+	return u.file.Close()
+}
+
+// update must be called while holding the lock
+func (u *UploadReader) update(transferred int64) {
+	// the sdk clients may read the file more than once (checksums , retries) , the progress is the furthest point reached
+	if transferred > u.progress.TotalBytes {
+		transferred = u.progress.TotalBytes
+	}
+	if transferred > u.progress.BytesTransferred {
+		u.progress.BytesTransferred = transferred
+	}
+	now := u.now()
+	if u.interval > 0 && now.Sub(u.lastLog) >= u.interval {
+		u.lastLog = now
+		log.Printf("Uploading file %s to %s %s", u.progress.FileName, u.progress.BucketName, u.snapshot())
+	}
+}
+
+// snapshot must be called while holding the lock
+func (u *UploadReader) snapshot() TransferProgress {
+	progress := u.progress
+	progress.ElapsedSeconds = u.now().Sub(u.progress.StartTime).Seconds()
+	if progress.ElapsedSeconds > 0 {
+		progress.Throughput = float64(progress.BytesTransferred) / progress.ElapsedSeconds
+	}
+	return progress
+}
+
+// TakeTransfers returns the progress of the uploads finished since the previous call
+func TakeTransfers() []TransferProgress {
+	transfersLock.Lock()
+	defer transfersLock.Unlock()
+	finished := transfers
+	transfers = nil
+	return finished
+}
+
+// getUploadLimiter returns the limiter shared by all the uploads of the process , nil if the upload rate is not limited
+func getUploadLimiter() (*rate.Limiter, error) {
+	uploadLimiterOnce.Do(func() {
+		value := strings.TrimSpace(os.Getenv("UPLOAD_RATE_LIMIT"))
+		if value == "" {
+			return
+		}
+		bytesPerSecond, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || bytesPerSecond < 0 {
+			uploadLimiterErr = fmt.Errorf("invalid UPLOAD_RATE_LIMIT %s. It must be the number of bytes per second", value)
+			return
+		}
+		if bytesPerSecond == 0 {
+			return
+		}
+		uploadLimiter = newRateLimiter(bytesPerSecond)
+		log.Printf("Upload rate limited to %s/s", FormatBytes(bytesPerSecond))
+	})
+	return uploadLimiter, uploadLimiterErr
+}
+
+func newRateLimiter(bytesPerSecond int64) *rate.Limiter {
+	burst := bytesPerSecond
+	if burst > maxRateLimitBurst {
+		burst = maxRateLimitBurst
+	}
+	return rate.NewLimiter(rate.Limit(bytesPerSecond), int(burst))
+}
+
+func getProgressInterval() (time.Duration, error) {
+	value := strings.TrimSpace(os.Getenv("UPLOAD_PROGRESS_INTERVAL"))
+	if value == "" {
+		return defaultProgressInterval, nil
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return 0, fmt.Errorf("invalid UPLOAD_PROGRESS_INTERVAL %s. It must be the number of seconds", value)
+	}
+	return time.Duration(seconds) * time.Second, nil
+}
+
+// FormatBytes returns the size in a human readable format. Ex: 1536 returns 1.5KiB
+func FormatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%dB", size)
+	}
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
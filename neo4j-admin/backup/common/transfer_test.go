@@ -0,0 +1,93 @@
+package common
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTransferProgress(t *testing.T) {
+	t.Parallel()
+
+	progress := TransferProgress{
+		TotalBytes:       1024 * 1024 * 1024,
+		BytesTransferred: 512 * 1024 * 1024,
+		ElapsedSeconds:   50,
+		Throughput:       10 * 1024 * 1024,
+	}
+	assert.Equal(t, 50.0, progress.Percent())
+	assert.Equal(t, 51200*time.Millisecond, progress.ETA())
+	assert.Equal(t, "512.0MiB/1.0GiB (50.0%) at 10.0MiB/s ETA 51s", progress.String())
+	assert.Equal(t, 100.0, TransferProgress{}.Percent())
+}
+
+func TestUploadReader(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "neo4j-2024-01-01T00-00-00.backup")
+	content := make([]byte, 64*1024)
+	assert.NoError(t, os.WriteFile(filePath, content, 0644))
+
+	t.Run("progress is tracked across rewinds", func(t *testing.T) {
+		file, err := os.Open(filePath)
+		assert.NoError(t, err)
+		start := time.Now()
+		clock := start
+		reader := newUploadReader(file, int64(len(content)), "demo", nil, 0, func() time.Time { return clock })
+
+		buffer := make([]byte, 16*1024)
+		_, err = reader.Read(buffer)
+		assert.NoError(t, err)
+		// the sdk clients rewind the file to compute checksums or retry a request
+		_, err = reader.Seek(0, io.SeekStart)
+		assert.NoError(t, err)
+		_, err = reader.Read(buffer[:8*1024])
+		assert.NoError(t, err)
+
+		clock = start.Add(2 * time.Second)
+		progress := reader.Progress()
+		assert.Equal(t, "neo4j-2024-01-01T00-00-00.backup", progress.FileName)
+		assert.Equal(t, "demo", progress.BucketName)
+		assert.Equal(t, int64(16*1024), progress.BytesTransferred)
+		assert.Equal(t, 25.0, progress.Percent())
+		assert.Equal(t, float64(8*1024), progress.Throughput)
+
+		_, err = io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(content)), reader.Progress().BytesTransferred)
+		assert.NoError(t, file.Close())
+	})
+
+	t.Run("reads are rate limited", func(t *testing.T) {
+		file, err := os.Open(filePath)
+		assert.NoError(t, err)
+		defer file.Close()
+		// the limiter starts with a full burst of 32KiB , the remaining 32KiB take one second
+		reader := newUploadReader(file, int64(len(content)), "demo", newRateLimiter(32*1024), 0, time.Now)
+
+		start := time.Now()
+		data, err := io.ReadAll(reader)
+		assert.NoError(t, err)
+		assert.Len(t, data, len(content))
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+	})
+
+	t.Run("read at is rate limited", func(t *testing.T) {
+		file, err := os.Open(filePath)
+		assert.NoError(t, err)
+		defer file.Close()
+		reader := newUploadReader(file, int64(len(content)), "demo", newRateLimiter(32*1024), 0, time.Now)
+
+		start := time.Now()
+		data := make([]byte, len(content))
+		n, err := reader.ReadAt(data, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, len(content), n)
+		assert.GreaterOrEqual(t, time.Since(start), 900*time.Millisecond)
+		assert.Equal(t, int64(len(content)), reader.Progress().BytesTransferred)
+	})
+}
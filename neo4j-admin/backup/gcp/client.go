@@ -10,11 +10,17 @@ import (
 
 type gcpClient struct {
 	storageClient *storage.Client
+	uploadOptions *uploadOptions
+	// clientOptions are reused to create the http client of the resumable uploads
+	clientOptions []option.ClientOption
+	// credentialPath is read for the project id when a bucket is created
+	credentialPath string
 }
 
 func NewGCPClient(credentialPath string) (*gcpClient, error) {
 	ctx := context.Background()
 	var client *storage.Client
+	var clientOptions []option.ClientOption
 	var err error
 
 	if credentialPath == "/credentials/" {
@@ -24,13 +30,22 @@ func NewGCPClient(credentialPath string) (*gcpClient, error) {
 			return nil, fmt.Errorf("Unable to create gcs storage client . Here's why: %v", err)
 		}
 	} else {
-		client, err = storage.NewClient(ctx, option.WithCredentialsFile(credentialPath))
+		clientOptions = append(clientOptions, option.WithCredentialsFile(credentialPath))
+		client, err = storage.NewClient(ctx, clientOptions...)
 		if err != nil {
 			return nil, fmt.Errorf("Unable to create gcs storage client with credentials file. Here's why: %v", err)
 		}
 	}
 
+	options, err := getUploadOptions()
+	if err != nil {
+		return nil, err
+	}
+
 	return &gcpClient{
-		storageClient: client,
+		storageClient:  client,
+		uploadOptions:  options,
+		clientOptions:  clientOptions,
+		credentialPath: credentialPath,
 	}, nil
 }
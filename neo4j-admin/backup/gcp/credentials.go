@@ -0,0 +1,44 @@
+package aws
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// CheckCredentials checks the format of the credentials json file , the workload identity is used when the service account is used
+// It returns a description of the credentials without connecting to gcp
+func CheckCredentials(credentialPath string) (string, error) {
+	if credentialPath == "/credentials/" {
+		return "workload identity of the service account", nil
+	}
+	data, err := os.ReadFile(credentialPath)
+	if err != nil {
+		return "", fmt.Errorf("unable to open gcp credential file \n %v", err)
+	}
+	var credentials struct {
+		Type        string `json:"type"`
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		Audience    string `json:"audience"`
+	}
+	if err = json.Unmarshal(data, &credentials); err != nil {
+		return "", fmt.Errorf("invalid gcp credential file %s , it must be a json key file \n %v", credentialPath, err)
+	}
+	switch credentials.Type {
+	case "service_account":
+		if credentials.ClientEmail == "" || credentials.PrivateKey == "" {
+			return "", fmt.Errorf("invalid gcp credential file %s , client_email and private_key are required for a service account key", credentialPath)
+		}
+		return fmt.Sprintf("service account %s", credentials.ClientEmail), nil
+	case "external_account":
+		if credentials.Audience == "" {
+			return "", fmt.Errorf("invalid gcp credential file %s , audience is required for an external account", credentialPath)
+		}
+		return fmt.Sprintf("external account of %s", credentials.Audience), nil
+	case "authorized_user":
+		return "authorized user", nil
+	default:
+		return "", fmt.Errorf("invalid gcp credential file %s , unsupported type %q", credentialPath, credentials.Type)
+	}
+}
@@ -0,0 +1,42 @@
+package aws
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckCredentials(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name        string
+		credentials string
+		want        string
+		wantErr     string
+	}{
+		{name: "service account", credentials: `{"type":"service_account","client_email":"backup@neo4j.iam.gserviceaccount.com","private_key":"key"}`, want: "service account backup@neo4j.iam.gserviceaccount.com"},
+		{name: "missing private key", credentials: `{"type":"service_account","client_email":"backup@neo4j.iam.gserviceaccount.com"}`, wantErr: "client_email and private_key are required"},
+		{name: "external account", credentials: `{"type":"external_account","audience":"//iam.googleapis.com/pool"}`, want: "external account of //iam.googleapis.com/pool"},
+		{name: "not json", credentials: "AZURE_STORAGE_ACCOUNT_NAME=neo4jbackups", wantErr: "it must be a json key file"},
+		{name: "unsupported type", credentials: `{"type":"api_key"}`, wantErr: `unsupported type "api_key"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			credentialPath := filepath.Join(t.TempDir(), "credentials")
+			assert.NoError(t, os.WriteFile(credentialPath, []byte(tt.credentials), 0600))
+			got, err := CheckCredentials(credentialPath)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.want, got)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+	got, err := CheckCredentials("/credentials/")
+	assert.NoError(t, err)
+	assert.Equal(t, "workload identity of the service account", got)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
 	"google.golang.org/api/iterator"
 	"io"
 	"log"
@@ -24,25 +25,14 @@ func (g *gcpClient) CheckBucketAccess(bucketName string) error {
 		query := &storage.Query{
 			Prefix: prefix,
 		}
-		objects := g.storageClient.Bucket(parentBucketName).Objects(ctx, query)
-		var present bool
-		for {
-			attrs, err := objects.Next()
-			if errors.Is(err, iterator.Done) {
-				break
-			}
-			if err != nil {
-				return fmt.Errorf("Unable to get the bucket %s \n Here's why %v", bucketName, err)
-			}
-			if strings.TrimSuffix(attrs.Name, "/") == prefix {
-				present = true
-				break
-			}
+		// the listing fails when the bucket does not exist or is not accessible , an empty prefix is valid
+		// since it is created by the first upload , no placeholder object is required
+		_, err := g.storageClient.Bucket(parentBucketName).Objects(ctx, query).Next()
+		if errors.Is(err, iterator.Done) {
+			log.Printf("Prefix of GCS bucket %s is empty", bucketName)
+		} else if err != nil {
+			return fmt.Errorf("Unable to get the bucket %s \n Here's why %v", bucketName, err)
 		}
-		if !present {
-			return fmt.Errorf("GCP bucket %s not found. Either the name is incorrect or you do not have access to this bucket", bucketName)
-		}
-
 	} else {
 		bucketAttrs, err := g.storageClient.Bucket(bucketName).Attrs(ctx)
 		if err != nil {
@@ -75,9 +65,9 @@ func (g *gcpClient) UploadFile(fileNames []string, bucketName string) error {
 	for _, fileName := range fileNames {
 
 		filePath := fmt.Sprintf("%s/%s", location, fileName)
-		file, err := os.Open(filePath)
+		file, err := common.OpenUploadFile(filePath, bucketName)
 		if err != nil {
-			return fmt.Errorf("Couldn't open file %v to upload. Here's why: %v\n", filePath, err)
+			return err
 		}
 
 		log.Printf("Starting upload of file %s", filePath)
@@ -85,19 +75,33 @@ func (g *gcpClient) UploadFile(fileNames []string, bucketName string) error {
 		if prefix != "" {
 			name = fmt.Sprintf("%s/%s", prefix, fileName)
 		}
+		// large files are uploaded via a resumable session which a retried run can continue
+		if common.ResumableUploadsEnabled() && file.Size() >= resumableThreshold {
+			err = g.resumableUpload(file, parentBucketName, name, fileName, filePath)
+			file.Close()
+			if err != nil {
+				return fmt.Errorf("Error writing file to gcs bucket %s\n Here's why: %v", bucketName, err)
+			}
+			log.Printf("File %s uploaded to GCS bucket %s !!", fileName, bucketName)
+			continue
+		}
+
 		// create a new object handle
 		object := g.storageClient.Bucket(parentBucketName).Object(name)
 
 		// create a new writer for the object
 		writer := object.NewWriter(context.Background())
+		g.uploadOptions.apply(writer, fileName)
 
 		// copy the file contents to the object writer
 		if _, err = io.Copy(writer, file); err != nil {
+			file.Close()
 			return fmt.Errorf("Error writing file to gcs bucket %s\n Here's why: %v", bucketName, err)
 		}
 
 		// close the object writer
 		if err := writer.Close(); err != nil {
+			file.Close()
 			return fmt.Errorf("Error closing writer while uploading file %s to gcs bucket %s \n Here's why: %v", fileName, bucketName, err)
 		}
 		log.Printf("File %s uploaded to GCS bucket %s !!", fileName, bucketName)
@@ -105,3 +109,67 @@ func (g *gcpClient) UploadFile(fileNames []string, bucketName string) error {
 	}
 	return nil
 }
+
+// ListObjects returns all the objects present below the given bucket name and prefix
+func (g *gcpClient) ListObjects(bucketName string) ([]common.ObjectInfo, error) {
+	parentBucketName, prefix := common.SplitBucketName(bucketName)
+	query := &storage.Query{}
+	if prefix != "" {
+		query.Prefix = fmt.Sprintf("%s/", strings.TrimSuffix(prefix, "/"))
+	}
+
+	var objects []common.ObjectInfo
+	iter := g.storageClient.Bucket(parentBucketName).Objects(context.Background(), query)
+	for {
+		attrs, err := iter.Next()
+		if errors.Is(err, iterator.Done) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("Unable to list objects of gcs bucket %s \n Here's why: %v", bucketName, err)
+		}
+		// skip the placeholder object representing the prefix
+		if strings.HasSuffix(attrs.Name, "/") {
+			continue
+		}
+		objects = append(objects, common.ObjectInfo{
+			Key:          attrs.Name,
+			Size:         attrs.Size,
+			LastModified: attrs.Updated,
+			Metadata:     attrs.Metadata,
+		})
+	}
+	return objects, nil
+}
+
+// DownloadFile downloads the object with the given key from the gcs bucket to the given file path
+func (g *gcpClient) DownloadFile(bucketName string, key string, filePath string) error {
+	parentBucketName, _ := common.SplitBucketName(bucketName)
+	file, err := os.Create(filePath)
+	if err != nil {
+		return fmt.Errorf("Couldn't create file %v to download. Here's why: %v\n", filePath, err)
+	}
+	defer file.Close()
+
+	log.Printf("Starting download of object %s from gcs bucket %s", key, parentBucketName)
+	reader, err := g.storageClient.Bucket(parentBucketName).Object(key).NewReader(context.Background())
+	if err != nil {
+		return fmt.Errorf("Couldn't read object %s from gcs bucket %s \n Here's why: %v", key, parentBucketName, err)
+	}
+	defer reader.Close()
+	if _, err = io.Copy(file, reader); err != nil {
+		return fmt.Errorf("Couldn't download object %s from gcs bucket %s \n Here's why: %v", key, parentBucketName, err)
+	}
+	log.Printf("Object %s downloaded to %s !!", key, filePath)
+	return nil
+}
+
+// DeleteObject deletes the object with the given key from the gcs bucket
+func (g *gcpClient) DeleteObject(bucketName string, key string) error {
+	parentBucketName, _ := common.SplitBucketName(bucketName)
+	if err := g.storageClient.Bucket(parentBucketName).Object(key).Delete(context.Background()); err != nil {
+		return fmt.Errorf("Couldn't delete object %s from gcs bucket %s \n Here's why: %v", key, parentBucketName, err)
+	}
+	log.Printf("Object %s deleted from gcs bucket %s !!", key, parentBucketName)
+	return nil
+}
@@ -38,9 +38,14 @@ func TestCheckBucketAccessForGCP(t *testing.T) {
 			bucketName: "does-not-exist-bucket",
 		},
 		{
-			name:       "invalid subdirectory",
+			name:       "invalid bucket with subdirectory",
 			wantErr:    true,
-			bucketName: "helm-backup-test/invalid",
+			bucketName: "does-not-exist-bucket/test",
+		},
+		{
+			name:       "empty subdirectory",
+			wantErr:    false,
+			bucketName: "helm-backup-test/empty",
 		},
 	}
 
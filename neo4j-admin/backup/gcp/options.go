@@ -0,0 +1,57 @@
+package aws
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+var storageClasses = []string{"STANDARD", "NEARLINE", "COLDLINE", "ARCHIVE"}
+
+// uploadOptions contains the gcs specific options applied to every uploaded object
+type uploadOptions struct {
+	storageClass string
+	kmsKeyName   string
+	metadata     map[string]string
+}
+
+// getUploadOptions reads the gcs upload options from the below env variables
+// GCP_STORAGE_CLASS , GCP_KMS_KEY_NAME , OBJECT_METADATA
+func getUploadOptions() (*uploadOptions, error) {
+	options := &uploadOptions{
+		storageClass: strings.ToUpper(strings.TrimSpace(os.Getenv("GCP_STORAGE_CLASS"))),
+		kmsKeyName:   strings.TrimSpace(os.Getenv("GCP_KMS_KEY_NAME")),
+	}
+	if options.storageClass != "" && !isValidStorageClass(options.storageClass) {
+		return nil, fmt.Errorf("invalid gcs storage class %s. Supported values are %v", options.storageClass, storageClasses)
+	}
+	var err error
+	options.metadata, err = common.ParseKeyValuePairs(os.Getenv("OBJECT_METADATA"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid object metadata \n %v", err)
+	}
+	return options, nil
+}
+
+// apply sets the storage class , kms key and custom metadata on the given object writer
+func (o *uploadOptions) apply(writer *storage.Writer, fileName string) {
+	writer.Metadata = common.MergeMetadata(o.metadata, common.ArtifactMetadata(fileName))
+	if o.storageClass != "" {
+		writer.StorageClass = o.storageClass
+	}
+	if o.kmsKeyName != "" {
+		writer.KMSKeyName = o.kmsKeyName
+	}
+}
+
+func isValidStorageClass(storageClass string) bool {
+	for _, value := range storageClasses {
+		if value == storageClass {
+			return true
+		}
+	}
+	return false
+}
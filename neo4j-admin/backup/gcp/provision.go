@@ -0,0 +1,118 @@
+package aws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// CreateBucket creates the gcs bucket of the given bucket name if it does not exist
+// The bucket is created in the project GCP_PROJECT_ID (or the project of the credentials file) at GCP_BUCKET_LOCATION (US by default)
+func (g *gcpClient) CreateBucket(bucketName string) (bool, error) {
+	parentBucketName, _ := common.SplitBucketName(bucketName)
+	bucket := g.storageClient.Bucket(parentBucketName)
+	_, err := bucket.Attrs(context.Background())
+	if err == nil {
+		return false, nil
+	}
+	if !errors.Is(err, storage.ErrBucketNotExist) {
+		return false, fmt.Errorf("Unable to check if gcs bucket %s exists \n Here's why: %v", parentBucketName, err)
+	}
+	projectID, err := g.projectID()
+	if err != nil {
+		return false, err
+	}
+	attrs := &storage.BucketAttrs{Location: strings.TrimSpace(os.Getenv("GCP_BUCKET_LOCATION"))}
+	if err = bucket.Create(context.Background(), projectID, attrs); err != nil {
+		return false, fmt.Errorf("Unable to create gcs bucket %s in project %s \n Here's why: %v", parentBucketName, projectID, err)
+	}
+	log.Printf("GCS bucket %s created in project %s !!", parentBucketName, projectID)
+	return true, nil
+}
+
+// backupRuleSuffixes are the suffixes of the backup artifacts , dumps and reports matched by the lifecycle rules of ApplyLifecycle
+// gcs rules do not have an id , the suffixes also tag the rules so that the rules defined by the users are never replaced
+var backupRuleSuffixes = []string{".backup", ".dump", ".tar.gz", ".json"}
+
+// ApplyLifecycle applies the deletion and the abort of incomplete multipart uploads to the prefix of the given bucket name
+// The rules previously applied to the same prefix are replaced , the update fails if the bucket is modified concurrently
+func (g *gcpClient) ApplyLifecycle(bucketName string, policy common.LifecyclePolicy) error {
+	parentBucketName, prefix := common.SplitBucketName(bucketName)
+	bucket := g.storageClient.Bucket(parentBucketName)
+	attrs, err := bucket.Attrs(context.Background())
+	if err != nil {
+		return fmt.Errorf("Unable to read the lifecycle rules of gcs bucket %s \n Here's why: %v", parentBucketName, err)
+	}
+	rules := lifecycleRules(attrs.Lifecycle.Rules, prefix, policy)
+	_, err = bucket.If(storage.BucketConditions{MetagenerationMatch: attrs.MetaGeneration}).
+		Update(context.Background(), storage.BucketAttrsToUpdate{Lifecycle: &storage.Lifecycle{Rules: rules}})
+	if err != nil {
+		return fmt.Errorf("Unable to apply the lifecycle rules to gcs bucket %s \n Here's why: %v", bucketName, err)
+	}
+	log.Printf("Lifecycle rules %s applied to gcs bucket %s !!", common.LifecycleRuleID(bucketName), parentBucketName)
+	return nil
+}
+
+// lifecycleRules returns the existing rules of the bucket in which the rules of the policy replace the ones previously applied to the prefix
+func lifecycleRules(existing []storage.LifecycleRule, prefix string, policy common.LifecyclePolicy) []storage.LifecycleRule {
+	var matchesPrefix []string
+	if prefix = strings.Trim(prefix, "/"); prefix != "" {
+		matchesPrefix = []string{prefix + "/"}
+	}
+	var rules []storage.LifecycleRule
+	for _, rule := range existing {
+		if !isBackupRule(rule, matchesPrefix) {
+			rules = append(rules, rule)
+		}
+	}
+	if policy.ExpirationDays > 0 {
+		rules = append(rules, storage.LifecycleRule{
+			Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+			Condition: storage.LifecycleCondition{AgeInDays: int64(policy.ExpirationDays), MatchesPrefix: matchesPrefix, MatchesSuffix: backupRuleSuffixes},
+		})
+	}
+	if policy.AbortIncompleteUploadDays > 0 {
+		rules = append(rules, storage.LifecycleRule{
+			Action:    storage.LifecycleAction{Type: storage.AbortIncompleteMPUAction},
+			Condition: storage.LifecycleCondition{AgeInDays: int64(policy.AbortIncompleteUploadDays), MatchesPrefix: matchesPrefix, MatchesSuffix: backupRuleSuffixes},
+		})
+	}
+	return rules
+}
+
+// isBackupRule returns true for a rule applied by ApplyLifecycle to the given prefixes , recognised by the backup suffixes
+// A rule without them (ex: a bucket wide rule of the user) is never replaced
+func isBackupRule(rule storage.LifecycleRule, matchesPrefix []string) bool {
+	if rule.Action.Type != storage.DeleteAction && rule.Action.Type != storage.AbortIncompleteMPUAction {
+		return false
+	}
+	condition := rule.Condition
+	condition.AgeInDays = 0
+	return fmt.Sprint(condition) == fmt.Sprint(storage.LifecycleCondition{MatchesPrefix: matchesPrefix, MatchesSuffix: backupRuleSuffixes})
+}
+
+// projectID returns GCP_PROJECT_ID , falling back to the project_id of the service account credentials file
+func (g *gcpClient) projectID() (string, error) {
+	if projectID := strings.TrimSpace(os.Getenv("GCP_PROJECT_ID")); projectID != "" {
+		return projectID, nil
+	}
+	if g.credentialPath != "" && g.credentialPath != "/credentials/" {
+		data, err := os.ReadFile(g.credentialPath)
+		if err == nil {
+			var credentials struct {
+				ProjectID string `json:"project_id"`
+			}
+			if json.Unmarshal(data, &credentials) == nil && credentials.ProjectID != "" {
+				return credentials.ProjectID, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("unable to create the gcs bucket , the project is unknown. Please set GCP_PROJECT_ID")
+}
@@ -0,0 +1,46 @@
+package aws
+
+import (
+	"testing"
+
+	"cloud.google.com/go/storage"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycleRules(t *testing.T) {
+	t.Parallel()
+
+	userDelete := storage.LifecycleRule{
+		Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+		Condition: storage.LifecycleCondition{AgeInDays: 365},
+	}
+	userAbort := storage.LifecycleRule{
+		Action:    storage.LifecycleAction{Type: storage.AbortIncompleteMPUAction},
+		Condition: storage.LifecycleCondition{AgeInDays: 1},
+	}
+	previous := storage.LifecycleRule{
+		Action:    storage.LifecycleAction{Type: storage.AbortIncompleteMPUAction},
+		Condition: storage.LifecycleCondition{AgeInDays: 3, MatchesSuffix: backupRuleSuffixes},
+	}
+	abort := storage.LifecycleRule{
+		Action:    storage.LifecycleAction{Type: storage.AbortIncompleteMPUAction},
+		Condition: storage.LifecycleCondition{AgeInDays: 7, MatchesSuffix: backupRuleSuffixes},
+	}
+
+	// the bucket wide rules of the user are kept for a destination without prefix , the previous rule of the chart is replaced
+	rules := lifecycleRules([]storage.LifecycleRule{userDelete, userAbort, previous}, "", common.LifecyclePolicy{AbortIncompleteUploadDays: 7})
+	assert.Equal(t, []storage.LifecycleRule{userDelete, userAbort, abort}, rules)
+
+	// the rules of another prefix are kept
+	rules = lifecycleRules(rules, "team/neo4j/", common.LifecyclePolicy{ExpirationDays: 30})
+	assert.Equal(t, []storage.LifecycleRule{userDelete, userAbort, abort, {
+		Action:    storage.LifecycleAction{Type: storage.DeleteAction},
+		Condition: storage.LifecycleCondition{AgeInDays: 30, MatchesPrefix: []string{"team/neo4j/"}, MatchesSuffix: backupRuleSuffixes},
+	}}, rules)
+
+	// disabling the rules only removes the rules of the chart
+	rules = lifecycleRules(rules, "", common.LifecyclePolicy{})
+	assert.Len(t, rules, 3)
+	assert.Equal(t, []storage.LifecycleRule{userDelete, userAbort}, rules[:2])
+}
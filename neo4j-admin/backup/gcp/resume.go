@@ -0,0 +1,210 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"google.golang.org/api/option"
+	htransport "google.golang.org/api/transport/http"
+)
+
+const (
+	// resumableChunkSize is the size of every request of a resumable upload , it must be a multiple of 256KiB
+	resumableChunkSize = 16 * 1024 * 1024
+	// resumableThreshold is the minimum file size uploaded via a resumable session
+	resumableThreshold = 1024 * 1024 * 1024
+	// statusResumeIncomplete is returned by gcs for a resumable upload which is not completed yet
+	statusResumeIncomplete = 308
+)
+
+// uploadURL is the gcs json api endpoint used to start resumable upload sessions
+var uploadURL = "https://storage.googleapis.com/upload/storage/v1/b/%s/o"
+
+// resumableUpload uploads the file using a gcs resumable upload session whose uri is persisted in the upload state
+// A retried run queries the offset persisted by gcs for the same session and uploads only the remaining bytes
+func (g *gcpClient) resumableUpload(file *common.UploadReader, bucketName string, name string, fileName string, filePath string) error {
+	ctx := context.Background()
+	checksum, err := common.FileChecksum(filePath)
+	if err != nil {
+		return err
+	}
+	httpClient, _, err := htransport.NewClient(ctx, append(g.clientOptions, option.WithScopes(storage.ScopeReadWrite))...)
+	if err != nil {
+		return fmt.Errorf("Unable to create gcs http client. Here's why: %v", err)
+	}
+
+	size := file.Size()
+	var offset int64
+	session, found := common.FindUploadSession("gcp", bucketName, name, checksum)
+	if found {
+		var completed bool
+		offset, completed, err = queryUploadOffset(ctx, httpClient, session.ID, size)
+		switch {
+		case err != nil:
+			log.Printf("Unable to resume upload session of %s , starting a new session: %v", name, err)
+			found = false
+			offset = 0
+		case completed:
+			file.Resumed(size)
+			return common.DeleteUploadSession("gcp", bucketName, name)
+		default:
+			log.Printf("Resuming upload session of %s at %s", name, common.FormatBytes(offset))
+		}
+	}
+	if !found {
+		sessionURI, err := g.startUploadSession(ctx, httpClient, bucketName, name, fileName, size)
+		if err != nil {
+			return err
+		}
+		session = common.UploadSession{
+			CloudProvider: "gcp",
+			BucketName:    bucketName,
+			Key:           name,
+			Checksum:      checksum,
+			ID:            sessionURI,
+			PartSize:      resumableChunkSize,
+			Created:       time.Now().UTC(),
+		}
+		if err = common.SaveUploadSession(session); err != nil {
+			return err
+		}
+	}
+
+	file.Resumed(offset)
+	for offset < size {
+		length := int64(resumableChunkSize)
+		if offset+length > size {
+			length = size - offset
+		}
+		var completed bool
+		offset, completed, err = uploadChunk(ctx, httpClient, session.ID, io.NewSectionReader(file, offset, length), offset, length, size)
+		if err != nil {
+			return err
+		}
+		if completed {
+			break
+		}
+	}
+	return common.DeleteUploadSession("gcp", bucketName, name)
+}
+
+// startUploadSession starts a resumable upload session carrying the object metadata , storage class and kms key and returns the session uri
+func (g *gcpClient) startUploadSession(ctx context.Context, httpClient *http.Client, bucketName string, name string, fileName string, size int64) (string, error) {
+	object := map[string]interface{}{
+		"name":     name,
+		"metadata": common.MergeMetadata(g.uploadOptions.metadata, common.ArtifactMetadata(fileName)),
+	}
+	if g.uploadOptions.storageClass != "" {
+		object["storageClass"] = g.uploadOptions.storageClass
+	}
+	if g.uploadOptions.kmsKeyName != "" {
+		object["kmsKeyName"] = g.uploadOptions.kmsKeyName
+	}
+	body, err := json.Marshal(object)
+	if err != nil {
+		return "", err
+	}
+	query := url.Values{}
+	query.Set("uploadType", "resumable")
+	query.Set("name", name)
+	requestURL := fmt.Sprintf(uploadURL, url.PathEscape(bucketName)) + "?" + query.Encode()
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	request.Header.Set("Content-Type", "application/json; charset=UTF-8")
+	request.Header.Set("X-Upload-Content-Length", strconv.FormatInt(size, 10))
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return "", fmt.Errorf("Couldn't start upload session for %s. Here's why: %v", name, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("Couldn't start upload session for %s. Here's why: %s", name, responseError(response))
+	}
+	sessionURI := response.Header.Get("Location")
+	if sessionURI == "" {
+		return "", fmt.Errorf("Couldn't start upload session for %s. Here's why: missing session uri", name)
+	}
+	return sessionURI, nil
+}
+
+// queryUploadOffset returns the number of bytes persisted by gcs for the session and whether the upload is already completed
+func queryUploadOffset(ctx context.Context, httpClient *http.Client, sessionURI string, size int64) (int64, bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, sessionURI, http.NoBody)
+	if err != nil {
+		return 0, false, err
+	}
+	request.Header.Set("Content-Range", fmt.Sprintf("bytes */%d", size))
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return 0, false, err
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return size, true, nil
+	case statusResumeIncomplete:
+		offset, err := persistedOffset(response)
+		return offset, false, err
+	default:
+		return 0, false, fmt.Errorf("unexpected upload session status %s", responseError(response))
+	}
+}
+
+// uploadChunk uploads a single chunk of the file and returns the offset of the next chunk
+func uploadChunk(ctx context.Context, httpClient *http.Client, sessionURI string, chunk io.Reader, offset int64, length int64, size int64) (int64, bool, error) {
+	request, err := http.NewRequestWithContext(ctx, http.MethodPut, sessionURI, chunk)
+	if err != nil {
+		return offset, false, err
+	}
+	request.ContentLength = length
+	request.Header.Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", offset, offset+length-1, size))
+	response, err := httpClient.Do(request)
+	if err != nil {
+		return offset, false, fmt.Errorf("Couldn't upload chunk at offset %d. Here's why: %v", offset, err)
+	}
+	defer response.Body.Close()
+	switch response.StatusCode {
+	case http.StatusOK, http.StatusCreated:
+		return size, true, nil
+	case statusResumeIncomplete:
+		next, err := persistedOffset(response)
+		return next, false, err
+	default:
+		return offset, false, fmt.Errorf("Couldn't upload chunk at offset %d. Here's why: %s", offset, responseError(response))
+	}
+}
+
+// persistedOffset parses the Range header (bytes=0-<last byte>) returned by gcs for an incomplete upload
+func persistedOffset(response *http.Response) (int64, error) {
+	value := response.Header.Get("Range")
+	if value == "" {
+		return 0, nil
+	}
+	index := strings.LastIndex(value, "-")
+	if index == -1 {
+		return 0, fmt.Errorf("invalid Range header %s", value)
+	}
+	last, err := strconv.ParseInt(value[index+1:], 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid Range header %s", value)
+	}
+	return last + 1, nil
+}
+
+func responseError(response *http.Response) string {
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 1024))
+	return fmt.Sprintf("%s %s", response.Status, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,98 @@
+package aws
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/api/option"
+)
+
+// fakeResumableServer implements the subset of the gcs resumable upload protocol used by resumableUpload
+type fakeResumableServer struct {
+	lock     sync.Mutex
+	received []byte
+	sessions int
+	// failAt makes the chunk starting at the given offset fail once
+	failAt int64
+}
+
+func (f *fakeResumableServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if r.Method == http.MethodPost {
+		f.sessions++
+		w.Header().Set("Location", fmt.Sprintf("http://%s/session/%d", r.Host, f.sessions))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	contentRange := strings.TrimPrefix(r.Header.Get("Content-Range"), "bytes ")
+	rangeValue, sizeValue, _ := strings.Cut(contentRange, "/")
+	size, _ := strconv.ParseInt(sizeValue, 10, 64)
+	if rangeValue != "*" {
+		start, _ := strconv.ParseInt(strings.Split(rangeValue, "-")[0], 10, 64)
+		if start == f.failAt {
+			f.failAt = -1
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.received = append(f.received[:start], data...)
+	}
+	if int64(len(f.received)) == size {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	if len(f.received) > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", len(f.received)-1))
+	}
+	w.WriteHeader(statusResumeIncomplete)
+}
+
+func TestResumableUpload(t *testing.T) {
+	t.Setenv("UPLOAD_STATE_FILE", filepath.Join(t.TempDir(), "upload-state.json"))
+	fake := &fakeResumableServer{failAt: resumableChunkSize}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	uploadURL = server.URL + "/upload/storage/v1/b/%s/o"
+
+	content := bytes.Repeat([]byte("neo4j"), (resumableChunkSize+1024*1024)/5)
+	filePath := filepath.Join(t.TempDir(), "neo4j-2024-01-01T00-00-00.backup")
+	assert.NoError(t, os.WriteFile(filePath, content, 0644))
+
+	client := &gcpClient{
+		uploadOptions: &uploadOptions{},
+		clientOptions: []option.ClientOption{option.WithHTTPClient(server.Client())},
+	}
+	upload := func() error {
+		file, err := common.OpenUploadFile(filePath, "demo")
+		assert.NoError(t, err)
+		defer file.Close()
+		return client.resumableUpload(file, "demo", "neo4j-2024-01-01T00-00-00.backup", "neo4j-2024-01-01T00-00-00.backup", filePath)
+	}
+
+	// the second chunk fails , the session uri is kept in the upload state
+	assert.Error(t, upload())
+	state, err := common.GetUploadState()
+	assert.NoError(t, err)
+	assert.Len(t, state.Sessions, 1)
+	assert.Len(t, fake.received, resumableChunkSize)
+
+	// the retried upload continues the same session from the persisted offset
+	assert.NoError(t, upload())
+	assert.Equal(t, 1, fake.sessions)
+	assert.Equal(t, content, fake.received)
+	state, err = common.GetUploadState()
+	assert.NoError(t, err)
+	assert.Empty(t, state.Sessions)
+}
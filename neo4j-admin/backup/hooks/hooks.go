@@ -0,0 +1,265 @@
+// Package hooks runs the commands and HTTP calls configured around the backup runs , ex: to pause an ETL before the backup
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+	"k8s.io/utils/strings/slices"
+)
+
+// events the hooks are run on
+const (
+	// PreBackup runs before the backup , a failure prevents the backup
+	PreBackup = "preBackup"
+	// PostBackup runs once the artifacts are created , before they are uploaded
+	PostBackup = "postBackup"
+	// PostUpload runs once the artifacts are uploaded to at least one destination
+	PostUpload = "postUpload"
+	// OnFailure runs when the run failed , its failures are only logged
+	OnFailure = "onFailure"
+)
+
+// failure policies of a hook
+const (
+	FailurePolicyFail   = "fail"
+	FailurePolicyIgnore = "ignore"
+)
+
+const (
+	defaultTimeout = time.Minute
+	// maxResponseLength is the longest part of a HTTP response logged
+	maxResponseLength = 4096
+)
+
+var events = []string{PreBackup, PostBackup, PostUpload, OnFailure}
+
+// Hook is either a command or a HTTP call run on an event
+type Hook struct {
+	Name  string `json:"name"`
+	Event string `json:"event"`
+	// Command is executed without a shell , ex: ["sh", "-c", "curl -X POST http://etl/pause"]
+	Command []string `json:"command,omitempty"`
+	// URL receives the input as json , ${VAR} in the url and the header values is replaced by the env variable
+	URL     string            `json:"url,omitempty"`
+	Method  string            `json:"method,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Timeout is a duration ex: 30s , 1m by default
+	Timeout string `json:"timeout,omitempty"`
+	// FailurePolicy is fail (default) or ignore. A failing hook with the fail policy fails the run
+	FailurePolicy string `json:"failurePolicy,omitempty"`
+
+	timeout time.Duration
+}
+
+// Input describes the run to the hooks , as json on the stdin of the commands and as the body of the HTTP calls
+// The commands get it as BACKUP_HOOK_* env variables as well
+type Input struct {
+	Event     string   `json:"event"`
+	Databases []string `json:"databases"`
+	Artifacts []string `json:"artifacts,omitempty"`
+	// Status is the status of the run , empty until the run is finished
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+	// Run is the run status as written to STATUS_FILE
+	Run json.RawMessage `json:"run,omitempty"`
+}
+
+// Runner runs the hooks of the events
+type Runner struct {
+	hooks  []Hook
+	client *http.Client
+}
+
+// Parse returns the hooks of the json list , the defaults are applied
+func Parse(value string) ([]Hook, error) {
+	var hooks []Hook
+	if strings.TrimSpace(value) == "" {
+		return hooks, nil
+	}
+	if err := json.Unmarshal([]byte(value), &hooks); err != nil {
+		return nil, fmt.Errorf("unable to parse BACKUP_HOOKS \n %v", err)
+	}
+	for i := range hooks {
+		h := &hooks[i]
+		if h.Name == "" {
+			h.Name = fmt.Sprintf("hook-%d", i+1)
+		}
+		if !slices.Contains(events, h.Event) {
+			return nil, fmt.Errorf("hook %s has an invalid event %q. Supported events are %s", h.Name, h.Event, strings.Join(events, " , "))
+		}
+		if (len(h.Command) == 0) == (h.URL == "") {
+			return nil, fmt.Errorf("hook %s must contain either command or url", h.Name)
+		}
+		if h.FailurePolicy == "" {
+			h.FailurePolicy = FailurePolicyFail
+		}
+		if h.FailurePolicy != FailurePolicyFail && h.FailurePolicy != FailurePolicyIgnore {
+			return nil, fmt.Errorf("hook %s has an invalid failurePolicy %q. Supported values are fail and ignore", h.Name, h.FailurePolicy)
+		}
+		h.timeout = defaultTimeout
+		if h.Timeout != "" {
+			timeout, err := time.ParseDuration(h.Timeout)
+			if err != nil || timeout <= 0 {
+				return nil, fmt.Errorf("hook %s has an invalid timeout %q", h.Name, h.Timeout)
+			}
+			h.timeout = timeout
+		}
+		if h.Method == "" {
+			h.Method = http.MethodPost
+		}
+	}
+	return hooks, nil
+}
+
+// NewRunner returns a runner of the given hooks
+func NewRunner(hooks []Hook) *Runner {
+	return &Runner{hooks: hooks, client: &http.Client{}}
+}
+
+// NewRunnerFromEnv returns a runner of the hooks of BACKUP_HOOKS , nil when no hook is configured
+func NewRunnerFromEnv() (*Runner, error) {
+	hooks, err := Parse(os.Getenv("BACKUP_HOOKS"))
+	if err != nil || len(hooks) == 0 {
+		return nil, err
+	}
+	return NewRunner(hooks), nil
+}
+
+// Run runs the hooks of the event in order , stopping at the first failing hook with the fail policy
+// The failures of the hooks with the ignore policy are logged
+func (r *Runner) Run(ctx context.Context, input Input) error {
+	if r == nil {
+		return nil
+	}
+	body, err := json.Marshal(input)
+	if err != nil {
+		return fmt.Errorf("unable to marshal the input of the %s hooks \n %v", input.Event, err)
+	}
+	for _, h := range r.hooks {
+		if h.Event != input.Event {
+			continue
+		}
+		log.Printf("Running %s hook %s", h.Event, h.Name)
+		start := time.Now()
+		// the span of the hook is the parent of the trace context passed to the command or the HTTP call
+		span := tracing.Start("hook", tracing.HookName.String(h.Name), tracing.HookEvent.String(h.Event))
+		hookCtx, cancel := context.WithTimeout(ctx, h.timeout)
+		if len(h.Command) > 0 {
+			err = runCommand(hookCtx, h, input, body)
+		} else {
+			err = r.call(hookCtx, h, body)
+		}
+		cancel()
+		span.End(err)
+		if err == nil {
+			log.Printf("%s hook %s completed in %v !!", h.Event, h.Name, time.Since(start).Round(time.Millisecond))
+			continue
+		}
+		if h.FailurePolicy == FailurePolicyIgnore {
+			log.Printf("Warning: %s hook %s failed , the failure is ignored: %v", h.Event, h.Name, err)
+			continue
+		}
+		return fmt.Errorf("%s hook %s failed \n %v", h.Event, h.Name, err)
+	}
+	return nil
+}
+
+// runCommand runs the command of the hook , its output is logged line by line prefixed with the hook name
+// The command gets the trace context of the span in progress as TRACEPARENT
+func runCommand(ctx context.Context, h Hook, input Input, body []byte) error {
+	cmd := exec.CommandContext(ctx, h.Command[0], h.Command[1:]...)
+	cmd.Env = append(os.Environ(),
+		"BACKUP_HOOK_NAME="+h.Name,
+		"BACKUP_HOOK_EVENT="+input.Event,
+		"BACKUP_HOOK_DATABASES="+strings.Join(input.Databases, ","),
+		"BACKUP_HOOK_ARTIFACTS="+strings.Join(input.Artifacts, ","),
+		"BACKUP_HOOK_STATUS="+input.Status,
+		"BACKUP_HOOK_ERROR="+input.Error,
+	)
+	cmd.Env = append(cmd.Env, tracing.Environment()...)
+	cmd.Stdin = bytes.NewReader(body)
+	output := &lineLogger{prefix: fmt.Sprintf("[hook %s] ", h.Name)}
+	cmd.Stdout, cmd.Stderr = output, output
+	// the output pipes of the children of a killed command may stay open
+	cmd.WaitDelay = 5 * time.Second
+	err := cmd.Run()
+	output.Flush()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("command %v timed out after %s", h.Command, h.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("command %v failed \n %v", h.Command, err)
+	}
+	return nil
+}
+
+// call sends the input to the url of the hook , a response outside of 2xx fails the hook
+// The request carries the trace context of the span in progress in the traceparent header
+func (r *Runner) call(ctx context.Context, h Hook, body []byte) error {
+	url := os.ExpandEnv(h.URL)
+	request, err := http.NewRequestWithContext(ctx, h.Method, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("invalid request %s %s \n %v", h.Method, h.URL, err)
+	}
+	request.Header.Set("Content-Type", "application/json")
+	tracing.Inject(request.Header)
+	for name, value := range h.Headers {
+		request.Header.Set(name, os.ExpandEnv(value))
+	}
+	response, err := r.client.Do(request)
+	if err != nil {
+		return fmt.Errorf("%s %s failed \n %v", h.Method, h.URL, err)
+	}
+	defer response.Body.Close()
+	data, _ := io.ReadAll(io.LimitReader(response.Body, maxResponseLength))
+	if text := strings.TrimSpace(string(data)); text != "" {
+		log.Printf("[hook %s] %s", h.Name, text)
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s %s returned %s", h.Method, h.URL, response.Status)
+	}
+	return nil
+}
+
+// lineLogger logs every complete line written with the given prefix
+type lineLogger struct {
+	prefix string
+	lock   sync.Mutex
+	buffer []byte
+}
+
+func (l *lineLogger) Write(p []byte) (int, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.buffer = append(l.buffer, p...)
+	for {
+		i := bytes.IndexByte(l.buffer, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		log.Printf("%s%s", l.prefix, l.buffer[:i])
+		l.buffer = l.buffer[i+1:]
+	}
+}
+
+// Flush logs the last line when it does not end with a new line
+func (l *lineLogger) Flush() {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if len(l.buffer) > 0 {
+		log.Printf("%s%s", l.prefix, l.buffer)
+		l.buffer = nil
+	}
+}
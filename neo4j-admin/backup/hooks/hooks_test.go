@@ -0,0 +1,193 @@
+package hooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestParse(t *testing.T) {
+	t.Parallel()
+
+	hooks, err := Parse(`[{"event":"preBackup","command":["echo","pause"]},{"name":"notify","event":"onFailure","url":"http://hooks","timeout":"5s","failurePolicy":"ignore"}]`)
+	assert.NoError(t, err)
+	if assert.Len(t, hooks, 2) {
+		assert.Equal(t, "hook-1", hooks[0].Name)
+		assert.Equal(t, FailurePolicyFail, hooks[0].FailurePolicy)
+		assert.Equal(t, time.Minute, hooks[0].timeout)
+		assert.Equal(t, http.MethodPost, hooks[1].Method)
+		assert.Equal(t, 5*time.Second, hooks[1].timeout)
+	}
+
+	tests := []struct {
+		name      string
+		value     string
+		wantError string
+	}{
+		{name: "invalid json", value: `{"event":"preBackup"}`, wantError: "unable to parse BACKUP_HOOKS"},
+		{name: "invalid event", value: `[{"event":"beforeBackup","command":["true"]}]`, wantError: "invalid event"},
+		{name: "command and url", value: `[{"event":"preBackup","command":["true"],"url":"http://hooks"}]`, wantError: "either command or url"},
+		{name: "neither command nor url", value: `[{"event":"preBackup"}]`, wantError: "either command or url"},
+		{name: "invalid policy", value: `[{"event":"preBackup","command":["true"],"failurePolicy":"retry"}]`, wantError: "invalid failurePolicy"},
+		{name: "invalid timeout", value: `[{"event":"preBackup","command":["true"],"timeout":"10"}]`, wantError: "invalid timeout"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(tt.value)
+			assert.ErrorContains(t, err, tt.wantError)
+		})
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	hooks, err := Parse(`[
+		{"name":"watermark","event":"postBackup","command":["sh","-c","echo $BACKUP_HOOK_EVENT $BACKUP_HOOK_DATABASES $BACKUP_HOOK_ARTIFACTS > $0/env && cat > $0/input.json","` + directory + `"]},
+		{"name":"other event","event":"preBackup","command":["sh","-c","touch $0/pre","` + directory + `"]}
+	]`)
+	assert.NoError(t, err)
+	input := Input{Event: PostBackup, Databases: []string{"neo4j", "orders"}, Artifacts: []string{"neo4j-2024-06-13T10-01-00.backup"}}
+	assert.NoError(t, NewRunner(hooks).Run(context.Background(), input))
+
+	env, err := os.ReadFile(filepath.Join(directory, "env"))
+	assert.NoError(t, err)
+	assert.Equal(t, "postBackup neo4j,orders neo4j-2024-06-13T10-01-00.backup\n", string(env))
+	data, err := os.ReadFile(filepath.Join(directory, "input.json"))
+	assert.NoError(t, err)
+	received := Input{}
+	assert.NoError(t, json.Unmarshal(data, &received))
+	assert.Equal(t, input, received)
+	assert.NoFileExists(t, filepath.Join(directory, "pre"), "only the hooks of the event are run")
+}
+
+func TestRunFailurePolicies(t *testing.T) {
+	t.Parallel()
+
+	directory := t.TempDir()
+	tests := []struct {
+		name      string
+		hooks     string
+		wantError string
+	}{
+		{name: "failure", hooks: `[{"name":"pause","event":"preBackup","command":["false"]},{"event":"preBackup","command":["touch","` + directory + `/failure"]}]`, wantError: "preBackup hook pause failed"},
+		{name: "ignored failure", hooks: `[{"event":"preBackup","command":["false"],"failurePolicy":"ignore"},{"event":"preBackup","command":["touch","` + directory + `/ignored"]}]`},
+		{name: "timeout", hooks: `[{"event":"preBackup","command":["sleep","5"],"timeout":"100ms"}]`, wantError: "timed out after 100ms"},
+		{name: "missing command", hooks: `[{"event":"preBackup","command":["/missing/command"]}]`, wantError: "no such file or directory"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hooks, err := Parse(tt.hooks)
+			assert.NoError(t, err)
+			err = NewRunner(hooks).Run(context.Background(), Input{Event: PreBackup})
+			if tt.wantError == "" {
+				assert.NoError(t, err)
+				return
+			}
+			assert.ErrorContains(t, err, tt.wantError)
+		})
+	}
+	assert.NoFileExists(t, filepath.Join(directory, "failure"), "the hooks following a failure are not run")
+	assert.FileExists(t, filepath.Join(directory, "ignored"))
+}
+
+func TestRunHTTP(t *testing.T) {
+	t.Setenv("HOOK_TOKEN", "secret")
+
+	var received Input
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&received))
+		if received.Status == "failed" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+		_, _ = w.Write([]byte("recorded"))
+	}))
+	defer server.Close()
+
+	hooks, err := Parse(`[{"name":"notify","event":"onFailure","url":"` + server.URL + `/runs","method":"PUT","headers":{"Authorization":"Bearer ${HOOK_TOKEN}"}}]`)
+	assert.NoError(t, err)
+	runner := NewRunner(hooks)
+	input := Input{Event: OnFailure, Databases: []string{"neo4j"}, Status: "partial", Error: "upload failed", Run: json.RawMessage(`{"status":"partial"}`)}
+	assert.NoError(t, runner.Run(context.Background(), input))
+	assert.Equal(t, input, received)
+
+	input.Status = "failed"
+	assert.ErrorContains(t, runner.Run(context.Background(), input), "returned 502 Bad Gateway")
+}
+
+func TestRunTraceContext(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	t.Setenv("TRACING_ENABLED", "false")
+	t.Setenv("TRACEPARENT", "")
+	assert.NoError(t, tracing.Init())
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+	directory := t.TempDir()
+	hooks, err := Parse(`[
+		{"name":"notify","event":"preBackup","url":"` + server.URL + `"},
+		{"name":"pause","event":"preBackup","command":["sh","-c","echo $TRACEPARENT > $0/traceparent","` + directory + `"]}
+	]`)
+	assert.NoError(t, err)
+
+	span := tracing.Start("hooks")
+	assert.NoError(t, NewRunner(hooks).Run(context.Background(), Input{Event: PreBackup}))
+	span.End(nil)
+
+	// traceparent is version-traceID-spanID-flags of the span of every hook , a child of the span in progress
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 3) {
+		for _, hookSpan := range spans[:2] {
+			assert.Equal(t, "hook", hookSpan.Name())
+			assert.Equal(t, spans[2].SpanContext().SpanID(), hookSpan.Parent().SpanID())
+		}
+		assert.Equal(t, traceParent(spans[0]), traceparent, "the HTTP call carries the trace context")
+		assert.Contains(t, spans[0].Attributes(), tracing.HookName.String("notify"))
+		data, err := os.ReadFile(filepath.Join(directory, "traceparent"))
+		assert.NoError(t, err)
+		assert.Equal(t, traceParent(spans[1]), strings.TrimSpace(string(data)), "the command gets the trace context as TRACEPARENT")
+	}
+}
+
+func traceParent(span sdktrace.ReadOnlySpan) string {
+	return fmt.Sprintf("00-%s-%s-01", span.SpanContext().TraceID(), span.SpanContext().SpanID())
+}
+
+func TestOutputIsLogged(t *testing.T) {
+	var output bytes.Buffer
+	log.SetOutput(&output)
+	defer log.SetOutput(os.Stderr)
+
+	hooks, err := Parse(`[{"name":"etl","event":"preBackup","command":["sh","-c","echo paused; echo -n 'watermark 42' >&2"]}]`)
+	assert.NoError(t, err)
+	assert.NoError(t, NewRunner(hooks).Run(context.Background(), Input{Event: PreBackup}))
+	assert.Contains(t, output.String(), "[hook etl] paused\n")
+	assert.Contains(t, output.String(), "[hook etl] watermark 42\n")
+
+	var runner *Runner
+	assert.NoError(t, runner.Run(context.Background(), Input{Event: PreBackup}), "a nil runner runs nothing")
+}
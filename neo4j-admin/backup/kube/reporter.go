@@ -0,0 +1,280 @@
+// Package kube reports the backup runs to Kubernetes as Events on the owning object and in a status ConfigMap
+package kube
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
+)
+
+// event reasons
+const (
+	ReasonStarted         = "BackupStarted"
+	ReasonSucceeded       = "BackupSucceeded"
+	ReasonPartiallyFailed = "BackupPartiallyFailed"
+	ReasonFailed          = "BackupFailed"
+)
+
+const (
+	component = "neo4j-backup"
+	// maxMessageLength is the longest event message accepted by the api server
+	maxMessageLength = 1024
+	requestTimeout   = 30 * time.Second
+)
+
+// DatabaseResult is the result of a run for a single database recorded in the status ConfigMap
+type DatabaseResult struct {
+	// Name is the database name , prefixed with the target name when the job backs up multiple Neo4j deployments
+	Name      string
+	Status    string
+	Artifacts []string
+	Error     string
+}
+
+// Reporter emits Events on the object owning the pod and keeps the status ConfigMap updated
+// Reporting never fails the run , failures are only logged
+type Reporter struct {
+	client    kubernetes.Interface
+	namespace string
+	podName   string
+	// events enables the Events , configMap is the name of the status ConfigMap (empty disables it)
+	events    bool
+	configMap string
+	now       func() time.Time
+
+	owner *corev1.ObjectReference
+}
+
+// NewReporter returns a reporter using the given client for the pod running the backup
+func NewReporter(client kubernetes.Interface, namespace string, podName string, events bool, configMap string) *Reporter {
+	return &Reporter{
+		client:    client,
+		namespace: namespace,
+		podName:   podName,
+		events:    events,
+		configMap: configMap,
+		now:       time.Now,
+	}
+}
+
+// NewReporterFromEnv returns a reporter using the in-cluster config when KUBERNETES_EVENTS_ENABLED is true or STATUS_CONFIGMAP is set
+// The pod is identified by POD_NAMESPACE and POD_NAME. A nil reporter is returned when reporting is disabled
+func NewReporterFromEnv() (*Reporter, error) {
+	events := os.Getenv("KUBERNETES_EVENTS_ENABLED") == "true"
+	configMap := strings.TrimSpace(os.Getenv("STATUS_CONFIGMAP"))
+	if !events && configMap == "" {
+		return nil, nil
+	}
+	namespace, podName := os.Getenv("POD_NAMESPACE"), os.Getenv("POD_NAME")
+	if namespace == "" || podName == "" {
+		return nil, fmt.Errorf("POD_NAMESPACE and POD_NAME must be set to report the backup to kubernetes")
+	}
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error seen while getting cluster config \n %v", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error seen while getting kubernetes client \n %v", err)
+	}
+	return NewReporter(client, namespace, podName, events, configMap), nil
+}
+
+// Started emits the BackupStarted event
+func (r *Reporter) Started(databases string) {
+	if r == nil {
+		return
+	}
+	r.emit(corev1.EventTypeNormal, ReasonStarted, fmt.Sprintf("Backup of database(s) %s started by pod %s", databases, r.podName))
+}
+
+// Finished emits the event matching the status of the run and records the results in the status ConfigMap
+func (r *Reporter) Finished(run *status.Run, results []DatabaseResult) {
+	if r == nil {
+		return
+	}
+	switch run.Status {
+	case status.Success:
+		r.emit(corev1.EventTypeNormal, ReasonSucceeded, fmt.Sprintf("Backup completed , %d artifact(s) uploaded: %s",
+			len(run.BackupFiles), strings.Join(run.BackupFiles, " , ")))
+	case status.Partial:
+		r.emit(corev1.EventTypeWarning, ReasonPartiallyFailed, fmt.Sprintf("Backup completed for some of the destinations only: %s", run.Error))
+	default:
+		r.emit(corev1.EventTypeWarning, ReasonFailed, fmt.Sprintf("Backup failed: %s", run.Error))
+	}
+	if err := r.updateStatusConfigMap(run, results); err != nil {
+		log.Printf("Warning: unable to update the status ConfigMap %s: %v", r.configMap, err)
+	}
+}
+
+// emit creates an event on the owner of the pod
+// The event is created directly instead of through an event recorder since the process may exit right after the run
+func (r *Reporter) emit(eventType string, reason string, message string) {
+	if !r.events {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	if r.owner == nil {
+		r.owner = r.resolveOwner(ctx)
+	}
+	if len(message) > maxMessageLength {
+		message = message[:maxMessageLength-3] + "..."
+	}
+	timeStamp := r.now()
+	now := metav1.NewTime(timeStamp)
+	event := &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			// named like the events of the client-go event recorder
+			Name:      fmt.Sprintf("%v.%x", r.owner.Name, timeStamp.UnixNano()),
+			Namespace: r.namespace,
+		},
+		InvolvedObject:      *r.owner,
+		Reason:              reason,
+		Message:             message,
+		Type:                eventType,
+		Source:              corev1.EventSource{Component: component},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: component,
+		ReportingInstance:   r.podName,
+	}
+	if _, err := r.client.CoreV1().Events(r.namespace).Create(ctx, event, metav1.CreateOptions{}); err != nil {
+		log.Printf("Warning: unable to emit event %s on %s %s: %v", reason, r.owner.Kind, r.owner.Name, err)
+	}
+}
+
+// resolveOwner follows the controllers of the pod up to the CronJob (or Deployment) running the backup
+// The pod itself is returned when its owner cannot be resolved , ex: the service account is not allowed to get it
+func (r *Reporter) resolveOwner(ctx context.Context) *corev1.ObjectReference {
+	owner := &corev1.ObjectReference{Kind: "Pod", APIVersion: "v1", Namespace: r.namespace, Name: r.podName}
+	pod, err := r.client.CoreV1().Pods(r.namespace).Get(ctx, r.podName, metav1.GetOptions{})
+	if err != nil {
+		log.Printf("Warning: unable to resolve the owner of pod %s , the events are emitted on the pod: %v", r.podName, err)
+		return owner
+	}
+	owner.UID = pod.UID
+	controller := metav1.GetControllerOf(pod)
+	for controller != nil {
+		owner = &corev1.ObjectReference{
+			Kind:       controller.Kind,
+			APIVersion: controller.APIVersion,
+			Namespace:  r.namespace,
+			Name:       controller.Name,
+			UID:        controller.UID,
+		}
+		controller, err = r.controllerOf(ctx, controller)
+		if err != nil {
+			log.Printf("Warning: unable to resolve the owner of %s %s: %v", owner.Kind, owner.Name, err)
+		}
+	}
+	return owner
+}
+
+// controllerOf returns the controller of a Job (CronJob) or a ReplicaSet (Deployment) , nil for any other kind
+func (r *Reporter) controllerOf(ctx context.Context, owner *metav1.OwnerReference) (*metav1.OwnerReference, error) {
+	var object metav1.Object
+	var err error
+	switch owner.Kind {
+	case "Job":
+		object, err = r.client.BatchV1().Jobs(r.namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	case "ReplicaSet":
+		object, err = r.client.AppsV1().ReplicaSets(r.namespace).Get(ctx, owner.Name, metav1.GetOptions{})
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return metav1.GetControllerOf(object), nil
+}
+
+// updateStatusConfigMap records the run and the result of every database in the status ConfigMap , creating it if missing
+// The entries of the databases which are not part of the run are preserved
+func (r *Reporter) updateStatusConfigMap(run *status.Run, results []DatabaseResult) error {
+	if r.configMap == "" {
+		return nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), requestTimeout)
+	defer cancel()
+	configMaps := r.client.CoreV1().ConfigMaps(r.namespace)
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		configMap, err := configMaps.Get(ctx, r.configMap, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) {
+			configMap = &corev1.ConfigMap{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      r.configMap,
+					Namespace: r.namespace,
+					Labels:    map[string]string{"app.kubernetes.io/managed-by": component},
+				},
+			}
+			applyRun(configMap, run, results)
+			_, err = configMaps.Create(ctx, configMap, metav1.CreateOptions{})
+			return err
+		}
+		if err != nil {
+			return err
+		}
+		applyRun(configMap, run, results)
+		_, err = configMaps.Update(ctx, configMap, metav1.UpdateOptions{})
+		return err
+	})
+}
+
+// applyRun sets the keys describing the run and the result of every database
+// Ex: lastRunStatus , neo4j.lastStatus , neo4j.lastSuccessTime , neo4j.lastArtifacts , neo4j.lastError
+func applyRun(configMap *corev1.ConfigMap, run *status.Run, results []DatabaseResult) {
+	if configMap.Data == nil {
+		configMap.Data = make(map[string]string)
+	}
+	data := configMap.Data
+	endTime := run.EndTime.Format(time.RFC3339)
+	data["lastRunTime"] = endTime
+	data["lastRunStatus"] = run.Status
+	setOrDelete(data, "lastRunError", run.Error)
+	if run.Status == status.Success {
+		data["lastSuccessTime"] = endTime
+	}
+	for _, result := range results {
+		prefix := configMapKey(result.Name)
+		data[prefix+".lastStatus"] = result.Status
+		data[prefix+".lastRunTime"] = endTime
+		setOrDelete(data, prefix+".lastError", result.Error)
+		if result.Status == status.Success {
+			data[prefix+".lastSuccessTime"] = endTime
+		}
+		if len(result.Artifacts) != 0 {
+			data[prefix+".lastArtifacts"] = strings.Join(result.Artifacts, ",")
+		}
+	}
+}
+
+func setOrDelete(data map[string]string, key string, value string) {
+	if value == "" {
+		delete(data, key)
+		return
+	}
+	data[key] = value
+}
+
+// configMapKey replaces the characters which are not allowed in a ConfigMap key
+func configMapKey(name string) string {
+	return strings.Map(func(c rune) rune {
+		if c == '-' || c == '_' || c == '.' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') {
+			return c
+		}
+		return '_'
+	}, name)
+}
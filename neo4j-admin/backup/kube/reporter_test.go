@@ -0,0 +1,147 @@
+package kube
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func controlledBy(kind string, name string) []metav1.OwnerReference {
+	controller := true
+	return []metav1.OwnerReference{{Kind: kind, Name: name, UID: types.UID("uid-" + name), Controller: &controller}}
+}
+
+func newCronJobPod() []runtime.Object {
+	return []runtime.Object{
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "backup-28650-abcde", Namespace: "neo4j", OwnerReferences: controlledBy("Job", "backup-28650")}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-28650", Namespace: "neo4j", OwnerReferences: controlledBy("CronJob", "backup")}},
+	}
+}
+
+func events(t *testing.T, client *fake.Clientset) []corev1.Event {
+	list, err := client.CoreV1().Events("neo4j").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	return list.Items
+}
+
+func TestEventsOnOwner(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		objects   []runtime.Object
+		wantKind  string
+		wantOwner string
+	}{
+		{name: "cronjob", objects: newCronJobPod(), wantKind: "CronJob", wantOwner: "backup"},
+		{
+			name: "daemon deployment",
+			objects: []runtime.Object{
+				&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "backup-7d9f-abcde", Namespace: "neo4j", OwnerReferences: controlledBy("ReplicaSet", "backup-7d9f")}},
+				&appsv1.ReplicaSet{ObjectMeta: metav1.ObjectMeta{Name: "backup-7d9f", Namespace: "neo4j", OwnerReferences: controlledBy("Deployment", "backup")}},
+			},
+			wantKind:  "Deployment",
+			wantOwner: "backup",
+		},
+		{name: "pod without owner", objects: []runtime.Object{&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "backup-7d9f-abcde", Namespace: "neo4j"}}}, wantKind: "Pod", wantOwner: "backup-7d9f-abcde"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := fake.NewSimpleClientset(tt.objects...)
+			podName := tt.objects[0].(*corev1.Pod).Name
+			reporter := NewReporter(client, "neo4j", podName, true, "")
+			reporter.Started("neo4j")
+			reporter.Finished(&status.Run{Status: status.Partial, Error: "destination dr failed"}, nil)
+
+			emitted := events(t, client)
+			if assert.Len(t, emitted, 2) {
+				assert.Equal(t, tt.wantKind, emitted[0].InvolvedObject.Kind)
+				assert.Equal(t, tt.wantOwner, emitted[0].InvolvedObject.Name)
+				assert.Equal(t, ReasonStarted, emitted[0].Reason)
+				assert.Equal(t, corev1.EventTypeNormal, emitted[0].Type)
+				assert.Equal(t, ReasonPartiallyFailed, emitted[1].Reason)
+				assert.Equal(t, corev1.EventTypeWarning, emitted[1].Type)
+				assert.Contains(t, emitted[1].Message, "destination dr failed")
+			}
+		})
+	}
+}
+
+func TestEventsWithoutPermissionToGetThePod(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(newCronJobPod()...)
+	client.PrependReactor("get", "pods", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		return true, nil, errors.New("pods is forbidden")
+	})
+	NewReporter(client, "neo4j", "backup-28650-abcde", true, "").Finished(&status.Run{Status: status.Failed, Error: "connectivity cannot be established"}, nil)
+
+	emitted := events(t, client)
+	if assert.Len(t, emitted, 1) {
+		assert.Equal(t, "Pod", emitted[0].InvolvedObject.Kind)
+		assert.Equal(t, ReasonFailed, emitted[0].Reason)
+	}
+}
+
+func TestStatusConfigMap(t *testing.T) {
+	t.Parallel()
+
+	client := fake.NewSimpleClientset(newCronJobPod()...)
+	reporter := NewReporter(client, "neo4j", "backup-28650-abcde", false, "backup-status")
+	first := time.Date(2024, 6, 13, 10, 0, 0, 0, time.UTC)
+	reporter.Finished(&status.Run{EndTime: first, Status: status.Success}, []DatabaseResult{
+		{Name: "neo4j", Status: status.Success, Artifacts: []string{"neo4j-2024-06-13T10-00-00.backup"}},
+		{Name: "prod/orders", Status: status.Success, Artifacts: []string{"orders-2024-06-13T10-00-00.backup"}},
+	})
+	second := first.Add(24 * time.Hour)
+	reporter.Finished(&status.Run{EndTime: second, Status: status.Failed, Error: "Backup Failed for database neo4j"}, []DatabaseResult{
+		{Name: "neo4j", Status: status.Failed, Error: "Backup Failed for database neo4j"},
+	})
+
+	configMap, err := client.CoreV1().ConfigMaps("neo4j").Get(context.Background(), "backup-status", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Empty(t, events(t, client), "events are disabled")
+	assert.Equal(t, map[string]string{
+		"lastRunTime":                 "2024-06-14T10:00:00Z",
+		"lastRunStatus":               "failed",
+		"lastRunError":                "Backup Failed for database neo4j",
+		"lastSuccessTime":             "2024-06-13T10:00:00Z",
+		"neo4j.lastStatus":            "failed",
+		"neo4j.lastRunTime":           "2024-06-14T10:00:00Z",
+		"neo4j.lastSuccessTime":       "2024-06-13T10:00:00Z",
+		"neo4j.lastArtifacts":         "neo4j-2024-06-13T10-00-00.backup",
+		"neo4j.lastError":             "Backup Failed for database neo4j",
+		"prod_orders.lastStatus":      "success",
+		"prod_orders.lastRunTime":     "2024-06-13T10:00:00Z",
+		"prod_orders.lastSuccessTime": "2024-06-13T10:00:00Z",
+		"prod_orders.lastArtifacts":   "orders-2024-06-13T10-00-00.backup",
+	}, configMap.Data)
+}
+
+func TestReporterDisabled(t *testing.T) {
+	t.Setenv("KUBERNETES_EVENTS_ENABLED", "false")
+	t.Setenv("STATUS_CONFIGMAP", "")
+	reporter, err := NewReporterFromEnv()
+	assert.NoError(t, err)
+	assert.Nil(t, reporter)
+	// a nil reporter does not report anything
+	reporter.Started("neo4j")
+	reporter.Finished(&status.Run{}, nil)
+
+	t.Setenv("KUBERNETES_EVENTS_ENABLED", "true")
+	t.Setenv("POD_NAME", "")
+	_, err = NewReporterFromEnv()
+	assert.Error(t, err)
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"k8s.io/utils/strings/slices"
+)
+
+const (
+	// sizeManifestFile is the file at LOCATION recording the artifact sizes of the previous run of every database
+	sizeManifestFile = ".sizes.json"
+	// defaultCapacityHeadroomPercent is added on top of the estimated space to absorb the growth of the databases
+	defaultCapacityHeadroomPercent = 20
+)
+
+// artifactSizes contains the size of the artifacts of the previous run of a database
+type artifactSizes struct {
+	Backup int64 `json:"backup"`
+	Report int64 `json:"report,omitempty"`
+}
+
+// capacityEstimate is the space needed at LOCATION by a backup run
+type capacityEstimate struct {
+	// backup is the space taken by the backup artifacts of every database which are all kept until uploaded
+	backup int64
+	// consistencyCheck is the space neo4j-admin needs to extract the largest backup artifact while checking it
+	consistencyCheck int64
+	// reports is the space taken by the consistency check report directories along with their tar archives
+	reports int64
+	// headroom is added to absorb the growth of the databases since the previous run
+	headroom int64
+	// unknown contains the databases without a previous artifact which are not part of the estimate
+	unknown []string
+}
+
+func (e capacityEstimate) total() int64 {
+	return e.backup + e.consistencyCheck + e.reports + e.headroom
+}
+
+// volumeSpace returns the number of bytes available to an unprivileged user along with the size of the filesystem containing the given path
+var volumeSpace = func(path string) (int64, int64, error) {
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(path, &stat); err != nil {
+		return 0, 0, fmt.Errorf("unable to retrieve the free space of %s \n %v", path, err)
+	}
+	return int64(stat.Bavail) * int64(stat.Bsize), int64(stat.Blocks) * int64(stat.Bsize), nil
+}
+
+// capacityCheckEnabled returns false only when CAPACITY_CHECK_ENABLED is set to false
+func capacityCheckEnabled() bool {
+	return strings.TrimSpace(os.Getenv("CAPACITY_CHECK_ENABLED")) != "false"
+}
+
+// checkCapacity fails when the free space at LOCATION is lower than the space estimated from the artifact sizes of the previous runs
+// The sizes are read from the bucket of the first destination , falling back to the size manifest present at LOCATION
+// The check is skipped when none of the databases has a previous artifact
+func checkCapacity(destinations []*destination) error {
+	if !capacityCheckEnabled() {
+		return nil
+	}
+	headroomPercent, err := getCapacityHeadroomPercent()
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+	sizeLimit, err := getVolumeSizeLimit()
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+
+	location := neo4jAdmin.BackupLocation()
+	sizes := loadSizeManifest()
+	if len(destinations) != 0 {
+		for database, size := range artifactSizesFromBucket(destinations[0]) {
+			known := sizes[database]
+			if size.Backup > 0 {
+				known.Backup = size.Backup
+			}
+			if size.Report > 0 {
+				known.Report = size.Report
+			}
+			sizes[database] = known
+		}
+	}
+	estimate := estimateCapacity(sizes, strings.Split(os.Getenv("DATABASE"), ","), consistencyCheckDatabases(), headroomPercent)
+	if len(estimate.unknown) != 0 {
+		log.Printf("No previous backup found for database(s) %v , they are not part of the space estimate", estimate.unknown)
+	}
+	if estimate.total() == 0 {
+		log.Printf("Skipping the free space check since no previous backup is known")
+		return nil
+	}
+
+	available, volumeSize, err := volumeSpace(location)
+	if err != nil {
+		log.Printf("Warning: skipping the free space check: %v", err)
+		return nil
+	}
+	// the free space of an emptyDir with a sizeLimit is the one of the node , the kubelet evicts the pod once the limit is exceeded
+	if sizeLimit > 0 {
+		used, err := directorySize(location)
+		if err != nil {
+			log.Printf("Warning: unable to compute the space used at %s: %v", location, err)
+		}
+		if remaining := sizeLimit - used; remaining < available {
+			available, volumeSize = remaining, sizeLimit
+		}
+	}
+
+	log.Printf("Free space at %s is %s , estimated space needed is %s (backup %s , consistency check %s , reports %s , headroom %s)",
+		location, common.FormatBytes(available), common.FormatBytes(estimate.total()), common.FormatBytes(estimate.backup),
+		common.FormatBytes(estimate.consistencyCheck), common.FormatBytes(estimate.reports), common.FormatBytes(estimate.headroom))
+	if available >= estimate.total() {
+		return nil
+	}
+	return withExitCode(exitCapacity, fmt.Errorf("not enough free space at %s to take the backup !! %s available , %s needed (backup %s , consistency check %s , reports %s , headroom %s). "+
+		"Please increase the size of the tempVolume to at least %s , set keepBackupFiles to false or remove the old backup files present at %s",
+		location, common.FormatBytes(available), common.FormatBytes(estimate.total()), common.FormatBytes(estimate.backup),
+		common.FormatBytes(estimate.consistencyCheck), common.FormatBytes(estimate.reports), common.FormatBytes(estimate.headroom),
+		common.FormatBytes(recommendedVolumeSize(volumeSize, available, estimate.total())), location))
+}
+
+// recommendedVolumeSize returns the size of a volume holding the files already present along with the estimated space , rounded up to the next GiB
+func recommendedVolumeSize(volumeSize int64, available int64, needed int64) int64 {
+	const gib = 1 << 30
+	size := volumeSize - available + needed
+	return (size + gib - 1) / gib * gib
+}
+
+// estimateCapacity returns the space needed to back up the given databases based on the artifact sizes of their previous run
+func estimateCapacity(sizes map[string]artifactSizes, databases []string, checkDatabases []string, headroomPercent int) capacityEstimate {
+	var estimate capacityEstimate
+	for _, database := range databases {
+		database = strings.TrimSpace(database)
+		if database == "*" || database == "" {
+			continue
+		}
+		if _, present := sizes[database]; !present {
+			estimate.unknown = append(estimate.unknown, database)
+		}
+	}
+	for _, database := range sortedDatabases(sizes) {
+		if !slices.Contains(databases, database) && !slices.Contains(databases, "*") {
+			continue
+		}
+		size := sizes[database]
+		estimate.backup += size.Backup
+		if slices.Contains(checkDatabases, database) {
+			// the databases are checked one after the other , only the largest extraction is needed at any time
+			if size.Backup > estimate.consistencyCheck {
+				estimate.consistencyCheck = size.Backup
+			}
+			// the report directory is kept next to its tar archive
+			estimate.reports += 2 * size.Report
+		}
+	}
+	estimate.headroom = (estimate.backup + estimate.consistencyCheck + estimate.reports) * int64(headroomPercent) / 100
+	return estimate
+}
+
+// artifactSizesFromBucket returns the size of the latest backup artifact and consistency check report of every database present in the bucket
+func artifactSizesFromBucket(d *destination) map[string]artifactSizes {
+	sizes := make(map[string]artifactSizes)
+	bucketName := common.JoinBucketPath(d.BucketName, strings.TrimSpace(os.Getenv("KEY_PREFIX")))
+	objects, err := d.client.ListObjects(bucketName)
+	if err != nil {
+		log.Printf("Warning: unable to list the previous backups of destination %s , using the size manifest: %v", d.Name, err)
+		return sizes
+	}
+	latest := make(map[string]string)
+	latestReport := make(map[string]string)
+	for _, object := range objects {
+		fileName := path.Base(object.Key)
+		database, timeStamp, err := common.ParseArtifactName(fileName)
+		if err != nil {
+			continue
+		}
+		size := sizes[database]
+		switch {
+		case common.ArtifactType(fileName) == common.ArtifactTypeConsistencyCheck:
+			if timeStamp >= latestReport[database] {
+				latestReport[database] = timeStamp
+				size.Report = object.Size
+			}
+		case strings.HasSuffix(fileName, ".backup") && object.Metadata["backup-type"] != "DIFF":
+			// a differential backup is smaller than the full backup neo4j-admin falls back to when no previous backup is present
+			if timeStamp >= latest[database] {
+				latest[database] = timeStamp
+				size.Backup = object.Size
+			}
+		default:
+			continue
+		}
+		sizes[database] = size
+	}
+	return sizes
+}
+
+// recordArtifactSizes writes the size of the given artifacts to the size manifest present at LOCATION
+func recordArtifactSizes(backupFileNames []string, consistencyCheckReports []string) {
+	sizes := loadSizeManifest()
+	location := neo4jAdmin.BackupLocation()
+	for _, fileName := range append(append([]string{}, backupFileNames...), consistencyCheckReports...) {
+		database, _, err := common.ParseArtifactName(fileName)
+		if err != nil {
+			continue
+		}
+		info, err := os.Stat(filepath.Join(location, fileName))
+		if err != nil {
+			continue
+		}
+		size := sizes[database]
+		switch {
+		case common.ArtifactType(fileName) == common.ArtifactTypeConsistencyCheck:
+			size.Report = info.Size()
+		case strings.HasSuffix(fileName, ".backup") && common.GetBackupType(fileName) != "DIFF":
+			size.Backup = info.Size()
+		}
+		sizes[database] = size
+	}
+	data, err := json.MarshalIndent(sizes, "", "  ")
+	if err == nil {
+		err = os.WriteFile(filepath.Join(location, sizeManifestFile), data, 0644)
+	}
+	if err != nil {
+		log.Printf("Warning: unable to write the size manifest: %v", err)
+	}
+}
+
+// loadSizeManifest reads the size manifest present at LOCATION , an empty manifest is returned if it is missing or corrupt
+func loadSizeManifest() map[string]artifactSizes {
+	sizes := make(map[string]artifactSizes)
+	manifestPath := filepath.Join(neo4jAdmin.BackupLocation(), sizeManifestFile)
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Warning: unable to read the size manifest: %v", err)
+		}
+		return sizes
+	}
+	if err = json.Unmarshal(data, &sizes); err != nil {
+		log.Printf("Warning: ignoring corrupt size manifest %s: %v", manifestPath, err)
+		return make(map[string]artifactSizes)
+	}
+	return sizes
+}
+
+// consistencyCheckDatabases returns the databases checked after the backup , none when the consistency check is disabled
+func consistencyCheckDatabases() []string {
+	if os.Getenv("CONSISTENCY_CHECK_ENABLE") != "true" {
+		return nil
+	}
+	return strings.Split(os.Getenv("CONSISTENCY_CHECK_DATABASE"), ",")
+}
+
+func getCapacityHeadroomPercent() (int, error) {
+	value := strings.TrimSpace(os.Getenv("CAPACITY_CHECK_HEADROOM_PERCENT"))
+	if value == "" {
+		return defaultCapacityHeadroomPercent, nil
+	}
+	percent, err := strconv.Atoi(value)
+	if err != nil || percent < 0 {
+		return 0, fmt.Errorf("invalid CAPACITY_CHECK_HEADROOM_PERCENT %s. It must be a positive number", value)
+	}
+	return percent, nil
+}
+
+var quantityRegex = regexp.MustCompile(`^([0-9]+(?:\.[0-9]+)?)(Ki|Mi|Gi|Ti|Pi|k|M|G|T|P)?$`)
+
+// getVolumeSizeLimit returns the sizeLimit of the emptyDir mounted at LOCATION set in BACKUP_VOLUME_SIZE_LIMIT , 0 if not set
+// The value is a kubernetes quantity , ex: 500Gi
+func getVolumeSizeLimit() (int64, error) {
+	value := strings.TrimSpace(os.Getenv("BACKUP_VOLUME_SIZE_LIMIT"))
+	if value == "" {
+		return 0, nil
+	}
+	matches := quantityRegex.FindStringSubmatch(value)
+	if matches == nil {
+		return 0, fmt.Errorf("invalid BACKUP_VOLUME_SIZE_LIMIT %s. It must be a quantity ex: 500Gi", value)
+	}
+	number, err := strconv.ParseFloat(matches[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid BACKUP_VOLUME_SIZE_LIMIT %s \n %v", value, err)
+	}
+	multipliers := map[string]float64{
+		"": 1, "k": 1e3, "M": 1e6, "G": 1e9, "T": 1e12, "P": 1e15,
+		"Ki": 1 << 10, "Mi": 1 << 20, "Gi": 1 << 30, "Ti": 1 << 40, "Pi": 1 << 50,
+	}
+	return int64(number * multipliers[matches[2]]), nil
+}
+
+// directorySize returns the total size of the regular files present below the given directory
+func directorySize(directory string) (int64, error) {
+	var size int64
+	err := filepath.WalkDir(directory, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.Type().IsRegular() {
+			info, err := entry.Info()
+			if err != nil {
+				return err
+			}
+			size += info.Size()
+		}
+		return nil
+	})
+	return size, err
+}
+
+func sortedDatabases(sizes map[string]artifactSizes) []string {
+	var databases []string
+	for database := range sizes {
+		databases = append(databases, database)
+	}
+	sort.Strings(databases)
+	return databases
+}
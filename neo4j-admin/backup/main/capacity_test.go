@@ -0,0 +1,125 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCapacity(t *testing.T) {
+	t.Parallel()
+
+	sizes := map[string]artifactSizes{
+		"neo4j":  {Backup: 1000, Report: 50},
+		"orders": {Backup: 3000},
+		"system": {Backup: 10},
+	}
+	tests := []struct {
+		name           string
+		databases      []string
+		checkDatabases []string
+		headroom       int
+		want           capacityEstimate
+	}{
+		{
+			name:      "backup only",
+			databases: []string{"neo4j", "orders"},
+			want:      capacityEstimate{backup: 4000},
+		},
+		{
+			name:           "largest consistency check extraction and reports",
+			databases:      []string{"*"},
+			checkDatabases: []string{"neo4j", "orders"},
+			want:           capacityEstimate{backup: 4010, consistencyCheck: 3000, reports: 100},
+		},
+		{
+			name:      "headroom",
+			databases: []string{"neo4j"},
+			headroom:  20,
+			want:      capacityEstimate{backup: 1000, headroom: 200},
+		},
+		{
+			name:      "database without a previous backup",
+			databases: []string{"neo4j", "movies"},
+			want:      capacityEstimate{backup: 1000, unknown: []string{"movies"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, estimateCapacity(sizes, tt.databases, tt.checkDatabases, tt.headroom))
+		})
+	}
+}
+
+func TestGetVolumeSizeLimit(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{value: "", want: 0},
+		{value: "1024", want: 1024},
+		{value: "500Gi", want: 500 << 30},
+		{value: "1.5Ti", want: 3 << 39},
+		{value: "2G", want: 2e9},
+		{value: "10GB", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			t.Setenv("BACKUP_VOLUME_SIZE_LIMIT", tt.value)
+			limit, err := getVolumeSizeLimit()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, limit)
+		})
+	}
+}
+
+func TestPipelineCapacityCheck(t *testing.T) {
+	admin, storage, location := setupPipeline(t)
+	t.Setenv("KEEP_BACKUP_FILES", "true")
+	t.Setenv("CAPACITY_CHECK_HEADROOM_PERCENT", "0")
+	original := volumeSpace
+	t.Cleanup(func() { volumeSpace = original })
+
+	// the first run does not know the size of the backups
+	volumeSpace = func(path string) (int64, int64, error) { return 0, 0, nil }
+	assert.NoError(t, runOperations())
+	objects, err := storage.ListObjects("backups/prod")
+	assert.NoError(t, err)
+	var backupSize int64
+	for _, object := range objects {
+		backupSize += object.Size
+	}
+	assert.FileExists(t, filepath.Join(location, sizeManifestFile))
+
+	// the second run needs room for the backup of both databases and the extraction of the largest one
+	// on top of the files already present on the volume
+	volumeSpace = func(path string) (int64, int64, error) { return backupSize, 10 << 30, nil }
+	commands := len(admin.Commands())
+	err = runOperations()
+	assert.Error(t, err)
+	assert.Equal(t, exitCapacity, exitCode(err))
+	assert.Contains(t, err.Error(), "increase the size of the tempVolume to at least 11.0GiB")
+	assert.Len(t, admin.Commands(), commands+1, "only the connectivity check is performed")
+	assert.Equal(t, status.Failed, readStatus(t, location).Status)
+
+	t.Setenv("CAPACITY_CHECK_ENABLED", "false")
+	assert.NoError(t, runOperations())
+
+	// without a destination the sizes are read from the size manifest , differential backups do not replace the full backup sizes
+	t.Setenv("CAPACITY_CHECK_ENABLED", "true")
+	t.Setenv("BUCKET_NAME", "")
+	t.Setenv("CLOUD_PROVIDER", "")
+	assert.NoError(t, os.WriteFile(filepath.Join(location, sizeManifestFile), []byte(`{"neo4j":{"backup":4096},"orders":{"backup":4096}}`), 0644))
+	volumeSpace = func(path string) (int64, int64, error) { return 12288, 1 << 30, nil }
+	assert.NoError(t, runOperations())
+	volumeSpace = func(path string) (int64, int64, error) { return 12287, 1 << 30, nil }
+	assert.Equal(t, exitCapacity, exitCode(runOperations()))
+}
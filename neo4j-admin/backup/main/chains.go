@@ -0,0 +1,285 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/catalog"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+	"k8s.io/utils/strings/slices"
+)
+
+// chainManifestFile is the file at LOCATION recording the chain of every database when the chains cannot be read from the bucket
+const chainManifestFile = ".chains.json"
+
+// fullBackupPolicy forces full backups and aggregations so that the differential chains do not grow without bounds
+type fullBackupPolicy struct {
+	// maxDifferentials forces a full backup once the chain of a database contains the given number of differential backups
+	maxDifferentials int
+	// maxAge forces a full backup once the full backup of the chain of a database is older than the given duration
+	maxAge time.Duration
+	// aggregateAfter aggregates the chain of a database once it contains the given number of differential backups
+	aggregateAfter int
+	// aggregateFromPath is the path holding the whole chain of the databases , a local path or s3://bucket/prefix
+	aggregateFromPath string
+}
+
+// chainState describes the current chain of a database
+type chainState struct {
+	FullBackupTime time.Time `json:"fullBackupTime"`
+	Differentials  int       `json:"differentials"`
+}
+
+// chainStates contains the current chain of every database
+type chainStates map[string]chainState
+
+// getFullBackupPolicy reads FULL_BACKUP_MAX_DIFFERENTIALS , FULL_BACKUP_MAX_DAYS and AGGREGATE_AFTER_DIFFERENTIALS
+// 0 or empty disables the respective limit
+// The aggregation requires AGGREGATE_BACKUP_FROM_PATH since LOCATION only holds the artifacts of the current run ,
+// a local path also requires KEEP_BACKUP_FILES since the artifacts are deleted once uploaded
+func getFullBackupPolicy() (*fullBackupPolicy, error) {
+	var values [3]int
+	for i, name := range []string{"FULL_BACKUP_MAX_DIFFERENTIALS", "FULL_BACKUP_MAX_DAYS", "AGGREGATE_AFTER_DIFFERENTIALS"} {
+		value := strings.TrimSpace(os.Getenv(name))
+		if value == "" {
+			continue
+		}
+		number, err := strconv.Atoi(value)
+		if err != nil || number < 0 {
+			return nil, fmt.Errorf("invalid %s %s. It must be a positive number or 0 to disable it", name, value)
+		}
+		values[i] = number
+	}
+	policy := &fullBackupPolicy{
+		maxDifferentials: values[0],
+		maxAge:           time.Duration(values[1]) * 24 * time.Hour,
+		aggregateAfter:   values[2],
+	}
+	if policy.aggregateAfter == 0 {
+		return policy, nil
+	}
+	policy.aggregateFromPath = strings.TrimSpace(os.Getenv("AGGREGATE_BACKUP_FROM_PATH"))
+	if policy.aggregateFromPath == "" {
+		return nil, fmt.Errorf("AGGREGATE_AFTER_DIFFERENTIALS requires AGGREGATE_BACKUP_FROM_PATH , the path holding the whole backup chain (ex: s3://bucket/prefix)")
+	}
+	if !strings.HasPrefix(policy.aggregateFromPath, "s3://") && os.Getenv("KEEP_BACKUP_FILES") != "true" {
+		return nil, fmt.Errorf("AGGREGATE_BACKUP_FROM_PATH %s is a local path , aggregating a local chain requires KEEP_BACKUP_FILES=true since the backup artifacts are deleted once uploaded", policy.aggregateFromPath)
+	}
+	return policy, nil
+}
+
+func (p *fullBackupPolicy) enabled() bool {
+	return p.maxDifferentials > 0 || p.maxAge > 0 || p.aggregateAfter > 0
+}
+
+// forceFull returns the reason for which the next backup of the given databases must be a full backup , empty if it must not
+// A database without a known full backup is left to neo4j-admin which takes a full backup when no previous backup is present
+func (p *fullBackupPolicy) forceFull(states chainStates, databases []string, now time.Time) string {
+	for _, database := range matchDatabases(states, databases) {
+		state := states[database]
+		if state.FullBackupTime.IsZero() {
+			continue
+		}
+		if p.maxDifferentials > 0 && state.Differentials >= p.maxDifferentials {
+			return fmt.Sprintf("the chain of database %s contains %d differential backup(s)", database, state.Differentials)
+		}
+		if p.maxAge > 0 && now.Sub(state.FullBackupTime) >= p.maxAge {
+			return fmt.Sprintf("the full backup of database %s was taken at %s", database, state.FullBackupTime.Format(time.RFC3339))
+		}
+	}
+	return ""
+}
+
+// chainsToAggregate returns the databases whose chain reached the aggregation threshold
+func (p *fullBackupPolicy) chainsToAggregate(states chainStates, databases []string) []string {
+	var aggregate []string
+	if p.aggregateAfter <= 0 {
+		return aggregate
+	}
+	for _, database := range matchDatabases(states, databases) {
+		if states[database].Differentials >= p.aggregateAfter {
+			aggregate = append(aggregate, database)
+		}
+	}
+	return aggregate
+}
+
+// matchDatabases returns the databases with a known chain among the given ones , * matches every database
+func matchDatabases(states chainStates, databases []string) []string {
+	var matched []string
+	for database := range states {
+		if slices.Contains(databases, database) || slices.Contains(databases, "*") {
+			matched = append(matched, database)
+		}
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// record updates the chains with the given backup artifacts , whose backup type is known once they are inspected
+func (s chainStates) record(backupFileNames []string) {
+	for _, fileName := range backupFileNames {
+		database, timeStamp, err := common.ParseArtifactName(fileName)
+		if err != nil {
+			continue
+		}
+		backupTime, err := time.Parse("2006-01-02T15-04-05", timeStamp)
+		if err != nil {
+			continue
+		}
+		switch common.GetBackupType(fileName) {
+		case catalog.TypeFull:
+			s[database] = chainState{FullBackupTime: backupTime}
+		case catalog.TypeDiff:
+			state := s[database]
+			state.Differentials++
+			s[database] = state
+		}
+	}
+}
+
+// chainStatesFromCatalog returns the latest chain of every database present in the catalog
+func chainStatesFromCatalog(backupCatalog *catalog.Catalog) chainStates {
+	states := make(chainStates)
+	for _, database := range backupCatalog.Databases {
+		if len(database.Chains) == 0 {
+			continue
+		}
+		chain := database.Chains[len(database.Chains)-1]
+		state := chainState{Differentials: len(chain.Differentials)}
+		if chain.Full != nil && chain.Full.Type == catalog.TypeFull {
+			state.FullBackupTime = chain.Full.Time
+		}
+		states[database.Name] = state
+	}
+	return states
+}
+
+// loadChainStates reads the chains from the first destination , falling back to the chain manifest present at LOCATION
+func loadChainStates(destinations []*destination) chainStates {
+	if len(destinations) != 0 {
+		d := destinations[0]
+		bucketName := common.JoinBucketPath(d.BucketName, strings.TrimSpace(os.Getenv("KEY_PREFIX")))
+		objects, err := d.client.ListObjects(bucketName)
+		if err == nil {
+			return chainStatesFromCatalog(catalog.Build(objects))
+		}
+		log.Printf("Warning: unable to list the backup chains of destination %s , using the chain manifest: %v", d.Name, err)
+	}
+	states := make(chainStates)
+	data, err := os.ReadFile(chainManifestPath())
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			log.Printf("Warning: unable to read the chain manifest: %v", err)
+		}
+		return states
+	}
+	if err = json.Unmarshal(data, &states); err != nil {
+		log.Printf("Warning: ignoring corrupt chain manifest %s: %v", chainManifestPath(), err)
+		return make(chainStates)
+	}
+	return states
+}
+
+// save writes the chains to the chain manifest
+func (s chainStates) save() {
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err == nil {
+		err = os.WriteFile(chainManifestPath(), data, 0644)
+	}
+	if err != nil {
+		log.Printf("Warning: unable to write the chain manifest %s: %v", chainManifestPath(), err)
+	}
+}
+
+func chainManifestPath() string {
+	return filepath.Join(neo4jAdmin.BackupLocation(), chainManifestFile)
+}
+
+// backup takes the backup forcing --type=FULL when the chains read from the first destination (or the chain manifest) require it
+// The chains are returned including the new artifacts , nil when the policy is disabled
+func (p *fullBackupPolicy) backup(destinations []*destination) (chainStates, []string, []string, error) {
+	if !p.enabled() {
+		backupFileNames, consistencyCheckReports, err := backupOperations()
+		return nil, backupFileNames, consistencyCheckReports, err
+	}
+	states := loadChainStates(destinations)
+	if backupType := strings.ToUpper(strings.TrimSpace(os.Getenv("TYPE"))); backupType != "FULL" {
+		databases := strings.Split(os.Getenv("DATABASE"), ",")
+		if reason := p.forceFull(states, databases, time.Now().UTC()); reason != "" {
+			log.Printf("Forcing a full backup since %s", reason)
+			// the daemon performs several runs in the same process , TYPE is restored for the next run
+			original := os.Getenv("TYPE")
+			os.Setenv("TYPE", "FULL")
+			defer os.Setenv("TYPE", original)
+		}
+	}
+	backupFileNames, consistencyCheckReports, err := backupOperations()
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	states.record(backupFileNames)
+	states.save()
+	return states, backupFileNames, consistencyCheckReports, nil
+}
+
+// aggregate aggregates the chains which reached the aggregation threshold and uploads the new local artifacts to the destinations
+// A failed aggregation does not fail the run since the backup itself succeeded
+func (p *fullBackupPolicy) aggregate(states chainStates, destinations []*destination, run *status.Run) {
+	databases := p.chainsToAggregate(states, strings.Split(os.Getenv("DATABASE"), ","))
+	if len(databases) == 0 {
+		return
+	}
+	if strings.HasPrefix(p.aggregateFromPath, "s3://") {
+		// the credentials are only exported while neo4j-admin aggregates the chains
+		restore, err := exportAwsCredentials(destinations)
+		if err != nil {
+			log.Printf("Warning: unable to aggregate the chains of %v from %s \n %v", databases, p.aggregateFromPath, err)
+			return
+		}
+		defer restore()
+	}
+	for _, database := range databases {
+		log.Printf("Aggregating the chain of database %s containing %d differential backup(s)", database, states[database].Differentials)
+		span := tracing.Start("aggregate", tracing.Database.String(database))
+		artifacts, err := neo4jAdmin.AggregateChain(database, p.aggregateFromPath)
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			span.End(err)
+			continue
+		}
+		var fileNames []string
+		for _, artifact := range artifacts {
+			// artifacts aggregated in a bucket (s3://...) are already in place , local artifacts are uploaded
+			if filepath.Dir(artifact) == neo4jAdmin.BackupLocation() {
+				fileNames = append(fileNames, filepath.Base(artifact))
+			}
+		}
+		if err = neo4jAdmin.InspectBackups(fileNames); err != nil {
+			log.Printf("Warning: unable to determine backup type of the aggregated artifacts: %v", err)
+		}
+		for _, d := range destinations {
+			if err = d.upload(fileNames); err != nil {
+				log.Printf("Warning: upload of the aggregated artifacts %v to destination %s failed: %v", fileNames, d.Name, err)
+			}
+		}
+		// the transfers of the aggregated artifacts are not part of the destination results of the run
+		common.TakeTransfers()
+		run.AggregatedFiles = append(run.AggregatedFiles, artifacts...)
+		states[database] = chainState{FullBackupTime: time.Now().UTC()}
+		span.SetAttributes(tracing.Artifacts.StringSlice(artifacts))
+		span.End(nil)
+	}
+	states.save()
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/catalog"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetFullBackupPolicy(t *testing.T) {
+	t.Setenv("FULL_BACKUP_MAX_DIFFERENTIALS", "6")
+	t.Setenv("FULL_BACKUP_MAX_DAYS", "7")
+	t.Setenv("AGGREGATE_AFTER_DIFFERENTIALS", "")
+	policy, err := getFullBackupPolicy()
+	assert.NoError(t, err)
+	assert.Equal(t, &fullBackupPolicy{maxDifferentials: 6, maxAge: 7 * 24 * time.Hour}, policy)
+	assert.True(t, policy.enabled())
+
+	t.Setenv("FULL_BACKUP_MAX_DAYS", "-1")
+	_, err = getFullBackupPolicy()
+	assert.Error(t, err)
+
+	t.Setenv("FULL_BACKUP_MAX_DAYS", "")
+	t.Setenv("AGGREGATE_AFTER_DIFFERENTIALS", "3")
+	t.Setenv("AGGREGATE_BACKUP_FROM_PATH", "")
+	_, err = getFullBackupPolicy()
+	assert.ErrorContains(t, err, "requires AGGREGATE_BACKUP_FROM_PATH", "LOCATION only holds the artifacts of the current run")
+
+	t.Setenv("AGGREGATE_BACKUP_FROM_PATH", "/backups")
+	t.Setenv("KEEP_BACKUP_FILES", "false")
+	_, err = getFullBackupPolicy()
+	assert.ErrorContains(t, err, "requires KEEP_BACKUP_FILES=true")
+
+	t.Setenv("AGGREGATE_BACKUP_FROM_PATH", "s3://backups/prod")
+	policy, err = getFullBackupPolicy()
+	assert.NoError(t, err)
+	assert.Equal(t, &fullBackupPolicy{maxDifferentials: 6, aggregateAfter: 3, aggregateFromPath: "s3://backups/prod"}, policy)
+}
+
+func TestForceFull(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, 6, 13, 10, 0, 0, 0, time.UTC)
+	states := chainStates{
+		"neo4j":  {FullBackupTime: now.Add(-48 * time.Hour), Differentials: 3},
+		"orders": {FullBackupTime: now.Add(-10 * 24 * time.Hour), Differentials: 1},
+		"legacy": {Differentials: 20},
+	}
+	tests := []struct {
+		name      string
+		policy    fullBackupPolicy
+		databases []string
+		wantFull  bool
+	}{
+		{name: "disabled", databases: []string{"*"}},
+		{name: "differentials below the limit", policy: fullBackupPolicy{maxDifferentials: 4}, databases: []string{"neo4j"}},
+		{name: "differentials reached the limit", policy: fullBackupPolicy{maxDifferentials: 3}, databases: []string{"neo4j"}, wantFull: true},
+		{name: "full backup too old", policy: fullBackupPolicy{maxAge: 7 * 24 * time.Hour}, databases: []string{"neo4j", "orders"}, wantFull: true},
+		{name: "full backup recent enough", policy: fullBackupPolicy{maxAge: 7 * 24 * time.Hour}, databases: []string{"neo4j"}},
+		{name: "chain without a full backup is ignored", policy: fullBackupPolicy{maxDifferentials: 3}, databases: []string{"legacy"}},
+		{name: "all databases", policy: fullBackupPolicy{maxDifferentials: 3}, databases: []string{"*"}, wantFull: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantFull, tt.policy.forceFull(states, tt.databases, now) != "")
+		})
+	}
+
+	policy := fullBackupPolicy{aggregateAfter: 3}
+	assert.Equal(t, []string{"legacy", "neo4j"}, policy.chainsToAggregate(states, []string{"*"}))
+}
+
+func TestChainStates(t *testing.T) {
+	t.Parallel()
+
+	backupCatalog := catalog.Build([]common.ObjectInfo{
+		{Key: "neo4j-2024-06-10T10-00-00.backup", Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "neo4j-2024-06-11T10-00-00.backup", Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "neo4j-2024-06-12T10-00-00.backup", Metadata: map[string]string{"backup-type": "FULL"}},
+		{Key: "neo4j-2024-06-13T10-00-00.backup", Metadata: map[string]string{"backup-type": "DIFF"}},
+		{Key: "orders-2024-06-13T10-00-00.backup", Metadata: map[string]string{"backup-type": "DIFF"}},
+	})
+	states := chainStatesFromCatalog(backupCatalog)
+	assert.Equal(t, chainStates{
+		"neo4j":  {FullBackupTime: time.Date(2024, 6, 12, 10, 0, 0, 0, time.UTC), Differentials: 1},
+		"orders": {Differentials: 1},
+	}, states)
+
+	common.SetBackupType("neo4j-2024-06-14T10-00-00.backup", "DIFF")
+	common.SetBackupType("orders-2024-06-14T10-00-00.backup", "FULL")
+	states.record([]string{"neo4j-2024-06-14T10-00-00.backup", "orders-2024-06-14T10-00-00.backup"})
+	assert.Equal(t, 2, states["neo4j"].Differentials)
+	assert.Equal(t, chainState{FullBackupTime: time.Date(2024, 6, 14, 10, 0, 0, 0, time.UTC)}, states["orders"])
+}
+
+func TestPipelineForcedFullBackups(t *testing.T) {
+	_, storage, _ := setupPipeline(t)
+	t.Setenv("DATABASE", "neo4j")
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	t.Setenv("KEEP_BACKUP_FILES", "true")
+	t.Setenv("FULL_BACKUP_MAX_DIFFERENTIALS", "2")
+
+	for i := 0; i < 4; i++ {
+		assert.NoError(t, runOperations())
+	}
+	objects, err := storage.ListObjects("backups/prod")
+	assert.NoError(t, err)
+	var types []string
+	for _, object := range objects {
+		types = append(types, object.Metadata["backup-type"])
+	}
+	assert.Equal(t, []string{"FULL", "DIFF", "DIFF", "FULL"}, types)
+}
+
+func TestPipelineAutomaticAggregation(t *testing.T) {
+	_, storage, location := setupPipeline(t)
+	t.Setenv("DATABASE", "neo4j")
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	t.Setenv("KEEP_BACKUP_FILES", "true")
+	t.Setenv("AGGREGATE_AFTER_DIFFERENTIALS", "2")
+	t.Setenv("AGGREGATE_BACKUP_FROM_PATH", location)
+
+	for i := 0; i < 3; i++ {
+		assert.NoError(t, runOperations())
+	}
+	run := readStatus(t, location)
+	assert.Len(t, run.AggregatedFiles, 1)
+
+	// the aggregated artifact is a full backup starting a new chain
+	objects, err := storage.ListObjects("backups/prod")
+	assert.NoError(t, err)
+	entry, present := catalog.Build(objects).FindDatabase("neo4j")
+	assert.True(t, present)
+	if assert.Len(t, entry.Chains, 2) {
+		assert.Len(t, entry.Chains[0].Differentials, 2)
+		assert.Empty(t, entry.Chains[1].Differentials)
+	}
+}
+
+func TestSaveEnv(t *testing.T) {
+	t.Setenv("AWS_REGION", "eu-west-1")
+	t.Setenv("AWS_SESSION_TOKEN", "")
+	os.Unsetenv("AWS_SESSION_TOKEN")
+
+	// the credentials exported for the aggregation do not leak into the later runs of the daemon
+	restore := saveEnv(awsCredentialEnvs)
+	os.Setenv("AWS_REGION", "us-east-1")
+	os.Setenv("AWS_SESSION_TOKEN", "token")
+	restore()
+	assert.Equal(t, "eu-west-1", os.Getenv("AWS_REGION"))
+	_, present := os.LookupEnv("AWS_SESSION_TOKEN")
+	assert.False(t, present)
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+// command is a single subcommand of the backup binary
+type command struct {
+	name        string
+	description string
+	// run parses the given arguments and executes the command
+	run func(args []string) error
+}
+
+func commands() []command {
+	return []command{
+		{name: "run", description: "take a backup , run the consistency check and upload the artifacts (default)", run: runCommand},
+		{name: "aggregate", description: "aggregate a backup chain into a single artifact", run: aggregateCommand},
+		{name: "check", description: "run the consistency check on the latest backup present at a path", run: checkCommand},
+		{name: "restore", description: "download the latest backup chain of a database (or the one covering a point in time) and restore it", run: restoreCommand},
+		{name: "dump", description: "dump the databases of a stopped server and upload the dumps", run: dumpCommand},
+		{name: "load", description: "download the latest dump of a database and load it into a stopped server", run: loadCommand},
+		{name: "import", description: "download CSV files from the bucket and import them into a database", run: importCommand},
+		{name: "copy", description: "restore the latest backup of a database , copy it with filters (compaction) and upload the dump", run: copyCommand},
+		{name: "migrate", description: "restore the latest backup of a database , migrate its store format and upload the dump", run: migrateCommand},
+		{name: "snapshot", description: "take a CSI VolumeSnapshot of every data volume of a release and apply the retention", run: snapshotCommand},
+		{name: "plan", description: "resolve the full and differential backups restoring a database as of a point in time", run: planCommand},
+		{name: "init", description: "create the bucket if requested , apply the lifecycle rules and verify the write permission of every destination", run: initCommand},
+		{name: "doctor", description: "check the credentials , the bucket access of every destination , the connectivity of every endpoint and the neo4j-admin version", run: doctorCommand},
+		{name: "list", description: "list the backup artifacts present in the bucket grouped into chains", run: listCommand},
+		{name: "prune", description: "delete old backup chains from the bucket", run: pruneCommand},
+		{name: "verify", description: "download the latest backup chain of a database and run the consistency check on it", run: verifyCommand},
+		{name: "daemon", description: "run the backup on a schedule and serve the health and status endpoints", run: daemonCommand},
+	}
+}
+
+// runCLI executes the subcommand present in the given arguments and returns the exit code
+// Without any argument the behaviour is driven by env variables only , which is how the CronJob invokes the binary
+func runCLI(args []string, stderr io.Writer) int {
+	if err := tracing.Init(); err != nil {
+		log.Printf("Warning: the backup is not traced: %v", err)
+	}
+	defer tracing.Shutdown()
+	if len(args) == 0 {
+		return exitCodeOf(defaultCommand())
+	}
+	name := args[0]
+	if name == "help" || name == "-h" || name == "--help" {
+		printUsage(stderr)
+		return exitSuccess
+	}
+	for _, c := range commands() {
+		if c.name == name {
+			return exitCodeOf(c.run(args[1:]))
+		}
+	}
+	fmt.Fprintf(stderr, "unknown command %s\n\n", name)
+	printUsage(stderr)
+	return exitUsage
+}
+
+// defaultCommand runs aggregate backup if AGGREGATE_BACKUP_ENABLED is true , a normal backup otherwise
+func defaultCommand() error {
+	if aggregateEnabled := os.Getenv("AGGREGATE_BACKUP_ENABLED"); aggregateEnabled == "true" {
+		return aggregateOperations()
+	}
+	cloudProvider := os.Getenv("CLOUD_PROVIDER")
+	switch cloudProvider {
+	case "aws", "azure", "gcp", "":
+		return runOperations()
+	default:
+		return withExitCode(exitConfiguration, fmt.Errorf("Incorrect cloud provider %s", cloudProvider))
+	}
+}
+
+func exitCodeOf(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		return exitSuccess
+	}
+	code := exitCode(err)
+	slog.Error(err.Error(), "exit_code", code)
+	return code
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "Usage: backup [command] [flags]")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Without a command the backup (or aggregate backup when AGGREGATE_BACKUP_ENABLED=true) is performed using env variables only.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Commands:")
+	for _, c := range commands() {
+		fmt.Fprintf(w, "  %-10s %s\n", c.name, c.description)
+	}
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Use 'backup <command> -h' for the flags of a command.")
+	fmt.Fprintln(w, "")
+	fmt.Fprintln(w, "Exit codes:")
+	fmt.Fprintf(w, "  %d success\n", exitSuccess)
+	fmt.Fprintf(w, "  %d unclassified failure\n", exitFailure)
+	fmt.Fprintf(w, "  %d invalid usage\n", exitUsage)
+	fmt.Fprintf(w, "  %d invalid configuration or credentials\n", exitConfiguration)
+	fmt.Fprintf(w, "  %d database not reachable\n", exitConnectivity)
+	fmt.Fprintf(w, "  %d backup or aggregate backup failed\n", exitBackup)
+	fmt.Fprintf(w, "  %d consistency check failed\n", exitConsistencyCheck)
+	fmt.Fprintf(w, "  %d bucket access , upload , download or delete failed\n", exitStorage)
+	fmt.Fprintf(w, "  %d restore failed\n", exitRestore)
+	fmt.Fprintf(w, "  %d not enough free space at /backups\n", exitCapacity)
+	fmt.Fprintf(w, "  %d consistency check found inconsistencies\n", exitInconsistencies)
+	fmt.Fprintf(w, "  %d import failed\n", exitImport)
+	fmt.Fprintf(w, "  %d copy or migrate failed\n", exitMaintenance)
+	fmt.Fprintf(w, "  %d volume snapshot failed\n", exitSnapshot)
+	fmt.Fprintf(w, "  %d hook failed\n", exitHook)
+}
+
+// envFlags binds command line flags to the env variables read by the backup operations
+// A flag provided on the command line overrides the respective env variable
+type envFlags struct {
+	*flag.FlagSet
+	envs map[string]string
+}
+
+func newEnvFlags(name string, description string) *envFlags {
+	flags := &envFlags{
+		FlagSet: flag.NewFlagSet(name, flag.ContinueOnError),
+		envs:    make(map[string]string),
+	}
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "Usage: backup %s [flags]\n\n%s\n\nFlags:\n", name, description)
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// env registers a string flag defaulting to the value of the given env variable
+func (f *envFlags) env(name string, env string, usage string) {
+	f.String(name, os.Getenv(env), fmt.Sprintf("%s (env %s)", usage, env))
+	f.envs[name] = env
+}
+
+// parse parses the arguments and exports the flags set on the command line as env variables
+func (f *envFlags) parse(args []string) error {
+	if err := f.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return withExitCode(exitUsage, err)
+	}
+	if f.NArg() > 0 {
+		return withExitCode(exitUsage, fmt.Errorf("unexpected arguments %s", strings.Join(f.Args(), " ")))
+	}
+	var err error
+	f.Visit(func(fl *flag.Flag) {
+		if env, present := f.envs[fl.Name]; present && err == nil {
+			err = os.Setenv(env, fl.Value.String())
+		}
+	})
+	return err
+}
+
+// storageFlags registers the flags describing the primary destination
+func (f *envFlags) storageFlags() {
+	f.env("cloud-provider", "CLOUD_PROVIDER", "cloud provider of the bucket (aws, azure or gcp)")
+	f.env("bucket", "BUCKET_NAME", "bucket (or container) name including the prefix")
+	f.env("credential-path", "CREDENTIAL_PATH", "path of the cloud provider credentials file , /credentials/ for workload identity")
+	f.env("upload-rate-limit", "UPLOAD_RATE_LIMIT", "maximum upload speed in bytes per second , 0 for unlimited")
+}
+
+func runCommand(args []string) error {
+	flags := newEnvFlags("run", "Takes a backup , runs the consistency check and uploads the artifacts to every destination.")
+	flags.storageFlags()
+	flags.env("database", "DATABASE", "comma separated list of databases to backup")
+	flags.env("endpoints", "DATABASE_BACKUP_ENDPOINTS", "comma separated list of backup endpoints <host:port>")
+	flags.env("type", "TYPE", "backup type (AUTO, FULL or DIFF)")
+	flags.env("consistency-check", "CONSISTENCY_CHECK_ENABLE", "run the consistency check after the backup (true or false)")
+	flags.env("keep-backup-files", "KEEP_BACKUP_FILES", "keep the backup files at /backups after the upload (true or false)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	return runOperations()
+}
+
+func aggregateCommand(args []string) error {
+	flags := newEnvFlags("aggregate", "Aggregates a backup chain into a single artifact.")
+	flags.storageFlags()
+	flags.env("database", "AGGREGATE_BACKUP_DATABASE", "database name to aggregate , can contain * and ? for globbing")
+	flags.env("from-path", "AGGREGATE_BACKUP_FROM_PATH", "path of the backup chain , local path or s3://bucket/prefix")
+	flags.env("keep-old-backup", "AGGREGATE_BACKUP_KEEPOLDBACKUP", "keep the aggregated backup artifacts (true or false)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	return aggregateOperations()
+}
+
+func listCommand(args []string) error {
+	return list(args, os.Stdout)
+}
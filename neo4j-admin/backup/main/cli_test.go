@@ -0,0 +1,48 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRunCLIUsage(t *testing.T) {
+	var stderr bytes.Buffer
+	assert.Equal(t, exitSuccess, runCLI([]string{"help"}, &stderr))
+	assert.Contains(t, stderr.String(), "restore")
+
+	stderr.Reset()
+	assert.Equal(t, exitUsage, runCLI([]string{"unknown"}, &stderr))
+	assert.Contains(t, stderr.String(), "unknown command unknown")
+
+	assert.Equal(t, exitUsage, runCLI([]string{"restore", "--no-such-flag"}, &stderr))
+	assert.Equal(t, exitUsage, runCLI([]string{"restore"}, &stderr), "restore without --database must fail")
+	assert.Equal(t, exitUsage, runCLI([]string{"list", "--output", "xml"}, &stderr))
+}
+
+func TestEnvFlagsOverrideEnv(t *testing.T) {
+	t.Setenv("BUCKET_NAME", "from-env")
+	t.Setenv("CLOUD_PROVIDER", "aws")
+
+	flags := newEnvFlags("test", "test command")
+	flags.storageFlags()
+	assert.NoError(t, flags.parse([]string{"--bucket", "from-flag"}))
+	assert.Equal(t, "from-flag", os.Getenv("BUCKET_NAME"))
+	assert.Equal(t, "aws", os.Getenv("CLOUD_PROVIDER"), "env variables of flags not set must not change")
+}
+
+func TestExitCode(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, exitSuccess, exitCode(nil))
+	assert.Equal(t, exitFailure, exitCode(errors.New("plain error")))
+
+	err := withExitCode(exitBackup, errors.New("backup failed"))
+	assert.Equal(t, exitBackup, exitCode(err))
+	assert.Equal(t, exitBackup, exitCode(withExitCode(exitStorage, err)), "the first classification must be preserved")
+	assert.Equal(t, exitBackup, exitCode(fmt.Errorf("wrapped: %w", err)))
+}
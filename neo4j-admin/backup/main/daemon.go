@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/neo4j/helm-charts/logging"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/schedule"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+)
+
+const (
+	// missedRunSkip skips the activations missed while a run was in progress or the daemon was not running
+	missedRunSkip = "skip"
+	// missedRunRunOnce runs once immediately for all the missed activations
+	missedRunRunOnce = "run-once"
+)
+
+// daemon runs the backup pipeline on a schedule and serves its health and last run status over http
+type daemon struct {
+	schedule        schedule.Schedule
+	jitter          time.Duration
+	missedRunPolicy string
+	// run performs a single backup recording the result in the given run
+	run func(run *status.Run) error
+	now func() time.Time
+
+	lock  sync.Mutex
+	state daemonState
+}
+
+// daemonState is served by the status endpoint
+type daemonState struct {
+	StartTime       time.Time       `json:"startTime"`
+	Running         bool            `json:"running"`
+	CurrentRunStart *time.Time      `json:"currentRunStart,omitempty"`
+	NextRun         *time.Time      `json:"nextRun,omitempty"`
+	Runs            int             `json:"runs"`
+	FailedRuns      int             `json:"failedRuns"`
+	MissedRuns      int             `json:"missedRuns"`
+	LastRun         json.RawMessage `json:"lastRun,omitempty"`
+	lastRunStart    time.Time
+}
+
+func daemonCommand(args []string) error {
+	flags := newEnvFlags("daemon", "Runs the backup on a schedule and serves the /healthz and /status endpoints until terminated.")
+	flags.env("schedule", "DAEMON_SCHEDULE", "cron expression (minute hour day-of-month month day-of-week) of the backup runs")
+	flags.env("interval", "DAEMON_INTERVAL", "interval between the backup runs (ex: 30m) , alternative to schedule")
+	flags.env("jitter", "DAEMON_JITTER", "maximum random delay added to every run (ex: 2m)")
+	flags.env("missed-runs", "DAEMON_MISSED_RUN_POLICY", "skip the missed runs or run once immediately (skip or run-once)")
+	flags.env("listen-address", "DAEMON_LISTEN_ADDRESS", "address of the health and status endpoints (default :8080)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	d, err := newDaemon()
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+	address := os.Getenv("DAEMON_LISTEN_ADDRESS")
+	if address == "" {
+		address = ":8080"
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT)
+	defer stop()
+	server := &http.Server{Addr: address, Handler: d.handler()}
+	serverErr := make(chan error, 1)
+	go func() {
+		log.Printf("Serving the health and status endpoints on %s", address)
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+			stop()
+		}
+	}()
+
+	d.loop(ctx)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err = server.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Warning: unable to shutdown the status server: %v", err)
+	}
+	select {
+	case err = <-serverErr:
+		return withExitCode(exitConfiguration, fmt.Errorf("unable to serve the status endpoints on %s \n %v", address, err))
+	default:
+		return nil
+	}
+}
+
+// newDaemon reads the schedule from DAEMON_SCHEDULE or DAEMON_INTERVAL along with DAEMON_JITTER and DAEMON_MISSED_RUN_POLICY
+func newDaemon() (*daemon, error) {
+	d := &daemon{
+		missedRunPolicy: strings.ToLower(strings.TrimSpace(os.Getenv("DAEMON_MISSED_RUN_POLICY"))),
+		run:             scheduledRun,
+		now:             time.Now,
+	}
+	cronExpression := strings.TrimSpace(os.Getenv("DAEMON_SCHEDULE"))
+	intervalValue := strings.TrimSpace(os.Getenv("DAEMON_INTERVAL"))
+	var err error
+	switch {
+	case cronExpression != "" && intervalValue != "":
+		return nil, fmt.Errorf("only one of DAEMON_SCHEDULE and DAEMON_INTERVAL can be set")
+	case cronExpression != "":
+		d.schedule, err = schedule.ParseCron(cronExpression)
+	case intervalValue != "":
+		var every time.Duration
+		if every, err = time.ParseDuration(intervalValue); err != nil {
+			return nil, fmt.Errorf("invalid DAEMON_INTERVAL %s \n %v", intervalValue, err)
+		}
+		d.schedule, err = schedule.Every(every)
+	default:
+		return nil, fmt.Errorf("either DAEMON_SCHEDULE or DAEMON_INTERVAL must be set")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if value := strings.TrimSpace(os.Getenv("DAEMON_JITTER")); value != "" {
+		if d.jitter, err = time.ParseDuration(value); err != nil || d.jitter < 0 {
+			return nil, fmt.Errorf("invalid DAEMON_JITTER %s", value)
+		}
+	}
+	if d.missedRunPolicy == "" {
+		d.missedRunPolicy = missedRunSkip
+	}
+	if d.missedRunPolicy != missedRunSkip && d.missedRunPolicy != missedRunRunOnce {
+		return nil, fmt.Errorf("invalid DAEMON_MISSED_RUN_POLICY %s. Supported values are skip and run-once", d.missedRunPolicy)
+	}
+	d.state.StartTime = d.now().UTC()
+	d.loadLastRun(os.Getenv("STATUS_FILE"))
+	return d, nil
+}
+
+// scheduledRun performs an aggregate backup if AGGREGATE_BACKUP_ENABLED is true , a normal backup otherwise
+func scheduledRun(run *status.Run) error {
+	if aggregateEnabled := os.Getenv("AGGREGATE_BACKUP_ENABLED"); aggregateEnabled == "true" {
+		err := aggregateOperations()
+		if err != nil {
+			run.Finish(status.Failed, err)
+		} else {
+			run.Finish(status.Success, nil)
+		}
+		return err
+	}
+	return runOperationsWithStatus(run)
+}
+
+// loadLastRun reads the status written by the previous run so that the runs missed while the daemon was not running are detected
+func (d *daemon) loadLastRun(filePath string) {
+	if filePath == "" {
+		return
+	}
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return
+	}
+	var lastRun status.Run
+	if err = json.Unmarshal(data, &lastRun); err != nil || lastRun.StartTime.IsZero() {
+		return
+	}
+	d.state.LastRun = data
+	d.state.lastRunStart = lastRun.StartTime
+}
+
+// loop runs the backup at every activation of the schedule until the context is cancelled
+// A run in progress is always completed before returning
+func (d *daemon) loop(ctx context.Context) {
+	next := d.schedule.Next(d.now())
+	if !d.state.lastRunStart.IsZero() {
+		next = d.nextActivation(d.state.lastRunStart)
+	}
+	for {
+		if next.IsZero() {
+			log.Printf("The schedule has no further activation , waiting for termination")
+			<-ctx.Done()
+			return
+		}
+		at := next.Add(d.jitterDelay())
+		d.setNextRun(at)
+		log.Printf("Next backup run scheduled at %s", at.UTC().Format(time.RFC3339))
+		timer := time.NewTimer(at.Sub(d.now()))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			log.Printf("Daemon terminated")
+			return
+		case <-timer.C:
+		}
+		d.execute()
+		next = d.nextActivation(next)
+	}
+}
+
+// nextActivation returns the activation following the given one
+// Activations which already passed (the run took longer than the interval or the daemon was not running) are missed runs ,
+// they are skipped or replaced by a single immediate run depending on the missed run policy
+func (d *daemon) nextActivation(previous time.Time) time.Time {
+	now := d.now()
+	next := d.schedule.Next(previous)
+	var missed int
+	for !next.IsZero() && next.Before(now) {
+		missed++
+		next = d.schedule.Next(next)
+	}
+	if missed == 0 {
+		return next
+	}
+	d.lock.Lock()
+	d.state.MissedRuns += missed
+	d.lock.Unlock()
+	if d.missedRunPolicy == missedRunRunOnce {
+		log.Printf("Missed %d backup run(s) , running once immediately", missed)
+		return now
+	}
+	log.Printf("Missed %d backup run(s) , skipping to the next activation", missed)
+	return next
+}
+
+func (d *daemon) jitterDelay() time.Duration {
+	if d.jitter <= 0 {
+		return 0
+	}
+	return time.Duration(rand.Int63n(int64(d.jitter)))
+}
+
+func (d *daemon) setNextRun(at time.Time) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	next := at.UTC()
+	d.state.NextRun = &next
+}
+
+// execute performs a single run , a panic of the run is recorded as a failed run instead of terminating the daemon
+func (d *daemon) execute() {
+	logging.StartRun()
+	run := status.NewRun()
+	d.lock.Lock()
+	d.state.Running = true
+	d.state.CurrentRunStart = &run.StartTime
+	d.state.NextRun = nil
+	d.lock.Unlock()
+
+	err := func() (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = fmt.Errorf("backup run panicked: %v", r)
+				run.Finish(status.Failed, err)
+			}
+		}()
+		return d.run(run)
+	}()
+	if err != nil {
+		slog.Error("Backup run failed", "exit_code", exitCode(err), "error", err)
+	}
+
+	lastRun, jsonErr := run.JSON()
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.state.Running = false
+	d.state.CurrentRunStart = nil
+	d.state.Runs++
+	if err != nil {
+		d.state.FailedRuns++
+	}
+	if jsonErr == nil {
+		d.state.LastRun = lastRun
+	}
+	d.state.lastRunStart = run.StartTime
+}
+
+// handler serves /healthz (the daemon is alive) and /status (the daemon state including the last run status)
+func (d *daemon) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintln(w, `{"status":"ok"}`)
+	})
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		d.lock.Lock()
+		data, err := json.MarshalIndent(d.state, "", "  ")
+		d.lock.Unlock()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	})
+	return mux
+}
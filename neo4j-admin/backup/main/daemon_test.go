@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/schedule"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDaemon(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule string
+		interval string
+		jitter   string
+		policy   string
+		wantErr  bool
+	}{
+		{name: "cron schedule", schedule: "0 */6 * * *"},
+		{name: "interval with jitter", interval: "30m", jitter: "1m", policy: "run-once"},
+		{name: "missing schedule", wantErr: true},
+		{name: "schedule and interval", schedule: "@daily", interval: "1h", wantErr: true},
+		{name: "invalid cron expression", schedule: "0 25 * * *", wantErr: true},
+		{name: "invalid jitter", interval: "1h", jitter: "soon", wantErr: true},
+		{name: "invalid missed run policy", interval: "1h", policy: "catch-up", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DAEMON_SCHEDULE", tt.schedule)
+			t.Setenv("DAEMON_INTERVAL", tt.interval)
+			t.Setenv("DAEMON_JITTER", tt.jitter)
+			t.Setenv("DAEMON_MISSED_RUN_POLICY", tt.policy)
+			t.Setenv("STATUS_FILE", "")
+			_, err := newDaemon()
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestDaemonNextActivation(t *testing.T) {
+	t.Parallel()
+
+	hourly, err := schedule.ParseCron("@hourly")
+	assert.NoError(t, err)
+	now := time.Date(2024, 6, 13, 12, 43, 0, 0, time.UTC)
+	// the previous run was scheduled at 09:00 , the runs of 10:00 , 11:00 and 12:00 were missed
+	previous := time.Date(2024, 6, 13, 9, 0, 0, 0, time.UTC)
+
+	d := &daemon{schedule: hourly, missedRunPolicy: missedRunSkip, now: func() time.Time { return now }}
+	assert.Equal(t, time.Date(2024, 6, 13, 13, 0, 0, 0, time.UTC), d.nextActivation(previous))
+	assert.Equal(t, 3, d.state.MissedRuns)
+
+	d = &daemon{schedule: hourly, missedRunPolicy: missedRunRunOnce, now: func() time.Time { return now }}
+	assert.Equal(t, now, d.nextActivation(previous))
+	assert.Equal(t, 3, d.state.MissedRuns)
+
+	// nothing missed
+	assert.Equal(t, time.Date(2024, 6, 13, 13, 0, 0, 0, time.UTC), d.nextActivation(time.Date(2024, 6, 13, 12, 0, 0, 0, time.UTC)))
+	assert.Equal(t, 3, d.state.MissedRuns)
+}
+
+func TestDaemonStatusEndpoints(t *testing.T) {
+	t.Parallel()
+
+	runs := 0
+	d := &daemon{
+		now: time.Now,
+		run: func(run *status.Run) error {
+			runs++
+			if runs == 2 {
+				panic("neo4j-admin crashed")
+			}
+			run.BackupFiles = []string{"neo4j-2024-06-13T12-43-43.backup"}
+			run.Finish(status.Success, nil)
+			return nil
+		},
+	}
+	server := httptest.NewServer(d.handler())
+	defer server.Close()
+
+	response, err := http.Get(server.URL + "/healthz")
+	assert.NoError(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	getState := func() daemonState {
+		response, err := http.Get(server.URL + "/status")
+		assert.NoError(t, err)
+		defer response.Body.Close()
+		var state daemonState
+		assert.NoError(t, json.NewDecoder(response.Body).Decode(&state))
+		return state
+	}
+
+	d.execute()
+	state := getState()
+	assert.Equal(t, 1, state.Runs)
+	assert.Equal(t, 0, state.FailedRuns)
+	assert.False(t, state.Running)
+	var lastRun status.Run
+	assert.NoError(t, json.Unmarshal(state.LastRun, &lastRun))
+	assert.Equal(t, status.Success, lastRun.Status)
+	assert.Equal(t, []string{"neo4j-2024-06-13T12-43-43.backup"}, lastRun.BackupFiles)
+
+	// a panicking run is recorded as failed and does not terminate the daemon
+	d.execute()
+	state = getState()
+	assert.Equal(t, 2, state.Runs)
+	assert.Equal(t, 1, state.FailedRuns)
+	assert.NoError(t, json.Unmarshal(state.LastRun, &lastRun))
+	assert.Equal(t, status.Failed, lastRun.Status)
+	assert.Contains(t, lastRun.Error, "neo4j-admin crashed")
+}
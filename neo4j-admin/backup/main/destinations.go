@@ -0,0 +1,245 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/aws"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/azure"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	gcp "github.com/neo4j/helm-charts/neo4j-admin/backup/gcp"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+const (
+	// failurePolicyAny fails the job if any of the destinations fails
+	failurePolicyAny = "any"
+	// failurePolicyAll fails the job only if all the destinations fail
+	failurePolicyAll = "all"
+)
+
+// destination describes a single location where the backup artifacts are uploaded to
+type destination struct {
+	Name           string `json:"name"`
+	CloudProvider  string `json:"cloudProvider"`
+	BucketName     string `json:"bucketName"`
+	CredentialPath string `json:"credentialPath"`
+	KeyLayout      string `json:"keyLayout"`
+	// AssumeRoles is the json list of roles assumed on top of the credentials of an aws destination , AWS_ASSUME_ROLES for the primary
+	AssumeRoles json.RawMessage `json:"assumeRoles,omitempty"`
+
+	client common.StorageClient
+	init   destinationInit
+}
+
+// getDestinations returns the primary destination (CLOUD_PROVIDER , BUCKET_NAME , CREDENTIAL_PATH , KEY_LAYOUT , AWS_ASSUME_ROLES)
+// followed by the additional destinations provided as a json list via BACKUP_DESTINATIONS
+// DESTINATION_FAILURE_POLICY is validated along with them so that an invalid policy fails the job before any backup is taken
+func getDestinations() ([]*destination, error) {
+	if _, err := getFailurePolicy(); err != nil {
+		return nil, err
+	}
+	var destinations []*destination
+	if cloudProvider := strings.TrimSpace(os.Getenv("CLOUD_PROVIDER")); cloudProvider != "" {
+		destinations = append(destinations, &destination{
+			Name:           "primary",
+			CloudProvider:  cloudProvider,
+			BucketName:     os.Getenv("BUCKET_NAME"),
+			CredentialPath: os.Getenv("CREDENTIAL_PATH"),
+			KeyLayout:      os.Getenv("KEY_LAYOUT"),
+			AssumeRoles:    json.RawMessage(strings.TrimSpace(os.Getenv("AWS_ASSUME_ROLES"))),
+		})
+	}
+
+	additionalDestinations, err := parseDestinations(os.Getenv("BACKUP_DESTINATIONS"))
+	if err != nil {
+		return nil, err
+	}
+	destinations = append(destinations, additionalDestinations...)
+
+	initOptions, err := getDestinationInit()
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool)
+	for _, d := range destinations {
+		if names[d.Name] {
+			return nil, fmt.Errorf("duplicate backup destination name %s", d.Name)
+		}
+		names[d.Name] = true
+		d.init = initOptions
+	}
+	return destinations, nil
+}
+
+// parseDestinations parses the json list of additional destinations
+// Ex: [{"name":"dr","cloudProvider":"gcp","bucketName":"dr-bucket/neo4j","credentialPath":"/credentials-dr/credentials","keyLayout":"{database}"}]
+// The aws destinations may contain their own roles to assume , ex: "assumeRoles":[{"roleArn":"arn:aws:iam::222222222222:role/dr"}]
+func parseDestinations(value string) ([]*destination, error) {
+	var destinations []*destination
+	if strings.TrimSpace(value) == "" {
+		return destinations, nil
+	}
+	if err := json.Unmarshal([]byte(value), &destinations); err != nil {
+		return nil, fmt.Errorf("unable to parse BACKUP_DESTINATIONS \n %v", err)
+	}
+	for i, d := range destinations {
+		if d.Name == "" {
+			d.Name = fmt.Sprintf("destination-%d", i+1)
+		}
+		if d.CloudProvider == "" || d.BucketName == "" {
+			return nil, fmt.Errorf("backup destination %s must contain both cloudProvider and bucketName", d.Name)
+		}
+		if d.CredentialPath == "" {
+			d.CredentialPath = "/credentials/"
+		}
+	}
+	return destinations, nil
+}
+
+// newStorageClient returns the storage client of the respective cloud provider
+// It is replaced by the tests to run the backup pipeline against an in-memory storage
+var newStorageClient = func(cloudProvider string, credentialPath string, assumeRoles string) (common.StorageClient, error) {
+	switch cloudProvider {
+	case "aws":
+		return aws.NewAwsClient(credentialPath, assumeRoles)
+	case "azure":
+		return azure.NewAzureClient(credentialPath)
+	case "gcp":
+		return gcp.NewGCPClient(credentialPath)
+	default:
+		return nil, fmt.Errorf("Incorrect cloud provider %s", cloudProvider)
+	}
+}
+
+// prepareDestinations creates the storage client of every destination and initialises it
+// The destinations which are not accessible are recorded as failed in the run status
+func prepareDestinations(destinations []*destination, run *status.Run) []*destination {
+	var ready []*destination
+	for _, d := range destinations {
+		client, err := newStorageClient(d.CloudProvider, d.CredentialPath, string(d.AssumeRoles))
+		if err == nil {
+			err = d.initialise(client)
+		}
+		if err != nil {
+			log.Printf("Destination %s (%s:%s) is not accessible: %v", d.Name, d.CloudProvider, d.BucketName, err)
+			run.AddDestination(d.result(nil, err))
+			continue
+		}
+		d.client = client
+		ready = append(ready, d)
+	}
+	return ready
+}
+
+// uploadToDestinations uploads the same set of files to every destination and records the result of each of them in the run status
+// The upload to every destination is traced as a span having a child span per file
+func uploadToDestinations(destinations []*destination, fileNames []string, run *status.Run) {
+	for _, d := range destinations {
+		if alreadyUploaded(d.Name, fileNames) {
+			log.Printf("Files %v were already uploaded to destination %s by the previous run", fileNames, d.Name)
+			run.AddDestination(d.result(fileNames, nil))
+			continue
+		}
+		span := tracing.Start("upload", tracing.Destination.String(d.Name), tracing.Bucket.String(d.BucketName), tracing.Artifacts.StringSlice(fileNames))
+		err := d.upload(fileNames)
+		if err != nil {
+			log.Printf("Upload to destination %s (%s:%s) failed: %v", d.Name, d.CloudProvider, d.BucketName, err)
+		} else {
+			recordUploaded(d.Name, fileNames)
+		}
+		result := d.result(fileNames, err)
+		result.Transfers = common.TakeTransfers()
+		var totalBytes int64
+		for _, transfer := range result.Transfers {
+			totalBytes += transfer.BytesTransferred
+			span.Record("upload file", transfer.StartTime, transfer.StartTime.Add(time.Duration(transfer.ElapsedSeconds*float64(time.Second))),
+				tracing.Artifact.String(transfer.FileName), tracing.Bucket.String(transfer.BucketName), tracing.Bytes.Int64(transfer.BytesTransferred))
+		}
+		span.SetAttributes(tracing.Bytes.Int64(totalBytes))
+		span.End(err)
+		run.AddDestination(result)
+	}
+}
+
+// upload uploads the files to the destination honouring the key layout of the destination
+// The expanded key layout is placed below KEY_PREFIX (if set) , which keeps apart the artifacts of the targets of a multi target job
+func (d *destination) upload(fileNames []string) error {
+	keyPrefix := strings.Trim(strings.TrimSpace(os.Getenv("KEY_PREFIX")), "/")
+	// group the files by the expanded key prefix so that files sharing a prefix are uploaded together
+	var prefixes []string
+	filesByPrefix := make(map[string][]string)
+	for _, fileName := range fileNames {
+		prefix := strings.Trim(path.Join(keyPrefix, common.KeyPrefix(d.KeyLayout, fileName)), "/")
+		if _, present := filesByPrefix[prefix]; !present {
+			prefixes = append(prefixes, prefix)
+		}
+		filesByPrefix[prefix] = append(filesByPrefix[prefix], fileName)
+	}
+	for _, prefix := range prefixes {
+		if err := d.client.UploadFile(filesByPrefix[prefix], common.JoinBucketPath(d.BucketName, prefix)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *destination) result(fileNames []string, err error) status.Destination {
+	result := status.Destination{
+		Name:          d.Name,
+		CloudProvider: d.CloudProvider,
+		BucketName:    d.BucketName,
+		Status:        status.Success,
+		Files:         fileNames,
+	}
+	if err != nil {
+		result.Status = status.Failed
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// getFailurePolicy returns the DESTINATION_FAILURE_POLICY , any when empty
+func getFailurePolicy() (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(os.Getenv("DESTINATION_FAILURE_POLICY")))
+	switch policy {
+	case "":
+		return failurePolicyAny, nil
+	case failurePolicyAny, failurePolicyAll:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("invalid DESTINATION_FAILURE_POLICY %s. Supported values are any and all", policy)
+	}
+}
+
+// evaluateDestinationResults applies the DESTINATION_FAILURE_POLICY to the destination results of the run
+// and returns the overall status along with an error if the job must fail
+func evaluateDestinationResults(run *status.Run) (string, error) {
+	policy, err := getFailurePolicy()
+	if err != nil {
+		return status.Failed, err
+	}
+
+	var failed []string
+	for _, d := range run.Destinations {
+		if d.Status == status.Failed {
+			failed = append(failed, d.Name)
+		}
+	}
+	switch {
+	case len(failed) == 0:
+		return status.Success, nil
+	case len(failed) == len(run.Destinations):
+		return status.Failed, fmt.Errorf("upload failed for all the destinations %v", failed)
+	case policy == failurePolicyAll:
+		return status.Partial, nil
+	default:
+		return status.Partial, fmt.Errorf("upload failed for destination(s) %v", failed)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseDestinations(t *testing.T) {
+	t.Parallel()
+
+	destinations, err := parseDestinations(`[{"name":"dr","cloudProvider":"gcp","bucketName":"dr-bucket/neo4j","credentialPath":"/credentials-dr/credentials","keyLayout":"{database}"},{"cloudProvider":"aws","bucketName":"second"}]`)
+	assert.NoError(t, err)
+	assert.Len(t, destinations, 2)
+	assert.Equal(t, "dr", destinations[0].Name)
+	assert.Equal(t, "{database}", destinations[0].KeyLayout)
+	assert.Equal(t, "destination-2", destinations[1].Name)
+	assert.Equal(t, "/credentials/", destinations[1].CredentialPath)
+
+	_, err = parseDestinations(`[{"name":"dr","cloudProvider":"gcp"}]`)
+	assert.Error(t, err)
+
+	_, err = parseDestinations(`not json`)
+	assert.Error(t, err)
+}
+
+func TestGetDestinationsAssumeRoles(t *testing.T) {
+	t.Setenv("CLOUD_PROVIDER", "aws")
+	t.Setenv("AWS_ASSUME_ROLES", `[{"roleArn":"arn:aws:iam::111111111111:role/backup"}]`)
+	t.Setenv("BACKUP_DESTINATIONS", `[{"name":"dr","cloudProvider":"aws","bucketName":"dr","credentialPath":"/credentials-dr/credentials","assumeRoles":[{"roleArn":"arn:aws:iam::222222222222:role/dr"}]},{"name":"copy","cloudProvider":"aws","bucketName":"copy"}]`)
+	t.Setenv("DESTINATION_FAILURE_POLICY", "")
+
+	destinations, err := getDestinations()
+	assert.NoError(t, err)
+	if assert.Len(t, destinations, 3) {
+		// the roles of the primary destination are not assumed by the additional destinations
+		assert.JSONEq(t, `[{"roleArn":"arn:aws:iam::111111111111:role/backup"}]`, string(destinations[0].AssumeRoles))
+		assert.JSONEq(t, `[{"roleArn":"arn:aws:iam::222222222222:role/dr"}]`, string(destinations[1].AssumeRoles))
+		assert.Empty(t, destinations[2].AssumeRoles)
+	}
+}
+
+func TestEvaluateDestinationResults(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		statuses   []string
+		wantStatus string
+		wantErr    bool
+	}{
+		{
+			name:       "all destinations succeeded",
+			policy:     "any",
+			statuses:   []string{status.Success, status.Success},
+			wantStatus: status.Success,
+		},
+		{
+			name:       "one destination failed with policy any",
+			policy:     "any",
+			statuses:   []string{status.Success, status.Failed},
+			wantStatus: status.Partial,
+			wantErr:    true,
+		},
+		{
+			name:       "one destination failed with policy all",
+			policy:     "all",
+			statuses:   []string{status.Success, status.Failed},
+			wantStatus: status.Partial,
+		},
+		{
+			name:       "all destinations failed with policy all",
+			policy:     "all",
+			statuses:   []string{status.Failed, status.Failed},
+			wantStatus: status.Failed,
+			wantErr:    true,
+		},
+		{
+			name:       "invalid policy",
+			policy:     "some",
+			statuses:   []string{status.Success},
+			wantStatus: status.Failed,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("DESTINATION_FAILURE_POLICY", tt.policy)
+			run := status.NewRun()
+			for i, s := range tt.statuses {
+				run.AddDestination(status.Destination{Name: string(rune('a' + i)), Status: s})
+			}
+			gotStatus, err := evaluateDestinationResults(run)
+			assert.Equal(t, tt.wantStatus, gotStatus)
+			assert.Equal(t, tt.wantErr, err != nil)
+		})
+	}
+}
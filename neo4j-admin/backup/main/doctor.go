@@ -0,0 +1,161 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/aws"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/azure"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	gcp "github.com/neo4j/helm-charts/neo4j-admin/backup/gcp"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+)
+
+// credentialCheckers check the credentials of every cloud provider without connecting to it
+var credentialCheckers = map[string]func(credentialPath string) (string, error){
+	"aws":   aws.CheckCredentials,
+	"azure": azure.CheckCredentials,
+	"gcp":   gcp.CheckCredentials,
+}
+
+// doctorCheck is a single line of the checklist printed by the doctor command
+type doctorCheck struct {
+	name   string
+	detail string
+	err    error
+}
+
+func (c doctorCheck) String() string {
+	if c.err != nil {
+		return fmt.Sprintf("[FAIL] %s: %s", c.name, strings.Join(strings.Fields(c.err.Error()), " "))
+	}
+	if c.detail == "" {
+		return fmt.Sprintf("[PASS] %s", c.name)
+	}
+	return fmt.Sprintf("[PASS] %s: %s", c.name, c.detail)
+}
+
+func doctorCommand(args []string) error {
+	flags := newEnvFlags("doctor", "Checks the credentials , the bucket access of every destination , the connectivity of every endpoint and the neo4j-admin version without taking a backup.")
+	flags.storageFlags()
+	flags.env("endpoints", "DATABASE_BACKUP_ENDPOINTS", "comma separated list of backup endpoints <host:port>")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	return doctor(os.Stdout)
+}
+
+// doctor runs every check , prints the checklist and fails when any of the checks fails
+func doctor(w io.Writer) error {
+	os.Setenv("LOCATION", neo4jAdmin.BackupLocation())
+	var checks []doctorCheck
+	checks = append(checks, destinationChecks()...)
+	checks = append(checks, endpointChecks()...)
+	version, err := neo4jAdmin.Version()
+	checks = append(checks, doctorCheck{name: "neo4j-admin version", detail: version, err: err})
+
+	failed := 0
+	for _, check := range checks {
+		fmt.Fprintln(w, check)
+		if check.err != nil {
+			failed++
+		}
+	}
+	fmt.Fprintf(w, "%d check(s) passed , %d failed\n", len(checks)-failed, failed)
+	if failed > 0 {
+		return withExitCode(exitConfiguration, fmt.Errorf("%d check(s) failed", failed))
+	}
+	return nil
+}
+
+// destinationChecks checks the credentials of every destination followed by the read , write and delete permissions of its bucket
+// The permissions are checked below KEY_PREFIX , the checks of a destination stop at its first failure
+func destinationChecks() []doctorCheck {
+	destinations, err := getDestinations()
+	if err != nil {
+		return []doctorCheck{{name: "destinations", err: err}}
+	}
+	if len(destinations) == 0 {
+		return []doctorCheck{{name: "destinations", err: fmt.Errorf("no backup destination configured")}}
+	}
+	var checks []doctorCheck
+	for _, d := range destinations {
+		name := fmt.Sprintf("destination %s (%s:%s)", d.Name, d.CloudProvider, d.BucketName)
+		checker, present := credentialCheckers[d.CloudProvider]
+		if !present {
+			checks = append(checks, doctorCheck{name: name + " credentials", err: fmt.Errorf("Incorrect cloud provider %s", d.CloudProvider)})
+			continue
+		}
+		detail, err := checker(d.CredentialPath)
+		checks = append(checks, doctorCheck{name: name + " credentials", detail: detail, err: err})
+		if err != nil {
+			continue
+		}
+
+		client, err := newStorageClient(d.CloudProvider, d.CredentialPath, string(d.AssumeRoles))
+		if err == nil {
+			err = client.CheckBucketAccess(d.BucketName)
+		}
+		checks = append(checks, doctorCheck{name: name + " read", err: err})
+		if err != nil {
+			continue
+		}
+		bucketName := common.JoinBucketPath(d.BucketName, strings.Trim(strings.TrimSpace(os.Getenv("KEY_PREFIX")), "/"))
+		key, err := uploadProbe(client, bucketName)
+		checks = append(checks, doctorCheck{name: name + " write", detail: key, err: err})
+		if err != nil {
+			continue
+		}
+		checks = append(checks, doctorCheck{name: name + " delete", detail: key, err: client.DeleteObject(bucketName, key)})
+	}
+	return checks
+}
+
+// endpointChecks checks the connectivity of every backup endpoint , the endpoints of every target when BACKUP_TARGETS is set
+func endpointChecks() []doctorCheck {
+	addresses := make(map[string]string)
+	var names []string
+	if targetsConfigured() {
+		targets, err := parseTargets(os.Getenv("BACKUP_TARGETS"))
+		if err != nil {
+			return []doctorCheck{{name: "targets", err: err}}
+		}
+		for _, t := range targets {
+			address, err := t.address()
+			if err != nil {
+				return []doctorCheck{{name: "target " + t.Name, err: err}}
+			}
+			addresses[t.Name] = address
+			names = append(names, t.Name)
+		}
+	} else {
+		address, err := generateAddress()
+		if err != nil {
+			return []doctorCheck{{name: "database connectivity", err: err}}
+		}
+		addresses[""] = address
+		names = append(names, "")
+	}
+
+	var checks []doctorCheck
+	for _, name := range names {
+		for _, endpoint := range strings.Split(addresses[name], ",") {
+			if endpoint = strings.TrimSpace(endpoint); endpoint == "" {
+				continue
+			}
+			check := doctorCheck{name: "database connectivity " + endpoint}
+			if name != "" {
+				check.name = fmt.Sprintf("database connectivity %s (target %s)", endpoint, name)
+			}
+			if !strings.Contains(endpoint, ":") {
+				check.err = fmt.Errorf("invalid endpoint %s. Expected format is <host:port>", endpoint)
+			} else {
+				check.err = neo4jAdmin.CheckDatabaseConnectivity(endpoint)
+			}
+			checks = append(checks, check)
+		}
+	}
+	return checks
+}
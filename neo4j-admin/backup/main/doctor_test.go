@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDoctor(t *testing.T) {
+	admin, storage, _ := setupPipeline(t)
+	credentialPath := filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(t, os.WriteFile(credentialPath, []byte("[default]\naws_access_key_id = AKIA\naws_secret_access_key = secret\n"), 0600))
+	t.Setenv("CREDENTIAL_PATH", credentialPath)
+	t.Setenv("DATABASE_BACKUP_ENDPOINTS", "db-0:6362,db-1:6362")
+
+	var output bytes.Buffer
+	assert.NoError(t, doctor(&output))
+	lines := strings.Split(strings.TrimSpace(output.String()), "\n")
+	assert.Equal(t, []string{
+		"[PASS] destination primary (aws:backups/prod) credentials: profile default of credential file " + credentialPath,
+		"[PASS] destination primary (aws:backups/prod) read",
+		"[PASS] destination primary (aws:backups/prod) write",
+		"[PASS] destination primary (aws:backups/prod) delete",
+		"[PASS] database connectivity db-0:6362",
+		"[PASS] database connectivity db-1:6362",
+		"[PASS] neo4j-admin version: 5.20.0",
+		"7 check(s) passed , 0 failed",
+	}, trimDetails(lines, ".write-probe-"))
+	assert.Empty(t, storage.Keys("backups"), "the probe is deleted")
+
+	admin.Unreachable = []string{"db-1:6362"}
+	t.Setenv("BACKUP_DESTINATIONS", `[{"name":"dr","cloudProvider":"gcp","bucketName":"dr","credentialPath":"`+credentialPath+`"}]`)
+	output.Reset()
+	err := doctor(&output)
+	assert.Equal(t, exitConfiguration, exitCode(err))
+	assert.ErrorContains(t, err, "2 check(s) failed")
+	assert.Contains(t, output.String(), "[FAIL] destination dr (gcp:dr) credentials: invalid gcp credential file")
+	assert.Contains(t, output.String(), "[FAIL] database connectivity db-1:6362: connectivity cannot be established")
+	assert.Contains(t, output.String(), "[PASS] database connectivity db-0:6362\n")
+
+	t.Setenv("BACKUP_DESTINATIONS", "")
+	storage.Fail("backups", fmt.Errorf("access denied"))
+	output.Reset()
+	assert.Error(t, doctor(&output))
+	assert.Contains(t, output.String(), "[FAIL] destination primary (aws:backups/prod) read: access denied")
+	assert.NotContains(t, output.String(), "write", "the checks of a destination stop at its first failure")
+}
+
+func TestDoctorTargets(t *testing.T) {
+	admin, _, _ := setupPipeline(t)
+	credentialPath := filepath.Join(t.TempDir(), "credentials")
+	assert.NoError(t, os.WriteFile(credentialPath, []byte("[default]\naws_access_key_id = AKIA\naws_secret_access_key = secret\n"), 0600))
+	t.Setenv("CREDENTIAL_PATH", credentialPath)
+	t.Setenv("DATABASE_BACKUP_PORT", "6362")
+	t.Setenv("DATABASE_CLUSTER_DOMAIN", "cluster.local")
+	t.Setenv("BACKUP_TARGETS", `[{"name":"sales","serviceName":"sales-admin","namespace":"sales"},{"name":"hr","endpoints":"hr-0:6362"}]`)
+	admin.Unreachable = []string{"hr-0:6362"}
+
+	var output bytes.Buffer
+	assert.Error(t, doctor(&output))
+	assert.Contains(t, output.String(), "[PASS] database connectivity sales-admin.sales.svc.cluster.local:6362 (target sales)\n")
+	assert.Contains(t, output.String(), "[FAIL] database connectivity hr-0:6362 (target hr)")
+	assert.NotContains(t, output.String(), "db-0:6362", "the endpoints of the job are replaced by the ones of the targets")
+}
+
+// trimDetails removes the details of the lines containing the marker , ex: the random key of the write probe
+func trimDetails(lines []string, marker string) []string {
+	var trimmed []string
+	for _, line := range lines {
+		if strings.Contains(line, marker) {
+			line, _, _ = strings.Cut(line, ": ")
+		}
+		trimmed = append(trimmed, line)
+	}
+	return trimmed
+}
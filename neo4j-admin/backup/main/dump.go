@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+// dumpMode returns true when BACKUP_MODE is dump. The databases are dumped from the data volume of a stopped server
+// instead of being backed up online , which is the only option for the Community edition
+func dumpMode() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("BACKUP_MODE")), "dump")
+}
+
+func dumpCommand(args []string) error {
+	flags := newEnvFlags("dump", "Dumps the databases of a stopped Neo4j server and uploads the dumps to every destination.")
+	flags.storageFlags()
+	flags.env("database", "DATABASE", "comma separated list of databases to dump , can contain * and ? for globbing")
+	flags.env("data-directory", "DATA_DIRECTORY", "data directory of the stopped Neo4j server , the one of the neo4j-admin config when empty")
+	flags.env("keep-backup-files", "KEEP_BACKUP_FILES", "keep the dump files at /backups after the upload (true or false)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if err := os.Setenv("BACKUP_MODE", "dump"); err != nil {
+		return err
+	}
+	return runOperations()
+}
+
+// dumpOperations dumps the databases and uploads the dump files to every destination
+// The dump files are kept at /backups when no destination is configured
+func dumpOperations(run *status.Run) error {
+	if err := neo4jAdmin.ConfigureDataDirectory(); err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+	destinations, err := prepareArtifactDestinations(run)
+	if err != nil {
+		return err
+	}
+
+	span := tracing.Start("dump", tracing.Databases.String(os.Getenv("DATABASE")))
+	dumpFileNames, err := neo4jAdmin.PerformDump()
+	span.SetAttributes(tracing.Artifacts.StringSlice(dumpFileNames))
+	span.End(err)
+	if err != nil {
+		err = withExitCode(exitBackup, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+	log.Printf("Dump File Name(s) %v", dumpFileNames)
+	run.BackupFiles = dumpFileNames
+	return uploadArtifacts(destinations, dumpFileNames, run)
+}
+
+// prepareArtifactDestinations returns the accessible destinations the artifacts of a dump (or store maintenance) are uploaded to
+// The run is finished with an error when none of the configured destinations is accessible
+func prepareArtifactDestinations(run *status.Run) ([]*destination, error) {
+	os.Setenv("LOCATION", neo4jAdmin.BackupLocation())
+	destinations, err := getDestinations()
+	if err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return nil, err
+	}
+	if len(destinations) == 0 {
+		return nil, nil
+	}
+	if destinations = prepareDestinations(destinations, run); len(destinations) == 0 {
+		err = withExitCode(exitStorage, fmt.Errorf("none of the backup destinations are accessible"))
+		run.Finish(status.Failed, err)
+		return nil, err
+	}
+	if _, err = evaluateDestinationResults(run); err != nil {
+		run.Finish(status.Failed, err)
+		return nil, withExitCode(exitStorage, err)
+	}
+	return destinations, nil
+}
+
+// uploadArtifacts uploads the artifacts to every destination , finishes the run and deletes the artifacts unless KEEP_BACKUP_FILES is true
+// The artifacts are kept at /backups when no destination is configured
+func uploadArtifacts(destinations []*destination, fileNames []string, run *status.Run) error {
+	if err := postBackupHooks(run); err != nil {
+		return err
+	}
+	if len(destinations) == 0 {
+		run.Finish(status.Success, nil)
+		return nil
+	}
+	uploadToDestinations(destinations, fileNames, run)
+	runStatus, err := evaluateDestinationResults(run)
+	run.Finish(runStatus, err)
+	if err != nil {
+		return withExitCode(exitStorage, err)
+	}
+	return deleteBackupFiles(fileNames, nil)
+}
+
+func loadCommand(args []string) error {
+	flags := newEnvFlags("load", "Downloads a dump of a database from the bucket and loads it. The database must be stopped.")
+	flags.storageFlags()
+	flags.env("data-directory", "DATA_DIRECTORY", "data directory of the stopped Neo4j server , the one of the neo4j-admin config when empty")
+	database := flags.String("database", "", "name of the database to load (required)")
+	dump := flags.String("dump", "", "file name of the dump to load (ex: neo4j-2024-06-13T10-00-00.dump or the neo4j-2024-06-13T10-00-00.copy.dump of a copy) , the latest dump of the database when empty")
+	downloadPath := flags.String("download-path", "/backups/load", "local directory the dump is downloaded to")
+	overwrite := flags.Bool("overwrite-destination", false, "replace the existing database")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if *database == "" {
+		return withExitCode(exitUsage, fmt.Errorf("missing --database"))
+	}
+	if err := neo4jAdmin.ConfigureDataDirectory(); err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+
+	client, err := primaryStorageClient()
+	if err != nil {
+		return err
+	}
+	objects, err := client.ListObjects(os.Getenv("BUCKET_NAME"))
+	if err != nil {
+		return withExitCode(exitStorage, err)
+	}
+	object, err := findDump(objects, *database, *dump)
+	if err != nil {
+		return withExitCode(exitRestore, err)
+	}
+	if err = os.MkdirAll(*downloadPath, 0755); err != nil {
+		return withExitCode(exitFailure, fmt.Errorf("unable to create download directory %s \n %v", *downloadPath, err))
+	}
+	// neo4j-admin loads <from-path>/<database>.dump
+	filePath := filepath.Join(*downloadPath, *database+".dump")
+	if err = client.DownloadFile(os.Getenv("BUCKET_NAME"), object.Key, filePath); err != nil {
+		return withExitCode(exitStorage, err)
+	}
+	log.Printf("Downloaded dump %s to %s", object.Key, filePath)
+	if err = neo4jAdmin.PerformLoad(*downloadPath, *database, *overwrite); err != nil {
+		return withExitCode(exitRestore, err)
+	}
+	return nil
+}
+
+// findDump returns the dump of the database with the given file name , the latest dump of the database when the name is empty
+// The dumps of the store maintenance operations are only returned by name , they are never the latest dump of the database
+func findDump(objects []common.ObjectInfo, database string, fileName string) (common.ObjectInfo, error) {
+	var found common.ObjectInfo
+	var foundTime string
+	for _, object := range objects {
+		name := path.Base(object.Key)
+		artifactType := common.ArtifactType(name)
+		if fileName == "" && artifactType != common.ArtifactTypeDump {
+			continue
+		}
+		if fileName != "" && (name != fileName || (artifactType != common.ArtifactTypeDump && artifactType != common.ArtifactTypeMaintenanceDump)) {
+			continue
+		}
+		dumpDatabase, timeStamp, err := common.ParseArtifactName(name)
+		if err != nil {
+			continue
+		}
+		if value := object.Metadata["database"]; value != "" {
+			dumpDatabase = value
+		}
+		// the timestamps sort in chronological order
+		if dumpDatabase == database && timeStamp > foundTime {
+			found, foundTime = object, timeStamp
+		}
+	}
+	if foundTime == "" {
+		if fileName != "" {
+			return found, fmt.Errorf("dump %s of database %s not found in %s", fileName, database, os.Getenv("BUCKET_NAME"))
+		}
+		return found, fmt.Errorf("no dump found for database %s in %s", database, os.Getenv("BUCKET_NAME"))
+	}
+	return found, nil
+}
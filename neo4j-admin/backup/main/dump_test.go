@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupDump(t *testing.T) {
+	t.Setenv("BACKUP_MODE", "dump")
+	t.Setenv("DATA_DIRECTORY", t.TempDir())
+	t.Setenv("NEO4J_ADMIN_DATA_CONF_PATH", filepath.Join(t.TempDir(), "neo4j-admin-data.conf"))
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "")
+}
+
+func TestPipelineDumpAndLoad(t *testing.T) {
+	admin, storage, location := setupPipeline(t)
+	setupDump(t)
+
+	assert.NoError(t, runOperations())
+	dumps := filterKeys(storage.Keys("backups"), ".dump")
+	if assert.Len(t, dumps, 2) {
+		assert.True(t, strings.HasPrefix(dumps[0], "prod/neo4j/neo4j-"), dumps[0])
+		assert.True(t, strings.HasPrefix(dumps[1], "prod/orders/orders-"), dumps[1])
+	}
+	run := readStatus(t, location)
+	assert.Equal(t, status.Success, run.Status)
+	assert.Len(t, run.BackupFiles, 2)
+	for _, command := range admin.Commands() {
+		if command[0] == "neo4j-admin" {
+			assert.Equal(t, "dump", command[2])
+			assert.Contains(t, command, "--additional-config="+os.Getenv("NEO4J_ADMIN_DATA_CONF_PATH"), "the data directory of the stopped server is dumped")
+		}
+	}
+	objects, err := storage.ListObjects("backups/prod/orders")
+	assert.NoError(t, err)
+	assert.Equal(t, common.ArtifactTypeDump, objects[0].Metadata["artifact-type"])
+	assert.Equal(t, "orders", objects[0].Metadata["database"])
+
+	downloadPath := t.TempDir()
+	assert.NoError(t, loadCommand([]string{"--database", "orders", "--download-path", downloadPath}))
+	assert.Equal(t, filepath.Join(downloadPath, "orders.dump"), admin.Loaded("orders"))
+	assert.Equal(t, exitRestore, exitCode(loadCommand([]string{"--database", "orders", "--download-path", downloadPath})), "the database exists")
+	assert.NoError(t, loadCommand([]string{"--database", "orders", "--download-path", downloadPath, "--overwrite-destination", "--dump", path.Base(dumps[1])}))
+	assert.Equal(t, exitRestore, exitCode(loadCommand([]string{"--database", "missing", "--download-path", downloadPath})))
+}
+
+func TestPipelineDumpOfRunningDatabase(t *testing.T) {
+	admin, storage, location := setupPipeline(t)
+	setupDump(t)
+	admin.InUse = []string{"orders"}
+
+	err := runOperations()
+	assert.Equal(t, exitBackup, exitCode(err))
+	assert.Contains(t, err.Error(), "is in use")
+	assert.Empty(t, storage.Keys("backups"))
+	assert.Equal(t, status.Failed, readStatus(t, location).Status)
+
+	t.Setenv("DATA_DIRECTORY", filepath.Join(t.TempDir(), "missing"))
+	assert.Equal(t, exitConfiguration, exitCode(runOperations()), "the data volume is not mounted")
+}
+
+func TestFindDump(t *testing.T) {
+	t.Parallel()
+
+	objects := []common.ObjectInfo{
+		{Key: "prod/neo4j/neo4j-2024-06-13T10-00-00.dump"},
+		{Key: "prod/neo4j/neo4j-2024-06-14T10-00-00.dump"},
+		{Key: "prod/neo4j/neo4j-2024-06-15T10-00-00.backup"},
+		{Key: "prod/my-db/my-db-2024-06-16T10-00-00.dump", Metadata: map[string]string{"database": "my-db"}},
+	}
+	tests := []struct {
+		name     string
+		database string
+		fileName string
+		wantKey  string
+	}{
+		{name: "latest dump", database: "neo4j", wantKey: "prod/neo4j/neo4j-2024-06-14T10-00-00.dump"},
+		{name: "given dump", database: "neo4j", fileName: "neo4j-2024-06-13T10-00-00.dump", wantKey: "prod/neo4j/neo4j-2024-06-13T10-00-00.dump"},
+		{name: "database name containing a dash", database: "my-db", wantKey: "prod/my-db/my-db-2024-06-16T10-00-00.dump"},
+		{name: "no dump", database: "orders"},
+		{name: "dump of another database", database: "neo4j", fileName: "my-db-2024-06-16T10-00-00.dump"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			object, err := findDump(objects, tt.database, tt.fileName)
+			if tt.wantKey == "" {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantKey, object.Key)
+		})
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"os"
+)
+
+// exit codes returned by the backup binary for every class of failure
+const (
+	exitSuccess          = 0
+	exitFailure          = 1
+	exitUsage            = 2
+	exitConfiguration    = 3
+	exitConnectivity     = 4
+	exitBackup           = 5
+	exitConsistencyCheck = 6
+	exitStorage          = 7
+	exitRestore          = 8
+	exitCapacity         = 9
+	exitInconsistencies  = 10
+	exitImport           = 11
+	exitMaintenance      = 12
+	exitSnapshot         = 13
+	exitHook             = 14
+)
+
+// exitError attaches an exit code to an error
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// withExitCode attaches the given exit code to the error
+// The exit code of an error which is already classified is preserved
+func withExitCode(code int, err error) error {
+	if err == nil {
+		return nil
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return err
+	}
+	return &exitError{code: code, err: err}
+}
+
+// exitCode returns the exit code attached to the error
+func exitCode(err error) int {
+	if err == nil {
+		return exitSuccess
+	}
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
+	}
+	return exitFailure
+}
+
+// handleError logs the error and exits with the exit code attached to the error
+func handleError(err error) {
+	if err != nil {
+		log.Print(err.Error())
+		os.Exit(exitCode(err))
+	}
+}
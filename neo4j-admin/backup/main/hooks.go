@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/hooks"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+// newHookRunner returns the runner of the hooks of BACKUP_HOOKS , nil when no hook is configured
+var newHookRunner = hooks.NewRunnerFromEnv
+
+// runHooks runs the hooks of the event with the input describing the run
+// The hooks run in the run of every target when the job backs up multiple Neo4j deployments , not in the run of the job
+func runHooks(event string, run *status.Run) error {
+	if targetsConfigured() {
+		return nil
+	}
+	runner, err := newHookRunner()
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+	if runner == nil {
+		return nil
+	}
+	input := hooks.Input{
+		Event:     event,
+		Artifacts: append(append([]string{}, run.BackupFiles...), run.ConsistencyCheckReports...),
+		Status:    run.Status,
+		Error:     run.Error,
+	}
+	for _, database := range strings.Split(os.Getenv("DATABASE"), ",") {
+		if database = strings.TrimSpace(database); database != "" {
+			input.Databases = append(input.Databases, database)
+		}
+	}
+	if data, jsonErr := run.JSON(); jsonErr == nil {
+		input.Run = data
+	}
+	span := tracing.Start("hooks", tracing.HookEvent.String(event))
+	err = runner.Run(context.Background(), input)
+	span.End(err)
+	return withExitCode(exitHook, err)
+}
+
+// postBackupHooks runs the postBackup hooks once the artifacts are created , the run fails when one of them fails
+func postBackupHooks(run *status.Run) error {
+	if err := runHooks(hooks.PostBackup, run); err != nil {
+		run.Finish(status.Failed, err)
+		return err
+	}
+	return nil
+}
+
+// uploaded returns true when the artifacts of the run are uploaded to at least one destination
+func uploaded(run *status.Run) bool {
+	for _, d := range run.Destinations {
+		if d.Status == status.Success {
+			return true
+		}
+	}
+	return false
+}
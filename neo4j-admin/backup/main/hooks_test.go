@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// recordingHook returns a hook of the event appending the event , the status and the artifacts of the run to the log file
+// The hook exits with the given code
+func recordingHook(event string, logFile string, exitCode int, failurePolicy string) string {
+	script := fmt.Sprintf(`echo "$BACKUP_HOOK_EVENT $BACKUP_HOOK_STATUS $BACKUP_HOOK_DATABASES $BACKUP_HOOK_ARTIFACTS" >> %s; exit %d`, logFile, exitCode)
+	return fmt.Sprintf(`{"name":"%s","event":"%s","command":["sh","-c",%q],"failurePolicy":"%s"}`, event, event, script, failurePolicy)
+}
+
+func readHookLog(t *testing.T, logFile string) []string {
+	data, err := os.ReadFile(logFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	assert.NoError(t, err)
+	var lines []string
+	for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+		lines = append(lines, strings.TrimSpace(line))
+	}
+	return lines
+}
+
+func TestPipelineHooks(t *testing.T) {
+	_, storage, location := setupPipeline(t)
+	logFile := filepath.Join(t.TempDir(), "hooks.log")
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	t.Setenv("BACKUP_HOOKS", "["+strings.Join([]string{
+		recordingHook("preBackup", logFile, 0, ""),
+		recordingHook("postBackup", logFile, 0, ""),
+		recordingHook("postUpload", logFile, 1, "ignore"),
+		recordingHook("onFailure", logFile, 0, ""),
+	}, ",")+"]")
+
+	assert.NoError(t, runOperations())
+	assert.Equal(t, status.Success, readStatus(t, location).Status, "the failure of the postUpload hook is ignored")
+	assert.Equal(t, []string{
+		"preBackup  neo4j,orders",
+		"postBackup  neo4j,orders neo4j-2024-06-13T10-01-00.backup,orders-2024-06-13T10-02-00.backup",
+		"postUpload success neo4j,orders neo4j-2024-06-13T10-01-00.backup,orders-2024-06-13T10-02-00.backup",
+	}, readHookLog(t, logFile))
+	assert.Len(t, filterKeys(storage.Keys("backups"), ".backup"), 2)
+}
+
+func TestPipelineHookTracing(t *testing.T) {
+	setupPipeline(t)
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+	t.Setenv("BACKUP_HOOKS", `[{"name":"pause-etl","event":"preBackup","url":"`+server.URL+`"}]`)
+
+	assert.NoError(t, runOperations())
+	// the first hooks span is the one of the preBackup event , the spans of the other events follow
+	spans := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		if _, present := spans[span.Name()]; !present {
+			spans[span.Name()] = span
+		}
+	}
+	run, hooksSpan, hook := spans["backup run"], spans["hooks"], spans["hook"]
+	if assert.NotNil(t, run) && assert.NotNil(t, hooksSpan) && assert.NotNil(t, hook) {
+		assert.Contains(t, hooksSpan.Attributes(), tracing.HookEvent.String("preBackup"))
+		assert.Equal(t, run.SpanContext().SpanID(), hooksSpan.Parent().SpanID())
+		assert.Equal(t, hooksSpan.SpanContext().SpanID(), hook.Parent().SpanID())
+		assert.Equal(t, fmt.Sprintf("00-%s-%s-01", run.SpanContext().TraceID(), hook.SpanContext().SpanID()), traceparent,
+			"the webhook joins the trace of the run under the span of the hook")
+	}
+}
+
+func TestPipelineHookFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		failingEvent string
+		wantCode     int
+		wantLog      []string
+		wantUploaded int
+	}{
+		{name: "preBackup", failingEvent: "preBackup", wantCode: exitHook, wantLog: []string{"preBackup", "onFailure"}},
+		{name: "postBackup", failingEvent: "postBackup", wantCode: exitHook, wantLog: []string{"preBackup", "postBackup", "onFailure"}},
+		{name: "postUpload", failingEvent: "postUpload", wantCode: exitHook, wantLog: []string{"preBackup", "postBackup", "postUpload", "onFailure"}, wantUploaded: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin, storage, location := setupPipeline(t)
+			logFile := filepath.Join(t.TempDir(), "hooks.log")
+			t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+			var hookList []string
+			for _, event := range []string{"preBackup", "postBackup", "postUpload", "onFailure"} {
+				exitCode := 0
+				if event == tt.failingEvent {
+					exitCode = 3
+				}
+				hookList = append(hookList, recordingHook(event, logFile, exitCode, ""))
+			}
+			t.Setenv("BACKUP_HOOKS", "["+strings.Join(hookList, ",")+"]")
+
+			err := runOperations()
+			assert.Equal(t, tt.wantCode, exitCode(err))
+			assert.ErrorContains(t, err, tt.failingEvent+" hook "+tt.failingEvent+" failed")
+			run := readStatus(t, location)
+			assert.Equal(t, status.Failed, run.Status)
+			var events []string
+			for _, line := range readHookLog(t, logFile) {
+				events = append(events, strings.Fields(line)[0])
+			}
+			assert.Equal(t, tt.wantLog, events)
+			assert.Len(t, filterKeys(storage.Keys("backups"), ".backup"), tt.wantUploaded)
+			if tt.failingEvent == "preBackup" {
+				assert.Empty(t, admin.Commands(), "the backup is not taken")
+			}
+			if lines := readHookLog(t, logFile); len(lines) > 0 {
+				assert.True(t, strings.HasPrefix(lines[len(lines)-1], "onFailure failed"), "the onFailure hook gets the status of the run")
+			}
+		})
+	}
+
+	_, _, _ = setupPipeline(t)
+	t.Setenv("BACKUP_HOOKS", `[{"event":"preBackup"}]`)
+	assert.Equal(t, exitConfiguration, exitCode(runOperations()))
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// importSource is a group of CSV files present in the bucket imported as nodes or relationships
+type importSource struct {
+	// Labels contains the labels of the nodes ex: Person:Employee , Type the type of the relationships ex: KNOWS
+	// Both are optional when the CSV files contain a :LABEL or :TYPE column
+	Labels string `json:"labels"`
+	Type   string `json:"type"`
+	// Files contains the object keys relative to BUCKET_NAME , the file containing the header first
+	// A key ending with / stands for every object below the prefix and a key containing * or ? for the matching objects
+	Files []string `json:"files"`
+}
+
+// importMode returns true when BACKUP_MODE is import. The CSV files present in the bucket are imported into DATABASE
+// using neo4j-admin database import instead of taking a backup
+func importMode() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("BACKUP_MODE")), "import")
+}
+
+func importCommand(args []string) error {
+	flags := newEnvFlags("import", "Downloads CSV files from the bucket and imports them into a database using neo4j-admin database import.")
+	flags.storageFlags()
+	flags.env("database", "DATABASE", "name of the database to import into")
+	flags.env("type", "IMPORT_TYPE", "import type (full or incremental)")
+	flags.env("nodes", "IMPORT_NODES", `json list of node files ex: [{"labels":"Person","files":["people/header.csv","people/data/"]}]`)
+	flags.env("relationships", "IMPORT_RELATIONSHIPS", `json list of relationship files ex: [{"type":"KNOWS","files":["knows.csv"]}]`)
+	flags.env("overwrite-destination", "IMPORT_OVERWRITE_DESTINATION", "replace the existing database on a full import (true or false)")
+	flags.env("additional-flags", "IMPORT_ADDITIONAL_FLAGS", "space separated neo4j-admin import flags ex: --delimiter=; --skip-bad-relationships=true")
+	flags.env("data-directory", "DATA_DIRECTORY", "data directory of the Neo4j server , the one of the neo4j-admin config when empty")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if err := os.Setenv("BACKUP_MODE", "import"); err != nil {
+		return err
+	}
+	return runOperations()
+}
+
+// importOperations downloads the CSV files declared by IMPORT_NODES and IMPORT_RELATIONSHIPS and imports them into DATABASE
+// The summary of the import is recorded in the run status
+func importOperations(run *status.Run) error {
+	options, sources, err := getImportOptions()
+	if err == nil {
+		err = neo4jAdmin.ConfigureDataDirectory()
+	}
+	if err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+	client, err := primaryStorageClient()
+	if err != nil {
+		run.Finish(status.Failed, err)
+		return err
+	}
+	bucketName := os.Getenv("BUCKET_NAME")
+	objects, err := client.ListObjects(bucketName)
+	if err != nil {
+		err = withExitCode(exitStorage, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+
+	downloadPath := filepath.Join(neo4jAdmin.BackupLocation(), "import")
+	defer os.RemoveAll(downloadPath)
+	span := tracing.Start("download", tracing.Bucket.String(bucketName))
+	var keys []string
+	options.Nodes, options.Relationships, keys, err = downloadImportSources(client, bucketName, objects, sources, downloadPath)
+	span.SetAttributes(tracing.Artifacts.StringSlice(keys))
+	span.End(err)
+	if err != nil {
+		run.Finish(status.Failed, err)
+		return err
+	}
+
+	span = tracing.Start("import", tracing.Database.String(options.Database), tracing.BackupType.String(options.Type))
+	summary, err := neo4jAdmin.PerformImport(options)
+	summary.Files = keys
+	span.SetAttributes(attributesOf(summary)...)
+	span.End(err)
+	run.Imports = append(run.Imports, *summary)
+	if data, marshalErr := json.MarshalIndent(summary, "", "  "); marshalErr == nil {
+		log.Printf("Import summary \n %s", string(data))
+	}
+	if err != nil {
+		err = withExitCode(exitImport, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+	run.Finish(status.Success, nil)
+	return nil
+}
+
+// getImportOptions returns the import described by the env variables along with the node and relationship sources
+func getImportOptions() (neo4jAdmin.ImportOptions, map[string][]importSource, error) {
+	options := neo4jAdmin.ImportOptions{
+		Database: strings.TrimSpace(os.Getenv("DATABASE")),
+		Type:     strings.ToLower(strings.TrimSpace(os.Getenv("IMPORT_TYPE"))),
+	}
+	if options.Type == "" {
+		options.Type = common.ImportTypeFull
+	}
+	if options.Type != common.ImportTypeFull && options.Type != common.ImportTypeIncremental {
+		return options, nil, fmt.Errorf("invalid IMPORT_TYPE %s. Supported values are full and incremental", options.Type)
+	}
+	if options.Database == "" || strings.ContainsAny(options.Database, ",*?") {
+		return options, nil, fmt.Errorf("DATABASE must contain the name of the single database to import into , found '%s'", options.Database)
+	}
+	if value := strings.TrimSpace(os.Getenv("IMPORT_OVERWRITE_DESTINATION")); value != "" {
+		overwrite, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, nil, fmt.Errorf("invalid IMPORT_OVERWRITE_DESTINATION %s \n %v", value, err)
+		}
+		options.OverwriteDestination = overwrite
+	}
+	options.AdditionalFlags = strings.Fields(os.Getenv("IMPORT_ADDITIONAL_FLAGS"))
+
+	sources := make(map[string][]importSource)
+	for _, kind := range []string{"nodes", "relationships"} {
+		env := "IMPORT_" + strings.ToUpper(kind)
+		value := strings.TrimSpace(os.Getenv(env))
+		if value == "" {
+			continue
+		}
+		var parsed []importSource
+		if err := json.Unmarshal([]byte(value), &parsed); err != nil {
+			return options, nil, fmt.Errorf("unable to parse %s \n %v", env, err)
+		}
+		for _, source := range parsed {
+			if len(source.Files) == 0 {
+				return options, nil, fmt.Errorf("every entry of %s must contain files", env)
+			}
+		}
+		sources[kind] = parsed
+	}
+	if len(sources["nodes"]) == 0 {
+		return options, nil, fmt.Errorf("IMPORT_NODES must contain at least one group of node files")
+	}
+	return options, sources, nil
+}
+
+// downloadImportSources downloads the CSV files of every source below the download path
+// and returns the import groups pointing to the local files along with the downloaded object keys
+func downloadImportSources(client common.StorageClient, bucketName string, objects []common.ObjectInfo, sources map[string][]importSource, downloadPath string) ([]neo4jAdmin.ImportGroup, []neo4jAdmin.ImportGroup, []string, error) {
+	_, prefix := common.SplitBucketName(bucketName)
+	var keys []string
+	groups := make(map[string][]neo4jAdmin.ImportGroup)
+	for _, kind := range []string{"nodes", "relationships"} {
+		for _, source := range sources[kind] {
+			group := neo4jAdmin.ImportGroup{Label: source.Labels}
+			if kind == "relationships" {
+				group.Label = source.Type
+			}
+			for _, file := range source.Files {
+				matched := matchImportFiles(objects, prefix, file)
+				if len(matched) == 0 {
+					return nil, nil, nil, withExitCode(exitConfiguration, fmt.Errorf("no object matching %s found in %s", file, bucketName))
+				}
+				for _, key := range matched {
+					filePath := filepath.Join(downloadPath, filepath.FromSlash(key))
+					if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+						return nil, nil, nil, withExitCode(exitFailure, fmt.Errorf("unable to create download directory %s \n %v", filepath.Dir(filePath), err))
+					}
+					if err := client.DownloadFile(bucketName, key, filePath); err != nil {
+						return nil, nil, nil, withExitCode(exitStorage, err)
+					}
+					group.FilePaths = append(group.FilePaths, filePath)
+					keys = append(keys, key)
+				}
+			}
+			groups[kind] = append(groups[kind], group)
+		}
+	}
+	log.Printf("Downloaded %d CSV file(s) to %s", len(keys), downloadPath)
+	return groups["nodes"], groups["relationships"], keys, nil
+}
+
+// matchImportFiles returns the sorted keys of the objects matching the given file relative to the prefix of the bucket
+// Ex: people/header.csv , people/data/ (every object below people/data) or people/part-*.csv
+func matchImportFiles(objects []common.ObjectInfo, prefix string, file string) []string {
+	pattern := strings.TrimPrefix(file, "/")
+	if prefix != "" {
+		pattern = strings.TrimSuffix(prefix, "/") + "/" + pattern
+	}
+	var matched []string
+	for _, object := range objects {
+		switch {
+		case strings.HasSuffix(pattern, "/"):
+			if !strings.HasPrefix(object.Key, pattern) || strings.HasSuffix(object.Key, "/") {
+				continue
+			}
+		case strings.ContainsAny(pattern, "*?["):
+			if ok, _ := path.Match(pattern, object.Key); !ok {
+				continue
+			}
+		case object.Key != pattern:
+			continue
+		}
+		matched = append(matched, object.Key)
+	}
+	sort.Strings(matched)
+	return matched
+}
+
+// attributesOf returns the span attributes describing the import
+func attributesOf(summary *common.ImportSummary) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		tracing.Status.String(summary.Status),
+		tracing.ImportNodes.Int64(summary.Nodes),
+		tracing.ImportRelationships.Int64(summary.Relationships),
+		tracing.ImportBadEntries.Int(summary.BadEntries),
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/memory"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+// uploadCSV uploads the given CSV files to backups/prod , the keys of the files are relative to the prefix
+func uploadCSV(t *testing.T, storage *memory.Storage, files map[string]string) {
+	location := os.Getenv("LOCATION")
+	directory := t.TempDir()
+	t.Setenv("LOCATION", directory)
+	defer t.Setenv("LOCATION", location)
+	for name, content := range files {
+		filePath := filepath.Join(directory, filepath.FromSlash(name))
+		assert.NoError(t, os.MkdirAll(filepath.Dir(filePath), 0755))
+		assert.NoError(t, os.WriteFile(filePath, []byte(content), 0644))
+		assert.NoError(t, storage.UploadFile([]string{name}, "backups/prod"))
+	}
+}
+
+func setupImport(t *testing.T, storage *memory.Storage) {
+	t.Setenv("BACKUP_MODE", "import")
+	t.Setenv("DATABASE", "graph")
+	t.Setenv("IMPORT_TYPE", "")
+	t.Setenv("IMPORT_OVERWRITE_DESTINATION", "")
+	t.Setenv("IMPORT_ADDITIONAL_FLAGS", "--skip-bad-relationships=true")
+	t.Setenv("IMPORT_NODES", `[{"labels":"Person","files":["csv/people/header.csv","csv/people/data/"]}]`)
+	t.Setenv("IMPORT_RELATIONSHIPS", `[{"type":"KNOWS","files":["csv/knows-*.csv"]}]`)
+	t.Setenv("DATA_DIRECTORY", t.TempDir())
+	t.Setenv("NEO4J_ADMIN_DATA_CONF_PATH", filepath.Join(t.TempDir(), "neo4j-admin-data.conf"))
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "")
+	uploadCSV(t, storage, map[string]string{
+		"csv/people/header.csv":       "personId:ID,name\n",
+		"csv/people/data/part-1.csv":  "1,Alice\n2,Bob\n",
+		"csv/people/data/part-2.csv":  "3,Carol\n",
+		"csv/knows-2024.csv":          ":START_ID,:END_ID,since\n1,2,2020\n2,4,2021\n",
+		"csv/unrelated/companies.csv": "companyId:ID,name\n1,Neo4j\n",
+	})
+}
+
+func TestPipelineImport(t *testing.T) {
+	admin, storage, location := setupPipeline(t)
+	setupImport(t, storage)
+
+	assert.NoError(t, runOperations())
+	run := readStatus(t, location)
+	assert.Equal(t, status.Success, run.Status)
+	if assert.Len(t, run.Imports, 1) {
+		summary := run.Imports[0]
+		assert.Equal(t, "graph", summary.Database)
+		assert.Equal(t, common.ImportTypeFull, summary.Type)
+		assert.Equal(t, common.ImportSucceeded, summary.Status)
+		assert.Equal(t, int64(3), summary.Nodes)
+		assert.Equal(t, int64(1), summary.Relationships)
+		assert.Equal(t, 1, summary.BadEntries, "the relationship to the missing node 4 is skipped")
+		assert.Equal(t, "1s 657ms", summary.Duration)
+		assert.Equal(t, []string{
+			"prod/csv/people/header.csv",
+			"prod/csv/people/data/part-1.csv",
+			"prod/csv/people/data/part-2.csv",
+			"prod/csv/knows-2024.csv",
+		}, summary.Files)
+	}
+	downloadPath := filepath.Join(location, "import")
+	for _, command := range admin.Commands() {
+		if command[0] == "neo4j-admin" {
+			assert.Equal(t, []string{"database", "import", "full"}, command[1:4])
+			assert.Equal(t, "--nodes=Person="+strings.Join([]string{
+				filepath.Join(downloadPath, "prod/csv/people/header.csv"),
+				filepath.Join(downloadPath, "prod/csv/people/data/part-1.csv"),
+				filepath.Join(downloadPath, "prod/csv/people/data/part-2.csv"),
+			}, ","), command[4])
+			assert.Equal(t, "--relationships=KNOWS="+filepath.Join(downloadPath, "prod/csv/knows-2024.csv"), command[5])
+			assert.Equal(t, "graph", command[len(command)-1])
+		}
+	}
+	assert.NoDirExists(t, downloadPath, "the CSV files are deleted once imported")
+
+	// the database exists now , a full import must overwrite it
+	err := runOperations()
+	assert.Equal(t, exitImport, exitCode(err))
+	run = readStatus(t, location)
+	assert.Equal(t, status.Failed, run.Status)
+	if assert.Len(t, run.Imports, 1) {
+		assert.Equal(t, common.ImportFailed, run.Imports[0].Status)
+		assert.Contains(t, run.Imports[0].Error, "already exists")
+	}
+	t.Setenv("IMPORT_OVERWRITE_DESTINATION", "true")
+	assert.NoError(t, runOperations())
+	t.Setenv("IMPORT_OVERWRITE_DESTINATION", "")
+	t.Setenv("IMPORT_TYPE", "incremental")
+	assert.NoError(t, runOperations())
+}
+
+func TestPipelineImportFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		env      map[string]string
+		wantCode int
+	}{
+		{name: "missing file", env: map[string]string{"IMPORT_NODES": `[{"labels":"Person","files":["csv/people/missing.csv"]}]`}, wantCode: exitConfiguration},
+		{name: "invalid nodes", env: map[string]string{"IMPORT_NODES": `{"labels":"Person"}`}, wantCode: exitConfiguration},
+		{name: "no nodes", env: map[string]string{"IMPORT_NODES": ""}, wantCode: exitConfiguration},
+		{name: "several databases", env: map[string]string{"DATABASE": "graph,neo4j"}, wantCode: exitConfiguration},
+		{name: "invalid type", env: map[string]string{"IMPORT_TYPE": "partial"}, wantCode: exitConfiguration},
+		{name: "bad relationships", env: map[string]string{"IMPORT_ADDITIONAL_FLAGS": ""}, wantCode: exitImport},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, storage, location := setupPipeline(t)
+			setupImport(t, storage)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			assert.Equal(t, tt.wantCode, exitCode(runOperations()))
+			assert.Equal(t, status.Failed, readStatus(t, location).Status)
+		})
+	}
+}
+
+func TestMatchImportFiles(t *testing.T) {
+	t.Parallel()
+
+	objects := []common.ObjectInfo{
+		{Key: "prod/csv/people/header.csv"},
+		{Key: "prod/csv/people/data/part-2.csv"},
+		{Key: "prod/csv/people/data/part-1.csv"},
+		{Key: "prod/csv/people/data/"},
+		{Key: "prod/csv/knows.csv"},
+	}
+	tests := []struct {
+		name   string
+		prefix string
+		file   string
+		want   []string
+	}{
+		{name: "file", prefix: "prod", file: "csv/knows.csv", want: []string{"prod/csv/knows.csv"}},
+		{name: "prefix", prefix: "prod", file: "csv/people/data/", want: []string{"prod/csv/people/data/part-1.csv", "prod/csv/people/data/part-2.csv"}},
+		{name: "glob", prefix: "prod/", file: "csv/people/*.csv", want: []string{"prod/csv/people/header.csv"}},
+		{name: "bucket without prefix", file: "prod/csv/knows.csv", want: []string{"prod/csv/knows.csv"}},
+		{name: "missing", prefix: "prod", file: "csv/movies.csv"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchImportFiles(objects, tt.prefix, tt.file))
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"log"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/kube"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"k8s.io/utils/strings/slices"
+)
+
+// newKubeReporter returns the reporter of the runs to kubernetes , nil when reporting is disabled
+var newKubeReporter = kube.NewReporterFromEnv
+
+// kubeReporter returns the reporter of the runs , reporting is disabled when the reporter cannot be created
+func kubeReporter() *kube.Reporter {
+	reporter, err := newKubeReporter()
+	if err != nil {
+		log.Printf("Warning: the backup is not reported to kubernetes: %v", err)
+		return nil
+	}
+	return reporter
+}
+
+// reportedDatabases returns the databases backed up by the run as shown in the BackupStarted event
+func reportedDatabases() string {
+	if targetsConfigured() {
+		targets, err := parseTargets(os.Getenv("BACKUP_TARGETS"))
+		if err != nil {
+			return os.Getenv("DATABASE")
+		}
+		var names []string
+		for _, t := range targets {
+			names = append(names, t.Name+"/"+databaseOrDefault(t.Database))
+		}
+		return strings.Join(names, " , ")
+	}
+	return os.Getenv("DATABASE")
+}
+
+// databaseResults returns the result of the run for every database , the databases of a target are prefixed with the target name
+func databaseResults(run *status.Run) []kube.DatabaseResult {
+	if !targetsConfigured() {
+		return runDatabaseResults("", strings.Split(os.Getenv("DATABASE"), ","), run)
+	}
+	targets, err := parseTargets(os.Getenv("BACKUP_TARGETS"))
+	if err != nil {
+		return nil
+	}
+	var results []kube.DatabaseResult
+	for _, t := range targets {
+		for _, targetStatus := range run.Targets {
+			if targetStatus.Name != t.Name {
+				continue
+			}
+			targetRun := targetStatus.Run
+			if targetRun == nil {
+				targetRun = &status.Run{Status: targetStatus.Status, Error: targetStatus.Error}
+			}
+			results = append(results, runDatabaseResults(t.Name+".", strings.Split(databaseOrDefault(t.Database), ","), targetRun)...)
+		}
+	}
+	return results
+}
+
+// runDatabaseResults returns the result of every database of a run , * is expanded to the databases of the backup artifacts
+func runDatabaseResults(prefix string, databases []string, run *status.Run) []kube.DatabaseResult {
+	artifacts := make(map[string][]string)
+	for _, fileName := range append(append([]string{}, run.BackupFiles...), run.ConsistencyCheckReports...) {
+		if database, _, err := common.ParseArtifactName(fileName); err == nil {
+			artifacts[database] = append(artifacts[database], fileName)
+		}
+	}
+	var names []string
+	for _, database := range databases {
+		if database = strings.TrimSpace(database); database != "" && database != "*" {
+			names = append(names, database)
+		}
+	}
+	if slices.Contains(databases, "*") {
+		for database := range artifacts {
+			if !slices.Contains(names, database) {
+				names = append(names, database)
+			}
+		}
+	}
+	sort.Strings(names)
+
+	var results []kube.DatabaseResult
+	for _, name := range names {
+		results = append(results, kube.DatabaseResult{
+			Name:      prefix + name,
+			Status:    run.Status,
+			Artifacts: artifacts[name],
+			Error:     run.Error,
+		})
+	}
+	return results
+}
+
+func databaseOrDefault(database string) string {
+	if database == "" {
+		return os.Getenv("DATABASE")
+	}
+	return database
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/kube"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestPipelineReportsToKubernetes(t *testing.T) {
+	admin, _, _ := setupPipeline(t)
+	controller := true
+	client := fake.NewSimpleClientset(
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "backup-28650-abcde", Namespace: "neo4j",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "Job", Name: "backup-28650", Controller: &controller}}}},
+		&batchv1.Job{ObjectMeta: metav1.ObjectMeta{Name: "backup-28650", Namespace: "neo4j",
+			OwnerReferences: []metav1.OwnerReference{{Kind: "CronJob", Name: "backup", Controller: &controller}}}},
+	)
+	original := newKubeReporter
+	newKubeReporter = func() (*kube.Reporter, error) {
+		return kube.NewReporter(client, "neo4j", "backup-28650-abcde", true, "backup-status"), nil
+	}
+	t.Cleanup(func() { newKubeReporter = original })
+
+	assert.NoError(t, runOperations())
+	admin.FailBackup = []string{"orders"}
+	assert.Error(t, runOperations())
+
+	events, err := client.CoreV1().Events("neo4j").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	var reasons []string
+	for _, event := range events.Items {
+		assert.Equal(t, "CronJob", event.InvolvedObject.Kind)
+		assert.Equal(t, "backup", event.InvolvedObject.Name)
+		reasons = append(reasons, event.Reason)
+	}
+	assert.ElementsMatch(t, []string{kube.ReasonStarted, kube.ReasonSucceeded, kube.ReasonStarted, kube.ReasonFailed}, reasons)
+
+	configMap, err := client.CoreV1().ConfigMaps("neo4j").Get(context.Background(), "backup-status", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, status.Failed, configMap.Data["lastRunStatus"])
+	assert.NotEmpty(t, configMap.Data["lastSuccessTime"])
+	assert.Equal(t, status.Failed, configMap.Data["orders.lastStatus"])
+	assert.Equal(t, configMap.Data["lastSuccessTime"], configMap.Data["neo4j.lastSuccessTime"])
+	assert.Contains(t, configMap.Data["neo4j.lastArtifacts"], "neo4j-2024-06-13T10-01-00.backup")
+}
+
+func TestDatabaseResults(t *testing.T) {
+	t.Setenv("BACKUP_TARGETS", "")
+	t.Setenv("DATABASE", "*")
+	run := &status.Run{
+		Status:      status.Success,
+		BackupFiles: []string{"system-2024-06-13T10-02-00.backup", "neo4j-2024-06-13T10-01-00.backup"},
+	}
+	assert.Equal(t, []kube.DatabaseResult{
+		{Name: "neo4j", Status: status.Success, Artifacts: []string{"neo4j-2024-06-13T10-01-00.backup"}},
+		{Name: "system", Status: status.Success, Artifacts: []string{"system-2024-06-13T10-02-00.backup"}},
+	}, databaseResults(run))
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/catalog"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// list prints the backup artifacts present in the configured bucket grouped by database into full/differential chains
+func list(args []string, w io.Writer) error {
+	flags := newEnvFlags("list", "Lists the backup artifacts present in the bucket grouped by database into full/differential chains.")
+	flags.storageFlags()
+	database := flags.String("database", "", "list only the artifacts of the given database")
+	output := flags.String("output", "table", "output format (table or json)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if *output != "table" && *output != "json" {
+		return withExitCode(exitUsage, fmt.Errorf("invalid output format %s. Supported values are table and json", *output))
+	}
+
+	_, backupCatalog, err := loadCatalog()
+	if err != nil {
+		return err
+	}
+	if *database != "" {
+		filtered := &catalog.Catalog{}
+		if entry, present := backupCatalog.FindDatabase(*database); present {
+			filtered.Databases = append(filtered.Databases, entry)
+		}
+		backupCatalog = filtered
+	}
+
+	if *output == "json" {
+		return backupCatalog.WriteJSON(w)
+	}
+	return backupCatalog.WriteTable(w, time.Now().UTC())
+}
+
+// primaryStorageClient returns the storage client of the primary destination (CLOUD_PROVIDER , CREDENTIAL_PATH)
+func primaryStorageClient() (common.StorageClient, error) {
+	client, err := newStorageClient(os.Getenv("CLOUD_PROVIDER"), os.Getenv("CREDENTIAL_PATH"), os.Getenv("AWS_ASSUME_ROLES"))
+	if err != nil {
+		return nil, withExitCode(exitConfiguration, err)
+	}
+	return client, nil
+}
+
+// loadCatalog lists the objects present in BUCKET_NAME and builds the backup catalog
+func loadCatalog() (common.StorageClient, *catalog.Catalog, error) {
+	client, err := primaryStorageClient()
+	if err != nil {
+		return nil, nil, err
+	}
+	objects, err := client.ListObjects(os.Getenv("BUCKET_NAME"))
+	if err != nil {
+		return nil, nil, withExitCode(exitStorage, err)
+	}
+	return client, catalog.Build(objects), nil
+}
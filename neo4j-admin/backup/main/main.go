@@ -3,30 +3,13 @@ package main
 import (
 	"log"
 	"os"
+
+	"github.com/neo4j/helm-charts/logging"
 )
 
 func main() {
-
-	if aggregateEnabled := os.Getenv("AGGREGATE_BACKUP_ENABLED"); aggregateEnabled != "true" {
-		startupOperations()
+	if err := logging.Setup("neo4j-backup"); err != nil {
+		log.Printf("Warning: the logs are not structured: %v", err)
 	}
-
-	cloudProvider := os.Getenv("CLOUD_PROVIDER")
-	switch cloudProvider {
-	case "aws":
-		awsOperations()
-		break
-	case "azure":
-		azureOperations()
-		break
-	case "gcp":
-		gcpOperations()
-		break
-	case "":
-		onPrem()
-		break
-	default:
-		log.Fatalf("Incorrect cloud provider %s", cloudProvider)
-	}
-
+	os.Exit(runCLI(os.Args[1:], os.Stderr))
 }
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+// copyFilterEnvs maps the copy filters to the env variables containing their comma separated values
+var copyFilterEnvs = []struct {
+	name string
+	env  string
+}{
+	{"copyOnlyNodesWithLabels", "COPY_NODES_WITH_LABELS"},
+	{"ignoreNodesWithLabels", "COPY_IGNORE_NODES_WITH_LABELS"},
+	{"copyOnlyRelationshipsWithTypes", "COPY_RELATIONSHIPS_WITH_TYPES"},
+	{"ignoreRelationshipsWithTypes", "COPY_IGNORE_RELATIONSHIPS_WITH_TYPES"},
+	{"skipLabels", "COPY_SKIP_LABELS"},
+	{"skipProperties", "COPY_SKIP_PROPERTIES"},
+}
+
+// maintenanceOperation returns copy or migrate when BACKUP_MODE is one of them , empty otherwise
+// The latest backup of DATABASE is restored , copied (or migrated) and dumped instead of taking a backup
+func maintenanceOperation() string {
+	mode := strings.ToLower(strings.TrimSpace(os.Getenv("BACKUP_MODE")))
+	if mode == common.MaintenanceCopy || mode == common.MaintenanceMigrate {
+		return mode
+	}
+	return ""
+}
+
+func copyCommand(args []string) error {
+	flags := newEnvFlags("copy", "Restores the latest backup of a database , copies it applying the filters (which compacts the store) and uploads the dump of the copy along with a report.")
+	maintenanceFlags(flags)
+	flags.env("nodes-with-labels", "COPY_NODES_WITH_LABELS", "comma separated labels of the nodes to copy , all nodes when empty")
+	flags.env("ignore-nodes-with-labels", "COPY_IGNORE_NODES_WITH_LABELS", "comma separated labels of the nodes not to copy")
+	flags.env("relationships-with-types", "COPY_RELATIONSHIPS_WITH_TYPES", "comma separated types of the relationships to copy , all relationships when empty")
+	flags.env("ignore-relationships-with-types", "COPY_IGNORE_RELATIONSHIPS_WITH_TYPES", "comma separated types of the relationships not to copy")
+	flags.env("skip-labels", "COPY_SKIP_LABELS", "comma separated labels removed from the copied nodes")
+	flags.env("skip-properties", "COPY_SKIP_PROPERTIES", "comma separated properties not to copy ex: Person.password")
+	flags.env("compact-node-store", "COPY_COMPACT_NODE_STORE", "compact the node store (true or false)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if err := os.Setenv("BACKUP_MODE", common.MaintenanceCopy); err != nil {
+		return err
+	}
+	return runOperations()
+}
+
+func migrateCommand(args []string) error {
+	flags := newEnvFlags("migrate", "Restores the latest backup of a database , migrates its store to another format (or version) and uploads the dump of the migrated database along with a report.")
+	maintenanceFlags(flags)
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if err := os.Setenv("BACKUP_MODE", common.MaintenanceMigrate); err != nil {
+		return err
+	}
+	return runOperations()
+}
+
+// maintenanceFlags registers the flags shared by the copy and migrate commands
+func maintenanceFlags(flags *envFlags) {
+	flags.storageFlags()
+	flags.env("database", "DATABASE", "name of the database whose latest backup is restored")
+	flags.env("to-format", "STORE_FORMAT", "store format of the result (aligned, standard, high_limit or block) , the latest version of the current format when empty")
+	flags.env("additional-flags", "MAINTENANCE_ADDITIONAL_FLAGS", "space separated flags passed as is to neo4j-admin")
+	flags.env("data-directory", "DATA_DIRECTORY", "data directory the backup is restored into , a directory below /backups when empty")
+	flags.env("keep-backup-files", "KEEP_BACKUP_FILES", "keep the dump and the report at /backups after the upload (true or false)")
+}
+
+// maintenanceOperations restores the latest backup chain of DATABASE into a scratch data directory , copies (or migrates) it
+// and uploads the dump of the result along with <dump>.maintenance.json describing the operation to every destination
+func maintenanceOperations(run *status.Run, operation string) error {
+	report := &common.MaintenanceReport{
+		Operation: operation,
+		Database:  strings.TrimSpace(os.Getenv("DATABASE")),
+		Status:    status.Failed,
+		ToFormat:  strings.TrimSpace(os.Getenv("STORE_FORMAT")),
+	}
+	run.Maintenance = report
+	fail := func(code int, err error) error {
+		err = withExitCode(code, err)
+		report.Error = err.Error()
+		run.Finish(status.Failed, err)
+		return err
+	}
+	if report.Database == "" || strings.ContainsAny(report.Database, ",*?") {
+		return fail(exitConfiguration, fmt.Errorf("DATABASE must contain the name of the single database to %s , found '%s'", operation, report.Database))
+	}
+	options, err := getCopyOptions(report)
+	if err != nil {
+		return fail(exitConfiguration, err)
+	}
+	scratchPath := filepath.Join(neo4jAdmin.BackupLocation(), ".maintenance")
+	defer os.RemoveAll(scratchPath)
+	reset, err := configureScratchDataDirectory(scratchPath)
+	defer reset()
+	if err != nil {
+		return fail(exitConfiguration, err)
+	}
+	destinations, err := prepareArtifactDestinations(run)
+	if err != nil {
+		report.Error = err.Error()
+		return err
+	}
+
+	span := tracing.Start("restore", tracing.Database.String(report.Database))
+	filePaths, err := downloadLatestChain(report.Database, filepath.Join(scratchPath, "download"))
+	if err == nil {
+		for _, filePath := range filePaths {
+			report.SourceArtifacts = append(report.SourceArtifacts, filepath.Base(filePath))
+			report.SourceBytes += fileSize(filePath)
+		}
+		err = withExitCode(exitRestore, neo4jAdmin.PerformRestore(filePaths, report.Database, true, ""))
+	}
+	span.End(err)
+	if err != nil {
+		return fail(exitRestore, err)
+	}
+
+	// the copy is written to a new database of the scratch data directory , the migration is done in place
+	result := report.Database
+	span = tracing.Start(operation, tracing.Database.String(report.Database))
+	start := time.Now()
+	var output []byte
+	if operation == common.MaintenanceCopy {
+		result = report.Database + "-copy"
+		output, err = neo4jAdmin.PerformCopy(report.Database, result, options)
+	} else {
+		output, err = neo4jAdmin.PerformMigrate(report.Database, report.ToFormat, options.AdditionalFlags)
+	}
+	report.Duration = time.Since(start).Round(time.Millisecond).String()
+	report.Output = lastOutputLines(string(output), 20)
+	span.End(err)
+	if err != nil {
+		return fail(exitMaintenance, err)
+	}
+
+	// the dump is named <database>-<timestamp>.<operation>.dump so that it is never picked as the latest dump of the database ,
+	// the filtered or migrated store is only loaded when its name is given to the load command
+	report.Artifact = fmt.Sprintf("%s-%s.%s.dump", report.Database, time.Now().UTC().Format("2006-01-02T15-04-05"), operation)
+	if err = neo4jAdmin.DumpDatabase(result, report.Artifact); err != nil {
+		return fail(exitBackup, err)
+	}
+	report.ArtifactBytes = fileSize(filepath.Join(neo4jAdmin.BackupLocation(), report.Artifact))
+	report.Status = status.Success
+	reportName := report.Artifact + ".maintenance.json"
+	if err = writeMaintenanceReport(report, filepath.Join(neo4jAdmin.BackupLocation(), reportName)); err != nil {
+		return fail(exitFailure, err)
+	}
+	log.Printf("%s of database %s completed !! %d bytes restored from %v , %d bytes dumped to %s", operation, report.Database,
+		report.SourceBytes, report.SourceArtifacts, report.ArtifactBytes, report.Artifact)
+	run.BackupFiles = []string{report.Artifact, reportName}
+	return uploadArtifacts(destinations, run.BackupFiles, run)
+}
+
+// getCopyOptions returns the copy filters and store format of the env variables and records them in the report
+func getCopyOptions(report *common.MaintenanceReport) (neo4jAdmin.CopyOptions, error) {
+	options := neo4jAdmin.CopyOptions{
+		ToFormat:        report.ToFormat,
+		AdditionalFlags: strings.Fields(os.Getenv("MAINTENANCE_ADDITIONAL_FLAGS")),
+	}
+	if report.Operation != common.MaintenanceCopy {
+		return options, nil
+	}
+	values := make(map[string][]string)
+	for _, filter := range copyFilterEnvs {
+		for _, value := range strings.Split(os.Getenv(filter.env), ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values[filter.name] = append(values[filter.name], value)
+			}
+		}
+	}
+	options.CopyOnlyNodesWithLabels = values["copyOnlyNodesWithLabels"]
+	options.IgnoreNodesWithLabels = values["ignoreNodesWithLabels"]
+	options.CopyOnlyRelationshipsWithTypes = values["copyOnlyRelationshipsWithTypes"]
+	options.IgnoreRelationshipsWithTypes = values["ignoreRelationshipsWithTypes"]
+	options.SkipLabels = values["skipLabels"]
+	options.SkipProperties = values["skipProperties"]
+	if len(values) > 0 {
+		report.Filters = values
+	}
+	if value := strings.TrimSpace(os.Getenv("COPY_COMPACT_NODE_STORE")); value != "" {
+		compact, err := strconv.ParseBool(value)
+		if err != nil {
+			return options, fmt.Errorf("invalid COPY_COMPACT_NODE_STORE %s \n %v", value, err)
+		}
+		options.CompactNodeStore = compact
+	}
+	return options, nil
+}
+
+// configureScratchDataDirectory points neo4j-admin to DATA_DIRECTORY , to a data directory below the scratch path when empty
+// The returned function resets DATA_DIRECTORY once the scratch path is deleted
+func configureScratchDataDirectory(scratchPath string) (func(), error) {
+	reset := func() {}
+	if strings.TrimSpace(os.Getenv("DATA_DIRECTORY")) == "" {
+		dataDirectory := filepath.Join(scratchPath, "data")
+		if err := os.MkdirAll(dataDirectory, 0755); err != nil {
+			return reset, fmt.Errorf("unable to create data directory %s \n %v", dataDirectory, err)
+		}
+		os.Setenv("DATA_DIRECTORY", dataDirectory)
+		reset = func() { os.Setenv("DATA_DIRECTORY", "") }
+	}
+	return reset, neo4jAdmin.ConfigureDataDirectory()
+}
+
+func writeMaintenanceReport(report *common.MaintenanceReport, filePath string) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return fmt.Errorf("unable to marshal maintenance report \n %v", err)
+	}
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("unable to write maintenance report to %s \n %v", filePath, err)
+	}
+	return nil
+}
+
+func fileSize(filePath string) int64 {
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
+
+// lastOutputLines returns the last n lines of the output of a command
+func lastOutputLines(output string, n int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/memory"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin/fake"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupMaintenance takes a backup of every database of the pipeline and switches to the given maintenance operation
+func setupMaintenance(t *testing.T, operation string) (*fake.Neo4jAdmin, *memory.Storage, string) {
+	admin, storage, location := setupPipeline(t)
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	assert.NoError(t, runOperations())
+	t.Setenv("BACKUP_MODE", operation)
+	t.Setenv("DATA_DIRECTORY", "")
+	t.Setenv("NEO4J_ADMIN_DATA_CONF_PATH", filepath.Join(t.TempDir(), "neo4j-admin-data.conf"))
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "")
+	t.Setenv("STORE_FORMAT", "")
+	t.Setenv("MAINTENANCE_ADDITIONAL_FLAGS", "")
+	for _, filter := range copyFilterEnvs {
+		t.Setenv(filter.env, "")
+	}
+	t.Setenv("COPY_COMPACT_NODE_STORE", "")
+	return admin, storage, location
+}
+
+func TestPipelineCopy(t *testing.T) {
+	admin, storage, location := setupMaintenance(t, common.MaintenanceCopy)
+	t.Setenv("DATABASE", "orders")
+	t.Setenv("COPY_IGNORE_NODES_WITH_LABELS", "Temp, Staging")
+	t.Setenv("COPY_COMPACT_NODE_STORE", "true")
+	t.Setenv("STORE_FORMAT", "block")
+
+	assert.NoError(t, runOperations())
+	assert.Equal(t, "block", admin.Store("orders-copy"))
+	for _, command := range admin.Commands() {
+		if len(command) > 2 && command[2] == "copy" {
+			assert.Equal(t, []string{"neo4j-admin", "database", "copy", "--ignore-nodes-with-labels=Temp,Staging", "--compact-node-store=true", "--to-format=block"}, command[:6])
+			assert.Equal(t, []string{"orders", "orders-copy"}, command[len(command)-2:])
+			assert.Contains(t, command, "--additional-config="+os.Getenv("NEO4J_ADMIN_DATA_CONF_PATH"), "the backup is restored into the scratch data directory")
+		}
+	}
+
+	run := readStatus(t, location)
+	assert.Equal(t, status.Success, run.Status)
+	if assert.NotNil(t, run.Maintenance) {
+		assert.Equal(t, status.Success, run.Maintenance.Status)
+		assert.Equal(t, []string{"orders-2024-06-13T10-02-00.backup"}, run.Maintenance.SourceArtifacts)
+		assert.Equal(t, map[string][]string{"ignoreNodesWithLabels": {"Temp", "Staging"}}, run.Maintenance.Filters)
+		assert.Contains(t, run.Maintenance.Output, "completed")
+	}
+	dumps := filterKeys(storage.Keys("backups"), ".copy.dump")
+	reports := filterKeys(storage.Keys("backups"), ".maintenance.json")
+	if assert.Len(t, dumps, 1) && assert.Len(t, reports, 1) {
+		assert.Equal(t, dumps[0]+".maintenance.json", reports[0])
+		content, _ := storage.Content("backups", reports[0])
+		report := &common.MaintenanceReport{}
+		assert.NoError(t, json.Unmarshal(content, report))
+		assert.Equal(t, common.MaintenanceCopy, report.Operation)
+		assert.Equal(t, filepath.Base(dumps[0]), report.Artifact)
+		assert.Positive(t, report.ArtifactBytes)
+	}
+	assert.NoDirExists(t, filepath.Join(location, ".maintenance"), "the scratch data directory is deleted")
+
+	// the compacted copy is never the latest dump of the database , it is loaded by name only
+	downloadPath := t.TempDir()
+	err := loadCommand([]string{"--database", "orders", "--download-path", downloadPath})
+	assert.Equal(t, exitRestore, exitCode(err))
+	assert.ErrorContains(t, err, "no dump found for database orders")
+	assert.Empty(t, admin.Loaded("orders"))
+	if assert.Len(t, dumps, 1) {
+		assert.NoError(t, loadCommand([]string{"--database", "orders", "--dump", filepath.Base(dumps[0]), "--download-path", downloadPath}))
+		assert.Equal(t, filepath.Join(downloadPath, "orders.dump"), admin.Loaded("orders"))
+	}
+}
+
+func TestPipelineMigrate(t *testing.T) {
+	admin, storage, location := setupMaintenance(t, common.MaintenanceMigrate)
+	t.Setenv("DATABASE", "neo4j")
+	t.Setenv("STORE_FORMAT", "block")
+
+	assert.NoError(t, runOperations())
+	assert.Equal(t, "block", admin.Store("neo4j"))
+	run := readStatus(t, location)
+	assert.Equal(t, status.Success, run.Status)
+	assert.Equal(t, "block", run.Maintenance.ToFormat)
+	assert.Len(t, filterKeys(storage.Keys("backups"), ".migrate.dump"), 1)
+}
+
+func TestPipelineCopyUnknownArtifact(t *testing.T) {
+	admin, storage, location := setupMaintenance(t, common.MaintenanceCopy)
+	t.Setenv("DATABASE", "neo4j")
+	uploadTypedArtifacts(t, storage, location, "neo4j", map[string]string{"neo4j-2024-06-13T11-00-00.backup": ""})
+
+	err := runOperations()
+	assert.Equal(t, exitRestore, exitCode(err))
+	assert.ErrorContains(t, err, "whose backup type is unknown")
+	assert.Empty(t, admin.Restored("neo4j"))
+	assert.Empty(t, filterKeys(storage.Keys("backups"), ".dump"))
+}
+
+func TestPipelineMaintenanceFailures(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		env       map[string]string
+		wantCode  int
+	}{
+		{name: "several databases", operation: common.MaintenanceCopy, env: map[string]string{"DATABASE": "*"}, wantCode: exitConfiguration},
+		{name: "invalid compact node store", operation: common.MaintenanceCopy, env: map[string]string{"COPY_COMPACT_NODE_STORE": "yes please"}, wantCode: exitConfiguration},
+		{name: "no backup", operation: common.MaintenanceCopy, env: map[string]string{"DATABASE": "missing"}, wantCode: exitRestore},
+		{name: "invalid format", operation: common.MaintenanceMigrate, env: map[string]string{"STORE_FORMAT": "fast"}, wantCode: exitMaintenance},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, storage, location := setupMaintenance(t, tt.operation)
+			t.Setenv("DATABASE", "neo4j")
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			assert.Equal(t, tt.wantCode, exitCode(runOperations()))
+			run := readStatus(t, location)
+			assert.Equal(t, status.Failed, run.Status)
+			if assert.NotNil(t, run.Maintenance) {
+				assert.NotEmpty(t, run.Maintenance.Error)
+			}
+			assert.Empty(t, filterKeys(storage.Keys("backups"), ".dump"))
+		})
+	}
+}
@@ -7,110 +7,254 @@ import (
 	"strings"
 
 	"github.com/neo4j/helm-charts/neo4j-admin/backup/aws"
-	"github.com/neo4j/helm-charts/neo4j-admin/backup/azure"
-	gcp "github.com/neo4j/helm-charts/neo4j-admin/backup/gcp"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/hooks"
 	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+	"go.opentelemetry.io/otel/attribute"
 	"k8s.io/utils/strings/slices"
 )
 
-func awsOperations() {
-
-	credentialPath := os.Getenv("CREDENTIAL_PATH")
-	awsClient, err := aws.NewAwsClient(credentialPath)
-	handleError(err)
-
-	if aggregateEnabled := os.Getenv("AGGREGATE_BACKUP_ENABLED"); aggregateEnabled == "true" {
+// runOperations checks the database connectivity , performs the backup and uploads the backup files and consistency check reports
+// to every destination. The backup files are kept at /backups when no destination is configured
+func runOperations() error {
+	return runOperationsWithStatus(status.NewRun())
+}
 
-		//service account is NOT used hence env variables need to be set for aggregate backup operation
-		if credentialPath != "/credentials/" {
-			err = awsClient.GenerateEnvVariablesFromCredentials()
-			handleError(err)
+// runOperationsWithStatus performs the backup recording the result in the given run , the hooks of BACKUP_HOOKS are run around it
+// Every target is backed up in turn when BACKUP_TARGETS is set , BACKUP_MODE selects a dump , an import , a store maintenance (copy or migrate) or volume snapshots instead
+// The run status is written to STATUS_FILE (if set) and reported to kubernetes (if enabled) once the run is finished
+// The run is traced as a single span , the parent of the spans of its phases
+func runOperationsWithStatus(run *status.Run) error {
+	span := tracing.Start("backup run", tracing.Databases.String(reportedDatabases()))
+	reporter := kubeReporter()
+	reporter.Started(reportedDatabases())
+	err := runHooks(hooks.PreBackup, run)
+	switch {
+	case err != nil:
+		run.Finish(status.Failed, err)
+	case dumpMode():
+		err = dumpOperations(run)
+	case importMode():
+		err = importOperations(run)
+	case maintenanceOperation() != "":
+		err = maintenanceOperations(run, maintenanceOperation())
+	case snapshotMode():
+		err = snapshotOperations(run)
+	case targetsConfigured():
+		err = targetOperations(run)
+	default:
+		err = performOperations(run)
+	}
+	if run.Status != status.Failed && uploaded(run) {
+		if hookErr := runHooks(hooks.PostUpload, run); hookErr != nil {
+			run.Finish(status.Failed, hookErr)
+			if err == nil {
+				err = hookErr
+			}
 		}
-
-		err = aggregateBackupOperations()
-		handleError(err)
-		return
 	}
-
-	bucketName := os.Getenv("BUCKET_NAME")
-	err = awsClient.CheckBucketAccess(bucketName)
-	handleError(err)
-
-	backupFileNames, consistencyCheckReports, err := backupOperations()
-	handleError(err)
-
-	err = awsClient.UploadFile(backupFileNames, bucketName)
-	handleError(err)
-
-	enableConsistencyCheck := os.Getenv("CONSISTENCY_CHECK_ENABLE")
-	if enableConsistencyCheck == "true" {
-		err = awsClient.UploadFile(consistencyCheckReports, bucketName)
-		handleError(err)
+	if run.Status == status.Failed {
+		if hookErr := runHooks(hooks.OnFailure, run); hookErr != nil {
+			log.Printf("Warning: %v", hookErr)
+		}
 	}
-	err = deleteBackupFiles(backupFileNames, consistencyCheckReports)
-	handleError(err)
+	if writeErr := run.Write(os.Getenv("STATUS_FILE")); writeErr != nil {
+		log.Printf("Warning: %v", writeErr)
+	}
+	reporter.Finished(run, databaseResults(run))
+	span.SetAttributes(tracing.Status.String(run.Status), tracing.ExitCode.Int(exitCode(err)), tracing.Artifacts.StringSlice(run.BackupFiles))
+	span.End(err)
+	return err
 }
 
-func gcpOperations() {
-	gcpClient, err := gcp.NewGCPClient(os.Getenv("CREDENTIAL_PATH"))
-	handleError(err)
-
-	bucketName := os.Getenv("BUCKET_NAME")
-	err = gcpClient.CheckBucketAccess(bucketName)
-	handleError(err)
+func performOperations(run *status.Run) error {
+	span := tracing.Start("connectivity")
+	err := startupOperations()
+	span.End(err)
+	if err != nil {
+		run.Finish(status.Failed, err)
+		return err
+	}
+	destinations, err := getDestinations()
+	if err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+	if len(destinations) == 0 {
+		return onPrem(run)
+	}
+	return cloudOperations(destinations, run)
+}
 
-	backupFileNames, consistencyCheckReports, err := backupOperations()
-	handleError(err)
+// aggregateOperations performs the aggregate backup. Additional destinations are not used for aggregate backup
+func aggregateOperations() error {
+	destinations, err := getDestinations()
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+	restore, err := exportAwsCredentials(destinations)
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+	defer restore()
+	return aggregateBackupOperations()
+}
 
-	err = gcpClient.UploadFile(backupFileNames, bucketName)
-	handleError(err)
+// awsCredentialEnvs are the env variables set by GenerateEnvVariablesFromCredentials
+var awsCredentialEnvs = []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN", "AWS_REGION"}
+
+// exportAwsCredentials sets the credentials of the primary aws destination as env variables for neo4j-admin
+// which reads the s3:// paths of the aggregate backup with the default credential chain
+// The returned func restores the previous env variables so that the credentials do not leak into the aws clients
+// and the hooks of the later runs of the process (ex: the daemon)
+func exportAwsCredentials(destinations []*destination) (func(), error) {
+	restore := func() {}
+	if len(destinations) == 0 || destinations[0].Name != "primary" || destinations[0].CloudProvider != "aws" {
+		return restore, nil
+	}
+	primary := destinations[0]
+	awsClient, err := aws.NewAwsClient(primary.CredentialPath, string(primary.AssumeRoles))
+	if err != nil {
+		return restore, err
+	}
+	//service account is NOT used or a role is assumed on top of it hence env variables need to be set for aggregate backup operation
+	if primary.CredentialPath != "/credentials/" || len(primary.AssumeRoles) != 0 {
+		restore = saveEnv(awsCredentialEnvs)
+		if err = awsClient.GenerateEnvVariablesFromCredentials(); err != nil {
+			restore()
+			return func() {}, err
+		}
+	}
+	return restore, nil
+}
 
-	enableConsistencyCheck := os.Getenv("CONSISTENCY_CHECK_ENABLE")
-	if enableConsistencyCheck == "true" {
-		err = gcpClient.UploadFile(consistencyCheckReports, bucketName)
-		handleError(err)
+// saveEnv returns a func restoring the given env variables to their current value , unsetting the ones which are not set
+func saveEnv(names []string) func() {
+	values := make(map[string]*string)
+	for _, name := range names {
+		if value, present := os.LookupEnv(name); present {
+			values[name] = &value
+		} else {
+			values[name] = nil
+		}
+	}
+	return func() {
+		for name, value := range values {
+			if value == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *value)
+			}
+		}
 	}
-	err = deleteBackupFiles(backupFileNames, consistencyCheckReports)
-	handleError(err)
 }
 
-func azureOperations() {
-	azureClient, err := azure.NewAzureClient(os.Getenv("CREDENTIAL_PATH"))
-	handleError(err)
+// cloudOperations performs the backup and uploads the backup files and consistency check reports to every destination
+func cloudOperations(destinations []*destination, run *status.Run) error {
+	destinations = prepareDestinations(destinations, run)
+	if len(destinations) == 0 {
+		err := withExitCode(exitStorage, fmt.Errorf("none of the backup destinations are accessible"))
+		run.Finish(status.Failed, err)
+		return err
+	}
+	if _, err := evaluateDestinationResults(run); err != nil {
+		run.Finish(status.Failed, err)
+		return withExitCode(exitStorage, err)
+	}
 
-	containerName := os.Getenv("BUCKET_NAME")
-	err = azureClient.CheckContainerAccess(containerName)
-	handleError(err)
+	policy, err := getFullBackupPolicy()
+	if err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
 
-	backupFileNames, consistencyCheckReports, err := backupOperations()
-	handleError(err)
+	var states chainStates
+	backupFileNames, consistencyCheckReports, resumed := resumeArtifacts()
+	if resumed {
+		if err := neo4jAdmin.InspectBackups(backupFileNames); err != nil {
+			log.Printf("Warning: unable to determine backup type of the backup artifacts , falling back to %s: %v", os.Getenv("TYPE"), err)
+		}
+	} else {
+		if err = checkCapacity(destinations); err != nil {
+			run.Finish(status.Failed, err)
+			return err
+		}
+		states, backupFileNames, consistencyCheckReports, err = policy.backup(destinations)
+		if err != nil {
+			run.Finish(status.Failed, err)
+			return err
+		}
+		recordArtifactSizes(backupFileNames, consistencyCheckReports)
+		if err = recordArtifacts(backupFileNames, consistencyCheckReports); err != nil {
+			log.Printf("Warning: the upload of the backup artifacts cannot be resumed by a retried run: %v", err)
+		}
+	}
+	run.BackupFiles = backupFileNames
+	run.ConsistencyCheckReports = consistencyCheckReports
+	if err = postBackupHooks(run); err != nil {
+		return err
+	}
 
-	err = azureClient.UploadFile(backupFileNames, containerName)
-	handleError(err)
+	fileNames := backupFileNames
+	if enableConsistencyCheck := os.Getenv("CONSISTENCY_CHECK_ENABLE"); enableConsistencyCheck == "true" {
+		fileNames = append(fileNames, consistencyCheckReports...)
+	}
+	uploadToDestinations(destinations, fileNames, run)
 
-	enableConsistencyCheck := os.Getenv("CONSISTENCY_CHECK_ENABLE")
-	if enableConsistencyCheck == "true" {
-		err = azureClient.UploadFile(consistencyCheckReports, containerName)
-		handleError(err)
+	runStatus, err := evaluateDestinationResults(run)
+	if err != nil {
+		run.Finish(runStatus, err)
+		return withExitCode(exitStorage, err)
+	}
+	checkErr := evaluateConsistencyChecks(consistencyCheckReports, run)
+	if checkErr == nil {
+		policy.aggregate(states, destinations, run)
+	} else {
+		runStatus = status.Failed
+	}
+	run.Finish(runStatus, checkErr)
+	if err = common.ClearUploadState(); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	if err = deleteBackupFiles(backupFileNames, consistencyCheckReports); err != nil {
+		return err
 	}
-	err = deleteBackupFiles(backupFileNames, consistencyCheckReports)
-	handleError(err)
+	return checkErr
 }
 
-func onPrem() {
-
-	if aggregateEnabled := os.Getenv("AGGREGATE_BACKUP_ENABLED"); aggregateEnabled == "true" {
-		err := aggregateBackupOperations()
-		handleError(err)
-		return
+func onPrem(run *status.Run) error {
+	policy, err := getFullBackupPolicy()
+	if err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return err
 	}
-
-	backupFileNames, consistencyCheckReports, err := backupOperations()
-	handleError(err)
-
-	err = deleteBackupFiles(backupFileNames, consistencyCheckReports)
-	handleError(err)
-
+	if err = checkCapacity(nil); err != nil {
+		run.Finish(status.Failed, err)
+		return err
+	}
+	states, backupFileNames, consistencyCheckReports, err := policy.backup(nil)
+	if err != nil {
+		run.Finish(status.Failed, err)
+		return err
+	}
+	recordArtifactSizes(backupFileNames, consistencyCheckReports)
+	run.BackupFiles = backupFileNames
+	run.ConsistencyCheckReports = consistencyCheckReports
+	if err = postBackupHooks(run); err != nil {
+		return err
+	}
+	if err = evaluateConsistencyChecks(consistencyCheckReports, run); err != nil {
+		run.Finish(status.Failed, err)
+		return err
+	}
+	policy.aggregate(states, nil, run)
+	run.Finish(status.Success, nil)
+	return deleteBackupFiles(backupFileNames, consistencyCheckReports)
 }
 
 // backupOperations returns backupFileNames , consistencyCheckReports and error
@@ -122,77 +266,153 @@ func backupOperations() ([]string, []string, error) {
 
 	address, err := generateAddress()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, withExitCode(exitConfiguration, err)
 	}
 	databases := strings.Split(os.Getenv("DATABASE"), ",")
 	consistencyCheckDBs := strings.Split(os.Getenv("CONSISTENCY_CHECK_DATABASE"), ",")
 	consistencyCheckEnabled := os.Getenv("CONSISTENCY_CHECK_ENABLE")
 
 	var consistencyCheckReports []string
-	backupFileNames, err := neo4jAdmin.PerformBackup(address)
+	backupFileNames, err := backup(address)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, withExitCode(exitBackup, err)
 	}
-	log.Printf("Backup File Name(s) %v", backupFileNames)
 
 	if consistencyCheckEnabled == "true" {
 		for _, consistencyCheckDB := range consistencyCheckDBs {
 			if slices.Contains(databases, consistencyCheckDB) || slices.Contains(databases, "*") {
-				reportArchiveName, err := neo4jAdmin.PerformConsistencyCheck(consistencyCheckDB)
+				report, err := consistencyCheckBackup(consistencyCheckDB)
 				if err != nil {
-					return nil, nil, err
-				}
-				if len(reportArchiveName) != 0 {
-					consistencyCheckReports = append(consistencyCheckReports, reportArchiveName)
+					return nil, nil, withExitCode(exitConsistencyCheck, err)
 				}
+				consistencyCheckReports = append(consistencyCheckReports, report...)
 			}
 		}
 	}
 	return backupFileNames, consistencyCheckReports, nil
 }
 
+// backup takes the backup of the databases and inspects the type of the artifacts
+// Every artifact is recorded as an event of the backup span along with its size
+func backup(address string) ([]string, error) {
+	span := tracing.Start("backup", tracing.Databases.String(os.Getenv("DATABASE")), tracing.BackupType.String(os.Getenv("TYPE")))
+	backupFileNames, err := neo4jAdmin.PerformBackup(address)
+	if err != nil {
+		span.End(err)
+		return nil, err
+	}
+	log.Printf("Backup File Name(s) %v", backupFileNames)
+
+	if err = neo4jAdmin.InspectBackups(backupFileNames); err != nil {
+		log.Printf("Warning: unable to determine backup type of the backup artifacts , falling back to %s: %v", os.Getenv("TYPE"), err)
+	}
+	var totalBytes int64
+	for _, backupFileName := range backupFileNames {
+		attributes := []attribute.KeyValue{tracing.Artifact.String(backupFileName), tracing.BackupType.String(common.GetBackupType(backupFileName))}
+		if database, _, err := common.ParseArtifactName(backupFileName); err == nil {
+			attributes = append(attributes, tracing.Database.String(database))
+		}
+		if info, err := os.Stat(fmt.Sprintf("%s/%s", neo4jAdmin.BackupLocation(), backupFileName)); err == nil {
+			attributes = append(attributes, tracing.Bytes.Int64(info.Size()))
+			totalBytes += info.Size()
+		}
+		span.AddEvent("artifact created", attributes...)
+	}
+	span.SetAttributes(tracing.Artifacts.StringSlice(backupFileNames), tracing.Bytes.Int64(totalBytes))
+	span.End(nil)
+	return backupFileNames, nil
+}
+
+// consistencyCheckBackup runs the consistency check of the database and returns its report and summary , if any
+func consistencyCheckBackup(database string) ([]string, error) {
+	span := tracing.Start("consistency check", tracing.Database.String(database))
+	summary, err := neo4jAdmin.PerformConsistencyCheck(database)
+	span.SetAttributes(tracing.Status.String(summary.Status), tracing.CheckErrors.Int(summary.Errors))
+	span.End(err)
+	if err != nil {
+		return nil, err
+	}
+	if len(summary.Report) == 0 {
+		return nil, nil
+	}
+	return []string{summary.Report, neo4jAdmin.ConsistencyCheckSummaryName(summary.Report)}, nil
+}
+
+// evaluateConsistencyChecks records the summaries of the consistency check reports in the run
+// and returns an error when inconsistencies were found unless CONSISTENCY_CHECK_FAIL_ON_INCONSISTENCIES is set to false
+func evaluateConsistencyChecks(consistencyCheckReports []string, run *status.Run) error {
+	var inconsistent []string
+	for _, report := range consistencyCheckReports {
+		if common.ArtifactType(report) != common.ArtifactTypeConsistencyCheckSummary {
+			continue
+		}
+		summary, err := common.ReadConsistencyCheckSummary(fmt.Sprintf("%s/%s", neo4jAdmin.BackupLocation(), report))
+		if err != nil {
+			log.Printf("Warning: %v", err)
+			continue
+		}
+		run.ConsistencyChecks = append(run.ConsistencyChecks, *summary)
+		if summary.Status == common.ConsistencyCheckInconsistent {
+			inconsistent = append(inconsistent, fmt.Sprintf("%s (%d error(s) , %d warning(s))", summary.Database, summary.Errors, summary.Warnings))
+		}
+	}
+	if len(inconsistent) == 0 || os.Getenv("CONSISTENCY_CHECK_FAIL_ON_INCONSISTENCIES") == "false" {
+		return nil
+	}
+	return withExitCode(exitInconsistencies, fmt.Errorf("inconsistencies found for database(s) %s. The reports and their summaries are uploaded next to the backups", strings.Join(inconsistent, " , ")))
+}
+
 // aggregateBackupOperations perform aggregate backup
 func aggregateBackupOperations() error {
 	err := neo4jAdmin.PerformAggregateBackup()
 	if err != nil {
-		return err
+		return withExitCode(exitBackup, err)
 	}
 	return nil
 }
 
-func startupOperations() {
+func startupOperations() error {
 	address, err := generateAddress()
-	handleError(err)
-
-	err = neo4jAdmin.CheckDatabaseConnectivity(address)
-	handleError(err)
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
 
-	os.Setenv("LOCATION", "/backups")
-}
+	if err = neo4jAdmin.CheckDatabaseConnectivity(address); err != nil {
+		return withExitCode(exitConnectivity, err)
+	}
 
-func handleError(err error) {
-	if err != nil {
-		log.Fatal(err.Error())
+	if sslEnabled := os.Getenv("BACKUP_SSL_ENABLED"); sslEnabled == "true" {
+		if err = neo4jAdmin.ConfigureBackupSSL(); err != nil {
+			return withExitCode(exitConfiguration, err)
+		}
 	}
+
+	os.Setenv("LOCATION", neo4jAdmin.BackupLocation())
+	return nil
 }
 
 // generateAddress returns the backup address in the format <hostip:port> or <standalone-admin.default.svc.cluster.local:port>
 func generateAddress() (string, error) {
-	if endpoints := os.Getenv("DATABASE_BACKUP_ENDPOINTS"); len(endpoints) > 0 {
+	return addressFromEnv(os.Getenv)
+}
+
+// addressFromEnv returns the backup address described by the env variables read with getenv
+func addressFromEnv(getenv func(string) string) (string, error) {
+	if endpoints := getenv("DATABASE_BACKUP_ENDPOINTS"); len(endpoints) > 0 {
 		return endpoints, nil
 	}
 
 	// Legacy support for single endpoint
-	if ip := os.Getenv("DATABASE_SERVICE_IP"); len(ip) > 0 {
-		return fmt.Sprintf("%s:%s", ip, os.Getenv("DATABASE_BACKUP_PORT")), nil
+	if ip := getenv("DATABASE_SERVICE_IP"); len(ip) > 0 {
+		return fmt.Sprintf("%s:%s", ip, getenv("DATABASE_BACKUP_PORT")), nil
 	}
 
-	if serviceName := os.Getenv("DATABASE_SERVICE_NAME"); len(serviceName) > 0 {
+	if serviceName := getenv("DATABASE_SERVICE_NAME"); len(serviceName) > 0 {
 		return fmt.Sprintf("%s.%s.svc.%s:%s",
 			serviceName,
-			os.Getenv("DATABASE_NAMESPACE"),
-			os.Getenv("DATABASE_CLUSTER_DOMAIN"),
-			os.Getenv("DATABASE_BACKUP_PORT")), nil
+			getenv("DATABASE_NAMESPACE"),
+			getenv("DATABASE_CLUSTER_DOMAIN"),
+			getenv("DATABASE_BACKUP_PORT")), nil
 	}
 
 	return "", fmt.Errorf("no valid backup endpoints specified")
@@ -200,16 +420,17 @@ func generateAddress() (string, error) {
 
 func deleteBackupFiles(backupFileNames, consistencyCheckReports []string) error {
 	if value, present := os.LookupEnv("KEEP_BACKUP_FILES"); present && value == "false" {
+		location := neo4jAdmin.BackupLocation()
 		for _, backupFileName := range backupFileNames {
-			log.Printf("Deleting file /backups/%s", backupFileName)
-			err := os.Remove(fmt.Sprintf("/backups/%s", backupFileName))
+			log.Printf("Deleting file %s/%s", location, backupFileName)
+			err := os.Remove(fmt.Sprintf("%s/%s", location, backupFileName))
 			if err != nil {
 				return err
 			}
 		}
 		for _, consistencyCheckReportName := range consistencyCheckReports {
-			log.Printf("Deleting file /backups/%s", consistencyCheckReportName)
-			err := os.Remove(fmt.Sprintf("/backups/%s", consistencyCheckReportName))
+			log.Printf("Deleting file %s/%s", location, consistencyCheckReportName)
+			err := os.Remove(fmt.Sprintf("%s/%s", location, consistencyCheckReportName))
 			if err != nil {
 				return err
 			}
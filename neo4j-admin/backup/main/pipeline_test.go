@@ -0,0 +1,302 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/memory"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin/fake"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// setupPipeline runs the backup pipeline against a fake neo4j-admin and an in-memory bucket named backups
+func setupPipeline(t *testing.T) (*fake.Neo4jAdmin, *memory.Storage, string) {
+	location := t.TempDir()
+	env := map[string]string{
+		"LOCATION":                   location,
+		"STATUS_FILE":                filepath.Join(location, ".status.json"),
+		"DATABASE_BACKUP_ENDPOINTS":  "db-0:6362",
+		"DATABASE":                   "neo4j,orders",
+		"TYPE":                       "AUTO",
+		"CLOUD_PROVIDER":             "aws",
+		"BUCKET_NAME":                "backups/prod",
+		"CREDENTIAL_PATH":            "/credentials/credentials",
+		"KEY_LAYOUT":                 "{database}",
+		"KEEP_BACKUP_FILES":          "false",
+		"CONSISTENCY_CHECK_ENABLE":   "true",
+		"CONSISTENCY_CHECK_DATABASE": "neo4j,orders",
+		"BACKUP_DESTINATIONS":        "",
+		"BACKUP_TARGETS":             "",
+		"BACKUP_SSL_ENABLED":         "false",
+		"UPLOAD_STATE_FILE":          "",
+		"KEY_PREFIX":                 "",
+		"CONSISTENCY_CHECK_FAIL_ON_INCONSISTENCIES": "",
+		"BACKUP_HOOKS": "",
+	}
+	for name, value := range env {
+		t.Setenv(name, value)
+	}
+
+	clock := time.Date(2024, 6, 13, 10, 0, 0, 0, time.UTC)
+	admin := &fake.Neo4jAdmin{
+		Databases: []string{"neo4j", "orders", "system"},
+		Now: func() time.Time {
+			clock = clock.Add(time.Minute)
+			return clock
+		},
+	}
+	previous := neo4jAdmin.SetCommandRunner(admin)
+	t.Cleanup(func() { neo4jAdmin.SetCommandRunner(previous) })
+
+	storage := memory.NewStorage("backups")
+	original := newStorageClient
+	newStorageClient = func(cloudProvider string, credentialPath string, assumeRoles string) (common.StorageClient, error) {
+		return storage, nil
+	}
+	t.Cleanup(func() { newStorageClient = original })
+	return admin, storage, location
+}
+
+func readStatus(t *testing.T, location string) *status.Run {
+	run := &status.Run{}
+	data, err := os.ReadFile(filepath.Join(location, ".status.json"))
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, run))
+	return run
+}
+
+func TestPipelineBackupCheckUploadRestore(t *testing.T) {
+	admin, storage, location := setupPipeline(t)
+	admin.Inconsistent = []string{"orders"}
+
+	// the first run takes full backups which are kept locally , the second one differential backups which are deleted once uploaded
+	// the inconsistencies fail the first run once the artifacts are uploaded
+	t.Setenv("KEEP_BACKUP_FILES", "true")
+	assert.Equal(t, exitInconsistencies, exitCode(runOperations()))
+	t.Setenv("KEEP_BACKUP_FILES", "false")
+	t.Setenv("CONSISTENCY_CHECK_FAIL_ON_INCONSISTENCIES", "false")
+	assert.NoError(t, runOperations())
+
+	keys := storage.Keys("backups")
+	assert.Equal(t, []string{
+		"prod/neo4j/neo4j-2024-06-13T10-01-00.backup",
+		"prod/neo4j/neo4j-2024-06-13T10-03-00.backup",
+		"prod/orders/orders-2024-06-13T10-02-00.backup",
+		"prod/orders/orders-2024-06-13T10-04-00.backup",
+	}, filterKeys(keys, ".backup"))
+	for _, key := range filterKeys(keys, ".report.tar.gz") {
+		assert.True(t, strings.HasPrefix(key, "prod/orders/orders-"), key)
+	}
+	assert.NotEmpty(t, filterKeys(keys, ".report.tar.gz"), "the inconsistency report of orders is uploaded")
+	assert.Len(t, filterKeys(keys, ".report.json"), len(filterKeys(keys, ".report.tar.gz")), "every report is uploaded along with its summary")
+
+	run := readStatus(t, location)
+	assert.Equal(t, status.Success, run.Status)
+	assert.Len(t, run.BackupFiles, 2)
+	assert.Len(t, run.ConsistencyCheckReports, 2)
+	if assert.Len(t, run.ConsistencyChecks, 1) {
+		assert.Equal(t, "orders", run.ConsistencyChecks[0].Database)
+		assert.Equal(t, common.ConsistencyCheckInconsistent, run.ConsistencyChecks[0].Status)
+		assert.Equal(t, 2, run.ConsistencyChecks[0].Errors)
+	}
+	if assert.Len(t, run.Destinations, 1) {
+		assert.Equal(t, status.Success, run.Destinations[0].Status)
+		assert.Len(t, run.Destinations[0].Transfers, 4)
+	}
+	objects, err := storage.ListObjects("backups/prod/neo4j")
+	assert.NoError(t, err)
+	assert.Equal(t, "FULL", objects[0].Metadata["backup-type"])
+	assert.Equal(t, "DIFF", objects[1].Metadata["backup-type"])
+
+	assert.FileExists(t, filepath.Join(location, "neo4j-2024-06-13T10-01-00.backup"))
+	_, err = os.Stat(filepath.Join(location, "neo4j-2024-06-13T10-03-00.backup"))
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+
+	// the restore downloads the full backup followed by the differential backup
+	downloadPath := filepath.Join(t.TempDir(), "restore")
+	assert.NoError(t, restoreCommand([]string{"--database", "neo4j", "--download-path", downloadPath}))
+	assert.Equal(t, []string{
+		filepath.Join(downloadPath, "neo4j-2024-06-13T10-01-00.backup"),
+		filepath.Join(downloadPath, "neo4j-2024-06-13T10-03-00.backup"),
+	}, admin.Restored("neo4j"))
+}
+
+// uploadTypedArtifacts uploads backup artifacts of the given types to the bucket of the database , an empty type uploads the artifact
+// without a FULL or DIFF backup type as done before the metadata existed
+func uploadTypedArtifacts(t *testing.T, storage *memory.Storage, location string, database string, backupTypes map[string]string) {
+	var fileNames []string
+	for fileName, backupType := range backupTypes {
+		assert.NoError(t, os.WriteFile(filepath.Join(location, fileName), []byte(fileName), 0644))
+		if backupType != "" {
+			common.SetBackupType(fileName, backupType)
+		}
+		fileNames = append(fileNames, fileName)
+	}
+	assert.NoError(t, storage.UploadFile(fileNames, "backups/prod/"+database))
+	// the transfers are not part of a run
+	common.TakeTransfers()
+}
+
+func TestPipelineUnknownArtifacts(t *testing.T) {
+	admin, storage, location := setupPipeline(t)
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	// full backup of neo4j at 10:01 followed by an artifact of unknown type and a differential backup
+	assert.NoError(t, runOperations())
+	uploadTypedArtifacts(t, storage, location, "neo4j", map[string]string{
+		"neo4j-2024-06-13T11-00-00.backup": "",
+		"neo4j-2024-06-13T12-00-00.backup": "DIFF",
+	})
+	keys := storage.Keys("backups")
+
+	downloadPath := filepath.Join(t.TempDir(), "restore")
+	err := restoreCommand([]string{"--database", "neo4j", "--download-path", downloadPath})
+	assert.Equal(t, exitRestore, exitCode(err))
+	assert.ErrorContains(t, err, "contains prod/neo4j/neo4j-2024-06-13T11-00-00.backup whose backup type is unknown")
+	assert.Empty(t, admin.Restored("neo4j"))
+	assert.Equal(t, exitRestore, exitCode(verifyCommand([]string{"--database", "neo4j", "--download-path", downloadPath})))
+
+	// the unknown artifact does not start a chain , hence the full backup the differential backup depends on is kept
+	assert.NoError(t, pruneCommand([]string{"--keep-chains", "1"}))
+	assert.Equal(t, keys, storage.Keys("backups"))
+
+	// the artifacts uploaded without the backup type metadata before the full backup are only pruned on request
+	uploadTypedArtifacts(t, storage, location, "neo4j", map[string]string{
+		"neo4j-2024-06-12T10-00-00.backup": "",
+	})
+	keys = storage.Keys("backups")
+	assert.NoError(t, pruneCommand([]string{"--max-age-days", "1"}))
+	assert.Equal(t, keys, storage.Keys("backups"))
+	assert.NoError(t, pruneCommand([]string{"--max-age-days", "1", "--prune-unknown"}))
+	assert.NotContains(t, storage.Keys("backups"), "prod/neo4j/neo4j-2024-06-12T10-00-00.backup")
+	assert.Len(t, storage.Keys("backups"), len(keys)-1)
+
+	// a lone differential backup is never restored as a full backup
+	uploadTypedArtifacts(t, storage, location, "legacy", map[string]string{
+		"legacy-2024-06-12T10-00-00.backup": "",
+		"legacy-2024-06-12T11-00-00.backup": "DIFF",
+	})
+	err = restoreCommand([]string{"--database", "legacy", "--download-path", downloadPath})
+	assert.Equal(t, exitRestore, exitCode(err))
+	assert.ErrorContains(t, err, "starting with prod/legacy/legacy-2024-06-12T10-00-00.backup does not start with a full backup")
+	assert.Empty(t, admin.Restored("legacy"))
+}
+
+func TestPipelineFailures(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(admin *fake.Neo4jAdmin, storage *memory.Storage)
+		wantCode int
+	}{
+		{
+			name:     "database not reachable",
+			setup:    func(admin *fake.Neo4jAdmin, storage *memory.Storage) { admin.Unreachable = []string{"db-0:6362"} },
+			wantCode: exitConnectivity,
+		},
+		{
+			name:     "backup failed",
+			setup:    func(admin *fake.Neo4jAdmin, storage *memory.Storage) { admin.FailBackup = []string{"orders"} },
+			wantCode: exitBackup,
+		},
+		{
+			name:     "consistency check crashed",
+			setup:    func(admin *fake.Neo4jAdmin, storage *memory.Storage) { admin.CrashCheck = []string{"orders"} },
+			wantCode: exitConsistencyCheck,
+		},
+		{
+			name: "bucket not accessible",
+			setup: func(admin *fake.Neo4jAdmin, storage *memory.Storage) {
+				storage.Fail("backups", errors.New("access denied"))
+			},
+			wantCode: exitStorage,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			admin, storage, location := setupPipeline(t)
+			tt.setup(admin, storage)
+
+			err := runOperations()
+			assert.Error(t, err)
+			assert.Equal(t, tt.wantCode, exitCode(err))
+			run := readStatus(t, location)
+			assert.Equal(t, status.Failed, run.Status)
+			assert.Empty(t, storage.Keys("backups"))
+		})
+	}
+}
+
+func TestPipelineInvalidFailurePolicy(t *testing.T) {
+	admin, storage, location := setupPipeline(t)
+	t.Setenv("DESTINATION_FAILURE_POLICY", "some")
+
+	err := runOperations()
+	assert.Equal(t, exitConfiguration, exitCode(err))
+	assert.ErrorContains(t, err, "invalid DESTINATION_FAILURE_POLICY some")
+	for _, command := range admin.Commands() {
+		assert.NotEqual(t, "neo4j-admin", command[0], "no backup is taken")
+	}
+	assert.Empty(t, storage.Keys("backups"))
+	assert.Equal(t, status.Failed, readStatus(t, location).Status)
+}
+
+func TestPipelineTracing(t *testing.T) {
+	admin, _, _ := setupPipeline(t)
+	admin.Inconsistent = []string{"orders"}
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	assert.Equal(t, exitInconsistencies, exitCode(runOperations()))
+
+	spans := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range recorder.Ended() {
+		spans[span.Name()] = append(spans[span.Name()], span)
+	}
+	if assert.Len(t, spans["backup run"], 1) {
+		run := spans["backup run"][0]
+		assert.Contains(t, run.Attributes(), tracing.ExitCode.Int(exitInconsistencies))
+		assert.Contains(t, run.Attributes(), tracing.Status.String(status.Failed))
+		for _, phase := range []string{"connectivity", "backup", "consistency check", "upload"} {
+			for _, span := range spans[phase] {
+				assert.Equal(t, run.SpanContext().SpanID(), span.Parent().SpanID(), phase)
+			}
+		}
+	}
+	assert.Len(t, spans["connectivity"], 1)
+	assert.Len(t, spans["nc"], 1)
+	if assert.Len(t, spans["backup"], 1) {
+		assert.Len(t, spans["backup"][0].Events(), 2, "an event per artifact")
+	}
+	assert.Len(t, spans["consistency check"], 2, "a span per database")
+	assert.Len(t, spans["neo4j-admin database check"], 2)
+	assert.Len(t, spans["tar"], 1, "the report of orders is archived")
+	if assert.Len(t, spans["upload"], 1) {
+		assert.Contains(t, spans["upload"][0].Attributes(), tracing.Destination.String("primary"))
+	}
+	if assert.Len(t, spans["upload file"], 4) {
+		assert.Equal(t, spans["upload"][0].SpanContext().SpanID(), spans["upload file"][0].Parent().SpanID())
+	}
+}
+
+func filterKeys(keys []string, suffix string) []string {
+	var filtered []string
+	for _, key := range keys {
+		if strings.HasSuffix(key, suffix) {
+			filtered = append(filtered, key)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/catalog"
+)
+
+// plan prints the artifacts restoring a database as of a point in time , read from the bucket listing or a catalog manifest
+func plan(args []string, w io.Writer) error {
+	flags := newEnvFlags("plan", "Resolves the full backup and the differential backups restoring a database as of a point in time.")
+	flags.storageFlags()
+	database := flags.String("database", "", "name of the database (required)")
+	at := flags.String("at", "", "point in time , yyyy-MM-dd HH:mm:ss (UTC) or RFC3339 (required)")
+	catalogFile := flags.String("catalog-file", "", "catalog written by 'list --output json' used instead of listing the bucket")
+	output := flags.String("output", "table", "output format (table or json)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if *database == "" || *at == "" {
+		return withExitCode(exitUsage, fmt.Errorf("missing --database or --at"))
+	}
+	if *output != "table" && *output != "json" {
+		return withExitCode(exitUsage, fmt.Errorf("invalid output format %s. Supported values are table and json", *output))
+	}
+	atTime, err := catalog.ParseTime(*at)
+	if err != nil {
+		return withExitCode(exitUsage, err)
+	}
+
+	var backupCatalog *catalog.Catalog
+	if *catalogFile != "" {
+		backupCatalog, err = readCatalogFile(*catalogFile)
+	} else {
+		_, backupCatalog, err = loadCatalog()
+	}
+	if err != nil {
+		return err
+	}
+	restorePlan, err := backupCatalog.PlanAt(*database, atTime)
+	if err != nil {
+		return withExitCode(exitRestore, err)
+	}
+	if *output == "json" {
+		return restorePlan.WriteJSON(w)
+	}
+	return restorePlan.WriteTable(w)
+}
+
+// readCatalogFile reads the catalog manifest written by the list command
+func readCatalogFile(fileName string) (*catalog.Catalog, error) {
+	file, err := os.Open(fileName)
+	if err != nil {
+		return nil, withExitCode(exitConfiguration, fmt.Errorf("unable to open catalog file %s \n %v", fileName, err))
+	}
+	defer file.Close()
+	backupCatalog, err := catalog.Read(file)
+	if err != nil {
+		return nil, withExitCode(exitConfiguration, err)
+	}
+	return backupCatalog, nil
+}
+
+func planCommand(args []string) error {
+	return plan(args, os.Stdout)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/catalog"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPipelinePointInTimeRestore(t *testing.T) {
+	admin, _, _ := setupPipeline(t)
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	t.Setenv("KEEP_BACKUP_FILES", "true")
+	// full backups of neo4j at 10:01 and orders at 10:02 , differential backups at 10:03 and 10:04
+	assert.NoError(t, runOperations())
+	assert.NoError(t, runOperations())
+
+	var output bytes.Buffer
+	assert.NoError(t, plan([]string{"--database", "neo4j", "--at", "2024-06-13 10:02:30", "--output", "json"}, &output))
+	restorePlan := &catalog.Plan{}
+	assert.NoError(t, json.Unmarshal(output.Bytes(), restorePlan))
+	if assert.Len(t, restorePlan.Artifacts, 1) {
+		assert.Equal(t, "prod/neo4j/neo4j-2024-06-13T10-01-00.backup", restorePlan.Artifacts[0].Key)
+	}
+
+	// the catalog written by the list command is used as a manifest of the bucket
+	output.Reset()
+	assert.NoError(t, list([]string{"--output", "json"}, &output))
+	catalogFile := filepath.Join(t.TempDir(), "catalog.json")
+	assert.NoError(t, os.WriteFile(catalogFile, output.Bytes(), 0644))
+	output.Reset()
+	assert.NoError(t, plan([]string{"--database", "orders", "--at", "2024-06-13T10:10:00Z", "--catalog-file", catalogFile}, &output))
+	assert.Contains(t, output.String(), "covered until 2024-06-13T10:04:00Z by 2 artifact(s)")
+	assert.Contains(t, output.String(), "prod/orders/orders-2024-06-13T10-04-00.backup")
+
+	downloadPath := filepath.Join(t.TempDir(), "restore")
+	assert.NoError(t, restoreCommand([]string{"--database", "neo4j", "--at", "2024-06-13T10:02:30Z", "--download-path", downloadPath}))
+	assert.Equal(t, []string{filepath.Join(downloadPath, "neo4j-2024-06-13T10-01-00.backup")}, admin.Restored("neo4j"),
+		"the differential backup taken after the point in time is not restored")
+
+	assert.Equal(t, exitRestore, exitCode(plan([]string{"--database", "neo4j", "--at", "2024-06-13 09:00:00"}, &output)))
+	assert.Equal(t, exitRestore, exitCode(restoreCommand([]string{"--database", "neo4j", "--at", "2024-06-13 09:00:00", "--download-path", downloadPath})))
+	assert.Equal(t, exitUsage, exitCode(plan([]string{"--database", "neo4j", "--at", "tuesday"}, &output)))
+	assert.Equal(t, exitUsage, exitCode(plan([]string{"--database", "neo4j"}, &output)))
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+)
+
+// destinationInit describes the initialisation of every destination before the artifacts are uploaded
+type destinationInit struct {
+	// createBucket creates the bucket (or container) when it does not exist
+	createBucket bool
+	// writeProbe uploads and deletes a small probe object verifying the write permission
+	writeProbe bool
+	lifecycle  common.LifecyclePolicy
+	// expireWholeBucket permits the expiration of a destination without prefix , which deletes every object of the bucket
+	expireWholeBucket bool
+}
+
+// getDestinationInit reads DESTINATION_CREATE_BUCKET , DESTINATION_WRITE_PROBE , LIFECYCLE_EXPIRATION_DAYS ,
+// LIFECYCLE_ABORT_INCOMPLETE_UPLOAD_DAYS and LIFECYCLE_EXPIRE_WHOLE_BUCKET
+func getDestinationInit() (destinationInit, error) {
+	options := destinationInit{}
+	for name, value := range map[string]*bool{
+		"DESTINATION_CREATE_BUCKET":     &options.createBucket,
+		"DESTINATION_WRITE_PROBE":       &options.writeProbe,
+		"LIFECYCLE_EXPIRE_WHOLE_BUCKET": &options.expireWholeBucket,
+	} {
+		env := strings.TrimSpace(os.Getenv(name))
+		if env == "" {
+			continue
+		}
+		enabled, err := strconv.ParseBool(env)
+		if err != nil {
+			return options, fmt.Errorf("invalid %s %s. It must be true or false", name, env)
+		}
+		*value = enabled
+	}
+	for name, value := range map[string]*int{
+		"LIFECYCLE_EXPIRATION_DAYS":              &options.lifecycle.ExpirationDays,
+		"LIFECYCLE_ABORT_INCOMPLETE_UPLOAD_DAYS": &options.lifecycle.AbortIncompleteUploadDays,
+	} {
+		env := strings.TrimSpace(os.Getenv(name))
+		if env == "" {
+			continue
+		}
+		days, err := strconv.Atoi(env)
+		if err != nil || days < 0 {
+			return options, fmt.Errorf("invalid %s %s. It must be a positive number of days or 0 to disable it", name, env)
+		}
+		*value = days
+	}
+	return options, nil
+}
+
+// initialise creates the bucket if requested , checks the bucket access and applies the lifecycle rules along with the write probe
+// An empty prefix is a valid destination. The lifecycle rules are applied below KEY_PREFIX , their failure is only logged
+// The expiration of a destination without prefix is refused unless LIFECYCLE_EXPIRE_WHOLE_BUCKET is set since it applies to the whole bucket
+func (d *destination) initialise(client common.StorageClient) error {
+	bucketName := common.JoinBucketPath(d.BucketName, strings.Trim(strings.TrimSpace(os.Getenv("KEY_PREFIX")), "/"))
+	if _, prefix := common.SplitBucketName(bucketName); d.init.lifecycle.ExpirationDays > 0 && strings.Trim(prefix, "/") == "" && !d.init.expireWholeBucket {
+		return fmt.Errorf("refusing to expire every object of bucket %s after %d days since neither the bucket name of destination %s nor KEY_PREFIX contains a prefix. "+
+			"Set LIFECYCLE_EXPIRE_WHOLE_BUCKET=true to expire the whole bucket", bucketName, d.init.lifecycle.ExpirationDays, d.Name)
+	}
+	if d.init.createBucket {
+		if creator, ok := client.(common.BucketCreator); ok {
+			if _, err := creator.CreateBucket(d.BucketName); err != nil {
+				return err
+			}
+		} else {
+			log.Printf("Warning: the bucket of destination %s cannot be created by the %s client", d.Name, d.CloudProvider)
+		}
+	}
+	if err := client.CheckBucketAccess(d.BucketName); err != nil {
+		return err
+	}
+	if d.init.lifecycle.Enabled() {
+		if manager, ok := client.(common.LifecycleManager); !ok {
+			log.Printf("Warning: the lifecycle rules of destination %s must be configured on the %s storage account", d.Name, d.CloudProvider)
+		} else if err := manager.ApplyLifecycle(bucketName, d.init.lifecycle); err != nil {
+			log.Printf("Warning: unable to apply the lifecycle rules to destination %s: %v", d.Name, err)
+		}
+	}
+	if d.init.writeProbe {
+		return probeWrite(client, bucketName)
+	}
+	return nil
+}
+
+// probeWrite uploads a small probe object to the bucket and deletes it
+// The probe is not deleted when the bucket does not permit it (ex: object lock) , which does not fail the probe
+func probeWrite(client common.StorageClient, bucketName string) error {
+	key, err := uploadProbe(client, bucketName)
+	if err != nil {
+		return err
+	}
+	if err = client.DeleteObject(bucketName, key); err != nil {
+		log.Printf("Warning: unable to delete the write probe %s from %s: %v", key, bucketName, err)
+	}
+	log.Printf("Write permission of %s verified", bucketName)
+	return nil
+}
+
+// uploadProbe uploads a small probe object to the bucket and returns its key
+func uploadProbe(client common.StorageClient, bucketName string) (string, error) {
+	fileName := fmt.Sprintf(".write-probe-%d", time.Now().UnixNano())
+	filePath := filepath.Join(neo4jAdmin.BackupLocation(), fileName)
+	if err := os.WriteFile(filePath, []byte("neo4j backup write probe\n"), 0644); err != nil {
+		return "", fmt.Errorf("unable to create the write probe %s \n %v", filePath, err)
+	}
+	defer os.Remove(filePath)
+	err := client.UploadFile([]string{fileName}, bucketName)
+	// the probe is not part of the transfers of the run
+	common.TakeTransfers()
+	if err != nil {
+		return "", fmt.Errorf("unable to write to %s \n %v", bucketName, err)
+	}
+	_, prefix := common.SplitBucketName(bucketName)
+	return strings.TrimPrefix(path.Join(strings.Trim(prefix, "/"), fileName), "/"), nil
+}
+
+func initCommand(args []string) error {
+	flags := newEnvFlags("init", "Initialises every destination: creates the bucket if requested , applies the lifecycle rules and verifies the write permission with a probe object.")
+	flags.storageFlags()
+	flags.env("create-bucket", "DESTINATION_CREATE_BUCKET", "create the bucket (or container) when it does not exist (true or false)")
+	flags.env("expiration-days", "LIFECYCLE_EXPIRATION_DAYS", "delete the artifacts older than the given number of days , 0 disables it")
+	flags.env("abort-incomplete-upload-days", "LIFECYCLE_ABORT_INCOMPLETE_UPLOAD_DAYS", "abort the multipart uploads left incomplete for the given number of days , 0 disables it")
+	flags.env("expire-whole-bucket", "LIFECYCLE_EXPIRE_WHOLE_BUCKET", "permit the expiration of a destination without prefix , which deletes every object of the bucket (true or false)")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	os.Setenv("LOCATION", neo4jAdmin.BackupLocation())
+	destinations, err := getDestinations()
+	if err != nil {
+		return withExitCode(exitConfiguration, err)
+	}
+	if len(destinations) == 0 {
+		return withExitCode(exitConfiguration, fmt.Errorf("no backup destination configured"))
+	}
+	for _, d := range destinations {
+		d.init.writeProbe = true
+	}
+	run := status.NewRun()
+	if ready := prepareDestinations(destinations, run); len(ready) != len(destinations) {
+		var failed []string
+		for _, result := range run.Destinations {
+			failed = append(failed, result.Name)
+		}
+		return withExitCode(exitStorage, fmt.Errorf("unable to initialise destination(s) %v", failed))
+	}
+	log.Printf("%d destination(s) initialised !!", len(destinations))
+	return nil
+}
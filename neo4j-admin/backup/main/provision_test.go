@@ -0,0 +1,122 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDestinationInit(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		want      destinationInit
+		wantError string
+	}{
+		{name: "defaults", want: destinationInit{}},
+		{
+			name: "all options",
+			env: map[string]string{"DESTINATION_CREATE_BUCKET": "true", "DESTINATION_WRITE_PROBE": "true",
+				"LIFECYCLE_EXPIRATION_DAYS": "90", "LIFECYCLE_ABORT_INCOMPLETE_UPLOAD_DAYS": "7", "LIFECYCLE_EXPIRE_WHOLE_BUCKET": "true"},
+			want: destinationInit{createBucket: true, writeProbe: true, lifecycle: common.LifecyclePolicy{ExpirationDays: 90, AbortIncompleteUploadDays: 7}, expireWholeBucket: true},
+		},
+		{name: "invalid flag", env: map[string]string{"DESTINATION_CREATE_BUCKET": "yes please"}, wantError: "invalid DESTINATION_CREATE_BUCKET"},
+		{name: "negative days", env: map[string]string{"LIFECYCLE_EXPIRATION_DAYS": "-1"}, wantError: "invalid LIFECYCLE_EXPIRATION_DAYS"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"DESTINATION_CREATE_BUCKET", "DESTINATION_WRITE_PROBE", "LIFECYCLE_EXPIRATION_DAYS", "LIFECYCLE_ABORT_INCOMPLETE_UPLOAD_DAYS", "LIFECYCLE_EXPIRE_WHOLE_BUCKET"} {
+				t.Setenv(name, tt.env[name])
+			}
+			got, err := getDestinationInit()
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestPipelineDestinationInit(t *testing.T) {
+	_, storage, location := setupPipeline(t)
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	t.Setenv("BUCKET_NAME", "team-a/prod")
+
+	// the bucket does not exist and is not created by default
+	assert.Equal(t, exitStorage, exitCode(runOperations()))
+
+	t.Setenv("DESTINATION_CREATE_BUCKET", "true")
+	t.Setenv("DESTINATION_WRITE_PROBE", "true")
+	t.Setenv("LIFECYCLE_EXPIRATION_DAYS", "30")
+	assert.NoError(t, runOperations())
+	assert.Equal(t, []string{"prod/neo4j/neo4j-2024-06-13T10-01-00.backup", "prod/orders/orders-2024-06-13T10-02-00.backup"},
+		storage.Keys("team-a"), "the write probe is deleted")
+	policy, present := storage.Lifecycle("team-a/prod")
+	assert.True(t, present)
+	assert.Equal(t, common.LifecyclePolicy{ExpirationDays: 30}, policy)
+	run := readStatus(t, location)
+	assert.Equal(t, status.Success, run.Status)
+	if assert.Len(t, run.Destinations, 1) {
+		assert.Len(t, run.Destinations[0].Transfers, 2, "the write probe is not part of the transfers")
+	}
+
+	t.Setenv("LIFECYCLE_ABORT_INCOMPLETE_UPLOAD_DAYS", "seven")
+	assert.Equal(t, exitConfiguration, exitCode(runOperations()))
+}
+
+func TestPipelineLifecycleWholeBucket(t *testing.T) {
+	_, storage, _ := setupPipeline(t)
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	t.Setenv("BUCKET_NAME", "backups")
+	t.Setenv("KEY_PREFIX", "")
+	t.Setenv("LIFECYCLE_EXPIRATION_DAYS", "30")
+	t.Setenv("LIFECYCLE_EXPIRE_WHOLE_BUCKET", "")
+
+	// the expiration would delete every object of the bucket
+	assert.Equal(t, exitStorage, exitCode(runOperations()))
+	_, present := storage.Lifecycle("backups")
+	assert.False(t, present)
+	assert.Empty(t, storage.Keys("backups"), "nothing is uploaded to the refused destination")
+
+	t.Setenv("KEY_PREFIX", "neo4j")
+	assert.NoError(t, runOperations())
+	policy, present := storage.Lifecycle("backups/neo4j")
+	assert.True(t, present, "the expiration is applied below KEY_PREFIX")
+	assert.Equal(t, 30, policy.ExpirationDays)
+
+	t.Setenv("KEY_PREFIX", "")
+	t.Setenv("LIFECYCLE_EXPIRE_WHOLE_BUCKET", "true")
+	assert.NoError(t, runOperations())
+	policy, present = storage.Lifecycle("backups")
+	assert.True(t, present, "the expiration of the whole bucket is opted in")
+	assert.Equal(t, 30, policy.ExpirationDays)
+}
+
+func TestInitCommand(t *testing.T) {
+	_, storage, location := setupPipeline(t)
+	t.Setenv("BACKUP_DESTINATIONS", `[{"name":"dr","cloudProvider":"gcp","bucketName":"dr/neo4j"}]`)
+	t.Setenv("DESTINATION_CREATE_BUCKET", "")
+	t.Setenv("DESTINATION_WRITE_PROBE", "false")
+
+	err := initCommand(nil)
+	assert.Equal(t, exitStorage, exitCode(err))
+	assert.ErrorContains(t, err, "[dr]")
+
+	assert.NoError(t, initCommand([]string{"--create-bucket", "true", "--abort-incomplete-upload-days", "3"}))
+	for _, bucket := range []string{"backups", "dr"} {
+		assert.Empty(t, storage.Keys(bucket), "the write probe is deleted from %s", bucket)
+	}
+	policy, _ := storage.Lifecycle("dr/neo4j")
+	assert.Equal(t, 3, policy.AbortIncompleteUploadDays)
+	entries, err := os.ReadDir(location)
+	assert.NoError(t, err)
+	for _, entry := range entries {
+		assert.False(t, strings.HasPrefix(entry.Name(), ".write-probe-"), "the local probe file is removed")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"os"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/catalog"
+)
+
+func pruneCommand(args []string) error {
+	flags := newEnvFlags("prune", "Deletes old backup chains (and their consistency check reports) from the bucket. The newest chain and the newest restorable chain of every database are always kept.")
+	flags.storageFlags()
+	database := flags.String("database", "", "prune only the chains of the given database")
+	keepChains := flags.Int("keep-chains", 0, "number of newest restorable chains to keep per database (0 keeps all) , the chains containing artifacts of unknown type do not count")
+	maxAgeDays := flags.Int("max-age-days", 0, "delete the chains whose newest artifact is older than the given number of days (0 disables)")
+	pruneUnknown := flags.Bool("prune-unknown", false, "also delete the chains without a full backup containing artifacts of unknown type (ex: uploaded before the backup type metadata) , they are kept by default since they may hold the full backup of the following artifacts")
+	dryRun := flags.Bool("dry-run", false, "only print the artifacts which would be deleted")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+
+	client, backupCatalog, err := loadCatalog()
+	if err != nil {
+		return err
+	}
+	bucketName := os.Getenv("BUCKET_NAME")
+	maxAge := time.Duration(*maxAgeDays) * 24 * time.Hour
+	for _, entry := range backupCatalog.Databases {
+		if *database != "" && entry.Name != *database {
+			continue
+		}
+		for _, chain := range catalog.ExpiredChains(entry, *keepChains, maxAge, time.Now().UTC(), *pruneUnknown) {
+			for _, key := range chainKeys(chain) {
+				if *dryRun {
+					log.Printf("[dry-run] Deleting %s", key)
+					continue
+				}
+				if err = client.DeleteObject(bucketName, key); err != nil {
+					return withExitCode(exitStorage, err)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// chainKeys returns the keys of all the artifacts and consistency check reports of the chain
+func chainKeys(chain catalog.Chain) []string {
+	var keys []string
+	for _, artifact := range chain.Artifacts() {
+		keys = append(keys, artifact.Key)
+		if artifact.ConsistencyCheckReport != "" {
+			keys = append(keys, artifact.ConsistencyCheckReport)
+		}
+	}
+	return keys
+}
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/catalog"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+)
+
+func restoreCommand(args []string) error {
+	flags := newEnvFlags("restore", "Downloads the latest backup chain of a database (or the chain covering --at) from the bucket and restores it.")
+	flags.storageFlags()
+	database := flags.String("database", "", "name of the database to restore (required)")
+	downloadPath := flags.String("download-path", "/backups/restore", "local directory the backup chain is downloaded to")
+	overwrite := flags.Bool("overwrite-destination", false, "replace the existing database")
+	restoreUntil := flags.String("restore-until", "", "restore the transaction logs up to the given date (yyyy-MM-dd HH:mm:ss) or transaction id")
+	at := flags.String("at", "", "restore the database as of the given time (yyyy-MM-dd HH:mm:ss UTC or RFC3339) instead of the latest backup")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if *database == "" {
+		return withExitCode(exitUsage, fmt.Errorf("missing --database"))
+	}
+
+	var filePaths []string
+	var err error
+	if *at != "" {
+		filePaths, err = downloadChainAt(*database, *at, *downloadPath)
+	} else {
+		filePaths, err = downloadLatestChain(*database, *downloadPath)
+	}
+	if err != nil {
+		return err
+	}
+	if err = neo4jAdmin.PerformRestore(filePaths, *database, *overwrite, *restoreUntil); err != nil {
+		return withExitCode(exitRestore, err)
+	}
+	return nil
+}
+
+func verifyCommand(args []string) error {
+	flags := newEnvFlags("verify", "Downloads the latest backup chain of a database from the bucket and runs the consistency check on it.")
+	flags.storageFlags()
+	database := flags.String("database", "", "name of the database to verify (required)")
+	downloadPath := flags.String("download-path", "/backups/verify", "local directory the backup chain is downloaded to")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if *database == "" {
+		return withExitCode(exitUsage, fmt.Errorf("missing --database"))
+	}
+
+	if _, err := downloadLatestChain(*database, *downloadPath); err != nil {
+		return err
+	}
+	return consistencyCheck(*database, *downloadPath)
+}
+
+func checkCommand(args []string) error {
+	flags := newEnvFlags("check", "Runs the consistency check on the latest backup of a database present at a local path.")
+	database := flags.String("database", "", "name of the database to check (required)")
+	fromPath := flags.String("from-path", "/backups", "local directory containing the backup artifacts")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if *database == "" {
+		return withExitCode(exitUsage, fmt.Errorf("missing --database"))
+	}
+	return consistencyCheck(*database, *fromPath)
+}
+
+// consistencyCheck runs the consistency check and returns an error if inconsistencies are found
+// A check which did not complete returns exitConsistencyCheck , inconsistencies return exitInconsistencies
+func consistencyCheck(database string, fromPath string) error {
+	summary, err := neo4jAdmin.PerformConsistencyCheckFromPath(database, fromPath)
+	if err != nil {
+		return withExitCode(exitConsistencyCheck, err)
+	}
+	if summary.Status == common.ConsistencyCheckInconsistent {
+		return withExitCode(exitInconsistencies, fmt.Errorf("inconsistencies found for database %s: %d error(s) , %d warning(s) %v. Report available at %s/%s",
+			database, summary.Errors, summary.Warnings, summary.Inconsistencies, neo4jAdmin.BackupLocation(), summary.Report))
+	}
+	return nil
+}
+
+// downloadLatestChain downloads the full backup and the differential backups of the latest chain of the database
+// and returns the local file paths ordered from the full backup to the newest differential backup
+// A latest chain without a full backup or containing an artifact of unknown type is refused
+func downloadLatestChain(database string, downloadPath string) ([]string, error) {
+	client, backupCatalog, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	entry, present := backupCatalog.FindDatabase(database)
+	if !present || len(entry.Chains) == 0 {
+		return nil, withExitCode(exitRestore, fmt.Errorf("no backup found for database %s in %s", database, os.Getenv("BUCKET_NAME")))
+	}
+	chain := entry.Chains[len(entry.Chains)-1]
+	if err := chain.Validate(); err != nil {
+		return nil, withExitCode(exitRestore, fmt.Errorf("unable to restore the latest backup chain of database %s \n %v", database, err))
+	}
+	return downloadArtifacts(client, os.Getenv("BUCKET_NAME"), chain.Artifacts(), downloadPath)
+}
+
+// downloadChainAt downloads the artifacts of the plan restoring the database as of the given time
+// and returns the local file paths ordered from the full backup to the newest differential backup
+func downloadChainAt(database string, at string, downloadPath string) ([]string, error) {
+	atTime, err := catalog.ParseTime(at)
+	if err != nil {
+		return nil, withExitCode(exitUsage, err)
+	}
+	client, backupCatalog, err := loadCatalog()
+	if err != nil {
+		return nil, err
+	}
+	restorePlan, err := backupCatalog.PlanAt(database, atTime)
+	if err != nil {
+		return nil, withExitCode(exitRestore, err)
+	}
+	log.Printf("Restoring database %s as of %s from %d artifact(s) covering it until %s", database,
+		atTime.Format(time.RFC3339), len(restorePlan.Artifacts), restorePlan.CoveredUntil.Format(time.RFC3339))
+	return downloadArtifacts(client, os.Getenv("BUCKET_NAME"), restorePlan.Artifacts, downloadPath)
+}
+
+// downloadArtifacts downloads the given artifacts to the download path and returns the local file paths
+func downloadArtifacts(client common.StorageClient, bucketName string, artifacts []catalog.Artifact, downloadPath string) ([]string, error) {
+	if err := os.MkdirAll(downloadPath, 0755); err != nil {
+		return nil, withExitCode(exitFailure, fmt.Errorf("unable to create download directory %s \n %v", downloadPath, err))
+	}
+	var filePaths []string
+	for _, artifact := range artifacts {
+		filePath := filepath.Join(downloadPath, artifact.FileName)
+		if err := client.DownloadFile(bucketName, artifact.Key, filePath); err != nil {
+			return nil, withExitCode(exitStorage, err)
+		}
+		filePaths = append(filePaths, filePath)
+	}
+	log.Printf("Downloaded %d artifact(s) to %s", len(filePaths), downloadPath)
+	return filePaths, nil
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"k8s.io/utils/strings/slices"
+)
+
+// resumeArtifacts returns the artifacts of a previous run whose upload did not complete
+// The artifacts are reused only if every file is still present at LOCATION with the checksum recorded by the previous run
+func resumeArtifacts() ([]string, []string, bool) {
+	if !common.ResumableUploadsEnabled() {
+		return nil, nil, false
+	}
+	state, err := common.GetUploadState()
+	if err != nil {
+		log.Printf("Warning: %v", err)
+		return nil, nil, false
+	}
+	if len(state.BackupFiles) == 0 {
+		return nil, nil, false
+	}
+	for _, fileName := range append(append([]string{}, state.BackupFiles...), state.ConsistencyCheckReports...) {
+		checksum, err := common.FileChecksum(fmt.Sprintf("%s/%s", os.Getenv("LOCATION"), fileName))
+		if err != nil || checksum != state.Checksums[fileName] {
+			log.Printf("Artifact %s of the previous run is missing or changed , a new backup will be taken", fileName)
+			return nil, nil, false
+		}
+	}
+	log.Printf("Resuming the upload of the artifacts of the previous run %v %v", state.BackupFiles, state.ConsistencyCheckReports)
+	return state.BackupFiles, state.ConsistencyCheckReports, true
+}
+
+// recordArtifacts stores the artifacts of the run along with their checksums in the upload state
+// The upload sessions of a previous run are dropped since their artifacts are not uploaded anymore
+func recordArtifacts(backupFileNames []string, consistencyCheckReports []string) error {
+	if !common.ResumableUploadsEnabled() {
+		return nil
+	}
+	checksums := make(map[string]string)
+	for _, fileName := range append(append([]string{}, backupFileNames...), consistencyCheckReports...) {
+		checksum, err := common.FileChecksum(fmt.Sprintf("%s/%s", os.Getenv("LOCATION"), fileName))
+		if err != nil {
+			return err
+		}
+		checksums[fileName] = checksum
+	}
+	return common.UpdateUploadState(func(state *common.UploadState) {
+		if len(state.Sessions) != 0 {
+			log.Printf("Warning: dropping %d upload session(s) of a previous run , the incomplete uploads are left to the bucket lifecycle rules", len(state.Sessions))
+		}
+		*state = common.UploadState{
+			BackupFiles:             backupFileNames,
+			ConsistencyCheckReports: consistencyCheckReports,
+			Checksums:               checksums,
+			Uploaded:                make(map[string][]string),
+		}
+	})
+}
+
+// alreadyUploaded returns true if a previous run uploaded all the files to the destination
+func alreadyUploaded(destinationName string, fileNames []string) bool {
+	if !common.ResumableUploadsEnabled() {
+		return false
+	}
+	state, err := common.GetUploadState()
+	if err != nil {
+		return false
+	}
+	uploaded := state.Uploaded[destinationName]
+	for _, fileName := range fileNames {
+		if !slices.Contains(uploaded, fileName) {
+			return false
+		}
+	}
+	return true
+}
+
+// recordUploaded stores the files uploaded to the destination in the upload state
+func recordUploaded(destinationName string, fileNames []string) {
+	if !common.ResumableUploadsEnabled() {
+		return
+	}
+	err := common.UpdateUploadState(func(state *common.UploadState) {
+		if state.Uploaded == nil {
+			state.Uploaded = make(map[string][]string)
+		}
+		state.Uploaded[destinationName] = fileNames
+	})
+	if err != nil {
+		log.Printf("Warning: %v", err)
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResumeArtifacts(t *testing.T) {
+	location := t.TempDir()
+	t.Setenv("LOCATION", location)
+	t.Setenv("UPLOAD_STATE_FILE", filepath.Join(location, ".upload-state.json"))
+	backupFile := "neo4j-2024-01-01T00-00-00.backup"
+	reportFile := "neo4j-2024-01-01T00-00-00.report.tar.gz"
+	assert.NoError(t, os.WriteFile(filepath.Join(location, backupFile), []byte("backup"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(location, reportFile), []byte("report"), 0644))
+
+	_, _, resumed := resumeArtifacts()
+	assert.False(t, resumed)
+
+	assert.NoError(t, recordArtifacts([]string{backupFile}, []string{reportFile}))
+	recordUploaded("primary", []string{backupFile, reportFile})
+	assert.True(t, alreadyUploaded("primary", []string{backupFile, reportFile}))
+	assert.False(t, alreadyUploaded("dr", []string{backupFile, reportFile}))
+
+	backupFiles, reports, resumed := resumeArtifacts()
+	assert.True(t, resumed)
+	assert.Equal(t, []string{backupFile}, backupFiles)
+	assert.Equal(t, []string{reportFile}, reports)
+
+	// a changed artifact cannot be resumed , a new backup has to be taken
+	assert.NoError(t, os.WriteFile(filepath.Join(location, backupFile), []byte("another backup"), 0644))
+	_, _, resumed = resumeArtifacts()
+	assert.False(t, resumed)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/snapshot"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+// newSnapshotter returns the snapshotter of the volumes of the release
+var newSnapshotter = snapshot.NewSnapshotterFromEnv
+
+// newQuiescer returns the quiescer of the databases , only called when SNAPSHOT_QUIESCE is true
+var newQuiescer = func() (snapshot.Quiescer, error) {
+	return snapshot.NewHTTPQuiescerFromEnv()
+}
+
+// snapshotMode returns true when BACKUP_MODE is snapshot. The data volumes of the release are snapshotted via the CSI driver
+// instead of backing up the databases over the network , which is much faster for very large stores
+func snapshotMode() bool {
+	return strings.EqualFold(strings.TrimSpace(os.Getenv("BACKUP_MODE")), "snapshot")
+}
+
+func snapshotCommand(args []string) error {
+	flags := newEnvFlags("snapshot", "Takes a CSI VolumeSnapshot of every data volume of a Neo4j release and deletes the snapshots beyond the retention.")
+	flags.env("release", "SNAPSHOT_RELEASE", "name of the neo4j release whose volumes are snapshotted")
+	flags.env("namespace", "DATABASE_NAMESPACE", "namespace of the neo4j release")
+	flags.env("volumes", "SNAPSHOT_VOLUMES", "comma separated volume claim templates snapshotted , data when empty")
+	flags.env("class", "SNAPSHOT_CLASS", "VolumeSnapshotClass of the snapshots , the default class when empty")
+	flags.env("ready-timeout", "SNAPSHOT_READY_TIMEOUT", "time waited for the snapshots to be ready to use ex: 30m")
+	flags.env("keep", "SNAPSHOT_KEEP", "number of ready snapshots kept per volume , 0 keeps every snapshot")
+	flags.env("quiesce", "SNAPSHOT_QUIESCE", "set the databases read only and checkpoint them until the snapshots are taken (true or false)")
+	flags.env("database", "DATABASE", "comma separated list of databases quiesced and recorded in the snapshot labels")
+	if err := flags.parse(args); err != nil {
+		return err
+	}
+	if err := os.Setenv("BACKUP_MODE", "snapshot"); err != nil {
+		return err
+	}
+	return runOperations()
+}
+
+// snapshotOperations snapshots every data volume of the release , waits for the snapshots to be ready to use and applies the retention
+// When SNAPSHOT_QUIESCE is true the databases are read only from before the snapshots are requested until they are taken
+func snapshotOperations(run *status.Run) error {
+	fail := func(code int, err error) error {
+		err = withExitCode(code, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+	var databases []string
+	for _, database := range strings.Split(os.Getenv("DATABASE"), ",") {
+		if database = strings.TrimSpace(database); database != "" {
+			databases = append(databases, database)
+		}
+	}
+	keep := 0
+	if value := strings.TrimSpace(os.Getenv("SNAPSHOT_KEEP")); value != "" {
+		var err error
+		if keep, err = strconv.Atoi(value); err != nil || keep < 0 {
+			return fail(exitConfiguration, fmt.Errorf("invalid SNAPSHOT_KEEP %s , it must be a positive number or 0", value))
+		}
+	}
+	quiesce := os.Getenv("SNAPSHOT_QUIESCE") == "true"
+	if quiesce && (len(databases) == 0 || strings.ContainsAny(strings.Join(databases, ","), "*?")) {
+		return fail(exitConfiguration, fmt.Errorf("SNAPSHOT_QUIESCE requires DATABASE to list the databases to quiesce , found '%s'", os.Getenv("DATABASE")))
+	}
+	snapshotter, err := newSnapshotter()
+	if err != nil {
+		return fail(exitConfiguration, err)
+	}
+
+	ctx := context.Background()
+	span := tracing.Start("snapshot", tracing.Databases.String(os.Getenv("DATABASE")))
+	volumes, err := snapshotter.Volumes(ctx)
+	if err != nil {
+		span.End(err)
+		return fail(exitSnapshot, err)
+	}
+	var quiescer snapshot.Quiescer
+	if quiesce {
+		if quiescer, err = newQuiescer(); err != nil {
+			span.End(err)
+			return fail(exitConfiguration, err)
+		}
+		// the databases quiesced before a failure are resumed as well
+		resume := func() {
+			if quiescer == nil {
+				return
+			}
+			if resumeErr := quiescer.Resume(ctx, databases); resumeErr != nil {
+				log.Printf("Warning: %v", resumeErr)
+			}
+			quiescer = nil
+		}
+		defer resume()
+		quiesceSpan := tracing.Start("quiesce", tracing.Databases.String(strings.Join(databases, ",")))
+		err = quiescer.Quiesce(ctx, databases)
+		quiesceSpan.End(err)
+		if err != nil {
+			span.End(err)
+			return fail(exitSnapshot, err)
+		}
+		run.Snapshots, err = snapshotter.Create(ctx, volumes, databases)
+		if err == nil {
+			err = snapshotter.WaitTaken(ctx, run.Snapshots)
+		}
+		resume()
+	} else {
+		run.Snapshots, err = snapshotter.Create(ctx, volumes, databases)
+	}
+	if err == nil {
+		err = snapshotter.WaitReady(ctx, run.Snapshots)
+	}
+	for _, s := range run.Snapshots {
+		run.BackupFiles = append(run.BackupFiles, s.Name)
+	}
+	span.SetAttributes(tracing.Artifacts.StringSlice(run.BackupFiles))
+	span.End(err)
+	if err != nil {
+		return fail(exitSnapshot, err)
+	}
+	log.Printf("Snapshot(s) %v ready to use !!", run.BackupFiles)
+
+	// the retention is applied once the snapshots of the run are ready , a failed prune does not fail the run
+	if run.PrunedSnapshots, err = snapshotter.Prune(ctx, keep); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	run.Finish(status.Success, nil)
+	return nil
+}
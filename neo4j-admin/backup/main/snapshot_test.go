@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/snapshot"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+// recordingQuiescer records the quiesce and resume calls in the shared log of the snapshot test
+type recordingQuiescer struct {
+	log  *[]string
+	fail bool
+}
+
+func (q *recordingQuiescer) Quiesce(_ context.Context, databases []string) error {
+	*q.log = append(*q.log, fmt.Sprintf("quiesce %v", databases))
+	if q.fail {
+		return fmt.Errorf("unable to set database neo4j read only")
+	}
+	return nil
+}
+
+func (q *recordingQuiescer) Resume(_ context.Context, databases []string) error {
+	*q.log = append(*q.log, fmt.Sprintf("resume %v", databases))
+	return nil
+}
+
+// setupSnapshot replaces the snapshotter by one of release graph using fake clients , the CSI driver reports snapshotStatus
+// The returned log contains the quiesce , resume and snapshot creation calls in order
+func setupSnapshot(t *testing.T, snapshotStatus map[string]interface{}, quiesceFails bool) (*dynamicfake.FakeDynamicClient, *[]string) {
+	t.Setenv("BACKUP_MODE", "snapshot")
+	t.Setenv("SNAPSHOT_QUIESCE", "true")
+	t.Setenv("SNAPSHOT_KEEP", "1")
+	calls := &[]string{}
+
+	claims := []runtime.Object{
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-graph-0", Namespace: "neo4j", Labels: map[string]string{snapshot.LabelRelease: "graph"}}},
+		&corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data-graph-1", Namespace: "neo4j", Labels: map[string]string{snapshot.LabelRelease: "graph"}}},
+	}
+	previous := &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      "data-graph-0-2024-06-12t10-00-00",
+			"namespace": "neo4j",
+			"labels": map[string]interface{}{snapshot.LabelManagedBy: "neo4j-backup", snapshot.LabelRelease: "graph",
+				snapshot.LabelVolume: "data-graph-0", snapshot.LabelRun: "2024-06-12t10-00-00"},
+		},
+		"status": map[string]interface{}{"readyToUse": true},
+	}}
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{snapshot.VolumeSnapshots: "VolumeSnapshotList"}, previous)
+	dynamicClient.PrependReactor("create", "volumesnapshots", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		object := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		*calls = append(*calls, "create "+object.GetName())
+		_ = unstructured.SetNestedMap(object.Object, snapshotStatus, "status")
+		return false, nil, nil
+	})
+	originalSnapshotter, originalQuiescer := newSnapshotter, newQuiescer
+	newSnapshotter = func() (*snapshot.Snapshotter, error) {
+		return snapshot.NewSnapshotter(dynamicClient, fake.NewSimpleClientset(claims...), "neo4j", "graph", "", []string{"data"}, time.Second), nil
+	}
+	newQuiescer = func() (snapshot.Quiescer, error) {
+		return &recordingQuiescer{log: calls, fail: quiesceFails}, nil
+	}
+	t.Cleanup(func() { newSnapshotter, newQuiescer = originalSnapshotter, originalQuiescer })
+	return dynamicClient, calls
+}
+
+func readySnapshot() map[string]interface{} {
+	return map[string]interface{}{"readyToUse": true, "creationTime": "2024-06-13T10:02:01Z", "restoreSize": "10Gi"}
+}
+
+func TestPipelineSnapshot(t *testing.T) {
+	_, _, location := setupPipeline(t)
+	dynamicClient, calls := setupSnapshot(t, readySnapshot(), false)
+
+	assert.NoError(t, runOperations())
+	run := readStatus(t, location)
+	assert.Equal(t, status.Success, run.Status)
+	if assert.Len(t, run.Snapshots, 2) {
+		assert.Equal(t, "data-graph-0", run.Snapshots[0].Volume)
+		assert.True(t, run.Snapshots[0].ReadyToUse)
+		assert.Equal(t, "10Gi", run.Snapshots[1].RestoreSize)
+		assert.Equal(t, []string{run.Snapshots[0].Name, run.Snapshots[1].Name}, run.BackupFiles)
+		assert.Equal(t, []string{
+			"quiesce [neo4j orders]",
+			"create " + run.Snapshots[0].Name,
+			"create " + run.Snapshots[1].Name,
+			"resume [neo4j orders]",
+		}, *calls, "the databases are resumed once the snapshots are taken")
+	}
+	assert.Equal(t, []string{"data-graph-0-2024-06-12t10-00-00"}, run.PrunedSnapshots, "a single ready snapshot is kept per volume")
+	list, err := dynamicClient.Resource(snapshot.VolumeSnapshots).Namespace("neo4j").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	assert.Len(t, list.Items, 2)
+}
+
+func TestPipelineSnapshotFailures(t *testing.T) {
+	tests := []struct {
+		name         string
+		env          map[string]string
+		status       map[string]interface{}
+		quiesceFails bool
+		wantCode     int
+		wantCalls    []string
+	}{
+		{name: "invalid keep", env: map[string]string{"SNAPSHOT_KEEP": "-1"}, wantCode: exitConfiguration},
+		{name: "quiesce all databases", env: map[string]string{"DATABASE": "*"}, wantCode: exitConfiguration},
+		{
+			name:         "quiesce failure",
+			quiesceFails: true,
+			wantCode:     exitSnapshot,
+			wantCalls:    []string{"quiesce [neo4j orders]", "resume [neo4j orders]"},
+		},
+		{
+			name:      "driver error",
+			status:    map[string]interface{}{"error": map[string]interface{}{"message": "snapshot quota exceeded"}},
+			wantCode:  exitSnapshot,
+			wantCalls: []string{"quiesce [neo4j orders]", "create", "create", "resume [neo4j orders]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, location := setupPipeline(t)
+			_, calls := setupSnapshot(t, tt.status, tt.quiesceFails)
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+			assert.Equal(t, tt.wantCode, exitCode(runOperations()))
+			run := readStatus(t, location)
+			assert.Equal(t, status.Failed, run.Status)
+			assert.Empty(t, run.PrunedSnapshots)
+			var kinds []string
+			for _, call := range *calls {
+				if strings.HasPrefix(call, "create ") {
+					call = "create"
+				}
+				kinds = append(kinds, call)
+			}
+			if len(tt.wantCalls) > 0 {
+				assert.Equal(t, tt.wantCalls, kinds)
+			}
+		})
+	}
+}
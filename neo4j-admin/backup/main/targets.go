@@ -0,0 +1,369 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/neo4j/helm-charts/logging"
+	neo4jAdmin "github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+var targetNamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// target is a single Neo4j deployment backed up by a job backing up multiple deployments
+// Every empty field falls back to the respective job level env variable
+type target struct {
+	Name        string `json:"name"`
+	Endpoints   string `json:"endpoints"`
+	ServiceName string `json:"serviceName"`
+	ServiceIP   string `json:"serviceIP"`
+	Namespace   string `json:"namespace"`
+	BackupPort  string `json:"backupPort"`
+	Database    string `json:"database"`
+	// KeyPrefix is the prefix of the object keys of the target below every bucket , defaults to the target name
+	KeyPrefix        string                  `json:"keyPrefix"`
+	ConsistencyCheck *targetConsistencyCheck `json:"consistencyCheck"`
+}
+
+// targetConsistencyCheck overrides the consistency check settings of the job for a target
+type targetConsistencyCheck struct {
+	Enable              *bool  `json:"enable"`
+	Database            string `json:"database"`
+	CheckIndexes        *bool  `json:"checkIndexes"`
+	CheckGraph          *bool  `json:"checkGraph"`
+	CheckCounts         *bool  `json:"checkCounts"`
+	CheckPropertyOwners *bool  `json:"checkPropertyOwners"`
+	MaxOffHeapMemory    string `json:"maxOffHeapMemory"`
+	Threads             string `json:"threads"`
+	Verbose             *bool  `json:"verbose"`
+}
+
+// targetCommand returns the command performing the backup of a single target
+// The same binary is executed with the env variables of the target so that the targets do not share any process state
+var targetCommand = func() (*exec.Cmd, error) {
+	executable, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("unable to locate the backup binary \n %v", err)
+	}
+	return exec.Command(executable, "run"), nil
+}
+
+// targetsConfigured returns true when the job backs up the list of targets provided via BACKUP_TARGETS
+func targetsConfigured() bool {
+	return strings.TrimSpace(os.Getenv("BACKUP_TARGETS")) != ""
+}
+
+// parseTargets parses the json list of targets
+// Ex: [{"name":"sales","serviceName":"sales-admin","namespace":"sales","database":"neo4j,orders","consistencyCheck":{"enable":true}}]
+func parseTargets(value string) ([]target, error) {
+	var targets []target
+	if err := json.Unmarshal([]byte(value), &targets); err != nil {
+		return nil, fmt.Errorf("unable to parse BACKUP_TARGETS \n %v", err)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("BACKUP_TARGETS must contain at least one target")
+	}
+	names := make(map[string]bool)
+	for _, t := range targets {
+		if !targetNamePattern.MatchString(t.Name) {
+			return nil, fmt.Errorf("invalid target name %q. It must contain only letters , digits , '.' , '_' and '-'", t.Name)
+		}
+		if names[t.Name] {
+			return nil, fmt.Errorf("duplicate backup target name %s", t.Name)
+		}
+		names[t.Name] = true
+		if t.Endpoints == "" && t.ServiceName == "" && t.ServiceIP == "" {
+			return nil, fmt.Errorf("backup target %s must contain one of endpoints , serviceName or serviceIP", t.Name)
+		}
+	}
+	return targets, nil
+}
+
+// targetParallelism returns the number of targets backed up at the same time , TARGET_PARALLELISM (default 1 i.e sequentially)
+func targetParallelism() (int, error) {
+	value := strings.TrimSpace(os.Getenv("TARGET_PARALLELISM"))
+	if value == "" {
+		return 1, nil
+	}
+	parallelism, err := strconv.Atoi(value)
+	if err != nil || parallelism < 1 {
+		return 0, fmt.Errorf("invalid TARGET_PARALLELISM %s. It must be a positive number", value)
+	}
+	return parallelism, nil
+}
+
+// location returns the directory holding the artifacts , status and upload state of the target
+func (t target) location() string {
+	return filepath.Join(neo4jAdmin.BackupLocation(), t.Name)
+}
+
+// env returns the env variables of the job overridden by the settings of the target
+func (t target) env() []string {
+	overrides := map[string]string{
+		"BACKUP_TARGETS": "",
+		"LOCATION":       t.location(),
+		"STATUS_FILE":    filepath.Join(t.location(), ".status.json"),
+		"KEY_PREFIX":     t.KeyPrefix,
+		// the job reports the result of every target to kubernetes once all the targets are backed up
+		"KUBERNETES_EVENTS_ENABLED": "false",
+		"STATUS_CONFIGMAP":          "",
+		logging.TargetEnv:           t.Name,
+	}
+	if t.KeyPrefix == "" {
+		overrides["KEY_PREFIX"] = t.Name
+	}
+	// the address of the target replaces the address of the job as a whole , see generateAddress
+	overrides["DATABASE_BACKUP_ENDPOINTS"] = t.Endpoints
+	overrides["DATABASE_SERVICE_IP"] = t.ServiceIP
+	overrides["DATABASE_SERVICE_NAME"] = t.ServiceName
+	setIfPresent(overrides, "DATABASE_NAMESPACE", t.Namespace)
+	setIfPresent(overrides, "DATABASE_BACKUP_PORT", t.BackupPort)
+	setIfPresent(overrides, "DATABASE", t.Database)
+	if t.Database != "" {
+		// the consistency check databases of the job rarely exist in another deployment
+		overrides["CONSISTENCY_CHECK_DATABASE"] = t.Database
+	}
+	if strings.TrimSpace(os.Getenv("UPLOAD_STATE_FILE")) != "" {
+		overrides["UPLOAD_STATE_FILE"] = filepath.Join(t.location(), ".upload-state.json")
+	}
+	if c := t.ConsistencyCheck; c != nil {
+		setBoolIfPresent(overrides, "CONSISTENCY_CHECK_ENABLE", c.Enable)
+		setIfPresent(overrides, "CONSISTENCY_CHECK_DATABASE", c.Database)
+		setBoolIfPresent(overrides, "CONSISTENCY_CHECK_INDEXES", c.CheckIndexes)
+		setBoolIfPresent(overrides, "CONSISTENCY_CHECK_GRAPH", c.CheckGraph)
+		setBoolIfPresent(overrides, "CONSISTENCY_CHECK_COUNTS", c.CheckCounts)
+		setBoolIfPresent(overrides, "CONSISTENCY_CHECK_PROPERTYOWNERS", c.CheckPropertyOwners)
+		setIfPresent(overrides, "CONSISTENCY_CHECK_MAXOFFHEAPMEMORY", c.MaxOffHeapMemory)
+		setIfPresent(overrides, "CONSISTENCY_CHECK_THREADS", c.Threads)
+		setBoolIfPresent(overrides, "CONSISTENCY_CHECK_VERBOSE", c.Verbose)
+	}
+
+	var env []string
+	for _, variable := range os.Environ() {
+		name, _, _ := strings.Cut(variable, "=")
+		if _, present := overrides[name]; !present {
+			env = append(env, variable)
+		}
+	}
+	for name, value := range overrides {
+		env = append(env, fmt.Sprintf("%s=%s", name, value))
+	}
+	return env
+}
+
+// address returns the backup address of the target , see generateAddress
+func (t target) address() (string, error) {
+	env := make(map[string]string)
+	for _, variable := range t.env() {
+		name, value, _ := strings.Cut(variable, "=")
+		env[name] = value
+	}
+	return addressFromEnv(func(name string) string { return env[name] })
+}
+
+func setIfPresent(env map[string]string, name string, value string) {
+	if strings.TrimSpace(value) != "" {
+		env[name] = strings.TrimSpace(value)
+	}
+}
+
+func setBoolIfPresent(env map[string]string, name string, value *bool) {
+	if value != nil {
+		env[name] = strconv.FormatBool(*value)
+	}
+}
+
+// targetOperations backs up every target of BACKUP_TARGETS , TARGET_PARALLELISM targets at a time
+// A failed target does not stop the remaining targets. The job fails with the exit code of the first failed target
+func targetOperations(run *status.Run) error {
+	targets, err := parseTargets(os.Getenv("BACKUP_TARGETS"))
+	if err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+	parallelism, err := targetParallelism()
+	if err != nil {
+		err = withExitCode(exitConfiguration, err)
+		run.Finish(status.Failed, err)
+		return err
+	}
+
+	log.Printf("Backing up %d target(s) , %d at a time", len(targets), parallelism)
+	results := backupTargets(targets, parallelism)
+	for _, result := range results {
+		run.AddTarget(result)
+	}
+	runStatus, err := evaluateTargetResults(results)
+	run.Finish(runStatus, err)
+	return err
+}
+
+// backupTargets runs the targets with bounded parallelism and returns their results in the order of the targets
+func backupTargets(targets []target, parallelism int) []status.Target {
+	results := make([]status.Target, len(targets))
+	semaphore := make(chan struct{}, parallelism)
+	var output sync.Mutex
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		semaphore <- struct{}{}
+		go func(i int, t target) {
+			defer wg.Done()
+			defer func() { <-semaphore }()
+			results[i] = backupTarget(t, &output)
+		}(i, t)
+	}
+	wg.Wait()
+	return results
+}
+
+// backupTarget runs the backup of a single target and reads the status written by it
+// The output of the target is prefixed with the target name so that the logs of parallel targets can be told apart
+func backupTarget(t target, output *sync.Mutex) status.Target {
+	result := status.Target{Name: t.Name, Status: status.Success}
+	fail := func(code int, err error) status.Target {
+		result.Status = status.Failed
+		result.ExitCode = code
+		result.Error = err.Error()
+		log.Printf("Backup of target %s failed (exit code %d): %v", t.Name, code, err)
+		return result
+	}
+
+	statusFile := filepath.Join(t.location(), ".status.json")
+	if err := os.MkdirAll(t.location(), 0755); err != nil {
+		return fail(exitConfiguration, fmt.Errorf("unable to create directory %s for target %s \n %v", t.location(), t.Name, err))
+	}
+	if err := os.Remove(statusFile); err != nil && !errors.Is(err, os.ErrNotExist) {
+		log.Printf("Warning: unable to delete the status of the previous run of target %s: %v", t.Name, err)
+	}
+	cmd, err := targetCommand()
+	if err != nil {
+		return fail(exitFailure, err)
+	}
+	// the targets run concurrently , the run of the target joins the trace through TRACEPARENT
+	span := tracing.StartDetached("backup target", tracing.Target.String(t.Name))
+	defer func() {
+		span.SetAttributes(tracing.Status.String(result.Status), tracing.ExitCode.Int(result.ExitCode))
+		if result.Error != "" {
+			span.End(errors.New(result.Error))
+			return
+		}
+		span.End(nil)
+	}()
+	cmd.Env = append(t.env(), span.Environment()...)
+	prefix := fmt.Sprintf("[%s] ", t.Name)
+	if logging.Format() == logging.FormatJSON {
+		// the records of the target carry its name , a prefix would break the json lines
+		prefix = ""
+	}
+	stdout := &prefixWriter{prefix: prefix, out: os.Stdout, lock: output}
+	stderr := &prefixWriter{prefix: prefix, out: os.Stderr, lock: output}
+	cmd.Stdout, cmd.Stderr = stdout, stderr
+
+	log.Printf("Starting backup of target %s", t.Name)
+	err = cmd.Run()
+	stdout.Flush()
+	stderr.Flush()
+
+	if data, readErr := os.ReadFile(statusFile); readErr == nil {
+		var targetRun status.Run
+		if jsonErr := json.Unmarshal(data, &targetRun); jsonErr == nil {
+			result.Run = &targetRun
+		}
+	}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			if result.Run != nil && result.Run.Error != "" {
+				return fail(exitErr.ExitCode(), errors.New(result.Run.Error))
+			}
+			return fail(exitErr.ExitCode(), err)
+		}
+		return fail(exitFailure, fmt.Errorf("unable to run the backup of target %s \n %v", t.Name, err))
+	}
+	if result.Run != nil && result.Run.Status != "" {
+		result.Status = result.Run.Status
+	}
+	log.Printf("Backup of target %s completed with status %s", t.Name, result.Status)
+	return result
+}
+
+// evaluateTargetResults returns the overall status of the targets along with an error if any of them failed
+func evaluateTargetResults(results []status.Target) (string, error) {
+	var failed []string
+	code := exitSuccess
+	for _, result := range results {
+		if result.Status == status.Failed {
+			failed = append(failed, result.Name)
+			if code == exitSuccess {
+				code = result.ExitCode
+			}
+		}
+	}
+	if code == exitSuccess {
+		code = exitFailure
+	}
+	switch {
+	case len(failed) == 0:
+		for _, result := range results {
+			if result.Status == status.Partial {
+				return status.Partial, nil
+			}
+		}
+		return status.Success, nil
+	case len(failed) == len(results):
+		return status.Failed, withExitCode(code, fmt.Errorf("backup failed for all the targets %v", failed))
+	default:
+		return status.Partial, withExitCode(code, fmt.Errorf("backup failed for target(s) %v", failed))
+	}
+}
+
+// prefixWriter writes every complete line with the given prefix , the lines of the writers sharing the lock are not interleaved
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	lock   *sync.Mutex
+	buffer bytes.Buffer
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buffer.Write(p)
+	for {
+		line, err := w.buffer.ReadBytes('\n')
+		if err != nil {
+			// keep the incomplete line until the rest of it is written
+			w.buffer.Write(line)
+			return len(p), nil
+		}
+		w.writeLine(line)
+	}
+}
+
+// Flush writes the incomplete line left in the buffer (if any)
+func (w *prefixWriter) Flush() {
+	if w.buffer.Len() > 0 {
+		line := append(w.buffer.Bytes(), '\n')
+		w.buffer.Reset()
+		w.writeLine(line)
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	fmt.Fprintf(w.out, "%s%s", w.prefix, line)
+}
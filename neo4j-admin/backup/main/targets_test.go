@@ -0,0 +1,157 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/status"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTargetHelperProcess is executed as the backup of a target by TestTargetOperations
+// It records the env of the target in the status file and fails for the database named "broken"
+func TestTargetHelperProcess(t *testing.T) {
+	if os.Getenv("TARGET_HELPER_PROCESS") != "true" {
+		return
+	}
+	run := status.NewRun()
+	run.BackupFiles = []string{os.Getenv("DATABASE"), os.Getenv("KEY_PREFIX"), os.Getenv("CONSISTENCY_CHECK_ENABLE")}
+	code := exitSuccess
+	if os.Getenv("DATABASE") == "broken" {
+		code = exitBackup
+		run.Finish(status.Failed, assert.AnError)
+	} else {
+		run.Finish(status.Success, nil)
+	}
+	os.Stderr.WriteString("backup of " + os.Getenv("DATABASE") + " done\n")
+	_ = run.Write(os.Getenv("STATUS_FILE"))
+	os.Exit(code)
+}
+
+func TestParseTargets(t *testing.T) {
+	t.Parallel()
+
+	targets, err := parseTargets(`[{"name":"sales","serviceName":"sales-admin","namespace":"sales","database":"neo4j,orders","consistencyCheck":{"enable":true}},{"name":"hr","endpoints":"hr-0:6362,hr-1:6362"}]`)
+	assert.NoError(t, err)
+	assert.Len(t, targets, 2)
+	assert.Equal(t, "sales-admin", targets[0].ServiceName)
+	assert.True(t, *targets[0].ConsistencyCheck.Enable)
+	assert.Equal(t, "hr-0:6362,hr-1:6362", targets[1].Endpoints)
+
+	invalid := []string{
+		`[]`,
+		`not json`,
+		`[{"name":"sales"}]`,
+		`[{"name":"../sales","serviceName":"sales-admin"}]`,
+		`[{"name":"sales","serviceName":"a"},{"name":"sales","serviceName":"b"}]`,
+	}
+	for _, value := range invalid {
+		_, err = parseTargets(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestTargetEnv(t *testing.T) {
+	t.Setenv("LOCATION", "/backups")
+	t.Setenv("DATABASE_SERVICE_NAME", "job-admin")
+	t.Setenv("DATABASE_NAMESPACE", "default")
+	t.Setenv("DATABASE", "*")
+	t.Setenv("CONSISTENCY_CHECK_ENABLE", "false")
+	t.Setenv("CONSISTENCY_CHECK_THREADS", "4")
+	t.Setenv("UPLOAD_STATE_FILE", "/backups/.upload-state.json")
+
+	enable := true
+	tg := target{
+		Name:             "sales",
+		Endpoints:        "sales-0:6362",
+		Database:         "orders",
+		ConsistencyCheck: &targetConsistencyCheck{Enable: &enable},
+	}
+	env := make(map[string]string)
+	for _, variable := range tg.env() {
+		name, value, _ := strings.Cut(variable, "=")
+		env[name] = value
+	}
+	assert.Equal(t, "sales-0:6362", env["DATABASE_BACKUP_ENDPOINTS"])
+	assert.Equal(t, "", env["DATABASE_SERVICE_NAME"])
+	assert.Equal(t, "default", env["DATABASE_NAMESPACE"])
+	assert.Equal(t, "orders", env["DATABASE"])
+	assert.Equal(t, "orders", env["CONSISTENCY_CHECK_DATABASE"])
+	assert.Equal(t, "true", env["CONSISTENCY_CHECK_ENABLE"])
+	assert.Equal(t, "4", env["CONSISTENCY_CHECK_THREADS"])
+	assert.Equal(t, "sales", env["KEY_PREFIX"])
+	assert.Equal(t, "/backups/sales", env["LOCATION"])
+	assert.Equal(t, "/backups/sales/.status.json", env["STATUS_FILE"])
+	assert.Equal(t, "/backups/sales/.upload-state.json", env["UPLOAD_STATE_FILE"])
+	assert.Equal(t, "", env["BACKUP_TARGETS"])
+}
+
+func TestTargetOperations(t *testing.T) {
+	location := t.TempDir()
+	t.Setenv("LOCATION", location)
+	t.Setenv("TARGET_HELPER_PROCESS", "true")
+	t.Setenv("TARGET_PARALLELISM", "2")
+	t.Setenv("BACKUP_TARGETS", `[{"name":"sales","endpoints":"sales:6362","database":"orders","consistencyCheck":{"enable":true}},{"name":"hr","endpoints":"hr:6362","database":"broken"},{"name":"ops","endpoints":"ops:6362","database":"neo4j","keyPrefix":"team/ops"}]`)
+	original := targetCommand
+	targetCommand = func() (*exec.Cmd, error) {
+		return exec.Command(os.Args[0], "-test.run=^TestTargetHelperProcess$"), nil
+	}
+	defer func() { targetCommand = original }()
+
+	run := status.NewRun()
+	err := targetOperations(run)
+	assert.Error(t, err)
+	assert.Equal(t, exitBackup, exitCode(err))
+	assert.Equal(t, status.Partial, run.Status)
+
+	assert.Len(t, run.Targets, 3)
+	sales, hr, ops := run.Targets[0], run.Targets[1], run.Targets[2]
+	assert.Equal(t, "sales", sales.Name)
+	assert.Equal(t, status.Success, sales.Status)
+	if assert.NotNil(t, sales.Run) {
+		assert.Equal(t, []string{"orders", "sales", "true"}, sales.Run.BackupFiles)
+	}
+	assert.Equal(t, status.Failed, hr.Status)
+	assert.Equal(t, exitBackup, hr.ExitCode)
+	assert.Equal(t, assert.AnError.Error(), hr.Error)
+	if assert.NotNil(t, ops.Run) {
+		assert.Equal(t, "team/ops", ops.Run.BackupFiles[1])
+	}
+	assert.FileExists(t, filepath.Join(location, "ops", ".status.json"))
+
+	data, err := run.JSON()
+	assert.NoError(t, err)
+	var combined map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &combined))
+	assert.Len(t, combined["targets"], 3)
+}
+
+func TestEvaluateTargetResults(t *testing.T) {
+	t.Parallel()
+
+	runStatus, err := evaluateTargetResults([]status.Target{{Name: "a", Status: status.Success}, {Name: "b", Status: status.Partial}})
+	assert.NoError(t, err)
+	assert.Equal(t, status.Partial, runStatus)
+
+	runStatus, err = evaluateTargetResults([]status.Target{{Name: "a", Status: status.Failed, ExitCode: exitConnectivity}, {Name: "b", Status: status.Failed, ExitCode: exitBackup}})
+	assert.Error(t, err)
+	assert.Equal(t, status.Failed, runStatus)
+	assert.Equal(t, exitConnectivity, exitCode(err))
+}
+
+func TestPrefixWriter(t *testing.T) {
+	t.Parallel()
+
+	var out bytes.Buffer
+	w := &prefixWriter{prefix: "[sales] ", out: &out, lock: &sync.Mutex{}}
+	w.Write([]byte("first line\nsecond "))
+	w.Write([]byte("line\nincomplete"))
+	w.Flush()
+	assert.Equal(t, "[sales] first line\n[sales] second line\n[sales] incomplete\n", out.String())
+}
@@ -0,0 +1,228 @@
+// Package memory provides an in-memory storage client used as a backup destination in tests
+package memory
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// object is a single object stored in a bucket
+type object struct {
+	content      []byte
+	lastModified time.Time
+	metadata     map[string]string
+}
+
+// Storage keeps the objects of every bucket in memory. It implements common.StorageClient
+type Storage struct {
+	lock    sync.Mutex
+	buckets map[string]map[string]object
+	// failures contains the error returned by the operations on a bucket
+	failures map[string]error
+	// lifecycles contains the lifecycle policy applied to every bucket name
+	lifecycles map[string]common.LifecyclePolicy
+}
+
+// NewStorage returns a storage containing the given empty buckets
+func NewStorage(buckets ...string) *Storage {
+	s := &Storage{
+		buckets:    make(map[string]map[string]object),
+		failures:   make(map[string]error),
+		lifecycles: make(map[string]common.LifecyclePolicy),
+	}
+	for _, bucket := range buckets {
+		s.buckets[bucket] = make(map[string]object)
+	}
+	return s
+}
+
+// Fail makes every operation on the given bucket return the error , a nil error restores the bucket
+func (s *Storage) Fail(bucket string, err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if err == nil {
+		delete(s.failures, bucket)
+		return
+	}
+	s.failures[bucket] = err
+}
+
+// Keys returns the keys of the objects present in the bucket in lexical order
+func (s *Storage) Keys(bucket string) []string {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	var keys []string
+	for key := range s.buckets[bucket] {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Content returns the content of the object with the given key
+func (s *Storage) Content(bucket string, key string) ([]byte, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	o, present := s.buckets[bucket][key]
+	return o.content, present
+}
+
+// bucket returns the objects of the parent bucket of the given bucket name along with the prefix
+// must be called while holding the lock
+func (s *Storage) bucket(bucketName string) (map[string]object, string, error) {
+	parent, prefix := common.SplitBucketName(bucketName)
+	if err := s.failures[parent]; err != nil {
+		return nil, "", err
+	}
+	objects, present := s.buckets[parent]
+	if !present {
+		return nil, "", fmt.Errorf("bucket %s does not exist", parent)
+	}
+	return objects, prefix, nil
+}
+
+// CheckBucketAccess checks if the given bucket exists
+func (s *Storage) CheckBucketAccess(bucketName string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	_, _, err := s.bucket(bucketName)
+	return err
+}
+
+// CreateBucket creates the parent bucket of the given bucket name if it does not exist
+func (s *Storage) CreateBucket(bucketName string) (bool, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	parent, _ := common.SplitBucketName(bucketName)
+	if err := s.failures[parent]; err != nil {
+		return false, err
+	}
+	if _, present := s.buckets[parent]; present {
+		return false, nil
+	}
+	s.buckets[parent] = make(map[string]object)
+	return true, nil
+}
+
+// ApplyLifecycle records the lifecycle policy of the given bucket name
+func (s *Storage) ApplyLifecycle(bucketName string, policy common.LifecyclePolicy) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if _, _, err := s.bucket(bucketName); err != nil {
+		return err
+	}
+	s.lifecycles[bucketName] = policy
+	return nil
+}
+
+// Lifecycle returns the lifecycle policy applied to the given bucket name
+func (s *Storage) Lifecycle(bucketName string) (common.LifecyclePolicy, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	policy, present := s.lifecycles[bucketName]
+	return policy, present
+}
+
+// UploadFile uploads the files present at LOCATION to the given bucket
+func (s *Storage) UploadFile(fileNames []string, bucketName string) error {
+	for _, fileName := range fileNames {
+		if err := s.uploadFile(fileName, bucketName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Storage) uploadFile(fileName string, bucketName string) error {
+	file, err := common.OpenUploadFile(fmt.Sprintf("%s/%s", os.Getenv("LOCATION"), fileName), bucketName)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return fmt.Errorf("Couldn't read file %v to upload. Here's why: %v\n", fileName, err)
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	objects, prefix, err := s.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	objects[joinKey(prefix, fileName)] = object{
+		content:      content,
+		lastModified: time.Now().UTC(),
+		metadata:     common.ArtifactMetadata(fileName),
+	}
+	return nil
+}
+
+// ListObjects returns all the objects present below the given bucket name and prefix
+func (s *Storage) ListObjects(bucketName string) ([]common.ObjectInfo, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	objects, prefix, err := s.bucket(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	var infos []common.ObjectInfo
+	for key, o := range objects {
+		if prefix != "" && !strings.HasPrefix(key, strings.TrimSuffix(prefix, "/")+"/") {
+			continue
+		}
+		infos = append(infos, common.ObjectInfo{
+			Key:          key,
+			Size:         int64(len(o.content)),
+			LastModified: o.lastModified,
+			Metadata:     o.metadata,
+		})
+	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Key < infos[j].Key })
+	return infos, nil
+}
+
+// DownloadFile writes the content of the object with the given key to the given file path
+func (s *Storage) DownloadFile(bucketName string, key string, filePath string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	objects, _, err := s.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	o, present := objects[key]
+	if !present {
+		return fmt.Errorf("object %s does not exist in bucket %s", key, bucketName)
+	}
+	if err = os.WriteFile(filePath, o.content, 0644); err != nil {
+		return fmt.Errorf("Couldn't create file %v. Here's why: %v\n", filePath, err)
+	}
+	return nil
+}
+
+// DeleteObject deletes the object with the given key
+func (s *Storage) DeleteObject(bucketName string, key string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	objects, _, err := s.bucket(bucketName)
+	if err != nil {
+		return err
+	}
+	delete(objects, key)
+	return nil
+}
+
+func joinKey(prefix string, fileName string) string {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return fileName
+	}
+	return fmt.Sprintf("%s/%s", prefix, fileName)
+}
@@ -0,0 +1,139 @@
+package neo4j_admin
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const defaultDataAdminConfigPath = "/tmp/neo4j-admin/neo4j-admin-data.conf"
+
+// ConfigureDataDirectory generates a neo4j-admin.conf pointing server.directories.data to DATA_DIRECTORY
+// It allows dumping , loading and importing the databases of a stopped server whose data volume is mounted in the backup pod
+// The generated config is passed to neo4j-admin via NEO4J_ADMIN_ADDITIONAL_CONFIG , nothing is done when DATA_DIRECTORY is not set
+// The settings of the config previously passed via NEO4J_ADMIN_ADDITIONAL_CONFIG (ex: the backup ssl policy) are merged into it
+func ConfigureDataDirectory() error {
+	dataDirectory := strings.TrimSpace(os.Getenv("DATA_DIRECTORY"))
+	if dataDirectory == "" {
+		return nil
+	}
+	if info, err := os.Stat(dataDirectory); err != nil || !info.IsDir() {
+		return fmt.Errorf("data directory %s is not mounted. Mount the data volume of the stopped Neo4j server \n %v", dataDirectory, err)
+	}
+	configPath := os.Getenv("NEO4J_ADMIN_DATA_CONF_PATH")
+	if configPath == "" {
+		configPath = defaultDataAdminConfigPath
+	}
+	if err := os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("unable to create directory for %s \n %v", configPath, err)
+	}
+	var lines []string
+	if existingPath := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); existingPath != "" {
+		existing, err := os.ReadFile(existingPath)
+		if err != nil {
+			return fmt.Errorf("unable to read neo4j-admin config %s \n %v", existingPath, err)
+		}
+		// the data directory of a previous call is replaced
+		for _, line := range strings.Split(string(existing), "\n") {
+			key, _, _ := strings.Cut(line, "=")
+			if strings.TrimSpace(line) != "" && strings.TrimSpace(key) != "server.directories.data" {
+				lines = append(lines, line)
+			}
+		}
+	}
+	lines = append(lines, fmt.Sprintf("server.directories.data=%s", dataDirectory))
+	if err := os.WriteFile(configPath, []byte(strings.Join(lines, "\n")+"\n"), 0600); err != nil {
+		return fmt.Errorf("unable to write neo4j-admin config %s \n %v", configPath, err)
+	}
+	log.Printf("Generated neo4j-admin config %s with data directory %s", configPath, dataDirectory)
+	return os.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", configPath)
+}
+
+// PerformDump dumps every database of DATABASE and returns the generated dump file names
+// neo4j-admin writes <database>.dump , which is renamed to <database>-<timestamp>.dump at the backup location
+// so that the dumps of successive runs do not overwrite each other in the bucket
+// The databases must be offline , neo4j-admin fails to dump a database in use by a running server
+func PerformDump() ([]string, error) {
+	stagingPath := filepath.Join(BackupLocation(), ".dump")
+	if err := os.RemoveAll(stagingPath); err != nil {
+		return nil, fmt.Errorf("unable to clean up directory %s \n %v", stagingPath, err)
+	}
+	if err := os.MkdirAll(stagingPath, 0755); err != nil {
+		return nil, fmt.Errorf("unable to create directory %s \n %v", stagingPath, err)
+	}
+	defer os.RemoveAll(stagingPath)
+
+	var dumpFileNames []string
+	for _, database := range strings.Split(os.Getenv("DATABASE"), ",") {
+		database = strings.TrimSpace(database)
+		if database == "" {
+			continue
+		}
+		flags := getDumpCommandFlags(database, stagingPath)
+		log.Printf("Printing dump flags %v", flags)
+		output, err := runCommand("neo4j-admin", flags...)
+		if err != nil {
+			return nil, fmt.Errorf("Dump Failed for database %s !! output = %s \n err = %v", database, string(output), err)
+		}
+		log.Printf("Dump Completed for database %s !!", database)
+	}
+
+	// a database name containing * or ? dumps every matching database
+	dumpFiles, _ := filepath.Glob(filepath.Join(stagingPath, "*.dump"))
+	if len(dumpFiles) == 0 {
+		return nil, fmt.Errorf("no dump file found at %s for database(s) %s", stagingPath, os.Getenv("DATABASE"))
+	}
+	timeStamp := time.Now().UTC().Format("2006-01-02T15-04-05")
+	for _, dumpFile := range dumpFiles {
+		fileName := fmt.Sprintf("%s-%s.dump", strings.TrimSuffix(filepath.Base(dumpFile), ".dump"), timeStamp)
+		if err := os.Rename(dumpFile, filepath.Join(BackupLocation(), fileName)); err != nil {
+			return nil, fmt.Errorf("unable to move dump %s to %s \n %v", dumpFile, BackupLocation(), err)
+		}
+		dumpFileNames = append(dumpFileNames, fileName)
+	}
+	return dumpFileNames, nil
+}
+
+// PerformLoad loads the dump of the database present at fromPath/<database>.dump
+func PerformLoad(fromPath string, database string, overwriteDestination bool) error {
+	flags := getLoadCommandFlags(fromPath, database, overwriteDestination)
+	log.Printf("Printing load flags %v", flags)
+	output, err := runCommand("neo4j-admin", flags...)
+	if err != nil {
+		return fmt.Errorf("Load Failed for database %s !! output = %s \n err = %v", database, string(output), err)
+	}
+	log.Printf("Load Completed for database %s !!", database)
+	log.Printf("%s", string(output))
+	return nil
+}
+
+// getDumpCommandFlags returns the flags of the neo4j-admin dump command writing the dump of the database to the given path
+func getDumpCommandFlags(database string, toPath string) []string {
+	flags := []string{"database", "dump"}
+	flags = append(flags, fmt.Sprintf("--to-path=%s", toPath))
+	flags = append(flags, "--overwrite-destination=true")
+	if os.Getenv("VERBOSE") == "true" {
+		flags = append(flags, "--verbose")
+	}
+	if additionalConfig := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); len(additionalConfig) > 0 {
+		flags = append(flags, fmt.Sprintf("--additional-config=%s", additionalConfig))
+	}
+	return append(flags, database)
+}
+
+// getLoadCommandFlags returns the flags of the neo4j-admin load command
+func getLoadCommandFlags(fromPath string, database string, overwriteDestination bool) []string {
+	flags := []string{"database", "load"}
+	flags = append(flags, fmt.Sprintf("--from-path=%s", fromPath))
+	flags = append(flags, fmt.Sprintf("--overwrite-destination=%t", overwriteDestination))
+	if os.Getenv("VERBOSE") == "true" {
+		flags = append(flags, "--verbose")
+	}
+	if additionalConfig := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); len(additionalConfig) > 0 {
+		flags = append(flags, fmt.Sprintf("--additional-config=%s", additionalConfig))
+	}
+	return append(flags, database)
+}
@@ -0,0 +1,46 @@
+package neo4j_admin
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfigureDataDirectory(t *testing.T) {
+	dataDirectory := t.TempDir()
+	sslConfigPath := filepath.Join(t.TempDir(), "neo4j-admin.conf")
+	assert.NoError(t, os.WriteFile(sslConfigPath, []byte("dbms.ssl.policy.backup.enabled=true\nserver.directories.data=/data\n"), 0600))
+	configPath := filepath.Join(t.TempDir(), "conf", "neo4j-admin-data.conf")
+	t.Setenv("DATA_DIRECTORY", dataDirectory)
+	t.Setenv("NEO4J_ADMIN_DATA_CONF_PATH", configPath)
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", sslConfigPath)
+
+	// the settings already passed to neo4j-admin are kept
+	assert.NoError(t, ConfigureDataDirectory())
+	assert.Equal(t, configPath, os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG"))
+	config, err := os.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "dbms.ssl.policy.backup.enabled=true\nserver.directories.data="+dataDirectory+"\n", string(config))
+
+	// a second call replaces the data directory of the generated config
+	otherDirectory := t.TempDir()
+	t.Setenv("DATA_DIRECTORY", otherDirectory)
+	assert.NoError(t, ConfigureDataDirectory())
+	config, err = os.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "dbms.ssl.policy.backup.enabled=true\nserver.directories.data="+otherDirectory+"\n", string(config))
+
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "")
+	assert.NoError(t, ConfigureDataDirectory())
+	config, err = os.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "server.directories.data="+otherDirectory+"\n", string(config))
+
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", filepath.Join(t.TempDir(), "missing.conf"))
+	assert.ErrorContains(t, ConfigureDataDirectory(), "unable to read neo4j-admin config")
+
+	t.Setenv("DATA_DIRECTORY", filepath.Join(dataDirectory, "missing"))
+	assert.ErrorContains(t, ConfigureDataDirectory(), "is not mounted")
+}
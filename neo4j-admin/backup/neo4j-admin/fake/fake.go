@@ -0,0 +1,615 @@
+// Package fake provides a scriptable replacement of the neo4j-admin , nc and tar commands
+// so that the backup operations can be tested without a running Neo4j
+package fake
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/utils/strings/slices"
+)
+
+// ExitError is returned for a command exiting with a non zero exit code , like *exec.ExitError
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit code of the command
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+// artifact is a backup artifact created by the fake neo4j-admin
+type artifact struct {
+	database   string
+	full       bool
+	lowestTx   int
+	highestTx  int
+	backupTime time.Time
+}
+
+// Neo4jAdmin emulates neo4j-admin , nc and tar. It implements neo4j_admin.CommandRunner
+// Backups create artifact files at --to-path , the first backup of a database (or a backup of type FULL) is a full backup ,
+// the following ones are differential backups
+type Neo4jAdmin struct {
+	// Databases are the databases of the deployment , "*" is expanded to them. Defaults to neo4j and system
+	Databases []string
+	// Unreachable contains the addresses (host:port) nc fails to connect to
+	Unreachable []string
+	// FailBackup contains the databases whose backup (or dump) fails
+	FailBackup []string
+	// InUse contains the databases in use by a running server , their dump and load fail
+	InUse []string
+	// Inconsistent contains the databases whose consistency check finds inconsistencies
+	Inconsistent []string
+	// CrashCheck contains the databases whose consistency check exits without writing a report
+	CrashCheck []string
+	// FailAggregate makes the aggregate backup fail
+	FailAggregate bool
+	// Now returns the time of the artifacts , defaults to time.Now
+	Now func() time.Time
+	// Version is printed by neo4j-admin --version , defaults to 5.20.0
+	Version string
+
+	lock      sync.Mutex
+	commands  [][]string
+	artifacts map[string]artifact
+	restored  map[string][]string
+	loaded    map[string]string
+	imported  map[string][]string
+	// stores contains the format of the databases restored , copied or migrated into the data directory
+	stores map[string]string
+}
+
+// New returns a fake neo4j-admin of a deployment containing the neo4j and system databases
+func New() *Neo4jAdmin {
+	return &Neo4jAdmin{}
+}
+
+// Commands returns every command run so far , the first element of each command is the command name
+func (f *Neo4jAdmin) Commands() [][]string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([][]string{}, f.commands...)
+}
+
+// Restored returns the artifacts restored for the given database
+func (f *Neo4jAdmin) Restored(database string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.restored[database]
+}
+
+// Loaded returns the dump loaded for the given database
+func (f *Neo4jAdmin) Loaded(database string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.loaded[database]
+}
+
+// Run runs the given command
+func (f *Neo4jAdmin) Run(name string, args ...string) ([]byte, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.commands = append(f.commands, append([]string{name}, args...))
+	if f.artifacts == nil {
+		f.artifacts = make(map[string]artifact)
+		f.restored = make(map[string][]string)
+		f.loaded = make(map[string]string)
+		f.imported = make(map[string][]string)
+		f.stores = make(map[string]string)
+	}
+
+	switch name {
+	case "nc":
+		return f.connect(args)
+	case "tar":
+		return f.tar(args)
+	case "neo4j-admin":
+		if len(args) == 1 && args[0] == "--version" {
+			if f.Version == "" {
+				return []byte("5.20.0\n"), nil
+			}
+			return []byte(f.Version + "\n"), nil
+		}
+		flags, positional := parseArgs(args)
+		if len(positional) < 2 || positional[0] != "database" {
+			return []byte(fmt.Sprintf("Unmatched arguments from index 0: %s", strings.Join(args, " "))), &ExitError{Code: 2}
+		}
+		databases := positional[2:]
+		switch positional[1] {
+		case "backup":
+			if inspectPath, present := flags["inspect-path"]; present {
+				return f.inspect(inspectPath)
+			}
+			return f.backup(flags, databases)
+		case "check":
+			return f.check(flags, databases)
+		case "aggregate-backup":
+			return f.aggregate(flags, databases)
+		case "restore":
+			return f.restore(flags, databases)
+		case "dump":
+			return f.dump(flags, databases)
+		case "load":
+			return f.load(flags, databases)
+		case "import":
+			return f.importCSV(args, flags, positional[2:])
+		case "copy":
+			return f.copy(flags, databases)
+		case "migrate":
+			return f.migrate(flags, databases)
+		}
+	}
+	return []byte(fmt.Sprintf("%s: command not found", name)), &ExitError{Code: 127}
+}
+
+func (f *Neo4jAdmin) now() time.Time {
+	if f.Now != nil {
+		return f.Now()
+	}
+	return time.Now()
+}
+
+func (f *Neo4jAdmin) expand(databases []string) []string {
+	var expanded []string
+	for _, database := range databases {
+		if database != "*" {
+			expanded = append(expanded, database)
+			continue
+		}
+		if len(f.Databases) == 0 {
+			expanded = append(expanded, "neo4j", "system")
+		} else {
+			expanded = append(expanded, f.Databases...)
+		}
+	}
+	return expanded
+}
+
+// connect emulates nc -vz host port
+func (f *Neo4jAdmin) connect(args []string) ([]byte, error) {
+	if len(args) != 3 {
+		return []byte("usage: nc [-vz] host port"), &ExitError{Code: 1}
+	}
+	host, port := args[1], args[2]
+	if slices.Contains(f.Unreachable, fmt.Sprintf("%s:%s", host, port)) {
+		return []byte(fmt.Sprintf("nc: connect to %s port %s (tcp) failed: Connection refused", host, port)), &ExitError{Code: 1}
+	}
+	return []byte(fmt.Sprintf("Connection to %s %s port [tcp/*] succeeded!", host, port)), nil
+}
+
+// backup emulates neo4j-admin database backup creating one artifact per database at --to-path
+func (f *Neo4jAdmin) backup(flags map[string]string, databases []string) ([]byte, error) {
+	toPath := flags["to-path"]
+	var output strings.Builder
+	for _, database := range f.expand(databases) {
+		if slices.Contains(f.FailBackup, database) {
+			fmt.Fprintf(&output, "Backup command failed for database '%s': Unable to connect to the backup service\n", database)
+			return []byte(output.String()), &ExitError{Code: 1}
+		}
+		backupTime := f.now().UTC()
+		fileName := fmt.Sprintf("%s-%s.backup", database, backupTime.Format("2006-01-02T15-04-05"))
+		a := artifact{database: database, full: true, lowestTx: 1, highestTx: 3, backupTime: backupTime}
+		if previous, found := f.latest(toPath, database); found && !strings.EqualFold(flags["type"], "FULL") {
+			a.full = false
+			a.lowestTx, a.highestTx = previous.highestTx+1, previous.highestTx+3
+		}
+		filePath := filepath.Join(toPath, fileName)
+		if err := os.WriteFile(filePath, []byte(fmt.Sprintf("fake backup of %s tx %d-%d", database, a.lowestTx, a.highestTx)), 0644); err != nil {
+			fmt.Fprintf(&output, "Backup command failed for database '%s': %v\n", database, err)
+			return []byte(output.String()), &ExitError{Code: 1}
+		}
+		f.artifacts[filePath] = a
+		fmt.Fprintf(&output, "Starting backup of database '%s'\n", database)
+		fmt.Fprintf(&output, "Finished artifact creation '%s' for database '%s', took 121ms.\n", fileName, database)
+	}
+	output.WriteString("Backup command completed.\n")
+	return []byte(output.String()), nil
+}
+
+// latest returns the latest artifact of the database created at the given path
+func (f *Neo4jAdmin) latest(path string, database string) (artifact, bool) {
+	var latest artifact
+	var found bool
+	for filePath, a := range f.artifacts {
+		if filepath.Dir(filePath) != filepath.Clean(path) || a.database != database {
+			continue
+		}
+		if _, err := os.Stat(filePath); err != nil {
+			continue
+		}
+		if !found || a.highestTx > latest.highestTx {
+			latest, found = a, true
+		}
+	}
+	return latest, found
+}
+
+// inspect emulates neo4j-admin database backup --inspect-path printing the table describing the artifact
+func (f *Neo4jAdmin) inspect(inspectPath string) ([]byte, error) {
+	a, present := f.artifacts[inspectPath]
+	if !present {
+		return []byte(fmt.Sprintf("Path '%s' does not contain any backup artifact", inspectPath)), &ExitError{Code: 1}
+	}
+	var output strings.Builder
+	output.WriteString("| FILE | DATABASE | DATABASE ID | TIME (UTC) | FULL | COMPRESSED | LOWEST TX | HIGHEST TX |\n")
+	fmt.Fprintf(&output, "| file://%s | %s | 9fe6e8b8-3c1f-4a6a-bf1b-4b2d1e5c1b55 | %s | %t | true | %d | %d |\n",
+		inspectPath, a.database, a.backupTime.Format("2006-01-02T15:04:05"), a.full, a.lowestTx, a.highestTx)
+	return []byte(output.String()), nil
+}
+
+// check emulates neo4j-admin database check , an inconsistent database gets a report at --report-path and exit code 1
+func (f *Neo4jAdmin) check(flags map[string]string, databases []string) ([]byte, error) {
+	if len(databases) != 1 {
+		return []byte("Missing required parameter: '<database>'"), &ExitError{Code: 2}
+	}
+	database := databases[0]
+	if _, found := f.latest(flags["from-path"], database); !found {
+		return []byte(fmt.Sprintf("No backup of database '%s' found at %s", database, flags["from-path"])), &ExitError{Code: 1}
+	}
+	if slices.Contains(f.CrashCheck, database) {
+		return []byte("java.lang.OutOfMemoryError: Java heap space"), &ExitError{Code: 1}
+	}
+	if !slices.Contains(f.Inconsistent, database) {
+		return []byte(fmt.Sprintf("Consistency check of database '%s' completed , no inconsistencies found", database)), nil
+	}
+	reportPath := flags["report-path"]
+	if err := os.MkdirAll(reportPath, 0755); err != nil {
+		return []byte(err.Error()), &ExitError{Code: 1}
+	}
+	if err := os.WriteFile(filepath.Join(reportPath, "inconsistencies-2024-06-13.report"), []byte(inconsistencyReport), 0644); err != nil {
+		return []byte(err.Error()), &ExitError{Code: 1}
+	}
+	return []byte(fmt.Sprintf("Inconsistencies found: 2 , see %s", reportPath)), &ExitError{Code: 1}
+}
+
+// inconsistencyReport is the report written for an inconsistent database , one node and one index inconsistency
+const inconsistencyReport = `ERROR: The referenced relationship record is not in use.
+    Node[12,used=true,rel=15,prop=-1,labels=Inline(0x1000000001:[1]),light,secondaryUnitId=-1]
+    Inconsistent with: Relationship[15,used=false,source=0,target=0,type=0,sPrev=0,sNext=0,tPrev=0,tNext=0,prop=0,secondaryUnitId=-1]
+ERROR: This index entry refers to a node record that is not in use.
+    IndexEntry[nodeId=42]
+`
+
+// aggregate emulates neo4j-admin database aggregate-backup replacing the chain of every database with a single full artifact
+func (f *Neo4jAdmin) aggregate(flags map[string]string, databases []string) ([]byte, error) {
+	fromPath := flags["from-path"]
+	if f.FailAggregate {
+		return []byte(fmt.Sprintf("Aggregate backup failed for %s", fromPath)), &ExitError{Code: 1}
+	}
+	var expanded []string
+	for _, pattern := range databases {
+		for _, a := range f.artifacts {
+			if matched, _ := filepath.Match(pattern, a.database); matched && !slices.Contains(expanded, a.database) {
+				expanded = append(expanded, a.database)
+			}
+		}
+	}
+	sort.Strings(expanded)
+	var output strings.Builder
+	for _, database := range expanded {
+		latest, found := f.latest(fromPath, database)
+		if !found {
+			continue
+		}
+		if latest.full {
+			fmt.Fprintf(&output, "Backup chain of database '%s' is a single full backup , no need to aggregate\n", database)
+			continue
+		}
+		latest.full, latest.lowestTx, latest.backupTime = true, 1, f.now().UTC()
+		fileName := fmt.Sprintf("%s-%s.backup", database, latest.backupTime.Format("2006-01-02T15-04-05"))
+		filePath := filepath.Join(fromPath, fileName)
+		if err := os.WriteFile(filePath, []byte(fmt.Sprintf("fake aggregated backup of %s", database)), 0644); err != nil {
+			return []byte(err.Error()), &ExitError{Code: 1}
+		}
+		f.artifacts[filePath] = latest
+		fmt.Fprintf(&output, "Successfully aggregated backup chain of database '%s', new artifact: '%s'.\n", database, filePath)
+	}
+	return []byte(output.String()), nil
+}
+
+// restore emulates neo4j-admin database restore recording the restored artifacts
+func (f *Neo4jAdmin) restore(flags map[string]string, databases []string) ([]byte, error) {
+	if len(databases) != 1 {
+		return []byte("Missing required parameter: '<database>'"), &ExitError{Code: 2}
+	}
+	database := databases[0]
+	fromPaths := strings.Split(flags["from-path"], ",")
+	for _, fromPath := range fromPaths {
+		if _, err := os.Stat(fromPath); err != nil {
+			return []byte(fmt.Sprintf("Restore of database '%s' failed: %s does not exist", database, fromPath)), &ExitError{Code: 1}
+		}
+	}
+	f.restored[database] = fromPaths
+	f.stores[database] = "aligned"
+	return []byte(fmt.Sprintf("Restore of database '%s' from %d artifact(s) completed", database, len(fromPaths))), nil
+}
+
+// dump emulates neo4j-admin database dump writing <database>.dump at --to-path for every database matching the given name
+func (f *Neo4jAdmin) dump(flags map[string]string, databases []string) ([]byte, error) {
+	if len(databases) != 1 {
+		return []byte("Missing required parameter: '<database>'"), &ExitError{Code: 2}
+	}
+	var output strings.Builder
+	candidates := f.expand([]string{"*"})
+	for database := range f.stores {
+		if !slices.Contains(candidates, database) {
+			candidates = append(candidates, database)
+		}
+	}
+	for _, database := range candidates {
+		if matched, _ := filepath.Match(databases[0], database); !matched {
+			continue
+		}
+		if slices.Contains(f.InUse, database) {
+			fmt.Fprintf(&output, "The database '%s' is in use. Stop the database before dumping it\n", database)
+			return []byte(output.String()), &ExitError{Code: 1}
+		}
+		if slices.Contains(f.FailBackup, database) {
+			fmt.Fprintf(&output, "Dump of database '%s' failed: the store files are not readable\n", database)
+			return []byte(output.String()), &ExitError{Code: 1}
+		}
+		filePath := filepath.Join(flags["to-path"], database+".dump")
+		if err := os.WriteFile(filePath, []byte(fmt.Sprintf("fake dump of %s", database)), 0644); err != nil {
+			fmt.Fprintf(&output, "Dump of database '%s' failed: %v\n", database, err)
+			return []byte(output.String()), &ExitError{Code: 1}
+		}
+		fmt.Fprintf(&output, "Dumping database '%s' to %s , done\n", database, filePath)
+	}
+	return []byte(output.String()), nil
+}
+
+// load emulates neo4j-admin database load recording the loaded dump
+func (f *Neo4jAdmin) load(flags map[string]string, databases []string) ([]byte, error) {
+	if len(databases) != 1 {
+		return []byte("Missing required parameter: '<database>'"), &ExitError{Code: 2}
+	}
+	database := databases[0]
+	if slices.Contains(f.InUse, database) {
+		return []byte(fmt.Sprintf("The database '%s' is in use. Stop the database before loading it", database)), &ExitError{Code: 1}
+	}
+	filePath := filepath.Join(flags["from-path"], database+".dump")
+	if _, err := os.Stat(filePath); err != nil {
+		return []byte(fmt.Sprintf("Load of database '%s' failed: %s does not exist", database, filePath)), &ExitError{Code: 1}
+	}
+	if _, exists := f.loaded[database]; exists && flags["overwrite-destination"] != "true" {
+		return []byte(fmt.Sprintf("Database '%s' already exists , use --overwrite-destination to replace it", database)), &ExitError{Code: 1}
+	}
+	f.loaded[database] = filePath
+	return []byte(fmt.Sprintf("Load of database '%s' completed", database)), nil
+}
+
+// Store returns the format of the store of the given database present in the data directory , empty if not present
+func (f *Neo4jAdmin) Store(database string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.stores[database]
+}
+
+// copy emulates neo4j-admin database copy creating the target database from the restored source database
+// A source in FailBackup fails to be copied
+func (f *Neo4jAdmin) copy(flags map[string]string, databases []string) ([]byte, error) {
+	if len(databases) != 2 {
+		return []byte("Missing required parameters: '<source>', '<target>'"), &ExitError{Code: 2}
+	}
+	source, target := databases[0], databases[1]
+	format, present := f.stores[source]
+	if !present {
+		return []byte(fmt.Sprintf("Database '%s' does not exist", source)), &ExitError{Code: 1}
+	}
+	if slices.Contains(f.FailBackup, source) {
+		return []byte(fmt.Sprintf("Copy of database '%s' failed: the store is corrupted", source)), &ExitError{Code: 1}
+	}
+	if _, exists := f.stores[target]; exists {
+		return []byte(fmt.Sprintf("Database '%s' already exists", target)), &ExitError{Code: 1}
+	}
+	if toFormat, present := flags["to-format"]; present {
+		format = toFormat
+	}
+	f.stores[target] = format
+	return []byte(fmt.Sprintf("Starting to copy store, output will be saved to: /logs/neo4j-admin-copy.log\nCopy of database '%s' to '%s' completed in 1s 210ms", source, target)), nil
+}
+
+// migrate emulates neo4j-admin database migrate changing the format of the restored database
+func (f *Neo4jAdmin) migrate(flags map[string]string, databases []string) ([]byte, error) {
+	if len(databases) != 1 {
+		return []byte("Missing required parameter: '<database>'"), &ExitError{Code: 2}
+	}
+	database := databases[0]
+	format, present := f.stores[database]
+	if !present {
+		return []byte(fmt.Sprintf("Database '%s' does not exist", database)), &ExitError{Code: 1}
+	}
+	toFormat := flags["to-format"]
+	if toFormat == "" {
+		toFormat = format
+	}
+	if toFormat != "aligned" && toFormat != "standard" && toFormat != "high_limit" && toFormat != "block" {
+		return []byte(fmt.Sprintf("Invalid store format '%s'", toFormat)), &ExitError{Code: 2}
+	}
+	f.stores[database] = toFormat
+	return []byte(fmt.Sprintf("Starting migration for database '%s'\nMigrating from %s to %s , done\nMigration completed in 2s 3ms", database, format, toFormat)), nil
+}
+
+// Imported returns the CSV files imported into the given database
+func (f *Neo4jAdmin) Imported(database string) []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.imported[database]
+}
+
+// importCSV emulates neo4j-admin database import full|incremental counting the rows of the CSV files
+// The first line of every --nodes and --relationships group is the header. A relationship referring to a node id missing from
+// the imported nodes is a bad entry , logged into --report-file with --skip-bad-relationships=true and failing the import otherwise
+func (f *Neo4jAdmin) importCSV(args []string, flags map[string]string, positional []string) ([]byte, error) {
+	if len(positional) != 2 || (positional[0] != "full" && positional[0] != "incremental") {
+		return []byte("Missing required parameter: '<database>'"), &ExitError{Code: 2}
+	}
+	database := positional[1]
+	if slices.Contains(f.InUse, database) {
+		return []byte(fmt.Sprintf("The database '%s' is in use. Stop the database before importing into it", database)), &ExitError{Code: 1}
+	}
+	if positional[0] == "incremental" && flags["force"] != "true" {
+		return []byte("Incremental import needs to be confirmed with --force"), &ExitError{Code: 1}
+	}
+	if _, exists := f.imported[database]; exists && positional[0] == "full" && flags["overwrite-destination"] != "true" {
+		return []byte(fmt.Sprintf("Database '%s' already exists , use --overwrite-destination to replace it", database)), &ExitError{Code: 1}
+	}
+	ids := make(map[string]bool)
+	var nodes, relationships, properties int
+	var badEntries []string
+	var files []string
+	for _, arg := range args {
+		name, value, _ := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if name != "nodes" && name != "relationships" {
+			continue
+		}
+		label, groupFiles, found := strings.Cut(value, "=")
+		if !found {
+			label, groupFiles = "", value
+		}
+		value = groupFiles
+		header, rows, err := readCSV(strings.Split(value, ","))
+		if err != nil {
+			return []byte(fmt.Sprintf("Import error: %v", err)), &ExitError{Code: 1}
+		}
+		files = append(files, strings.Split(value, ",")...)
+		for _, row := range rows {
+			if name == "nodes" {
+				ids[row[0]] = true
+				nodes++
+			} else {
+				start, end := row[slices.Index(header, ":START_ID")], row[slices.Index(header, ":END_ID")]
+				if column := slices.Index(header, ":TYPE"); column >= 0 {
+					label = row[column]
+				}
+				if !ids[start] || !ids[end] {
+					badEntries = append(badEntries, fmt.Sprintf("%s (global id space)-[%s]->%s (global id space) referring to missing node", start, label, end))
+					continue
+				}
+				relationships++
+			}
+			for _, column := range header {
+				if !strings.HasPrefix(column, ":") && !strings.HasSuffix(column, ":ID") {
+					properties++
+				}
+			}
+		}
+	}
+	if len(badEntries) > 0 {
+		if flags["skip-bad-relationships"] != "true" {
+			return []byte(fmt.Sprintf("Import error: %s", badEntries[0])), &ExitError{Code: 1}
+		}
+		if err := os.WriteFile(flags["report-file"], []byte(strings.Join(badEntries, "\n")+"\n"), 0644); err != nil {
+			return []byte(err.Error()), &ExitError{Code: 1}
+		}
+	}
+	f.imported[database] = append(f.imported[database], files...)
+	return []byte(fmt.Sprintf("Neo4j version: 5.26.0\nImporting the contents of these files into %s\n\nIMPORT DONE in 1s 657ms. \nImported:\n  %d nodes\n  %d relationships\n  %d properties\nPeak memory usage: 1.032GiB\n",
+		database, nodes, relationships, properties)), nil
+}
+
+// readCSV returns the header and the rows of the given comma separated files , the header being the first line of the first file
+func readCSV(filePaths []string) ([]string, [][]string, error) {
+	var header []string
+	var rows [][]string
+	for _, filePath := range filePaths {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("file %s not found", filePath)
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if header == nil {
+				header = strings.Split(line, ",")
+				continue
+			}
+			rows = append(rows, strings.Split(line, ","))
+		}
+	}
+	return header, rows, nil
+}
+
+// tar emulates tar -czvf archive directory creating a gzip compressed archive of the directory
+func (f *Neo4jAdmin) tar(args []string) ([]byte, error) {
+	if len(args) < 3 || args[0] != "-czvf" {
+		return []byte("tar: invalid arguments"), &ExitError{Code: 2}
+	}
+	archivePath, directory := args[1], args[2]
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return []byte(fmt.Sprintf("tar: %v", err)), &ExitError{Code: 2}
+	}
+	defer file.Close()
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+	var output strings.Builder
+	err = filepath.Walk(directory, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		header, err := tar.FileInfoHeader(info, "")
+		if err != nil {
+			return err
+		}
+		header.Name = path
+		if err = tarWriter.WriteHeader(header); err != nil {
+			return err
+		}
+		fmt.Fprintln(&output, path)
+		if info.IsDir() {
+			return nil
+		}
+		content, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer content.Close()
+		_, err = io.Copy(tarWriter, content)
+		return err
+	})
+	if err == nil {
+		err = tarWriter.Close()
+	}
+	if err == nil {
+		err = gzipWriter.Close()
+	}
+	if err != nil {
+		return []byte(fmt.Sprintf("tar: %v", err)), &ExitError{Code: 2}
+	}
+	return []byte(output.String()), nil
+}
+
+// parseArgs splits the arguments into the --name=value flags and the positional arguments
+// Flags without a value (ex: --verbose) are set to true
+func parseArgs(args []string) (map[string]string, []string) {
+	flags := make(map[string]string)
+	var positional []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "--") {
+			positional = append(positional, arg)
+			continue
+		}
+		name, value, found := strings.Cut(strings.TrimPrefix(arg, "--"), "=")
+		if !found {
+			value = "true"
+		}
+		flags[name] = value
+	}
+	return flags, positional
+}
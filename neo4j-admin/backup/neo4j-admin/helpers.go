@@ -3,10 +3,20 @@ package neo4j_admin
 import (
 	"fmt"
 	"os"
+	"path"
 	"regexp"
 	"strings"
 )
 
+// BackupLocation returns the directory the backup artifacts and consistency check reports are written to
+// It is LOCATION when set (every target of a multi target job has its own directory) , /backups otherwise
+func BackupLocation() string {
+	if location := strings.TrimSpace(os.Getenv("LOCATION")); location != "" {
+		return strings.TrimSuffix(location, "/")
+	}
+	return "/backups"
+}
+
 // getBackupCommandFlags returns a slice of string containing all the flags to be passed with the neo4j-admin backup command
 func getBackupCommandFlags(address string) []string {
 	flags := []string{"database", "backup"}
@@ -21,7 +31,7 @@ func getBackupCommandFlags(address string) []string {
 	flags = append(flags, fmt.Sprintf("--keep-failed=%s", os.Getenv("KEEP_FAILED")))
 	flags = append(flags, fmt.Sprintf("--parallel-recovery=%s", os.Getenv("PARALLEL_RECOVERY")))
 	flags = append(flags, fmt.Sprintf("--type=%s", os.Getenv("TYPE")))
-	flags = append(flags, fmt.Sprintf("--to-path=%s", "/backups"))
+	flags = append(flags, fmt.Sprintf("--to-path=%s", BackupLocation()))
 
 	if len(strings.TrimSpace(os.Getenv("PAGE_CACHE"))) > 0 {
 		flags = append(flags, fmt.Sprintf("--pagecache=%s", os.Getenv("PAGE_CACHE")))
@@ -31,6 +41,11 @@ func getBackupCommandFlags(address string) []string {
 		flags = append(flags, "--verbose")
 	}
 
+	// neo4j-admin.conf containing the backup ssl policy , generated when the backup port is secured via ssl
+	if additionalConfig := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); len(additionalConfig) > 0 {
+		flags = append(flags, fmt.Sprintf("--additional-config=%s", additionalConfig))
+	}
+
 	for _, db := range strings.Split(os.Getenv("DATABASE"), ",") {
 		flags = append(flags, strings.TrimSpace(db))
 	}
@@ -40,9 +55,13 @@ func getBackupCommandFlags(address string) []string {
 
 // getAggregateBackupCommandFlags returns a slice of string containing all the flags to be passed with the neo4j-admin aggregate backup command
 func getAggregateBackupCommandFlags() []string {
-	database := os.Getenv("AGGREGATE_BACKUP_DATABASE")
+	return getAggregateChainCommandFlags(os.Getenv("AGGREGATE_BACKUP_DATABASE"), os.Getenv("AGGREGATE_BACKUP_FROM_PATH"))
+}
+
+// getAggregateChainCommandFlags returns the flags of the neo4j-admin aggregate backup command for the given database and path
+func getAggregateChainCommandFlags(database string, fromPath string) []string {
 	flags := []string{"database", "aggregate-backup"}
-	flags = append(flags, fmt.Sprintf("--from-path=%s", os.Getenv("AGGREGATE_BACKUP_FROM_PATH")))
+	flags = append(flags, fmt.Sprintf("--from-path=%s", fromPath))
 	flags = append(flags, fmt.Sprintf("--keep-old-backup=%s", os.Getenv("AGGREGATE_BACKUP_KEEPOLDBACKUP")))
 	flags = append(flags, fmt.Sprintf("--parallel-recovery=%s", os.Getenv("AGGREGATE_BACKUP_PARALLEL_RECOVERY")))
 
@@ -66,15 +85,15 @@ func getAggregateBackupCommandFlags() []string {
 //	maxOffHeapMemory: ""
 //	threads: ""
 //	verbose: true
-func getConsistencyCheckCommandFlags(fileName string, database string) []string {
+func getConsistencyCheckCommandFlags(fileName string, database string, fromPath string) []string {
 	flags := []string{"database", "check"}
 
 	flags = append(flags, fmt.Sprintf("--check-indexes=%s", os.Getenv("CONSISTENCY_CHECK_INDEXES")))
 	flags = append(flags, fmt.Sprintf("--check-graph=%s", os.Getenv("CONSISTENCY_CHECK_GRAPH")))
 	flags = append(flags, fmt.Sprintf("--check-counts=%s", os.Getenv("CONSISTENCY_CHECK_COUNTS")))
 	flags = append(flags, fmt.Sprintf("--check-property-owners=%s", os.Getenv("CONSISTENCY_CHECK_PROPERTYOWNERS")))
-	flags = append(flags, fmt.Sprintf("--report-path=/backups/%s.report", fileName))
-	flags = append(flags, fmt.Sprintf("--from-path=/backups"))
+	flags = append(flags, fmt.Sprintf("--report-path=%s/%s.report", BackupLocation(), fileName))
+	flags = append(flags, fmt.Sprintf("--from-path=%s", fromPath))
 	if len(strings.TrimSpace(os.Getenv("CONSISTENCY_CHECK_THREADS"))) > 0 {
 		flags = append(flags, fmt.Sprintf("--threads=%s", os.Getenv("CONSISTENCY_CHECK_THREADS")))
 	}
@@ -90,6 +109,25 @@ func getConsistencyCheckCommandFlags(fileName string, database string) []string
 	return flags
 }
 
+// getRestoreCommandFlags returns a slice of string containing all the flags to be passed with the neo4j-admin restore command
+func getRestoreCommandFlags(fromPaths []string, database string, overwriteDestination bool, restoreUntil string) []string {
+	flags := []string{"database", "restore"}
+	flags = append(flags, fmt.Sprintf("--from-path=%s", strings.Join(fromPaths, ",")))
+	flags = append(flags, fmt.Sprintf("--overwrite-destination=%t", overwriteDestination))
+	if len(strings.TrimSpace(restoreUntil)) > 0 {
+		flags = append(flags, fmt.Sprintf("--restore-until=%s", restoreUntil))
+	}
+	if os.Getenv("VERBOSE") == "true" {
+		flags = append(flags, "--verbose")
+	}
+	// the data directory of the server restored into , see ConfigureDataDirectory
+	if additionalConfig := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); len(additionalConfig) > 0 {
+		flags = append(flags, fmt.Sprintf("--additional-config=%s", additionalConfig))
+	}
+	flags = append(flags, database)
+	return flags
+}
+
 // retrieveBackupFileNames takes the backup command output and looks for the below string and retrieves the backup file names
 // Ex: Finished artifact creation 'neo4j-2023-05-04T17-21-27.backup' for database 'neo4j', took 121ms.
 func retrieveBackupFileNames(cmdOutput string) ([]string, error) {
@@ -116,3 +154,57 @@ func retrieveAggregatedBackupFileNames(cmdOutput string) ([]string, error) {
 	}
 	return matches, nil
 }
+
+// retrieveAggregatedArtifacts takes the output of aggregate backup command and returns the paths of the new artifacts
+// Ex: Successfully aggregated backup chain of database 'neo4j2', new artifact: '/backups/neo4j2-2024-06-13T12-43-43.backup'. returns /backups/neo4j2-2024-06-13T12-43-43.backup
+func retrieveAggregatedArtifacts(cmdOutput string) []string {
+	re := regexp.MustCompile(`Successfully aggregated backup chain.*new artifact: '(.*)'`)
+	var artifacts []string
+	for _, match := range re.FindAllStringSubmatch(cmdOutput, -1) {
+		artifacts = append(artifacts, match[1])
+	}
+	return artifacts
+}
+
+// retrieveBackupTypes takes the output of the backup inspect command and returns the backup type (FULL or DIFF) of each artifact
+// Ex:
+// |                                         FILE | DATABASE |                          DATABASE ID |          TIME (UTC) |  FULL | COMPRESSED | LOWEST TX | HIGHEST TX |
+// | file:///backups/neo4j-2023-06-29T14-51-33.backup |    neo4j | 9fe6e8b8-3c1f-4a6a-bf1b-4b2d1e5c1b55 | 2023-06-29T12:51:33 |  true |       true |         1 |          3 |
+func retrieveBackupTypes(cmdOutput string) (map[string]string, error) {
+	fileIndex, fullIndex := -1, -1
+	backupTypes := make(map[string]string)
+	for _, line := range strings.Split(cmdOutput, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "|") {
+			continue
+		}
+		columns := strings.Split(strings.Trim(line, "|"), "|")
+		for i := range columns {
+			columns[i] = strings.TrimSpace(columns[i])
+		}
+		if fileIndex == -1 {
+			for i, column := range columns {
+				switch strings.ToUpper(column) {
+				case "FILE":
+					fileIndex = i
+				case "FULL":
+					fullIndex = i
+				}
+			}
+			continue
+		}
+		if fullIndex == -1 || len(columns) <= fullIndex || len(columns) <= fileIndex {
+			continue
+		}
+		fileName := path.Base(columns[fileIndex])
+		backupType := "DIFF"
+		if strings.EqualFold(columns[fullIndex], "true") {
+			backupType = "FULL"
+		}
+		backupTypes[fileName] = backupType
+	}
+	if len(backupTypes) == 0 {
+		return nil, fmt.Errorf("cannot retrieve backup type from inspect output \n %s", cmdOutput)
+	}
+	return backupTypes, nil
+}
@@ -1 +1,38 @@
 package neo4j_admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetrieveBackupTypes(t *testing.T) {
+	t.Parallel()
+
+	output := `|                                             FILE | DATABASE |                          DATABASE ID |          TIME (UTC) |  FULL | COMPRESSED | LOWEST TX | HIGHEST TX |
+| file:///backups/neo4j-2023-06-29T14-51-33.backup |    neo4j | 9fe6e8b8-3c1f-4a6a-bf1b-4b2d1e5c1b55 | 2023-06-29T12:51:33 |  true |       true |         1 |          3 |
+| file:///backups/neo4j-2023-06-29T15-51-33.backup |    neo4j | 9fe6e8b8-3c1f-4a6a-bf1b-4b2d1e5c1b55 | 2023-06-29T13:51:33 | false |       true |         4 |          9 |`
+
+	backupTypes, err := retrieveBackupTypes(output)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"neo4j-2023-06-29T14-51-33.backup": "FULL",
+		"neo4j-2023-06-29T15-51-33.backup": "DIFF",
+	}, backupTypes)
+
+	_, err = retrieveBackupTypes("no table present")
+	assert.Error(t, err)
+}
+
+func TestRetrieveAggregatedArtifacts(t *testing.T) {
+	t.Parallel()
+
+	output := `Successfully aggregated backup chain of database 'neo4j', new artifact: '/backups/neo4j-2024-06-13T12-43-43.backup'.
+Successfully aggregated backup chain of database 'orders', new artifact: 's3://bucket/orders-2024-06-13T12-44-02.backup'.`
+
+	assert.Equal(t, []string{
+		"/backups/neo4j-2024-06-13T12-43-43.backup",
+		"s3://bucket/orders-2024-06-13T12-44-02.backup",
+	}, retrieveAggregatedArtifacts(output))
+	assert.Empty(t, retrieveAggregatedArtifacts("nothing to aggregate"))
+}
@@ -0,0 +1,171 @@
+package neo4j_admin
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+var (
+	// importDoneRegex matches the end of the import. Ex: IMPORT DONE in 1m 2s 345ms.
+	importDoneRegex = regexp.MustCompile(`IMPORT DONE in (.+?)\.?\s*$`)
+	// importCountRegex matches the imported entity counts printed below Imported: Ex:   31258 nodes
+	importCountRegex = regexp.MustCompile(`^\s*(\d+) (nodes|relationships|properties)\s*$`)
+	// importPeakMemoryRegex matches the peak memory usage. Ex: Peak memory usage: 1.032GiB
+	importPeakMemoryRegex = regexp.MustCompile(`Peak memory usage: (\S+)`)
+)
+
+// ImportGroup is a group of CSV files imported via a single --nodes or --relationships flag
+type ImportGroup struct {
+	// Label contains the labels of the nodes (ex: Person:Employee) or the type of the relationships (ex: KNOWS)
+	// It is empty when the labels (or type) are present in the CSV files
+	Label string
+	// FilePaths are the local paths of the CSV files , the file containing the header first
+	FilePaths []string
+}
+
+// ImportOptions describes a neo4j-admin database import
+type ImportOptions struct {
+	Database string
+	// Type is full or incremental
+	Type                 string
+	Nodes                []ImportGroup
+	Relationships        []ImportGroup
+	OverwriteDestination bool
+	// AdditionalFlags are passed as is to neo4j-admin. Ex: --delimiter=; or --skip-bad-relationships=true
+	AdditionalFlags []string
+}
+
+// PerformImport imports the CSV files into the database and returns the summary of the import
+// The bad entries skipped by the import are logged into <location>/import-<database>.report
+func PerformImport(options ImportOptions) (*common.ImportSummary, error) {
+	reportPath := filepath.Join(BackupLocation(), fmt.Sprintf("import-%s.report", options.Database))
+	summary := &common.ImportSummary{Database: options.Database, Type: options.Type, Status: common.ImportFailed, Report: reportPath}
+	// the report of a previous import would be counted as bad entries of this one
+	os.Remove(reportPath)
+	flags := getImportCommandFlags(options, reportPath)
+	log.Printf("Printing import flags %v", flags)
+	output, err := runCommand("neo4j-admin", flags...)
+	parseImportOutput(summary, output)
+	summary.BadEntries = countReportLines(reportPath)
+	if err != nil {
+		summary.Error = fmt.Sprintf("%v \n %s", err, lastLines(string(output), 20))
+		return summary, fmt.Errorf("Import Failed for database %s !! output = %s \n err = %v", options.Database, string(output), err)
+	}
+	summary.Status = common.ImportSucceeded
+	log.Printf("Import Completed for database %s !! %d nodes , %d relationships , %d properties imported in %s",
+		options.Database, summary.Nodes, summary.Relationships, summary.Properties, summary.Duration)
+	if summary.BadEntries > 0 {
+		log.Printf("Warning: %d bad entries were skipped by the import of database %s , see %s", summary.BadEntries, options.Database, reportPath)
+	}
+	return summary, nil
+}
+
+// getImportCommandFlags returns the flags of the neo4j-admin import command
+// Ex: database import full --nodes=Person=/import/people/header.csv,/import/people/part-1.csv --relationships=KNOWS=... neo4j
+func getImportCommandFlags(options ImportOptions, reportPath string) []string {
+	flags := []string{"database", "import", options.Type}
+	for _, group := range options.Nodes {
+		flags = append(flags, fmt.Sprintf("--nodes=%s", importGroupValue(group)))
+	}
+	for _, group := range options.Relationships {
+		flags = append(flags, fmt.Sprintf("--relationships=%s", importGroupValue(group)))
+	}
+	flags = append(flags, fmt.Sprintf("--report-file=%s", reportPath))
+	if options.Type == common.ImportTypeIncremental {
+		// an incremental import must be confirmed explicitly
+		flags = append(flags, "--force")
+	} else if options.OverwriteDestination {
+		flags = append(flags, "--overwrite-destination=true")
+	}
+	if os.Getenv("VERBOSE") == "true" {
+		flags = append(flags, "--verbose")
+	}
+	if additionalConfig := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); len(additionalConfig) > 0 {
+		flags = append(flags, fmt.Sprintf("--additional-config=%s", additionalConfig))
+	}
+	flags = append(flags, options.AdditionalFlags...)
+	return append(flags, options.Database)
+}
+
+func importGroupValue(group ImportGroup) string {
+	files := strings.Join(group.FilePaths, ",")
+	if group.Label == "" {
+		return files
+	}
+	return fmt.Sprintf("%s=%s", group.Label, files)
+}
+
+// parseImportOutput fills the summary with the figures printed at the end of the import
+// Ex:
+// IMPORT DONE in 1s 657ms.
+// Imported:
+//
+//	4 nodes
+//	3 relationships
+//	12 properties
+//
+// Peak memory usage: 1.032GiB
+func parseImportOutput(summary *common.ImportSummary, output []byte) {
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if match := importDoneRegex.FindStringSubmatch(line); match != nil {
+			summary.Duration = strings.TrimSpace(match[1])
+			continue
+		}
+		if match := importPeakMemoryRegex.FindStringSubmatch(line); match != nil {
+			summary.PeakMemory = match[1]
+			continue
+		}
+		match := importCountRegex.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+		count, _ := strconv.ParseInt(match[1], 10, 64)
+		switch match[2] {
+		case "nodes":
+			summary.Nodes = count
+		case "relationships":
+			summary.Relationships = count
+		case "properties":
+			summary.Properties = count
+		}
+	}
+}
+
+// countReportLines returns the number of bad entries logged into the import report , 0 if the report does not exist
+func countReportLines(reportPath string) int {
+	file, err := os.Open(reportPath)
+	if err != nil {
+		return 0
+	}
+	defer file.Close()
+	count := 0
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) != "" {
+			count++
+		}
+	}
+	return count
+}
+
+// lastLines returns the last n lines of the output , which contain the cause of a failed import
+func lastLines(output string, n int) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,62 @@
+package neo4j_admin
+
+import (
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImportOutput(t *testing.T) {
+	t.Parallel()
+
+	output := `Neo4j version: 5.26.0
+Importing the contents of these files into /data/databases/graph:
+Nodes:
+  [Person]:
+  /backups/import/people/header.csv
+  /backups/import/people/part-1.csv
+
+Available resources:
+  Total machine memory: 15.50GiB
+  Free machine memory: 1.228GiB
+
+IMPORT DONE in 1m 2s 345ms.
+Imported:
+  31258 nodes
+  155875 relationships
+  707632 properties
+Peak memory usage: 1.032GiB
+There were bad entries which were skipped and logged into /backups/import-graph.report
+`
+	summary := &common.ImportSummary{}
+	parseImportOutput(summary, []byte(output))
+	assert.Equal(t, int64(31258), summary.Nodes)
+	assert.Equal(t, int64(155875), summary.Relationships)
+	assert.Equal(t, int64(707632), summary.Properties)
+	assert.Equal(t, "1m 2s 345ms", summary.Duration)
+	assert.Equal(t, "1.032GiB", summary.PeakMemory)
+}
+
+func TestGetImportCommandFlags(t *testing.T) {
+	t.Setenv("VERBOSE", "false")
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "")
+
+	options := ImportOptions{
+		Database:             "graph",
+		Type:                 common.ImportTypeFull,
+		Nodes:                []ImportGroup{{Label: "Person:Employee", FilePaths: []string{"/import/header.csv", "/import/part-1.csv"}}, {FilePaths: []string{"/import/movies.csv"}}},
+		Relationships:        []ImportGroup{{Label: "KNOWS", FilePaths: []string{"/import/knows.csv"}}},
+		OverwriteDestination: true,
+		AdditionalFlags:      []string{"--delimiter=;"},
+	}
+	assert.Equal(t, []string{"database", "import", "full",
+		"--nodes=Person:Employee=/import/header.csv,/import/part-1.csv", "--nodes=/import/movies.csv",
+		"--relationships=KNOWS=/import/knows.csv", "--report-file=/backups/import-graph.report",
+		"--overwrite-destination=true", "--delimiter=;", "graph"}, getImportCommandFlags(options, "/backups/import-graph.report"))
+
+	options.Type = common.ImportTypeIncremental
+	flags := getImportCommandFlags(options, "/backups/import-graph.report")
+	assert.Contains(t, flags, "--force", "an incremental import must be confirmed")
+	assert.NotContains(t, flags, "--overwrite-destination=true")
+}
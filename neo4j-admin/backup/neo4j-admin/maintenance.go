@@ -0,0 +1,122 @@
+package neo4j_admin
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// CopyOptions contains the filters and the store format of neo4j-admin database copy
+type CopyOptions struct {
+	CopyOnlyNodesWithLabels        []string
+	IgnoreNodesWithLabels          []string
+	CopyOnlyRelationshipsWithTypes []string
+	IgnoreRelationshipsWithTypes   []string
+	// SkipLabels contains the labels removed from the copied nodes
+	SkipLabels []string
+	// SkipProperties contains the properties not copied. Ex: Person.password
+	SkipProperties   []string
+	CompactNodeStore bool
+	// ToFormat is the store format of the copy (ex: block) , the format of the source when empty
+	ToFormat string
+	// AdditionalFlags are passed as is to neo4j-admin
+	AdditionalFlags []string
+}
+
+// PerformCopy copies the source database into the target database applying the filters , which compacts the store
+// Both databases are present in the data directory configured via ConfigureDataDirectory
+func PerformCopy(source string, target string, options CopyOptions) ([]byte, error) {
+	flags := getCopyCommandFlags(source, target, options)
+	log.Printf("Printing copy flags %v", flags)
+	output, err := runCommand("neo4j-admin", flags...)
+	if err != nil {
+		return output, fmt.Errorf("Copy Failed for database %s !! output = %s \n err = %v", source, string(output), err)
+	}
+	log.Printf("Copy Completed for database %s to %s !!", source, target)
+	return output, nil
+}
+
+// PerformMigrate migrates the store of the database to the given format , to the latest version of its format when empty
+func PerformMigrate(database string, toFormat string, additionalFlags []string) ([]byte, error) {
+	flags := getMigrateCommandFlags(database, toFormat, additionalFlags)
+	log.Printf("Printing migrate flags %v", flags)
+	output, err := runCommand("neo4j-admin", flags...)
+	if err != nil {
+		return output, fmt.Errorf("Migration Failed for database %s !! output = %s \n err = %v", database, string(output), err)
+	}
+	log.Printf("Migration Completed for database %s !!", database)
+	return output, nil
+}
+
+// DumpDatabase dumps a single database to <location>/<fileName>
+func DumpDatabase(database string, fileName string) error {
+	stagingPath := filepath.Join(BackupLocation(), ".dump")
+	if err := os.MkdirAll(stagingPath, 0755); err != nil {
+		return fmt.Errorf("unable to create directory %s \n %v", stagingPath, err)
+	}
+	defer os.RemoveAll(stagingPath)
+	flags := getDumpCommandFlags(database, stagingPath)
+	log.Printf("Printing dump flags %v", flags)
+	output, err := runCommand("neo4j-admin", flags...)
+	if err != nil {
+		return fmt.Errorf("Dump Failed for database %s !! output = %s \n err = %v", database, string(output), err)
+	}
+	if err = os.Rename(filepath.Join(stagingPath, database+".dump"), filepath.Join(BackupLocation(), fileName)); err != nil {
+		return fmt.Errorf("unable to move dump of database %s to %s \n %v", database, BackupLocation(), err)
+	}
+	log.Printf("Dump Completed for database %s !!", database)
+	return nil
+}
+
+// getCopyCommandFlags returns the flags of the neo4j-admin copy command
+func getCopyCommandFlags(source string, target string, options CopyOptions) []string {
+	flags := []string{"database", "copy"}
+	filters := []struct {
+		name   string
+		values []string
+	}{
+		{"copy-only-nodes-with-labels", options.CopyOnlyNodesWithLabels},
+		{"ignore-nodes-with-labels", options.IgnoreNodesWithLabels},
+		{"copy-only-relationships-with-types", options.CopyOnlyRelationshipsWithTypes},
+		{"ignore-relationships-with-types", options.IgnoreRelationshipsWithTypes},
+		{"skip-labels", options.SkipLabels},
+		{"skip-properties", options.SkipProperties},
+	}
+	for _, filter := range filters {
+		if len(filter.values) > 0 {
+			flags = append(flags, fmt.Sprintf("--%s=%s", filter.name, strings.Join(filter.values, ",")))
+		}
+	}
+	if options.CompactNodeStore {
+		flags = append(flags, "--compact-node-store=true")
+	}
+	if options.ToFormat != "" {
+		flags = append(flags, fmt.Sprintf("--to-format=%s", options.ToFormat))
+	}
+	if os.Getenv("VERBOSE") == "true" {
+		flags = append(flags, "--verbose")
+	}
+	if additionalConfig := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); len(additionalConfig) > 0 {
+		flags = append(flags, fmt.Sprintf("--additional-config=%s", additionalConfig))
+	}
+	flags = append(flags, options.AdditionalFlags...)
+	return append(flags, source, target)
+}
+
+// getMigrateCommandFlags returns the flags of the neo4j-admin migrate command
+func getMigrateCommandFlags(database string, toFormat string, additionalFlags []string) []string {
+	flags := []string{"database", "migrate"}
+	if toFormat != "" {
+		flags = append(flags, fmt.Sprintf("--to-format=%s", toFormat))
+	}
+	if os.Getenv("VERBOSE") == "true" {
+		flags = append(flags, "--verbose")
+	}
+	if additionalConfig := strings.TrimSpace(os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG")); len(additionalConfig) > 0 {
+		flags = append(flags, fmt.Sprintf("--additional-config=%s", additionalConfig))
+	}
+	flags = append(flags, additionalFlags...)
+	return append(flags, database)
+}
@@ -0,0 +1,24 @@
+package neo4j_admin
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetCopyCommandFlags(t *testing.T) {
+	t.Setenv("VERBOSE", "false")
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "/config/neo4j-admin.conf")
+
+	options := CopyOptions{
+		CopyOnlyNodesWithLabels: []string{"Person", "Movie"},
+		SkipProperties:          []string{"Person.password"},
+		CompactNodeStore:        true,
+		ToFormat:                "block",
+		AdditionalFlags:         []string{"--temp-path=/tmp"},
+	}
+	assert.Equal(t, []string{"database", "copy", "--copy-only-nodes-with-labels=Person,Movie", "--skip-properties=Person.password",
+		"--compact-node-store=true", "--to-format=block", "--additional-config=/config/neo4j-admin.conf", "--temp-path=/tmp",
+		"neo4j", "neo4j-copy"}, getCopyCommandFlags("neo4j", "neo4j-copy", options))
+	assert.Equal(t, []string{"database", "migrate", "--additional-config=/config/neo4j-admin.conf", "neo4j"}, getMigrateCommandFlags("neo4j", "", nil))
+}
@@ -5,15 +5,16 @@ import (
 	"fmt"
 	"log"
 	"os"
-	"os/exec"
 	"strings"
 	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
 )
 
 // CheckDatabaseConnectivity checks if there is connectivity with the provided backup instance or not
 func CheckDatabaseConnectivity(hostPort string) error {
 	address := strings.Split(hostPort, ":")
-	output, err := exec.Command("nc", "-vz", address[0], address[1]).CombinedOutput()
+	output, err := runCommand("nc", "-vz", address[0], address[1])
 	if err != nil {
 		return fmt.Errorf("connectivity cannot be established \n output = %s \n err = %v", string(output), err)
 	}
@@ -25,6 +26,16 @@ func CheckDatabaseConnectivity(hostPort string) error {
 	return nil
 }
 
+// Version returns the version printed by neo4j-admin --version
+func Version() (string, error) {
+	output, err := runCommand("neo4j-admin", "--version")
+	if err != nil {
+		return "", fmt.Errorf("unable to run neo4j-admin --version \n output = %s \n err = %v", string(output), err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1]), nil
+}
+
 // PerformBackup performs the backup operation and returns the generated backup file name
 func PerformBackup(address string) ([]string, error) {
 
@@ -32,8 +43,8 @@ func PerformBackup(address string) ([]string, error) {
 	flags := getBackupCommandFlags(address)
 	log.Printf("Printing backup flags %v", flags)
 	dir, _ := os.Getwd()
-	log.Println("current directory", dir)
-	output, err := exec.Command("neo4j-admin", flags...).CombinedOutput()
+	log.Printf("current directory %s", dir)
+	output, err := runCommand("neo4j-admin", flags...)
 	if err != nil {
 		return nil, fmt.Errorf("Backup Failed for database %s !! output = %s \n err = %v", databases, string(output), err)
 	}
@@ -45,44 +56,70 @@ func PerformBackup(address string) ([]string, error) {
 	return backupFileNames, nil
 }
 
-// PerformConsistencyCheck performs the consistency check on the backup taken and returns the generated report tar name
-func PerformConsistencyCheck(database string) (string, error) {
+// PerformConsistencyCheck performs the consistency check on the backup taken and returns its summary
+func PerformConsistencyCheck(database string) (*common.ConsistencyCheckSummary, error) {
+	return PerformConsistencyCheckFromPath(database, BackupLocation())
+}
+
+// PerformConsistencyCheckFromPath performs the consistency check on the latest backup of the database present at the given path
+// and returns its summary. When inconsistencies are found the report directory is archived to <report>.tar.gz and the summary
+// is written to <report>.json next to it
+// A check which exits without writing a report did not complete and returns an error along with a failed summary
+func PerformConsistencyCheckFromPath(database string, fromPath string) (*common.ConsistencyCheckSummary, error) {
+	summary := &common.ConsistencyCheckSummary{Database: database, Status: common.ConsistencyCheckConsistent}
 	timeStamp := time.Now().Format("2006-01-02T15-04-05")
 	fileName := fmt.Sprintf("%s-%s.backup", database, timeStamp)
-	flags := getConsistencyCheckCommandFlags(fileName, database)
+	flags := getConsistencyCheckCommandFlags(fileName, database, fromPath)
 	log.Printf("Printing consistency check flags %v", flags)
-	output, err := exec.Command("neo4j-admin", flags...).CombinedOutput()
+	output, err := runCommand("neo4j-admin", flags...)
 	if err == nil {
 		log.Printf("No inconsistencies found for %s database !! No Inconsistency report generated.", database)
-		return "", nil
+		return summary, nil
 	}
 
-	var me *exec.ExitError
-	if errors.As(err, &me) {
-		log.Printf("Inconsistencies found for %s database. Exit code was %d\n", database, me.ExitCode())
-		log.Printf("Consistency Check Completed !!")
+	directoryName := fmt.Sprintf("%s/%s.report", BackupLocation(), fileName)
+	reportFiles := findReportFiles(directoryName)
+	var me interface{ ExitCode() int }
+	if !errors.As(err, &me) || len(reportFiles) == 0 {
+		summary.Status = common.ConsistencyCheckFailed
+		summary.Error = fmt.Sprintf("%v \n %s", err, string(output))
+		return summary, fmt.Errorf("Consistency Check Failed for database %s!! \n output = %s \n err = %v", database, string(output), err)
+	}
+	log.Printf("Inconsistencies found for %s database. Exit code was %d\n", database, me.ExitCode())
+	log.Printf("Consistency Check Completed !!")
 
-		tarFileName := fmt.Sprintf("/backups/%s.report.tar.gz", fileName)
-		directoryName := fmt.Sprintf("/backups/%s.report", fileName)
-		log.Printf("tarfileName %s directoryName %s", tarFileName, directoryName)
-		_, err = exec.Command("tar", "-czvf", tarFileName, directoryName, "--absolute-names").CombinedOutput()
-		if err != nil {
-			return "", fmt.Errorf("Unable to create a tar archive of consistency check report for database %s !! \n output = %s \n err = %v", database, string(output), err)
-		}
-		log.Printf("Consistency Check Report tar archive created for database %s at %s !!", database, tarFileName)
-		return fmt.Sprintf("%s.report.tar.gz", fileName), nil
+	summary.Status = common.ConsistencyCheckInconsistent
+	if err = parseConsistencyReports(summary, reportFiles); err != nil {
+		log.Printf("Warning: the consistency check summary of database %s is incomplete: %v", database, err)
+	}
+	tarFileName := fmt.Sprintf("%s/%s.report.tar.gz", BackupLocation(), fileName)
+	log.Printf("tarfileName %s directoryName %s", tarFileName, directoryName)
+	_, err = runCommand("tar", "-czvf", tarFileName, directoryName, "--absolute-names")
+	if err != nil {
+		return summary, fmt.Errorf("Unable to create a tar archive of consistency check report for database %s !! \n output = %s \n err = %v", database, string(output), err)
 	}
-	return "", fmt.Errorf("Consistency Check Failed for database %s!! \n output = %s \n err = %v", database, string(output), err)
+	log.Printf("Consistency Check Report tar archive created for database %s at %s !!", database, tarFileName)
+	summary.Report = fmt.Sprintf("%s.report.tar.gz", fileName)
+	if err = summary.Write(fmt.Sprintf("%s/%s.report.json", BackupLocation(), fileName)); err != nil {
+		return summary, err
+	}
+	log.Printf("Inconsistencies found for database %s: %d error(s) , %d warning(s) %v", database, summary.Errors, summary.Warnings, summary.Inconsistencies)
+	return summary, nil
+}
+
+// ConsistencyCheckSummaryName returns the name of the summary written next to the given report tar archive
+func ConsistencyCheckSummaryName(report string) string {
+	return strings.TrimSuffix(report, ".tar.gz") + ".json"
 }
 
 // PerformAggregateBackup triggers the neo4j-admin aggregate backup command
 func PerformAggregateBackup() error {
 	flags := getAggregateBackupCommandFlags()
 	database := os.Getenv("AGGREGATE_BACKUP_DATABASE")
-	log.Println("Printing aggregate backup flags %v", flags)
+	log.Printf("Printing aggregate backup flags %v", flags)
 	dir, _ := os.Getwd()
-	log.Println("current directory", dir)
-	output, err := exec.Command("neo4j-admin", flags...).CombinedOutput()
+	log.Printf("current directory %s", dir)
+	output, err := runCommand("neo4j-admin", flags...)
 	if err != nil {
 		return fmt.Errorf("Aggregate Backup Failed for database %s !! output = %s \n err = %v", database, string(output), err)
 	}
@@ -94,6 +131,53 @@ func PerformAggregateBackup() error {
 		}
 		log.Printf("%s", backupFileNames)
 	}
-	log.Printf(string(output))
+	log.Printf("%s", output)
+	return nil
+}
+
+// AggregateChain aggregates the backup chain of the database present at the given path and returns the paths of the new artifacts
+// No artifact is returned when the chain is a single full backup
+func AggregateChain(database string, fromPath string) ([]string, error) {
+	flags := getAggregateChainCommandFlags(database, fromPath)
+	log.Printf("Printing aggregate backup flags %v", flags)
+	output, err := runCommand("neo4j-admin", flags...)
+	if err != nil {
+		return nil, fmt.Errorf("Aggregate Backup Failed for database %s !! output = %s \n err = %v", database, string(output), err)
+	}
+	log.Printf("Aggregate Backup Completed for database %s !!", database)
+	return retrieveAggregatedArtifacts(string(output)), nil
+}
+
+// PerformRestore restores the database from the given backup artifacts
+// fromPaths must contain the full backup artifact followed by the differential backup artifacts of the chain
+func PerformRestore(fromPaths []string, database string, overwriteDestination bool, restoreUntil string) error {
+	flags := getRestoreCommandFlags(fromPaths, database, overwriteDestination, restoreUntil)
+	log.Printf("Printing restore flags %v", flags)
+	output, err := runCommand("neo4j-admin", flags...)
+	if err != nil {
+		return fmt.Errorf("Restore Failed for database %s !! output = %s \n err = %v", database, string(output), err)
+	}
+	log.Printf("Restore Completed for database %s !!", database)
+	log.Printf("%s", string(output))
+	return nil
+}
+
+// InspectBackups inspects the given backup artifacts present at the backup location and records whether each of them is a FULL or DIFF backup
+func InspectBackups(backupFileNames []string) error {
+	for _, backupFileName := range backupFileNames {
+		flags := []string{"database", "backup", fmt.Sprintf("--inspect-path=%s/%s", BackupLocation(), backupFileName)}
+		output, err := runCommand("neo4j-admin", flags...)
+		if err != nil {
+			return fmt.Errorf("Unable to inspect backup artifact %s !! output = %s \n err = %v", backupFileName, string(output), err)
+		}
+		backupTypes, err := retrieveBackupTypes(string(output))
+		if err != nil {
+			return err
+		}
+		for fileName, backupType := range backupTypes {
+			log.Printf("Backup artifact %s is of type %s", fileName, backupType)
+			common.SetBackupType(fileName, backupType)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,75 @@
+package neo4j_admin
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/neo4j-admin/fake"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOperationsWithFakeNeo4jAdmin(t *testing.T) {
+	location := t.TempDir()
+	t.Setenv("LOCATION", location)
+	t.Setenv("DATABASE", "*")
+	t.Setenv("TYPE", "AUTO")
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "")
+	clock := time.Date(2024, 6, 13, 10, 0, 0, 0, time.UTC)
+	admin := &fake.Neo4jAdmin{
+		Databases:    []string{"neo4j", "system"},
+		Unreachable:  []string{"down:6362"},
+		Inconsistent: []string{"system"},
+		Now: func() time.Time {
+			clock = clock.Add(time.Hour)
+			return clock
+		},
+	}
+	previous := SetCommandRunner(admin)
+	defer SetCommandRunner(previous)
+
+	assert.NoError(t, CheckDatabaseConnectivity("db:6362"))
+	assert.Error(t, CheckDatabaseConnectivity("down:6362"))
+
+	first, err := PerformBackup("db:6362")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"neo4j-2024-06-13T11-00-00.backup", "system-2024-06-13T12-00-00.backup"}, first)
+	second, err := PerformBackup("db:6362")
+	assert.NoError(t, err)
+	assert.NoError(t, InspectBackups(append(first, second...)))
+	assert.Equal(t, "FULL", common.GetBackupType(first[0]))
+	assert.Equal(t, "DIFF", common.GetBackupType(second[0]))
+
+	summary, err := PerformConsistencyCheck("neo4j")
+	assert.NoError(t, err)
+	assert.Equal(t, common.ConsistencyCheckConsistent, summary.Status)
+	assert.Empty(t, summary.Report)
+	summary, err = PerformConsistencyCheck("system")
+	assert.NoError(t, err)
+	assert.Equal(t, common.ConsistencyCheckInconsistent, summary.Status)
+	assert.Equal(t, 2, summary.Errors)
+	assert.Equal(t, map[string]int{"nodes": 1, "indexes": 1}, summary.Inconsistencies)
+	assert.Equal(t, map[string][]int64{"nodes": {12}, "indexes": {42}}, summary.Samples)
+	assert.FileExists(t, filepath.Join(location, summary.Report))
+	written, err := common.ReadConsistencyCheckSummary(filepath.Join(location, ConsistencyCheckSummaryName(summary.Report)))
+	assert.NoError(t, err)
+	assert.Equal(t, summary, written)
+
+	admin.CrashCheck = []string{"neo4j"}
+	summary, err = PerformConsistencyCheck("neo4j")
+	assert.Error(t, err)
+	assert.Equal(t, common.ConsistencyCheckFailed, summary.Status)
+	assert.Contains(t, summary.Error, "OutOfMemoryError")
+
+	t.Setenv("AGGREGATE_BACKUP_FROM_PATH", location)
+	t.Setenv("AGGREGATE_BACKUP_DATABASE", "neo4j")
+	assert.NoError(t, PerformAggregateBackup())
+	admin.FailAggregate = true
+	assert.Error(t, PerformAggregateBackup())
+
+	t.Setenv("DATABASE", "neo4j")
+	admin.FailBackup = []string{"neo4j"}
+	_, err = PerformBackup("db:6362")
+	assert.Error(t, err)
+}
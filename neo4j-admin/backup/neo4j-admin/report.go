@@ -0,0 +1,109 @@
+package neo4j_admin
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+// maxSampleIds is the number of affected entity ids kept by inconsistency type in the summary
+const maxSampleIds = 10
+
+// reportEntityRegex matches the record an inconsistency is reported on
+// Ex:     Node[12,used=true,rel=15,prop=-1,labels=Inline(0x1000000001:[1]),light,secondaryUnitId=-1]
+// Ex:     IndexEntry[nodeId=3]
+var reportEntityRegex = regexp.MustCompile(`^\s+(?:Inconsistent with: )?([A-Za-z]+)\[(?:[A-Za-z]+=)?(-?\d+)`)
+
+// findReportFiles returns the report files written by the consistency check to the given report directory
+func findReportFiles(reportPath string) []string {
+	reportFiles, _ := filepath.Glob(filepath.Join(reportPath, "*.report"))
+	return reportFiles
+}
+
+// parseConsistencyReports counts the inconsistencies present in the given report files by type
+// Every inconsistency starts with an ERROR: or WARNING: line followed by the record it is reported on
+// Ex:
+// ERROR: The referenced relationship record is not in use.
+//
+//	Node[12,used=true,rel=15,prop=-1,labels=Inline(0x1000000001:[1]),light,secondaryUnitId=-1]
+//	Inconsistent with: Relationship[15,used=false,source=0,target=0,type=0,sPrev=0,sNext=0,tPrev=0,tNext=0,prop=0,secondaryUnitId=-1]
+func parseConsistencyReports(summary *common.ConsistencyCheckSummary, reportFiles []string) error {
+	summary.Inconsistencies = make(map[string]int)
+	summary.Samples = make(map[string][]int64)
+	for _, reportFile := range reportFiles {
+		file, err := os.Open(reportFile)
+		if err != nil {
+			return fmt.Errorf("unable to open consistency check report %s \n %v", reportFile, err)
+		}
+		err = parseConsistencyReport(summary, bufio.NewScanner(file))
+		file.Close()
+		if err != nil {
+			return fmt.Errorf("unable to read consistency check report %s \n %v", reportFile, err)
+		}
+	}
+	return nil
+}
+
+func parseConsistencyReport(summary *common.ConsistencyCheckSummary, scanner *bufio.Scanner) error {
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	// message is the inconsistency waiting for the record it is reported on
+	var message string
+	record := func(inconsistencyType string, id string) {
+		summary.Inconsistencies[inconsistencyType]++
+		if id, err := strconv.ParseInt(id, 10, 64); err == nil && len(summary.Samples[inconsistencyType]) < maxSampleIds {
+			summary.Samples[inconsistencyType] = append(summary.Samples[inconsistencyType], id)
+		}
+		message = ""
+	}
+	for scanner.Scan() {
+		line := scanner.Text()
+		isError, isWarning := strings.HasPrefix(line, "ERROR:"), strings.HasPrefix(line, "WARNING:")
+		if isError || isWarning {
+			if message != "" {
+				record(common.InconsistencyOther, "")
+			}
+			if isError {
+				summary.Errors++
+			} else {
+				summary.Warnings++
+			}
+			message = line
+			continue
+		}
+		if message == "" {
+			continue
+		}
+		if matches := reportEntityRegex.FindStringSubmatch(line); matches != nil {
+			record(classifyInconsistency(message, matches[1]), matches[2])
+		}
+	}
+	if message != "" {
+		record(common.InconsistencyOther, "")
+	}
+	return scanner.Err()
+}
+
+// classifyInconsistency returns the type of the inconsistency based on its message and the record it is reported on
+func classifyInconsistency(message string, record string) string {
+	switch {
+	case strings.Contains(strings.ToLower(message), "owner"):
+		return common.InconsistencyPropertyOwners
+	case strings.Contains(record, "Count"):
+		return common.InconsistencyCounts
+	case strings.Contains(record, "Index") || strings.Contains(record, "Scan") || strings.Contains(record, "Schema"):
+		return common.InconsistencyIndexes
+	case strings.HasPrefix(record, "Relationship"):
+		return common.InconsistencyRelationships
+	case strings.HasPrefix(record, "Node"):
+		return common.InconsistencyNodes
+	case strings.HasPrefix(record, "Property"):
+		return common.InconsistencyPropertyOwners
+	}
+	return common.InconsistencyOther
+}
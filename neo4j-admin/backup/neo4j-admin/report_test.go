@@ -0,0 +1,47 @@
+package neo4j_admin
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseConsistencyReport(t *testing.T) {
+	t.Parallel()
+
+	report := `ERROR: The referenced relationship record is not in use.
+    Node[12,used=true,rel=15,prop=-1,labels=Inline(0x1000000001:[1]),light,secondaryUnitId=-1]
+    Inconsistent with: Relationship[15,used=false,source=0,target=0,type=0,sPrev=0,sNext=0,tPrev=0,tNext=0,prop=0,secondaryUnitId=-1]
+ERROR: The first outgoing relationship is not the first in its chain.
+    Relationship[7,used=true,source=1,target=2,type=0,sPrev=3,sNext=-1,tPrev=-1,tNext=-1,prop=-1,secondaryUnitId=-1,sFirst=true,tFirst=true]
+WARNING: The property record is referenced by multiple owners.
+    Property[31,used=true,prev=-1,next=-1]
+ERROR: The counts store contains an entry which does not match the store.
+    CountsEntry[0,label=1,count=3]
+ERROR: This index entry refers to a node record that is not in use.
+    IndexEntry[nodeId=42]
+ERROR: The schema rule is not in use.
+`
+	summary := &common.ConsistencyCheckSummary{Inconsistencies: map[string]int{}, Samples: map[string][]int64{}}
+	assert.NoError(t, parseConsistencyReport(summary, bufio.NewScanner(strings.NewReader(report))))
+	assert.Equal(t, 5, summary.Errors)
+	assert.Equal(t, 1, summary.Warnings)
+	assert.Equal(t, map[string]int{
+		common.InconsistencyNodes:          1,
+		common.InconsistencyRelationships:  1,
+		common.InconsistencyPropertyOwners: 1,
+		common.InconsistencyCounts:         1,
+		common.InconsistencyIndexes:        1,
+		common.InconsistencyOther:          1,
+	}, summary.Inconsistencies)
+	assert.Equal(t, map[string][]int64{
+		common.InconsistencyNodes:          {12},
+		common.InconsistencyRelationships:  {7},
+		common.InconsistencyPropertyOwners: {31},
+		common.InconsistencyCounts:         {0},
+		common.InconsistencyIndexes:        {42},
+	}, summary.Samples)
+}
@@ -0,0 +1,62 @@
+package neo4j_admin
+
+import (
+	"errors"
+	"os/exec"
+	"strings"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/tracing"
+)
+
+// CommandRunner runs the external commands (neo4j-admin , nc and tar) and returns their combined output
+// The error returned for a command exiting with a non zero exit code must implement ExitCode() int like *exec.ExitError
+type CommandRunner interface {
+	Run(name string, args ...string) ([]byte, error)
+}
+
+// execRunner runs the commands present on the PATH of the backup container
+type execRunner struct{}
+
+func (execRunner) Run(name string, args ...string) ([]byte, error) {
+	return exec.Command(name, args...).CombinedOutput()
+}
+
+var runner CommandRunner = execRunner{}
+
+// SetCommandRunner replaces the runner of the external commands and returns the previous one
+// It allows running the backup operations against a fake neo4j-admin (see the fake package)
+func SetCommandRunner(r CommandRunner) CommandRunner {
+	previous := runner
+	runner = r
+	return previous
+}
+
+// runCommand runs the command through the runner and records it as a span along with its exit code
+func runCommand(name string, args ...string) ([]byte, error) {
+	span := tracing.Start(commandSpanName(name, args), tracing.Command.String(strings.Join(append([]string{name}, args...), " ")))
+	output, err := runner.Run(name, args...)
+	var exitErr interface{ ExitCode() int }
+	switch {
+	case err == nil:
+		span.SetAttributes(tracing.ExitCode.Int(0))
+	case errors.As(err, &exitErr):
+		span.SetAttributes(tracing.ExitCode.Int(exitErr.ExitCode()))
+	}
+	span.End(err)
+	return output, err
+}
+
+// commandSpanName returns the command along with its subcommands. Ex: neo4j-admin database backup
+func commandSpanName(name string, args []string) string {
+	spanName := name
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-") || name != "neo4j-admin" {
+			break
+		}
+		spanName += " " + arg
+		if strings.Count(spanName, " ") == 2 {
+			break
+		}
+	}
+	return spanName
+}
@@ -0,0 +1,183 @@
+package neo4j_admin
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultSSLBaseDirectory = "/var/lib/neo4j/certificates/backup"
+	defaultAdminConfigPath  = "/tmp/neo4j-admin/neo4j-admin.conf"
+	// certificateExpiryWarning is the remaining validity below which a warning is logged
+	certificateExpiryWarning = 30 * 24 * time.Hour
+)
+
+// sslConfig contains the client side backup ssl policy used by neo4j-admin
+type sslConfig struct {
+	baseDirectory  string
+	clientAuth     string
+	verifyHostname string
+	minValidity    time.Duration
+}
+
+// getSSLConfig reads the backup ssl policy from the below env variables
+// BACKUP_SSL_BASE_DIRECTORY , BACKUP_SSL_CLIENT_AUTH , BACKUP_SSL_VERIFY_HOSTNAME , BACKUP_SSL_MIN_VALIDITY_DAYS
+func getSSLConfig() (*sslConfig, error) {
+	config := &sslConfig{
+		baseDirectory:  strings.TrimSpace(os.Getenv("BACKUP_SSL_BASE_DIRECTORY")),
+		clientAuth:     strings.ToUpper(strings.TrimSpace(os.Getenv("BACKUP_SSL_CLIENT_AUTH"))),
+		verifyHostname: strings.ToLower(strings.TrimSpace(os.Getenv("BACKUP_SSL_VERIFY_HOSTNAME"))),
+	}
+	if config.baseDirectory == "" {
+		config.baseDirectory = defaultSSLBaseDirectory
+	}
+	if config.clientAuth == "" {
+		config.clientAuth = "REQUIRE"
+	}
+	if config.clientAuth != "NONE" && config.clientAuth != "OPTIONAL" && config.clientAuth != "REQUIRE" {
+		return nil, fmt.Errorf("invalid BACKUP_SSL_CLIENT_AUTH %s. Supported values are NONE, OPTIONAL and REQUIRE", config.clientAuth)
+	}
+	if config.verifyHostname == "" {
+		config.verifyHostname = "false"
+	}
+	if config.verifyHostname != "true" && config.verifyHostname != "false" {
+		return nil, fmt.Errorf("invalid BACKUP_SSL_VERIFY_HOSTNAME %s. Supported values are true and false", config.verifyHostname)
+	}
+	if value := strings.TrimSpace(os.Getenv("BACKUP_SSL_MIN_VALIDITY_DAYS")); value != "" {
+		days, err := strconv.Atoi(value)
+		if err != nil || days < 0 {
+			return nil, fmt.Errorf("invalid BACKUP_SSL_MIN_VALIDITY_DAYS %s", value)
+		}
+		config.minValidity = time.Duration(days) * 24 * time.Hour
+	}
+	return config, nil
+}
+
+// ConfigureBackupSSL validates the mounted certificates and generates the neo4j-admin.conf containing the backup ssl policy
+// The generated config is passed to neo4j-admin via NEO4J_ADMIN_ADDITIONAL_CONFIG
+func ConfigureBackupSSL() error {
+	config, err := getSSLConfig()
+	if err != nil {
+		return err
+	}
+	if err = validateCertificates(config.baseDirectory, config.minValidity, time.Now()); err != nil {
+		return err
+	}
+
+	configPath := os.Getenv("NEO4J_ADMIN_CONF_PATH")
+	if configPath == "" {
+		configPath = defaultAdminConfigPath
+	}
+	if err = os.MkdirAll(filepath.Dir(configPath), 0700); err != nil {
+		return fmt.Errorf("unable to create directory for %s \n %v", configPath, err)
+	}
+	if err = os.WriteFile(configPath, []byte(config.adminConfig()), 0600); err != nil {
+		return fmt.Errorf("unable to write neo4j-admin config %s \n %v", configPath, err)
+	}
+	log.Printf("Generated neo4j-admin config %s with backup ssl policy (client_auth=%s)", configPath, config.clientAuth)
+	return os.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", configPath)
+}
+
+// adminConfig returns the neo4j-admin.conf content containing the backup ssl policy
+func (s *sslConfig) adminConfig() string {
+	lines := []string{
+		"dbms.ssl.policy.backup.enabled=true",
+		fmt.Sprintf("dbms.ssl.policy.backup.base_directory=%s", s.baseDirectory),
+		"dbms.ssl.policy.backup.private_key=private.key",
+		"dbms.ssl.policy.backup.public_certificate=public.crt",
+		"dbms.ssl.policy.backup.trusted_dir=trusted",
+		fmt.Sprintf("dbms.ssl.policy.backup.client_auth=%s", s.clientAuth),
+		fmt.Sprintf("dbms.ssl.policy.backup.verify_hostname=%s", s.verifyHostname),
+	}
+	if info, err := os.Stat(filepath.Join(s.baseDirectory, "revoked")); err == nil && info.IsDir() {
+		lines = append(lines, "dbms.ssl.policy.backup.revoked_dir=revoked")
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
+
+// validateCertificates checks the private key , public certificate and trusted certificates present in the base directory
+// An error is returned if any certificate is not yet valid , expired or expires within minValidity
+func validateCertificates(baseDirectory string, minValidity time.Duration, now time.Time) error {
+	keyBytes, err := os.ReadFile(filepath.Join(baseDirectory, "private.key"))
+	if err != nil {
+		return fmt.Errorf("unable to read backup ssl private key \n %v", err)
+	}
+	if block, _ := pem.Decode(keyBytes); block == nil || !strings.Contains(block.Type, "PRIVATE KEY") {
+		return fmt.Errorf("backup ssl private key %s is not a PEM encoded private key", filepath.Join(baseDirectory, "private.key"))
+	}
+
+	if err = validateCertificateFile(filepath.Join(baseDirectory, "public.crt"), minValidity, now); err != nil {
+		return err
+	}
+
+	trustedFiles, err := os.ReadDir(filepath.Join(baseDirectory, "trusted"))
+	if err != nil {
+		return fmt.Errorf("unable to read backup ssl trusted certificates directory \n %v", err)
+	}
+	var trustedCount int
+	for _, trustedFile := range trustedFiles {
+		// projected volumes contain hidden timestamped directories and symlinks
+		if strings.HasPrefix(trustedFile.Name(), ".") || trustedFile.IsDir() {
+			continue
+		}
+		if err = validateCertificateFile(filepath.Join(baseDirectory, "trusted", trustedFile.Name()), minValidity, now); err != nil {
+			return err
+		}
+		trustedCount++
+	}
+	if trustedCount == 0 {
+		return fmt.Errorf("no trusted certificates found in %s", filepath.Join(baseDirectory, "trusted"))
+	}
+	return nil
+}
+
+// validateCertificateFile checks the validity period of every certificate present in the PEM file
+func validateCertificateFile(filePath string, minValidity time.Duration, now time.Time) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("unable to read certificate %s \n %v", filePath, err)
+	}
+	var certificates []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		certificate, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return fmt.Errorf("unable to parse certificate %s \n %v", filePath, err)
+		}
+		certificates = append(certificates, certificate)
+	}
+	if len(certificates) == 0 {
+		return fmt.Errorf("no PEM encoded certificate found in %s", filePath)
+	}
+
+	var errs []error
+	for _, certificate := range certificates {
+		subject := certificate.Subject.String()
+		switch {
+		case now.Before(certificate.NotBefore):
+			errs = append(errs, fmt.Errorf("certificate %s (%s) is not valid before %s", filePath, subject, certificate.NotBefore.UTC()))
+		case now.After(certificate.NotAfter):
+			errs = append(errs, fmt.Errorf("certificate %s (%s) expired at %s", filePath, subject, certificate.NotAfter.UTC()))
+		case certificate.NotAfter.Sub(now) < minValidity:
+			errs = append(errs, fmt.Errorf("certificate %s (%s) expires at %s which is within the minimum validity of %v", filePath, subject, certificate.NotAfter.UTC(), minValidity))
+		case certificate.NotAfter.Sub(now) < certificateExpiryWarning:
+			log.Printf("Warning: certificate %s (%s) expires at %s", filePath, subject, certificate.NotAfter.UTC())
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,77 @@
+package neo4j_admin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCertificates writes a self signed certificate valid until notAfter , its private key and a trusted certificate to the directory
+func writeTestCertificates(t *testing.T, directory string, notAfter time.Time) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "backup"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	certificate, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	assert.NoError(t, err)
+	keyBytes, err := x509.MarshalPKCS8PrivateKey(key)
+	assert.NoError(t, err)
+
+	certificatePEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificate})
+	assert.NoError(t, os.MkdirAll(filepath.Join(directory, "trusted"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, "public.crt"), certificatePEM, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, "trusted", "ca.crt"), certificatePEM, 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(directory, "private.key"), pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyBytes}), 0600))
+}
+
+func TestValidateCertificates(t *testing.T) {
+	t.Parallel()
+
+	valid := t.TempDir()
+	writeTestCertificates(t, valid, time.Now().Add(90*24*time.Hour))
+	assert.NoError(t, validateCertificates(valid, 0, time.Now()))
+	assert.NoError(t, validateCertificates(valid, 30*24*time.Hour, time.Now()))
+	assert.Error(t, validateCertificates(valid, 100*24*time.Hour, time.Now()), "certificate expiring within the minimum validity must fail")
+	assert.Error(t, validateCertificates(valid, 0, time.Now().Add(91*24*time.Hour)), "expired certificate must fail")
+
+	missingTrusted := t.TempDir()
+	writeTestCertificates(t, missingTrusted, time.Now().Add(90*24*time.Hour))
+	assert.NoError(t, os.RemoveAll(filepath.Join(missingTrusted, "trusted")))
+	assert.Error(t, validateCertificates(missingTrusted, 0, time.Now()))
+}
+
+func TestConfigureBackupSSL(t *testing.T) {
+	directory := t.TempDir()
+	writeTestCertificates(t, directory, time.Now().Add(90*24*time.Hour))
+	configPath := filepath.Join(t.TempDir(), "conf", "neo4j-admin.conf")
+	t.Setenv("BACKUP_SSL_BASE_DIRECTORY", directory)
+	t.Setenv("BACKUP_SSL_CLIENT_AUTH", "optional")
+	t.Setenv("NEO4J_ADMIN_CONF_PATH", configPath)
+	t.Setenv("NEO4J_ADMIN_ADDITIONAL_CONFIG", "")
+
+	assert.NoError(t, ConfigureBackupSSL())
+	assert.Equal(t, configPath, os.Getenv("NEO4J_ADMIN_ADDITIONAL_CONFIG"))
+	config, err := os.ReadFile(configPath)
+	assert.NoError(t, err)
+	assert.Contains(t, string(config), "dbms.ssl.policy.backup.enabled=true")
+	assert.Contains(t, string(config), "dbms.ssl.policy.backup.base_directory="+directory)
+	assert.Contains(t, string(config), "dbms.ssl.policy.backup.client_auth=OPTIONAL")
+	assert.Contains(t, getBackupCommandFlags("localhost:6362"), "--additional-config="+configPath)
+
+	t.Setenv("BACKUP_SSL_CLIENT_AUTH", "SOMETIMES")
+	assert.Error(t, ConfigureBackupSSL())
+}
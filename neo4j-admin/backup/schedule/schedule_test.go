@@ -0,0 +1,110 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCron(t *testing.T) {
+	t.Parallel()
+
+	after := time.Date(2024, 6, 13, 12, 43, 20, 0, time.UTC)
+	tests := []struct {
+		name       string
+		expression string
+		want       time.Time
+		wantErr    bool
+	}{
+		{
+			name:       "every minute",
+			expression: "* * * * *",
+			want:       time.Date(2024, 6, 13, 12, 44, 0, 0, time.UTC),
+		},
+		{
+			name:       "every 15 minutes",
+			expression: "*/15 * * * *",
+			want:       time.Date(2024, 6, 13, 12, 45, 0, 0, time.UTC),
+		},
+		{
+			name:       "daily at 02:30",
+			expression: "30 2 * * *",
+			want:       time.Date(2024, 6, 14, 2, 30, 0, 0, time.UTC),
+		},
+		{
+			name:       "hour list",
+			expression: "0 6,18 * * *",
+			want:       time.Date(2024, 6, 13, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "weekdays only",
+			expression: "0 1 * * 1-5",
+			// 2024-06-14 is a friday
+			want: time.Date(2024, 6, 14, 1, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "sunday as 7",
+			expression: "0 0 * * 7",
+			want:       time.Date(2024, 6, 16, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "day of month or day of week",
+			expression: "0 0 1 * 6",
+			want:       time.Date(2024, 6, 15, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "yearly descriptor wraps the year",
+			expression: "@yearly",
+			want:       time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "leap day",
+			expression: "0 0 29 2 *",
+			want:       time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name:       "never matching",
+			expression: "0 0 30 2 *",
+			want:       time.Time{},
+		},
+		{
+			name:       "missing field",
+			expression: "* * * *",
+			wantErr:    true,
+		},
+		{
+			name:       "out of range",
+			expression: "60 * * * *",
+			wantErr:    true,
+		},
+		{
+			name:       "invalid step",
+			expression: "*/0 * * * *",
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			schedule, err := ParseCron(tt.expression)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, schedule.Next(after))
+		})
+	}
+}
+
+func TestEvery(t *testing.T) {
+	t.Parallel()
+
+	schedule, err := Every(30 * time.Second)
+	assert.NoError(t, err)
+	after := time.Date(2024, 6, 13, 12, 43, 20, 0, time.UTC)
+	assert.Equal(t, after.Add(30*time.Second), schedule.Next(after))
+
+	_, err = Every(0)
+	assert.Error(t, err)
+}
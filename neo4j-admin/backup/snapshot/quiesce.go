@@ -0,0 +1,197 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// accessReadOnly is the access mode returned by SHOW DATABASE for a read only database
+const accessReadOnly = "read-only"
+
+// Quiescer stops the writes to the databases while the volumes are snapshotted
+type Quiescer interface {
+	Quiesce(ctx context.Context, databases []string) error
+	Resume(ctx context.Context, databases []string) error
+}
+
+// HTTPQuiescer quiesces the databases through the HTTP API of the server: the databases are set read only and checkpointed
+// so that the snapshot contains an up to date store. Resume sets the databases set read only by Quiesce back to read write
+type HTTPQuiescer struct {
+	endpoint string
+	username string
+	password string
+	client   *http.Client
+
+	lock sync.Mutex
+	// quiesced are the databases set read only by Quiesce , the databases which were already read only are not part of it
+	quiesced map[string]bool
+}
+
+// NewHTTPQuiescer returns a quiescer calling the HTTP API at the given endpoint , ex: http://neo4j-admin.neo4j.svc.cluster.local:7474
+func NewHTTPQuiescer(endpoint string, username string, password string) *HTTPQuiescer {
+	return &HTTPQuiescer{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		username: username,
+		password: password,
+		client:   &http.Client{Timeout: requestTimeout},
+		quiesced: make(map[string]bool),
+	}
+}
+
+// NewHTTPQuiescerFromEnv returns a quiescer of the server at SNAPSHOT_QUIESCE_ENDPOINT , the HTTP port of the admin service when empty
+// The credentials are read from NEO4J_AUTH (<username>/<password>) like the neo4j chart does
+func NewHTTPQuiescerFromEnv() (*HTTPQuiescer, error) {
+	endpoint := strings.TrimSpace(os.Getenv("SNAPSHOT_QUIESCE_ENDPOINT"))
+	if endpoint == "" {
+		host := strings.TrimSpace(os.Getenv("DATABASE_SERVICE_IP"))
+		if serviceName := strings.TrimSpace(os.Getenv("DATABASE_SERVICE_NAME")); serviceName != "" {
+			host = fmt.Sprintf("%s.%s.svc.%s", serviceName, os.Getenv("DATABASE_NAMESPACE"), os.Getenv("DATABASE_CLUSTER_DOMAIN"))
+		}
+		if host == "" {
+			return nil, fmt.Errorf("SNAPSHOT_QUIESCE_ENDPOINT , DATABASE_SERVICE_NAME or DATABASE_SERVICE_IP must be set to quiesce the databases")
+		}
+		endpoint = fmt.Sprintf("http://%s:7474", host)
+	}
+	username, password, found := strings.Cut(os.Getenv("NEO4J_AUTH"), "/")
+	if !found {
+		return nil, fmt.Errorf("NEO4J_AUTH must contain the credentials used to quiesce the databases as <username>/<password>")
+	}
+	return NewHTTPQuiescer(endpoint, username, password), nil
+}
+
+// Quiesce sets every database read only and forces a checkpoint
+// The access mode of every database is recorded first , the databases which are already read only are only checkpointed
+func (q *HTTPQuiescer) Quiesce(ctx context.Context, databases []string) error {
+	for _, database := range databases {
+		access, err := q.access(ctx, database)
+		if err != nil {
+			return fmt.Errorf("unable to read the access mode of database %s \n %v", database, err)
+		}
+		if access == accessReadOnly {
+			log.Printf("Database %s is already read only , it is kept read only once the snapshots are taken", database)
+		} else {
+			if _, err = q.run(ctx, "system", "ALTER DATABASE $name SET ACCESS READ ONLY WAIT", map[string]interface{}{"name": database}); err != nil {
+				return fmt.Errorf("unable to set database %s read only \n %v", database, err)
+			}
+			q.lock.Lock()
+			q.quiesced[database] = true
+			q.lock.Unlock()
+		}
+		if _, err = q.run(ctx, database, "CALL db.checkpoint()", nil); err != nil {
+			return fmt.Errorf("unable to checkpoint database %s \n %v", database, err)
+		}
+		log.Printf("Database %s quiesced !!", database)
+	}
+	return nil
+}
+
+// Resume sets the databases set read only by Quiesce back to read write , the databases which were read only before are left untouched
+// Every database is resumed even when some of them fail
+func (q *HTTPQuiescer) Resume(ctx context.Context, databases []string) error {
+	var errs []string
+	for _, database := range databases {
+		q.lock.Lock()
+		quiesced := q.quiesced[database]
+		q.lock.Unlock()
+		if !quiesced {
+			continue
+		}
+		if _, err := q.run(ctx, "system", "ALTER DATABASE $name SET ACCESS READ WRITE WAIT", map[string]interface{}{"name": database}); err != nil {
+			errs = append(errs, fmt.Sprintf("database %s: %v", database, err))
+			continue
+		}
+		q.lock.Lock()
+		delete(q.quiesced, database)
+		q.lock.Unlock()
+		log.Printf("Database %s resumed !!", database)
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("unable to set the database(s) read write \n %s", strings.Join(errs, "\n"))
+	}
+	return nil
+}
+
+// access returns the access mode of the database , read-write or read-only
+func (q *HTTPQuiescer) access(ctx context.Context, database string) (string, error) {
+	rows, err := q.run(ctx, "system", "SHOW DATABASE $name YIELD access", map[string]interface{}{"name": database})
+	if err != nil {
+		return "", err
+	}
+	// a clustered database returns a row per server , all of them with the same access mode
+	if len(rows) == 0 || len(rows[0]) == 0 {
+		return "", fmt.Errorf("database %s not found", database)
+	}
+	access, _ := rows[0][0].(string)
+	return access, nil
+}
+
+type statement struct {
+	Statement  string                 `json:"statement"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+}
+
+type queryResponse struct {
+	Results []struct {
+		Data []struct {
+			Row []interface{} `json:"row"`
+		} `json:"data"`
+	} `json:"results"`
+	Errors []struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// run executes a single statement in an implicit transaction of the database and returns the rows of its result
+func (q *HTTPQuiescer) run(ctx context.Context, database string, query string, parameters map[string]interface{}) ([][]interface{}, error) {
+	body, err := json.Marshal(map[string][]statement{"statements": {{Statement: query, Parameters: parameters}}})
+	if err != nil {
+		return nil, err
+	}
+	requestURL := fmt.Sprintf("%s/db/%s/tx/commit", q.endpoint, url.PathEscape(database))
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, requestURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("Accept", "application/json")
+	request.SetBasicAuth(q.username, q.password)
+	start := time.Now()
+	response, err := q.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("request to %s failed \n %v", requestURL, err)
+	}
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the response of %s \n %v", requestURL, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned %s: %s", requestURL, response.Status, strings.TrimSpace(string(data)))
+	}
+	result := &queryResponse{}
+	if err = json.Unmarshal(data, result); err != nil {
+		return nil, fmt.Errorf("unable to parse the response of %s \n %v", requestURL, err)
+	}
+	if len(result.Errors) > 0 {
+		return nil, fmt.Errorf("%s: %s", result.Errors[0].Code, result.Errors[0].Message)
+	}
+	log.Printf("%s executed on database %s in %v", query, database, time.Since(start).Round(time.Millisecond))
+	var rows [][]interface{}
+	for _, queryResult := range result.Results {
+		for _, data := range queryResult.Data {
+			rows = append(rows, data.Row)
+		}
+	}
+	return rows, nil
+}
@@ -0,0 +1,114 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// queryServer records the statements received by the HTTP API and fails the ones containing failOn
+// SHOW DATABASE returns read-only for the databases of readOnly and read-write for the others
+func queryServer(t *testing.T, failOn string, readOnly ...string) (*httptest.Server, *[]string) {
+	var lock sync.Mutex
+	var statements []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, _ := r.BasicAuth()
+		assert.Equal(t, "neo4j", username)
+		assert.Equal(t, "secret", password)
+		request := map[string][]statement{}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		query := request["statements"][0]
+		lock.Lock()
+		statements = append(statements, strings.TrimPrefix(r.URL.Path, "/db/")+": "+query.Statement+" "+strings.TrimSpace(toString(query.Parameters["name"])))
+		lock.Unlock()
+		if failOn != "" && strings.Contains(query.Statement, failOn) {
+			_, _ = w.Write([]byte(`{"results":[],"errors":[{"code":"Neo.ClientError.Security.Forbidden","message":"Permission denied"}]}`))
+			return
+		}
+		if strings.HasPrefix(query.Statement, "SHOW DATABASE") {
+			access := "read-write"
+			for _, database := range readOnly {
+				if database == query.Parameters["name"] {
+					access = accessReadOnly
+				}
+			}
+			_, _ = w.Write([]byte(`{"results":[{"columns":["access"],"data":[{"row":["` + access + `"]}]}],"errors":[]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"results":[{"columns":[],"data":[]}],"errors":[]}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, &statements
+}
+
+func toString(value interface{}) string {
+	if value == nil {
+		return ""
+	}
+	return value.(string)
+}
+
+func TestHTTPQuiescer(t *testing.T) {
+	t.Parallel()
+
+	server, statements := queryServer(t, "")
+	quiescer := NewHTTPQuiescer(server.URL+"/", "neo4j", "secret")
+	assert.NoError(t, quiescer.Quiesce(context.Background(), []string{"neo4j", "orders"}))
+	assert.NoError(t, quiescer.Resume(context.Background(), []string{"neo4j", "orders"}))
+	assert.Equal(t, []string{
+		"system/tx/commit: SHOW DATABASE $name YIELD access neo4j",
+		"system/tx/commit: ALTER DATABASE $name SET ACCESS READ ONLY WAIT neo4j",
+		"neo4j/tx/commit: CALL db.checkpoint() ",
+		"system/tx/commit: SHOW DATABASE $name YIELD access orders",
+		"system/tx/commit: ALTER DATABASE $name SET ACCESS READ ONLY WAIT orders",
+		"orders/tx/commit: CALL db.checkpoint() ",
+		"system/tx/commit: ALTER DATABASE $name SET ACCESS READ WRITE WAIT neo4j",
+		"system/tx/commit: ALTER DATABASE $name SET ACCESS READ WRITE WAIT orders",
+	}, *statements)
+}
+
+func TestHTTPQuiescerReadOnlyDatabase(t *testing.T) {
+	t.Parallel()
+
+	server, statements := queryServer(t, "", "orders")
+	quiescer := NewHTTPQuiescer(server.URL, "neo4j", "secret")
+	assert.NoError(t, quiescer.Quiesce(context.Background(), []string{"neo4j", "orders"}))
+	assert.NoError(t, quiescer.Resume(context.Background(), []string{"neo4j", "orders"}))
+	assert.Equal(t, []string{
+		"system/tx/commit: SHOW DATABASE $name YIELD access neo4j",
+		"system/tx/commit: ALTER DATABASE $name SET ACCESS READ ONLY WAIT neo4j",
+		"neo4j/tx/commit: CALL db.checkpoint() ",
+		"system/tx/commit: SHOW DATABASE $name YIELD access orders",
+		"orders/tx/commit: CALL db.checkpoint() ",
+		"system/tx/commit: ALTER DATABASE $name SET ACCESS READ WRITE WAIT neo4j",
+	}, *statements, "the database read only before the quiesce is kept read only")
+}
+
+func TestHTTPQuiescerErrors(t *testing.T) {
+	t.Parallel()
+
+	server, statements := queryServer(t, "checkpoint")
+	quiescer := NewHTTPQuiescer(server.URL, "neo4j", "secret")
+	assert.ErrorContains(t, quiescer.Quiesce(context.Background(), []string{"neo4j", "orders"}), "Permission denied")
+	assert.Len(t, *statements, 3, "the quiesce stops at the first failure")
+	*statements = nil
+	assert.NoError(t, quiescer.Resume(context.Background(), []string{"neo4j", "orders"}))
+	assert.Equal(t, []string{"system/tx/commit: ALTER DATABASE $name SET ACCESS READ WRITE WAIT neo4j"}, *statements,
+		"only the database set read only before the failure is resumed")
+
+	server, statements = queryServer(t, "READ WRITE")
+	quiescer = NewHTTPQuiescer(server.URL, "neo4j", "secret")
+	assert.NoError(t, quiescer.Quiesce(context.Background(), []string{"neo4j", "orders"}))
+	*statements = nil
+	assert.ErrorContains(t, quiescer.Resume(context.Background(), []string{"neo4j", "orders"}), "database orders")
+	assert.Len(t, *statements, 2, "every database is resumed")
+
+	quiescer = NewHTTPQuiescer("http://127.0.0.1:1", "neo4j", "secret")
+	assert.ErrorContains(t, quiescer.Quiesce(context.Background(), []string{"neo4j"}), "request to http://127.0.0.1:1/db/system/tx/commit failed")
+}
@@ -0,0 +1,313 @@
+// Package snapshot backs up the data volumes of a Neo4j release as CSI VolumeSnapshots
+package snapshot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+)
+
+// VolumeSnapshots is the resource of the CSI snapshot api , the external-snapshotter CRDs must be installed in the cluster
+var VolumeSnapshots = schema.GroupVersionResource{Group: "snapshot.storage.k8s.io", Version: "v1", Resource: "volumesnapshots"}
+
+// labels and annotations set on every snapshot
+const (
+	LabelManagedBy = "app.kubernetes.io/managed-by"
+	// LabelRelease is the label of the PersistentVolumeClaims of a release set by the neo4j chart
+	LabelRelease   = "helm.neo4j.com/instance"
+	LabelVolume    = "backup.neo4j.com/volume"
+	LabelDatabases = "backup.neo4j.com/databases"
+	// LabelRun identifies the run which took the snapshot , its value sorts in chronological order
+	LabelRun = "backup.neo4j.com/run"
+	// AnnotationDatabases contains the comma separated databases of the run , the label only contains a valid label value
+	AnnotationDatabases = "backup.neo4j.com/databases"
+
+	managedBy = "neo4j-backup"
+)
+
+const (
+	defaultReadyTimeout = 30 * time.Minute
+	pollInterval        = 5 * time.Second
+	requestTimeout      = 30 * time.Second
+	maxLabelLength      = 63
+)
+
+var invalidLabelCharacters = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Snapshotter snapshots the PersistentVolumeClaims of a release and applies the retention of the snapshots
+type Snapshotter struct {
+	dynamic   dynamic.Interface
+	client    kubernetes.Interface
+	namespace string
+	release   string
+	// className is the VolumeSnapshotClass of the snapshots , the default class of the CSI driver when empty
+	className string
+	// volumes contains the names of the volume claim templates snapshotted , ex: data
+	volumes      []string
+	readyTimeout time.Duration
+	pollInterval time.Duration
+	now          func() time.Time
+}
+
+// NewSnapshotter returns a snapshotter of the volumes of the release in the given namespace
+func NewSnapshotter(dynamicClient dynamic.Interface, client kubernetes.Interface, namespace string, release string, className string, volumes []string, readyTimeout time.Duration) *Snapshotter {
+	if readyTimeout <= 0 {
+		readyTimeout = defaultReadyTimeout
+	}
+	return &Snapshotter{
+		dynamic:      dynamicClient,
+		client:       client,
+		namespace:    namespace,
+		release:      release,
+		className:    className,
+		volumes:      volumes,
+		readyTimeout: readyTimeout,
+		pollInterval: pollInterval,
+		now:          time.Now,
+	}
+}
+
+// NewSnapshotterFromEnv returns a snapshotter using the in-cluster config
+// SNAPSHOT_RELEASE is the neo4j release whose volumes are snapshotted in DATABASE_NAMESPACE , SNAPSHOT_VOLUMES the comma separated
+// volume claim templates (data by default) , SNAPSHOT_CLASS the VolumeSnapshotClass and SNAPSHOT_READY_TIMEOUT the time waited for readyToUse
+func NewSnapshotterFromEnv() (*Snapshotter, error) {
+	release := strings.TrimSpace(os.Getenv("SNAPSHOT_RELEASE"))
+	if release == "" {
+		return nil, fmt.Errorf("SNAPSHOT_RELEASE must contain the name of the neo4j release whose volumes are snapshotted")
+	}
+	namespace := strings.TrimSpace(os.Getenv("DATABASE_NAMESPACE"))
+	if namespace == "" {
+		namespace = "default"
+	}
+	var volumes []string
+	for _, volume := range strings.Split(os.Getenv("SNAPSHOT_VOLUMES"), ",") {
+		if volume = strings.TrimSpace(volume); volume != "" {
+			volumes = append(volumes, volume)
+		}
+	}
+	if len(volumes) == 0 {
+		volumes = []string{"data"}
+	}
+	var readyTimeout time.Duration
+	if value := strings.TrimSpace(os.Getenv("SNAPSHOT_READY_TIMEOUT")); value != "" {
+		timeout, err := time.ParseDuration(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid SNAPSHOT_READY_TIMEOUT %s \n %v", value, err)
+		}
+		readyTimeout = timeout
+	}
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		return nil, fmt.Errorf("error seen while getting cluster config \n %v", err)
+	}
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error seen while getting kubernetes client \n %v", err)
+	}
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("error seen while getting kubernetes dynamic client \n %v", err)
+	}
+	return NewSnapshotter(dynamicClient, client, namespace, release, strings.TrimSpace(os.Getenv("SNAPSHOT_CLASS")), volumes, readyTimeout), nil
+}
+
+// Volumes returns the sorted names of the PersistentVolumeClaims of the release created from the snapshotted volume claim templates
+func (s *Snapshotter) Volumes(ctx context.Context) ([]string, error) {
+	list, err := s.client.CoreV1().PersistentVolumeClaims(s.namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s", LabelRelease, s.release),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the persistent volume claims of release %s \n %v", s.release, err)
+	}
+	var names []string
+	for _, claim := range list.Items {
+		for _, volume := range s.volumes {
+			// the claims of a statefulset are named <volume claim template>-<pod>
+			if strings.HasPrefix(claim.Name, volume+"-") {
+				names = append(names, claim.Name)
+				break
+			}
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no persistent volume claim of volume(s) %v found for release %s in namespace %s", s.volumes, s.release, s.namespace)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+// Create requests a snapshot of every volume without waiting for the snapshots to be taken
+// The snapshots created before a failure are returned along with the error
+func (s *Snapshotter) Create(ctx context.Context, volumes []string, databases []string) ([]common.SnapshotSummary, error) {
+	run := strings.ToLower(s.now().UTC().Format("2006-01-02T15-04-05"))
+	var snapshots []common.SnapshotSummary
+	for _, volume := range volumes {
+		snapshot := common.SnapshotSummary{Name: fmt.Sprintf("%s-%s", volume, run), Volume: volume, Class: s.className}
+		spec := map[string]interface{}{
+			"source": map[string]interface{}{"persistentVolumeClaimName": volume},
+		}
+		if s.className != "" {
+			spec["volumeSnapshotClassName"] = s.className
+		}
+		object := &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": VolumeSnapshots.GroupVersion().String(),
+			"kind":       "VolumeSnapshot",
+			"metadata": map[string]interface{}{
+				"name":      snapshot.Name,
+				"namespace": s.namespace,
+				"labels": map[string]interface{}{
+					LabelManagedBy: managedBy,
+					LabelRelease:   s.release,
+					LabelVolume:    labelValue(volume),
+					LabelDatabases: databasesLabel(databases),
+					LabelRun:       run,
+				},
+				"annotations": map[string]interface{}{
+					AnnotationDatabases: strings.Join(databases, ","),
+				},
+			},
+			"spec": spec,
+		}}
+		requestCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+		_, err := s.dynamic.Resource(VolumeSnapshots).Namespace(s.namespace).Create(requestCtx, object, metav1.CreateOptions{})
+		cancel()
+		if err != nil {
+			return snapshots, fmt.Errorf("unable to create snapshot %s of volume %s \n %v", snapshot.Name, volume, err)
+		}
+		log.Printf("Snapshot %s of volume %s requested", snapshot.Name, volume)
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, nil
+}
+
+// WaitTaken waits until the point in time of every snapshot is cut (status.creationTime is set) , the databases can be resumed then
+func (s *Snapshotter) WaitTaken(ctx context.Context, snapshots []common.SnapshotSummary) error {
+	return s.wait(ctx, snapshots, func(snapshot *common.SnapshotSummary) bool { return snapshot.CreationTime != "" })
+}
+
+// WaitReady waits until every snapshot is ready to be used to restore a volume
+func (s *Snapshotter) WaitReady(ctx context.Context, snapshots []common.SnapshotSummary) error {
+	return s.wait(ctx, snapshots, func(snapshot *common.SnapshotSummary) bool { return snapshot.ReadyToUse })
+}
+
+// wait polls every snapshot until the condition is met , the snapshots are updated with their status
+// The wait fails as soon as the CSI driver reports an error for a snapshot
+func (s *Snapshotter) wait(ctx context.Context, snapshots []common.SnapshotSummary, done func(snapshot *common.SnapshotSummary) bool) error {
+	for i := range snapshots {
+		snapshot := &snapshots[i]
+		err := wait.PollUntilContextTimeout(ctx, s.pollInterval, s.readyTimeout, true, func(ctx context.Context) (bool, error) {
+			if err := s.refresh(ctx, snapshot); err != nil {
+				return false, err
+			}
+			if snapshot.Error != "" {
+				return false, fmt.Errorf("snapshot %s failed: %s", snapshot.Name, snapshot.Error)
+			}
+			return done(snapshot), nil
+		})
+		if err != nil {
+			if snapshot.Error == "" {
+				snapshot.Error = err.Error()
+			}
+			return fmt.Errorf("error seen while waiting for snapshot %s of volume %s \n %v", snapshot.Name, snapshot.Volume, err)
+		}
+	}
+	return nil
+}
+
+// refresh updates the snapshot with the status of the VolumeSnapshot
+func (s *Snapshotter) refresh(ctx context.Context, snapshot *common.SnapshotSummary) error {
+	requestCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	object, err := s.dynamic.Resource(VolumeSnapshots).Namespace(s.namespace).Get(requestCtx, snapshot.Name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("unable to get snapshot %s \n %v", snapshot.Name, err)
+	}
+	snapshot.ReadyToUse, _, _ = unstructured.NestedBool(object.Object, "status", "readyToUse")
+	snapshot.CreationTime, _, _ = unstructured.NestedString(object.Object, "status", "creationTime")
+	snapshot.Error, _, _ = unstructured.NestedString(object.Object, "status", "error", "message")
+	if restoreSize, found, _ := unstructured.NestedFieldNoCopy(object.Object, "status", "restoreSize"); found && restoreSize != nil {
+		snapshot.RestoreSize = fmt.Sprint(restoreSize)
+	}
+	return nil
+}
+
+// Prune keeps the given number of ready snapshots of every volume of the release and deletes the older snapshots
+// Snapshots more recent than the oldest kept one are left untouched , even when not ready. Nothing is deleted when keep is 0
+func (s *Snapshotter) Prune(ctx context.Context, keep int) ([]string, error) {
+	if keep <= 0 {
+		return nil, nil
+	}
+	requestCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+	list, err := s.dynamic.Resource(VolumeSnapshots).Namespace(s.namespace).List(requestCtx, metav1.ListOptions{
+		LabelSelector: fmt.Sprintf("%s=%s,%s=%s", LabelManagedBy, managedBy, LabelRelease, s.release),
+	})
+	cancel()
+	if err != nil {
+		return nil, fmt.Errorf("unable to list the snapshots of release %s \n %v", s.release, err)
+	}
+	byVolume := make(map[string][]unstructured.Unstructured)
+	for _, item := range list.Items {
+		volume := item.GetLabels()[LabelVolume]
+		byVolume[volume] = append(byVolume[volume], item)
+	}
+	var deleted []string
+	for volume, items := range byVolume {
+		sort.Slice(items, func(i, j int) bool { return items[i].GetLabels()[LabelRun] > items[j].GetLabels()[LabelRun] })
+		ready := 0
+		for _, item := range items {
+			if ready >= keep {
+				requestCtx, cancel := context.WithTimeout(ctx, requestTimeout)
+				err = s.dynamic.Resource(VolumeSnapshots).Namespace(s.namespace).Delete(requestCtx, item.GetName(), metav1.DeleteOptions{})
+				cancel()
+				if err != nil {
+					return deleted, fmt.Errorf("unable to delete snapshot %s of volume %s \n %v", item.GetName(), volume, err)
+				}
+				log.Printf("Snapshot %s of volume %s deleted !!", item.GetName(), volume)
+				deleted = append(deleted, item.GetName())
+				continue
+			}
+			if readyToUse, _, _ := unstructured.NestedBool(item.Object, "status", "readyToUse"); readyToUse {
+				ready++
+			}
+		}
+	}
+	sort.Strings(deleted)
+	return deleted, nil
+}
+
+// databasesLabel returns the databases as a label value , * stands for all the databases
+func databasesLabel(databases []string) string {
+	var names []string
+	for _, database := range databases {
+		if database = strings.TrimSpace(database); database == "*" {
+			return "all"
+		} else if database != "" {
+			names = append(names, database)
+		}
+	}
+	// database names only contain letters , digits , dots and dashes hence _ separates them unambiguously
+	return labelValue(strings.Join(names, "_"))
+}
+
+// labelValue returns the value trimmed to a valid label value
+func labelValue(value string) string {
+	value = invalidLabelCharacters.ReplaceAllString(value, "-")
+	if len(value) > maxLabelLength {
+		value = value[:maxLabelLength]
+	}
+	return strings.Trim(value, "._-")
+}
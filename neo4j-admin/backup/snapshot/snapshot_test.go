@@ -0,0 +1,172 @@
+package snapshot
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func claim(name string, release string) *corev1.PersistentVolumeClaim {
+	return &corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "neo4j", Labels: map[string]string{LabelRelease: release}}}
+}
+
+func volumeSnapshot(name string, volume string, run string, readyToUse bool) runtime.Object {
+	return &unstructured.Unstructured{Object: map[string]interface{}{
+		"apiVersion": "snapshot.storage.k8s.io/v1",
+		"kind":       "VolumeSnapshot",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": "neo4j",
+			"labels":    map[string]interface{}{LabelManagedBy: managedBy, LabelRelease: "graph", LabelVolume: volume, LabelRun: run},
+		},
+		"status": map[string]interface{}{"readyToUse": readyToUse},
+	}}
+}
+
+// newSnapshotter returns a snapshotter of release graph , the created snapshots get the status of their volume in statuses
+func newSnapshotter(statuses map[string]map[string]interface{}, objects ...runtime.Object) (*Snapshotter, *dynamicfake.FakeDynamicClient) {
+	client := fake.NewSimpleClientset(claim("data-graph-0", "graph"), claim("data-graph-1", "graph"), claim("logs-graph-0", "graph"), claim("data-other-0", "other"))
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{VolumeSnapshots: "VolumeSnapshotList"}, objects...)
+	// the CSI driver takes the snapshots
+	dynamicClient.PrependReactor("create", "volumesnapshots", func(action k8stesting.Action) (bool, runtime.Object, error) {
+		object := action.(k8stesting.CreateAction).GetObject().(*unstructured.Unstructured)
+		volume, _, _ := unstructured.NestedString(object.Object, "spec", "source", "persistentVolumeClaimName")
+		if snapshotStatus, found := statuses[volume]; found {
+			_ = unstructured.SetNestedMap(object.Object, snapshotStatus, "status")
+		}
+		return false, nil, nil
+	})
+	snapshotter := NewSnapshotter(dynamicClient, client, "neo4j", "graph", "csi-snapclass", []string{"data"}, time.Second)
+	snapshotter.pollInterval = 10 * time.Millisecond
+	snapshotter.now = func() time.Time { return time.Date(2024, 6, 13, 10, 2, 0, 0, time.UTC) }
+	return snapshotter, dynamicClient
+}
+
+func ready(size string) map[string]interface{} {
+	return map[string]interface{}{"readyToUse": true, "creationTime": "2024-06-13T10:02:01Z", "restoreSize": size}
+}
+
+func TestSnapshot(t *testing.T) {
+	t.Parallel()
+
+	snapshotter, dynamicClient := newSnapshotter(map[string]map[string]interface{}{"data-graph-0": ready("10Gi"), "data-graph-1": ready("12Gi")})
+	ctx := context.Background()
+	volumes, err := snapshotter.Volumes(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data-graph-0", "data-graph-1"}, volumes)
+
+	snapshots, err := snapshotter.Create(ctx, volumes, []string{"neo4j", "orders"})
+	assert.NoError(t, err)
+	assert.NoError(t, snapshotter.WaitTaken(ctx, snapshots))
+	assert.NoError(t, snapshotter.WaitReady(ctx, snapshots))
+	if assert.Len(t, snapshots, 2) {
+		assert.Equal(t, "data-graph-0-2024-06-13t10-02-00", snapshots[0].Name)
+		assert.True(t, snapshots[0].ReadyToUse)
+		assert.Equal(t, "10Gi", snapshots[0].RestoreSize)
+		assert.Equal(t, "2024-06-13T10:02:01Z", snapshots[0].CreationTime)
+	}
+
+	object, err := dynamicClient.Resource(VolumeSnapshots).Namespace("neo4j").Get(ctx, "data-graph-1-2024-06-13t10-02-00", metav1.GetOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		LabelManagedBy: managedBy,
+		LabelRelease:   "graph",
+		LabelVolume:    "data-graph-1",
+		LabelDatabases: "neo4j_orders",
+		LabelRun:       "2024-06-13t10-02-00",
+	}, object.GetLabels())
+	assert.Equal(t, "neo4j,orders", object.GetAnnotations()[AnnotationDatabases])
+	className, _, _ := unstructured.NestedString(object.Object, "spec", "volumeSnapshotClassName")
+	assert.Equal(t, "csi-snapclass", className)
+}
+
+func TestSnapshotFailures(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name      string
+		statuses  map[string]map[string]interface{}
+		wantError string
+	}{
+		{
+			name:      "driver error",
+			statuses:  map[string]map[string]interface{}{"data-graph-0": {"readyToUse": false, "error": map[string]interface{}{"message": "quota exceeded"}}},
+			wantError: "quota exceeded",
+		},
+		{name: "never ready", statuses: map[string]map[string]interface{}{"data-graph-0": {"creationTime": "2024-06-13T10:02:01Z"}}, wantError: "context deadline exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			snapshotter, _ := newSnapshotter(tt.statuses)
+			snapshotter.readyTimeout = 100 * time.Millisecond
+			snapshots, err := snapshotter.Create(context.Background(), []string{"data-graph-0"}, []string{"*"})
+			assert.NoError(t, err)
+			err = snapshotter.WaitReady(context.Background(), snapshots)
+			assert.ErrorContains(t, err, tt.wantError)
+			assert.NotEmpty(t, snapshots[0].Error)
+		})
+	}
+
+	snapshotter, _ := newSnapshotter(nil)
+	snapshotter.release = "missing"
+	_, err := snapshotter.Volumes(context.Background())
+	assert.ErrorContains(t, err, "no persistent volume claim")
+}
+
+func TestPrune(t *testing.T) {
+	t.Parallel()
+
+	snapshotter, dynamicClient := newSnapshotter(nil,
+		volumeSnapshot("data-graph-0-1", "data-graph-0", "2024-06-10t10-00-00", true),
+		volumeSnapshot("data-graph-0-2", "data-graph-0", "2024-06-11t10-00-00", false),
+		volumeSnapshot("data-graph-0-3", "data-graph-0", "2024-06-12t10-00-00", true),
+		volumeSnapshot("data-graph-0-4", "data-graph-0", "2024-06-13t10-00-00", true),
+		volumeSnapshot("data-graph-0-5", "data-graph-0", "2024-06-14t10-00-00", false),
+		volumeSnapshot("data-graph-1-1", "data-graph-1", "2024-06-13t10-00-00", true),
+	)
+	deleted, err := snapshotter.Prune(context.Background(), 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"data-graph-0-1", "data-graph-0-2"}, deleted)
+
+	list, err := dynamicClient.Resource(VolumeSnapshots).Namespace("neo4j").List(context.Background(), metav1.ListOptions{})
+	assert.NoError(t, err)
+	var names []string
+	for _, item := range list.Items {
+		names = append(names, item.GetName())
+	}
+	sort.Strings(names)
+	assert.Equal(t, []string{"data-graph-0-3", "data-graph-0-4", "data-graph-0-5", "data-graph-1-1"}, names)
+
+	deleted, err = snapshotter.Prune(context.Background(), 0)
+	assert.NoError(t, err)
+	assert.Empty(t, deleted, "every snapshot is kept")
+}
+
+func TestDatabasesLabel(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		databases []string
+		want      string
+	}{
+		{databases: []string{"neo4j"}, want: "neo4j"},
+		{databases: []string{"neo4j", " orders.eu "}, want: "neo4j_orders.eu"},
+		{databases: []string{"neo4j", "*"}, want: "all"},
+		{databases: []string{"a234567890123456789012345678901234567890", "b234567890123456789012345678901234567890"}, want: "a234567890123456789012345678901234567890_b234567890123456789012"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, databasesLabel(tt.databases))
+	}
+}
@@ -0,0 +1,127 @@
+package status
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/neo4j/helm-charts/logging"
+	"github.com/neo4j/helm-charts/neo4j-admin/backup/common"
+)
+
+const (
+	Success = "success"
+	Partial = "partial"
+	Failed  = "failed"
+	Skipped = "skipped"
+)
+
+// Destination contains the upload result of a single backup destination
+type Destination struct {
+	Name          string   `json:"name"`
+	CloudProvider string   `json:"cloudProvider"`
+	BucketName    string   `json:"bucketName"`
+	Status        string   `json:"status"`
+	Files         []string `json:"files,omitempty"`
+	Error         string   `json:"error,omitempty"`
+	// Transfers contains the progress figures of every file uploaded to the destination
+	Transfers []common.TransferProgress `json:"transfers,omitempty"`
+}
+
+// Target contains the result of a single target of a job backing up multiple Neo4j deployments
+type Target struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	ExitCode int    `json:"exitCode"`
+	Error    string `json:"error,omitempty"`
+	// Run is the status written by the run of the target , missing if the run did not complete
+	Run *Run `json:"run,omitempty"`
+}
+
+// Run contains the result of a single execution of the backup binary
+type Run struct {
+	// ID is the correlation ID of the run present in every log record of the run
+	ID                      string    `json:"id,omitempty"`
+	StartTime               time.Time `json:"startTime"`
+	EndTime                 time.Time `json:"endTime"`
+	Status                  string    `json:"status"`
+	BackupFiles             []string  `json:"backupFiles,omitempty"`
+	ConsistencyCheckReports []string  `json:"consistencyCheckReports,omitempty"`
+	// ConsistencyChecks contains the summary of every consistency check of the run , consistent or not
+	ConsistencyChecks []common.ConsistencyCheckSummary `json:"consistencyChecks,omitempty"`
+	// AggregatedFiles contains the artifacts created by the aggregation of the chains which reached the aggregation threshold
+	AggregatedFiles []string `json:"aggregatedFiles,omitempty"`
+	// Imports contains the summary of the import when the run imports CSV files
+	Imports []common.ImportSummary `json:"imports,omitempty"`
+	// Maintenance contains the report of the copy (or migrate) when the run performs a store maintenance operation
+	Maintenance *common.MaintenanceReport `json:"maintenance,omitempty"`
+	// Snapshots contains the VolumeSnapshots taken when the run snapshots the data volumes
+	Snapshots []common.SnapshotSummary `json:"snapshots,omitempty"`
+	// PrunedSnapshots contains the snapshots deleted by the retention
+	PrunedSnapshots []string      `json:"prunedSnapshots,omitempty"`
+	Destinations    []Destination `json:"destinations,omitempty"`
+	// Targets contains the result of every target when the job backs up multiple Neo4j deployments
+	Targets []Target `json:"targets,omitempty"`
+	Error   string   `json:"error,omitempty"`
+
+	lock sync.Mutex
+}
+
+// NewRun returns a new Run with the start time set to now
+func NewRun() *Run {
+	return &Run{
+		ID:        logging.RunID(),
+		StartTime: time.Now().UTC(),
+	}
+}
+
+// AddDestination records the upload result of a destination
+func (r *Run) AddDestination(destination Destination) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Destinations = append(r.Destinations, destination)
+}
+
+// AddTarget records the result of a target
+func (r *Run) AddTarget(target Target) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.Targets = append(r.Targets, target)
+}
+
+// Finish sets the end time and the final status of the run
+func (r *Run) Finish(status string, err error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.EndTime = time.Now().UTC()
+	r.Status = status
+	if err != nil {
+		r.Error = err.Error()
+	}
+}
+
+// JSON returns the run as an indented json document
+func (r *Run) JSON() ([]byte, error) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return json.MarshalIndent(r, "", "  ")
+}
+
+// Write logs the run status and writes it to the given file path (if provided)
+func (r *Run) Write(filePath string) error {
+	data, err := r.JSON()
+	if err != nil {
+		return fmt.Errorf("unable to marshal run status \n %v", err)
+	}
+	log.Printf("Run status \n %s", string(data))
+	if filePath == "" {
+		return nil
+	}
+	if err = os.WriteFile(filePath, data, 0644); err != nil {
+		return fmt.Errorf("unable to write run status to %s \n %v", filePath, err)
+	}
+	return nil
+}
@@ -0,0 +1,231 @@
+// Package tracing exports the phases of a backup run as OpenTelemetry spans to an OTLP endpoint
+// The phases run one after another , hence the span in progress is kept by the package and every new span becomes its child
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	tracerName         = "github.com/neo4j/helm-charts/neo4j-admin/backup"
+	defaultServiceName = "neo4j-backup"
+	shutdownTimeout    = 10 * time.Second
+)
+
+// attribute keys of the spans
+const (
+	Database            = attribute.Key("neo4j.database")
+	Databases           = attribute.Key("neo4j.databases")
+	Artifact            = attribute.Key("backup.artifact")
+	Artifacts           = attribute.Key("backup.artifacts")
+	Bytes               = attribute.Key("backup.bytes")
+	Destination         = attribute.Key("backup.destination")
+	Bucket              = attribute.Key("backup.bucket")
+	Target              = attribute.Key("backup.target")
+	BackupType          = attribute.Key("backup.type")
+	Status              = attribute.Key("backup.status")
+	CheckErrors         = attribute.Key("consistency_check.errors")
+	ImportNodes         = attribute.Key("import.nodes")
+	ImportRelationships = attribute.Key("import.relationships")
+	ImportBadEntries    = attribute.Key("import.bad_entries")
+	HookEvent           = attribute.Key("hook.event")
+	HookName            = attribute.Key("hook.name")
+	ExitCode            = attribute.Key("backup.exit_code")
+	Command             = attribute.Key("process.command")
+)
+
+var (
+	propagator = propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{})
+
+	lock     sync.Mutex
+	current  = context.Background()
+	provider *sdktrace.TracerProvider
+)
+
+// Init exports the spans to the OTLP endpoint when TRACING_ENABLED is true
+// The exporter is configured by the standard OTEL_EXPORTER_OTLP_* env variables (endpoint , headers , insecure ...) and
+// the sampler by OTEL_TRACES_SAMPLER. The run joins the trace present in TRACEPARENT , which is how the targets of a
+// multi target job line up with the parent job
+func Init() error {
+	lock.Lock()
+	defer lock.Unlock()
+	current = propagator.Extract(context.Background(), environmentCarrier{})
+	if os.Getenv("TRACING_ENABLED") != "true" {
+		return nil
+	}
+	exporter, err := otlptracehttp.New(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to create the OTLP trace exporter \n %v", err)
+	}
+	res, err := newResource()
+	if err != nil {
+		return fmt.Errorf("unable to create the trace resource \n %v", err)
+	}
+	provider = sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+	otel.SetTracerProvider(provider)
+	return nil
+}
+
+// newResource describes the backup job , OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES take precedence
+func newResource() (*resource.Resource, error) {
+	attributes := []attribute.KeyValue{semconv.ServiceName(defaultServiceName)}
+	if podName := os.Getenv("POD_NAME"); podName != "" {
+		attributes = append(attributes, semconv.K8SPodName(podName))
+	}
+	if namespace := os.Getenv("POD_NAMESPACE"); namespace != "" {
+		attributes = append(attributes, semconv.K8SNamespaceName(namespace))
+	}
+	return resource.New(context.Background(),
+		resource.WithTelemetrySDK(),
+		resource.WithAttributes(attributes...),
+		resource.WithFromEnv(),
+	)
+}
+
+// Shutdown exports the pending spans , it must be called before the process exits
+func Shutdown() {
+	lock.Lock()
+	defer lock.Unlock()
+	if provider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := provider.Shutdown(ctx); err != nil {
+		log.Printf("Warning: unable to export the traces: %v", err)
+	}
+	provider = nil
+}
+
+// Span is a phase of the run
+type Span struct {
+	ctx  context.Context
+	span trace.Span
+	// previous is the span in progress restored once the span ends , nil when the span was started detached
+	previous context.Context
+}
+
+// Start starts a child of the span in progress which becomes the span in progress until it ends
+func Start(name string, attributes ...attribute.KeyValue) *Span {
+	lock.Lock()
+	defer lock.Unlock()
+	s := start(current, name, attributes...)
+	s.previous = current
+	current = s.ctx
+	return s
+}
+
+// StartDetached starts a child of the span in progress without making it the span in progress
+// It is used for the work running concurrently , ex: the targets of a multi target job
+func StartDetached(name string, attributes ...attribute.KeyValue) *Span {
+	lock.Lock()
+	defer lock.Unlock()
+	return start(current, name, attributes...)
+}
+
+func start(parent context.Context, name string, attributes ...attribute.KeyValue) *Span {
+	ctx, span := otel.Tracer(tracerName).Start(parent, name, trace.WithAttributes(attributes...))
+	return &Span{ctx: ctx, span: span}
+}
+
+// SetAttributes adds the given attributes to the span
+func (s *Span) SetAttributes(attributes ...attribute.KeyValue) {
+	s.span.SetAttributes(attributes...)
+}
+
+// AddEvent records an event of the span , ex: an artifact created by the backup
+func (s *Span) AddEvent(name string, attributes ...attribute.KeyValue) {
+	s.span.AddEvent(name, trace.WithAttributes(attributes...))
+}
+
+// Record adds a finished child span , ex: the upload of a file whose timings are known once the upload completed
+func (s *Span) Record(name string, startTime time.Time, endTime time.Time, attributes ...attribute.KeyValue) {
+	_, span := otel.Tracer(tracerName).Start(s.ctx, name, trace.WithTimestamp(startTime), trace.WithAttributes(attributes...))
+	span.End(trace.WithTimestamp(endTime))
+}
+
+// End ends the span recording the given error , if any
+func (s *Span) End(err error) {
+	if err != nil {
+		s.span.RecordError(err)
+		s.span.SetStatus(codes.Error, firstLine(err.Error()))
+	}
+	s.span.End()
+	if s.previous == nil {
+		return
+	}
+	lock.Lock()
+	defer lock.Unlock()
+	if current == s.ctx {
+		current = s.previous
+	}
+}
+
+// Environment returns the TRACEPARENT (and TRACESTATE) env variables of the span to be passed to a child process
+func (s *Span) Environment() []string {
+	return environment(s.ctx)
+}
+
+// Environment returns the TRACEPARENT (and TRACESTATE) env variables of the span in progress , ex: for a hook command
+func Environment() []string {
+	lock.Lock()
+	ctx := current
+	lock.Unlock()
+	return environment(ctx)
+}
+
+// Inject adds the trace context of the span in progress to the headers of an outgoing request , ex: a webhook call
+func Inject(header http.Header) {
+	lock.Lock()
+	ctx := current
+	lock.Unlock()
+	propagator.Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+func environment(ctx context.Context) []string {
+	carrier := make(propagation.MapCarrier)
+	propagator.Inject(ctx, carrier)
+	var env []string
+	for _, key := range carrier.Keys() {
+		env = append(env, fmt.Sprintf("%s=%s", strings.ToUpper(key), carrier.Get(key)))
+	}
+	return env
+}
+
+// environmentCarrier reads the trace context passed by the parent process , ex: TRACEPARENT
+type environmentCarrier struct{}
+
+func (environmentCarrier) Get(key string) string {
+	return os.Getenv(strings.ToUpper(key))
+}
+
+func (environmentCarrier) Set(key string, value string) {}
+
+func (environmentCarrier) Keys() []string {
+	return []string{"traceparent", "tracestate", "baggage"}
+}
+
+func firstLine(message string) string {
+	message = strings.TrimSpace(message)
+	if i := strings.IndexByte(message, '\n'); i >= 0 {
+		return strings.TrimSpace(message[:i])
+	}
+	return message
+}
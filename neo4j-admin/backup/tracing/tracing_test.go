@@ -0,0 +1,92 @@
+package tracing
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanNamed(spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	return nil
+}
+
+func TestSpansFollowThePhases(t *testing.T) {
+	recorder := recordSpans(t)
+	t.Setenv("TRACING_ENABLED", "false")
+	t.Setenv("TRACEPARENT", "")
+	assert.NoError(t, Init())
+
+	run := Start("backup run")
+	backup := Start("backup", Databases.String("neo4j"))
+	backup.End(nil)
+	check := Start("consistency check", Database.String("neo4j"))
+	check.End(errors.New("Consistency Check Failed for database neo4j!! \n output = java.lang.OutOfMemoryError"))
+	target := StartDetached("backup target", Target.String("prod"))
+	upload := Start("upload")
+	header := make(http.Header)
+	Inject(header)
+	upload.End(nil)
+	target.End(nil)
+	run.End(nil)
+
+	spans := recorder.Ended()
+	assert.Len(t, spans, 5)
+	runSpan := spanNamed(spans, "backup run")
+	assert.False(t, runSpan.Parent().IsValid())
+	for _, name := range []string{"backup", "consistency check", "backup target", "upload"} {
+		assert.Equal(t, runSpan.SpanContext().SpanID(), spanNamed(spans, name).Parent().SpanID(), name)
+		assert.Equal(t, runSpan.SpanContext().TraceID(), spanNamed(spans, name).SpanContext().TraceID(), name)
+	}
+	checkSpan := spanNamed(spans, "consistency check")
+	assert.Equal(t, codes.Error, checkSpan.Status().Code)
+	assert.Equal(t, "Consistency Check Failed for database neo4j!!", checkSpan.Status().Description)
+	assert.Contains(t, header.Get("traceparent"), spanNamed(spans, "upload").SpanContext().SpanID().String())
+}
+
+func TestChildProcessJoinsTheTrace(t *testing.T) {
+	recorder := recordSpans(t)
+	t.Setenv("TRACING_ENABLED", "false")
+	t.Setenv("TRACEPARENT", "")
+	assert.NoError(t, Init())
+
+	target := StartDetached("backup target")
+	env := target.Environment()
+	target.End(nil)
+	if assert.Len(t, env, 1) {
+		name, value, _ := strings.Cut(env[0], "=")
+		assert.Equal(t, "TRACEPARENT", name)
+		// the child process reads the trace context of the target span at startup
+		t.Setenv(name, value)
+	}
+	assert.NoError(t, Init())
+	Start("backup run").End(nil)
+	t.Setenv("TRACEPARENT", "")
+	assert.NoError(t, Init())
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, spans[0].SpanContext().TraceID(), spans[1].SpanContext().TraceID())
+		assert.Equal(t, spans[0].SpanContext().SpanID(), spans[1].Parent().SpanID())
+		assert.True(t, spans[1].Parent().IsRemote())
+	}
+}
@@ -86,3 +86,433 @@ limits:
   {{- end }}
 
 {{- end -}}
+
+{{/* keyValuePairs converts a map into a comma separated list of key=value pairs */}}
+{{- define "neo4j.backup.keyValuePairs" -}}
+    {{- $pairs := list -}}
+    {{- range $key, $value := . -}}
+        {{- $pairs = append $pairs (printf "%s=%s" $key ($value | toString)) -}}
+    {{- end -}}
+    {{- join "," $pairs -}}
+{{- end -}}
+
+{{/* destinations returns the additional backup destinations as a json list consumed by the backup binary */}}
+{{- define "neo4j.backup.destinations" -}}
+    {{- $destinations := list -}}
+    {{- range $destination := .Values.backup.destinations -}}
+        {{- $credentialPath := "/credentials/" -}}
+        {{- if $destination.secretName -}}
+            {{- $credentialPath = printf "/credentials-%s/%s" $destination.name $destination.secretKeyName -}}
+        {{- end -}}
+        {{- $entry := dict "name" $destination.name "cloudProvider" $destination.cloudProvider "bucketName" $destination.bucketName "credentialPath" $credentialPath "keyLayout" ($destination.keyLayout | default "") -}}
+        {{- if $destination.assumeRoles -}}
+            {{- $_ := set $entry "assumeRoles" $destination.assumeRoles -}}
+        {{- end -}}
+        {{- $destinations = append $destinations $entry -}}
+    {{- end -}}
+    {{- if $destinations -}}
+        {{- toJson $destinations -}}
+    {{- end -}}
+{{- end -}}
+
+{{/* sslEnabled returns true when the certificates of the backup ssl policy are provided */}}
+{{- define "neo4j.backup.sslEnabled" -}}
+    {{- if and .Values.ssl .Values.ssl.backup (or .Values.ssl.backup.privateKey.secretName .Values.ssl.backup.publicCertificate.secretName) -}}
+        {{- true -}}
+    {{- else -}}
+        {{- false -}}
+    {{- end -}}
+{{- end -}}
+
+{{- define "neo4j.backup.ssl.volumesFromSecrets" -}}
+{{- range $name, $sslSpec := . -}}
+{{- if ( or $sslSpec.privateKey.secretName $sslSpec.publicCertificate.secretName ) }}
+- name: "{{ $name }}-cert"
+  secret:
+    secretName: "{{ required (printf "When ssl.%s.privateKey is set then ssl.%s.publicCertificate.secretName must also be provided" $name $name) $sslSpec.publicCertificate.secretName }}"
+- name: "{{ $name }}-key"
+  secret:
+    secretName: "{{ required (printf "When ssl.%s.publicCertificate is set then ssl.%s.privateKey.secretName must also be provided" $name $name) $sslSpec.privateKey.secretName }}"
+{{- if $sslSpec.trustedCerts.sources }}
+- name: "{{ $name }}-trusted"
+  projected:
+    defaultMode: 0440
+    {{- $sslSpec.trustedCerts | toYaml | nindent 4 }}
+{{- end }}
+{{- if $sslSpec.revokedCerts.sources }}
+- name: "{{ $name }}-revoked"
+  projected:
+    defaultMode: 0440
+    {{- $sslSpec.revokedCerts | toYaml | nindent 4 }}
+{{- end }}
+{{- end -}}
+{{- end -}}
+{{- end -}}
+
+{{- define "neo4j.backup.ssl.volumeMountsFromSecrets" -}}
+{{- range $name, $sslSpec := . -}}
+{{- if ( or $sslSpec.privateKey.secretName $sslSpec.publicCertificate.secretName ) }}
+- name: "{{ $name }}-cert"
+  mountPath: "/var/lib/neo4j/certificates/{{ $name }}/public.crt"
+  subPath: "{{ $sslSpec.publicCertificate.subPath | default "public.crt" }}"
+  readOnly: true
+- name: "{{ $name }}-key"
+  mountPath: "/var/lib/neo4j/certificates/{{ $name }}/private.key"
+  subPath: "{{ $sslSpec.privateKey.subPath | default "private.key" }}"
+  readOnly: true
+{{- if $sslSpec.trustedCerts.sources }}
+- name: "{{ $name }}-trusted"
+  mountPath: "/var/lib/neo4j/certificates/{{ $name }}/trusted"
+  readOnly: true
+{{- end }}
+{{- if $sslSpec.revokedCerts.sources }}
+- name: "{{ $name }}-revoked"
+  mountPath: "/var/lib/neo4j/certificates/{{ $name }}/revoked"
+  readOnly: true
+{{- end }}
+{{- end -}}
+{{- end -}}
+{{- end -}}
+
+{{/* env contains the env variables of the backup container shared by the CronJob and the daemon Deployment */}}
+{{- define "neo4j.backup.env" -}}
+- name: DATABASE_SERVICE_NAME
+  value: {{ .Values.backup.databaseAdminServiceName  | trim }}
+- name: DATABASE_SERVICE_IP
+  value: {{ .Values.backup.databaseAdminServiceIP  | trim }}
+- name: DATABASE_NAMESPACE
+  value: {{ .Values.backup.databaseNamespace | default "default"  | trim }}
+- name: DATABASE_BACKUP_PORT
+  value: {{ .Values.backup.databaseBackupPort | default "6362" | trim | quote }}
+- name: DATABASE_CLUSTER_DOMAIN
+  value: {{ .Values.backup.databaseClusterDomain | default "cluster.local"  | trim | quote }}
+- name: DATABASE
+  value: {{ .Values.backup.database | default "*" | trim | quote }}
+- name: CLOUD_PROVIDER
+  value: {{ .Values.backup.cloudProvider | trim }}
+- name: BUCKET_NAME
+  value: {{ .Values.backup.bucketName | trim }}
+- name: KEEP_BACKUP_FILES
+  value: "{{ .Values.backup.keepBackupFiles | default true }}"
+- name: PAGE_CACHE
+  value: {{ .Values.backup.pageCache | trim }}
+- name: HEAP_SIZE
+  value: {{ .Values.backup.heapSize | trim }}
+- name: INCLUDE_METADATA
+  value: "{{ .Values.backup.includeMetadata | default "all" | trim }}"
+- name: PARALLEL_RECOVERY
+  value: "{{ .Values.backup.parallelRecovery | default false }}"
+- name: TYPE
+  value: "{{ .Values.backup.type | default "AUTO" | trim }}"
+- name: KEEP_FAILED
+  value: "{{ .Values.backup.keepFailed | default false }}"
+- name: CREDENTIAL_PATH
+  value: "{{ printf "/credentials/%s" .Values.backup.secretKeyName | default ""  }}"
+- name: VERBOSE
+  value: "{{ .Values.backup.verbose | default true }}"
+- name: AZURE_STORAGE_ACCOUNT_NAME
+  value: "{{ .Values.backup.azureStorageAccountName | default "" }}"
+- name: ENDPOINT
+  value: "{{ .Values.backup.minioEndpoint | default "" }}"
+- name: CONSISTENCY_CHECK_ENABLE
+  value: "{{ .Values.consistencyCheck.enable | default false }}"
+- name: CONSISTENCY_CHECK_INDEXES
+  value: "{{ .Values.consistencyCheck.checkIndexes | default false }}"
+- name: CONSISTENCY_CHECK_DATABASE
+  value: {{ .Values.consistencyCheck.database | default .Values.backup.database | trim }}
+- name: CONSISTENCY_CHECK_GRAPH
+  value: "{{ .Values.consistencyCheck.checkGraph | default false }}"
+- name: CONSISTENCY_CHECK_COUNTS
+  value: "{{ .Values.consistencyCheck.checkCounts | default false }}"
+- name: CONSISTENCY_CHECK_PROPERTYOWNERS
+  value: "{{ .Values.consistencyCheck.checkPropertyOwners | default false }}"
+- name: CONSISTENCY_CHECK_MAXOFFHEAPMEMORY
+  value: "{{ .Values.consistencyCheck.maxOffHeapMemory | default "" | trim }}"
+- name: CONSISTENCY_CHECK_THREADS
+  value: "{{ .Values.consistencyCheck.threads | default "" | trim }}"
+- name: CONSISTENCY_CHECK_VERBOSE
+  value: "{{ .Values.consistencyCheck.verbose | default true }}"
+- name: CONSISTENCY_CHECK_FAIL_ON_INCONSISTENCIES
+  value: "{{ dig "failOnInconsistencies" true .Values.consistencyCheck }}"
+- name: AGGREGATE_BACKUP_ENABLED
+  value: "{{ .Values.backup.aggregate.enabled | default false }}"
+- name: AGGREGATE_BACKUP_VERBOSE
+  value: "{{ .Values.backup.aggregate.verbose | default true }}"
+- name: AGGREGATE_BACKUP_KEEPOLDBACKUP
+  value: "{{ .Values.backup.aggregate.keepOldBackup | default false }}"
+- name: AGGREGATE_BACKUP_PARALLEL_RECOVERY
+  value: "{{ .Values.backup.aggregate.parallelRecovery | default false }}"
+- name: AGGREGATE_BACKUP_FROM_PATH
+  value: "{{ .Values.backup.aggregate.fromPath | default "/backups" | trim }}"
+- name: AGGREGATE_BACKUP_DATABASE
+  value: "{{ .Values.backup.aggregate.database | default "*" | trim  }}"
+- name: FULL_BACKUP_MAX_DIFFERENTIALS
+  value: "{{ .Values.backup.fullBackupPolicy.maxDifferentials | default 0 | int }}"
+- name: FULL_BACKUP_MAX_DAYS
+  value: "{{ .Values.backup.fullBackupPolicy.maxDays | default 0 | int }}"
+- name: AGGREGATE_AFTER_DIFFERENTIALS
+  value: "{{ .Values.backup.fullBackupPolicy.aggregateAfterDifferentials | default 0 | int }}"
+- name: DATABASE_BACKUP_ENDPOINTS
+  value: {{ .Values.backup.databaseBackupEndpoints | trim }}
+- name: BACKUP_SSL_ENABLED
+  value: "{{ include "neo4j.backup.sslEnabled" . }}"
+- name: BACKUP_SSL_BASE_DIRECTORY
+  value: "/var/lib/neo4j/certificates/backup"
+- name: BACKUP_SSL_CLIENT_AUTH
+  value: {{ .Values.ssl.backup.clientAuth | default "REQUIRE" | quote }}
+- name: BACKUP_SSL_VERIFY_HOSTNAME
+  value: "{{ .Values.ssl.backup.verifyHostname | default false }}"
+- name: BACKUP_SSL_MIN_VALIDITY_DAYS
+  value: "{{ .Values.ssl.backup.minValidityDays | default 0 }}"
+- name: KEY_LAYOUT
+  value: {{ .Values.backup.keyLayout | default "" | trim | quote }}
+- name: BACKUP_DESTINATIONS
+  value: {{ include "neo4j.backup.destinations" . | quote }}
+- name: DESTINATION_FAILURE_POLICY
+  value: {{ .Values.backup.destinationFailurePolicy | default "any" | trim | quote }}
+{{- with .Values.backup.destinationInit | default dict }}
+- name: DESTINATION_CREATE_BUCKET
+  value: "{{ .createBucket | default false }}"
+- name: DESTINATION_WRITE_PROBE
+  value: "{{ .writeProbe | default false }}"
+- name: LIFECYCLE_EXPIRATION_DAYS
+  value: "{{ dig "expirationDays" 0 (.lifecycle | default dict) | int }}"
+- name: LIFECYCLE_ABORT_INCOMPLETE_UPLOAD_DAYS
+  value: "{{ dig "abortIncompleteUploadDays" 0 (.lifecycle | default dict) | int }}"
+- name: LIFECYCLE_EXPIRE_WHOLE_BUCKET
+  value: "{{ dig "expireWholeBucket" false (.lifecycle | default dict) }}"
+{{- end }}
+- name: UPLOAD_RATE_LIMIT
+  value: "{{ .Values.backup.uploadRateLimit | default 0 | int64 }}"
+{{- if .Values.backup.targets }}
+- name: BACKUP_TARGETS
+  value: {{ toJson .Values.backup.targets | quote }}
+- name: TARGET_PARALLELISM
+  value: "{{ .Values.backup.targetParallelism | default 1 | int }}"
+{{- end }}
+- name: BACKUP_HOOKS
+  value: {{ .Values.backup.hooks | default list | toJson | quote }}
+{{- if .Values.backup.resumableUploads }}
+- name: UPLOAD_STATE_FILE
+  value: "/backups/.upload-state.json"
+{{- end }}
+- name: UPLOAD_PROGRESS_INTERVAL
+  value: "{{ .Values.backup.uploadProgressInterval | default 30 | int }}"
+- name: CAPACITY_CHECK_ENABLED
+  value: "{{ dig "capacityCheck" "enabled" true .Values.backup }}"
+- name: CAPACITY_CHECK_HEADROOM_PERCENT
+  value: "{{ dig "capacityCheck" "headroomPercent" 20 .Values.backup | int }}"
+- name: BACKUP_VOLUME_SIZE_LIMIT
+  value: {{ dig "emptyDir" "sizeLimit" "" (.Values.tempVolume | default dict) | toString | quote }}
+- name: BACKUP_MODE
+  value: {{ include "neo4j.backup.mode" . | quote }}
+{{- if eq (include "neo4j.backup.offlineMode" .) "true" }}
+- name: DATA_DIRECTORY
+  value: {{ dig "dataDirectory" "/data" (index .Values.backup (include "neo4j.backup.mode" .) | default dict) | trim | quote }}
+{{- end }}
+{{- if eq (include "neo4j.backup.mode" .) "import" }}
+- name: IMPORT_TYPE
+  value: {{ .Values.backup.import.type | default "full" | trim | quote }}
+- name: IMPORT_NODES
+  value: {{ .Values.backup.import.nodes | default list | toJson | quote }}
+- name: IMPORT_RELATIONSHIPS
+  value: {{ .Values.backup.import.relationships | default list | toJson | quote }}
+- name: IMPORT_OVERWRITE_DESTINATION
+  value: "{{ .Values.backup.import.overwriteDestination | default false }}"
+- name: IMPORT_ADDITIONAL_FLAGS
+  value: {{ .Values.backup.import.additionalFlags | default list | join " " | quote }}
+{{- end }}
+{{- if eq (include "neo4j.backup.maintenanceMode" .) "true" }}
+{{- $maintenance := .Values.backup.maintenance | default dict }}
+- name: STORE_FORMAT
+  value: {{ $maintenance.toFormat | default "" | trim | quote }}
+- name: COPY_NODES_WITH_LABELS
+  value: {{ $maintenance.copyOnlyNodesWithLabels | default list | join "," | quote }}
+- name: COPY_IGNORE_NODES_WITH_LABELS
+  value: {{ $maintenance.ignoreNodesWithLabels | default list | join "," | quote }}
+- name: COPY_RELATIONSHIPS_WITH_TYPES
+  value: {{ $maintenance.copyOnlyRelationshipsWithTypes | default list | join "," | quote }}
+- name: COPY_IGNORE_RELATIONSHIPS_WITH_TYPES
+  value: {{ $maintenance.ignoreRelationshipsWithTypes | default list | join "," | quote }}
+- name: COPY_SKIP_LABELS
+  value: {{ $maintenance.skipLabels | default list | join "," | quote }}
+- name: COPY_SKIP_PROPERTIES
+  value: {{ $maintenance.skipProperties | default list | join "," | quote }}
+- name: COPY_COMPACT_NODE_STORE
+  value: "{{ $maintenance.compactNodeStore | default false }}"
+- name: MAINTENANCE_ADDITIONAL_FLAGS
+  value: {{ $maintenance.additionalFlags | default list | join " " | quote }}
+{{- end }}
+{{- if eq (include "neo4j.backup.mode" .) "snapshot" }}
+{{- $snapshot := .Values.backup.snapshot | default dict }}
+- name: SNAPSHOT_RELEASE
+  value: {{ $snapshot.release | default "" | trim | quote }}
+- name: SNAPSHOT_VOLUMES
+  value: {{ $snapshot.volumes | default (list "data") | join "," | quote }}
+- name: SNAPSHOT_CLASS
+  value: {{ $snapshot.className | default "" | trim | quote }}
+- name: SNAPSHOT_READY_TIMEOUT
+  value: {{ $snapshot.readyTimeout | default "30m" | trim | quote }}
+- name: SNAPSHOT_KEEP
+  value: "{{ $snapshot.keep | default 0 | int }}"
+- name: SNAPSHOT_QUIESCE
+  value: "{{ dig "quiesce" "enabled" false $snapshot }}"
+{{- if dig "quiesce" "enabled" false $snapshot }}
+- name: SNAPSHOT_QUIESCE_ENDPOINT
+  value: {{ dig "quiesce" "endpoint" "" $snapshot | trim | quote }}
+- name: NEO4J_AUTH
+  valueFrom:
+    secretKeyRef:
+      name: {{ $snapshot.quiesce.passwordFromSecret | quote }}
+      key: NEO4J_AUTH
+{{- end }}
+{{- end }}
+- name: KUBERNETES_EVENTS_ENABLED
+  value: "{{ dig "events" false (.Values.kubernetesReporting | default dict) }}"
+- name: STATUS_CONFIGMAP
+  value: "{{ if dig "statusConfigMap" false (.Values.kubernetesReporting | default dict) }}{{ include "neo4j.fullname" . }}-status{{ end }}"
+- name: POD_NAME
+  valueFrom:
+    fieldRef:
+      fieldPath: metadata.name
+- name: POD_NAMESPACE
+  valueFrom:
+    fieldRef:
+      fieldPath: metadata.namespace
+- name: LOG_FORMAT
+  value: {{ dig "format" "text" (.Values.logging | default dict) | quote }}
+- name: LOG_LEVEL
+  value: {{ dig "level" "info" (.Values.logging | default dict) | quote }}
+- name: TRACING_ENABLED
+  value: "{{ dig "enabled" false (.Values.tracing | default dict) }}"
+{{- if dig "enabled" false (.Values.tracing | default dict) }}
+- name: OTEL_EXPORTER_OTLP_ENDPOINT
+  value: {{ .Values.tracing.endpoint | trim | quote }}
+- name: OTEL_EXPORTER_OTLP_INSECURE
+  value: "{{ .Values.tracing.insecure | default false }}"
+- name: OTEL_SERVICE_NAME
+  value: {{ .Values.tracing.serviceName | default "neo4j-backup" | quote }}
+- name: OTEL_TRACES_SAMPLER
+  value: "parentbased_traceidratio"
+- name: OTEL_TRACES_SAMPLER_ARG
+  value: "{{ dig "sampleRatio" 1 .Values.tracing }}"
+{{- with dig "headersSecret" "name" "" .Values.tracing }}
+- name: OTEL_EXPORTER_OTLP_HEADERS
+  valueFrom:
+    secretKeyRef:
+      name: {{ . | quote }}
+      key: {{ dig "headersSecret" "key" "headers" $.Values.tracing | quote }}
+{{- end }}
+{{- end }}
+- name: OBJECT_TAGS
+  value: {{ include "neo4j.backup.keyValuePairs" .Values.backup.objectTags | quote }}
+- name: OBJECT_METADATA
+  value: {{ include "neo4j.backup.keyValuePairs" .Values.backup.objectMetadata | quote }}
+- name: AWS_STORAGE_CLASS
+  value: "{{ .Values.backup.aws.storageClass | default "" | trim }}"
+- name: AWS_SSE_KMS_KEY_ID
+  value: "{{ .Values.backup.aws.sseKmsKeyId | default "" | trim }}"
+- name: AWS_OBJECT_LOCK_MODE
+  value: "{{ .Values.backup.aws.objectLock.mode | default "" | trim }}"
+- name: AWS_OBJECT_LOCK_RETAIN_DAYS
+  value: "{{ .Values.backup.aws.objectLock.retainDays | default "" }}"
+{{- if .Values.backup.aws.assumeRoles }}
+- name: AWS_ASSUME_ROLES
+  value: {{ toJson .Values.backup.aws.assumeRoles | quote }}
+{{- end }}
+- name: GCP_STORAGE_CLASS
+  value: "{{ .Values.backup.gcp.storageClass | default "" | trim }}"
+- name: GCP_KMS_KEY_NAME
+  value: "{{ .Values.backup.gcp.kmsKeyName | default "" | trim }}"
+- name: GCP_PROJECT_ID
+  value: "{{ .Values.backup.gcp.projectId | default "" | trim }}"
+- name: GCP_BUCKET_LOCATION
+  value: "{{ .Values.backup.gcp.bucketLocation | default "" | trim }}"
+- name: AZURE_ACCESS_TIER
+  value: "{{ .Values.backup.azure.accessTier | default "" | trim }}"
+- name: AZURE_ENCRYPTION_SCOPE
+  value: "{{ .Values.backup.azure.encryptionScope | default "" | trim }}"
+{{- end -}}
+
+{{- define "neo4j.backup.volumeMounts" -}}
+{{- if .Values.backup.secretName }}
+- name: credentials
+  mountPath: /credentials
+  readOnly: true
+{{- end }}
+{{- range $destination := .Values.backup.destinations }}
+{{- if $destination.secretName }}
+- name: "credentials-{{ $destination.name }}"
+  mountPath: "/credentials-{{ $destination.name }}"
+  readOnly: true
+{{- end }}
+{{- end }}
+{{- include "neo4j.backup.ssl.volumeMountsFromSecrets" .Values.ssl }}
+- name: "backup"
+  mountPath: "/backups"
+{{- if eq (include "neo4j.backup.offlineMode" .) "true" }}
+- name: "data"
+  mountPath: {{ dig "dataDirectory" "/data" (index .Values.backup (include "neo4j.backup.mode" .) | default dict) | trim | quote }}
+{{- end }}
+{{- end -}}
+
+{{- define "neo4j.backup.volumes" -}}
+{{- if .Values.backup.secretName }}
+- name: credentials
+  secret:
+    secretName: "{{ .Values.backup.secretName }}"
+    items:
+      - key: "{{ .Values.backup.secretKeyName }}"
+        path: "{{ .Values.backup.secretKeyName }}"
+{{- end }}
+{{- range $destination := .Values.backup.destinations }}
+{{- if $destination.secretName }}
+- name: "credentials-{{ $destination.name }}"
+  secret:
+    secretName: "{{ $destination.secretName }}"
+    items:
+      - key: "{{ $destination.secretKeyName }}"
+        path: "{{ $destination.secretKeyName }}"
+{{- end }}
+{{- end }}
+{{- include "neo4j.backup.ssl.volumesFromSecrets" .Values.ssl }}
+- name: "backup"
+{{- if $.Values.tempVolume }}
+{{- toYaml $.Values.tempVolume | nindent 2 }}
+{{- else }}
+{{- printf "emptyDir: {}" | nindent 2 }}
+{{- end }}
+{{- if eq (include "neo4j.backup.offlineMode" .) "true" }}
+- name: "data"
+{{- get (index .Values.backup (include "neo4j.backup.mode" .) | default dict) "dataVolume" | toYaml | nindent 2 }}
+{{- end }}
+{{- end -}}
+
+{{- define "neo4j.backup.mode" -}}
+{{- .Values.backup.mode | default "backup" | trim -}}
+{{- end -}}
+
+{{/* dump and import modes work on the data directory of a stopped server whose data volume is mounted in the pod */}}
+{{- define "neo4j.backup.offlineMode" -}}
+{{- has (include "neo4j.backup.mode" .) (list "dump" "import") -}}
+{{- end -}}
+
+{{/* copy and migrate modes restore the latest backup into tempVolume , the server is not involved */}}
+{{- define "neo4j.backup.maintenanceMode" -}}
+{{- has (include "neo4j.backup.mode" .) (list "copy" "migrate") -}}
+{{- end -}}
+
+{{/* the backup mode , and the snapshot mode quiescing the databases through the admin service , connect to the Neo4j server */}}
+{{- define "neo4j.backup.requiresServer" -}}
+{{- $mode := include "neo4j.backup.mode" . -}}
+{{- $snapshot := .Values.backup.snapshot | default dict -}}
+{{- or (eq $mode "backup") (and (eq $mode "snapshot") (dig "quiesce" "enabled" false $snapshot) (empty (dig "quiesce" "endpoint" "" $snapshot | trim))) -}}
+{{- end -}}
+
+{{- define "neo4j.backup.component" -}}
+    {{- if and (not (kindIs "invalid" .Values.backup.aggregate)) .Values.backup.aggregate.enabled -}}
+        aggregate-backup
+    {{- else -}}
+        backup
+    {{- end -}}
+{{- end -}}
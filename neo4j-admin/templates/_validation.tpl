@@ -59,7 +59,7 @@
 
 {{- define "neo4j.backup.checkDatabaseIPAndServiceName" -}}
 
-    {{- if or (kindIs "invalid" .Values.backup.aggregate) (not .Values.backup.aggregate.enabled) -}}
+    {{- if and (eq (include "neo4j.backup.requiresServer" .) "true") (or (kindIs "invalid" .Values.backup.aggregate) (not .Values.backup.aggregate.enabled)) (empty .Values.backup.targets) -}}
         {{- if and (kindIs "invalid" .Values.backup.databaseAdminServiceName) (kindIs "invalid" .Values.backup.databaseAdminServiceIP) -}}
             {{- fail (printf "Missing fields. Please set databaseAdminServiceName via --set backup.databaseAdminServiceName or databaseAdminServiceIP via --set backup.databaseAdminServiceIP")}}
         {{- end -}}
@@ -74,3 +74,172 @@
     {{- end -}}
 
 {{- end -}}
+
+{{/* checks that every additional destination contains a unique name , cloudProvider and bucketName */}}
+{{- define "neo4j.backup.checkDestinations" -}}
+    {{- if not (has (.Values.backup.destinationFailurePolicy | default "any" | trim) (list "any" "all")) -}}
+        {{- fail (printf "Incorrect backup.destinationFailurePolicy %s. Supported values are any and all" .Values.backup.destinationFailurePolicy) -}}
+    {{- end -}}
+    {{- $names := dict -}}
+    {{- range $destination := .Values.backup.destinations -}}
+        {{- if or (empty $destination.name) (empty $destination.cloudProvider) (empty $destination.bucketName) -}}
+            {{- fail (printf "Every entry in backup.destinations must contain name, cloudProvider and bucketName") -}}
+        {{- end -}}
+        {{- if not (has $destination.cloudProvider (list "aws" "azure" "gcp")) -}}
+            {{- fail (printf "Incorrect cloudProvider %s for destination %s. Supported values are aws, azure and gcp" $destination.cloudProvider $destination.name) -}}
+        {{- end -}}
+        {{- if hasKey $names $destination.name -}}
+            {{- fail (printf "Duplicate destination name %s in backup.destinations" $destination.name) -}}
+        {{- end -}}
+        {{- $_ := set $names $destination.name true -}}
+        {{- if and $destination.secretName (empty $destination.secretKeyName) -}}
+            {{- fail (printf "Missing secretKeyName for destination %s" $destination.name) -}}
+        {{- end -}}
+        {{- if and $destination.assumeRoles (ne $destination.cloudProvider "aws") -}}
+            {{- fail (printf "assumeRoles of destination %s requires cloudProvider aws" $destination.name) -}}
+        {{- end -}}
+    {{- end -}}
+{{- end -}}
+
+{{/* checks that every target contains a unique name along with endpoints , serviceName or serviceIP */}}
+{{- define "neo4j.backup.checkTargets" -}}
+    {{- $names := dict -}}
+    {{- range $target := .Values.backup.targets -}}
+        {{- if empty $target.name -}}
+            {{- fail (printf "Every entry in backup.targets must contain a name") -}}
+        {{- end -}}
+        {{- if not (regexMatch "^[A-Za-z0-9][A-Za-z0-9._-]*$" $target.name) -}}
+            {{- fail (printf "Invalid backup target name %s. It must contain only letters, digits, '.', '_' and '-'" $target.name) -}}
+        {{- end -}}
+        {{- if hasKey $names $target.name -}}
+            {{- fail (printf "Duplicate backup target name %s" $target.name) -}}
+        {{- end -}}
+        {{- $_ := set $names $target.name true -}}
+        {{- if and (empty $target.endpoints) (empty $target.serviceName) (empty $target.serviceIP) -}}
+            {{- fail (printf "Backup target %s must contain one of endpoints, serviceName or serviceIP" $target.name) -}}
+        {{- end -}}
+    {{- end -}}
+{{- end -}}
+
+{{/* dump and import modes need the data volume of the stopped server and run against a single server */}}
+{{- define "neo4j.backup.checkMode" -}}
+    {{- $mode := include "neo4j.backup.mode" . -}}
+    {{- if not (has $mode (list "backup" "dump" "import" "copy" "migrate" "snapshot")) -}}
+        {{ fail (printf "Incorrect backup.mode %s. Supported values are backup, dump, import, copy, migrate and snapshot" $mode) }}
+    {{- end -}}
+    {{- if eq (include "neo4j.backup.offlineMode" .) "true" -}}
+        {{- if empty (get (index .Values.backup (include "neo4j.backup.mode" .) | default dict) "dataVolume") -}}
+            {{ fail (printf "backup.mode %s requires the data volume of the Neo4j server. Please set it via backup.%s.dataVolume" $mode $mode) }}
+        {{- end -}}
+        {{- if not (empty .Values.backup.targets) -}}
+            {{ fail (printf "backup.mode %s cannot be used along with backup.targets" $mode) }}
+        {{- end -}}
+        {{- if and (not (kindIs "invalid" .Values.backup.aggregate)) .Values.backup.aggregate.enabled -}}
+            {{ fail (printf "backup.mode %s cannot be used along with backup.aggregate" $mode) }}
+        {{- end -}}
+    {{- end -}}
+    {{- if eq $mode "import" -}}
+        {{- if empty .Values.backup.import.nodes -}}
+            {{ fail (printf "backup.mode import requires the node files. Please set them via backup.import.nodes") }}
+        {{- end -}}
+        {{- if or (empty (.Values.backup.database | trim)) (regexMatch "[,*?]" .Values.backup.database) -}}
+            {{ fail (printf "backup.mode import requires the name of the single database to import into. Please set it via backup.database") }}
+        {{- end -}}
+        {{- if not (has (.Values.backup.import.type | default "full") (list "full" "incremental")) -}}
+            {{ fail (printf "Incorrect backup.import.type %s. Supported values are full and incremental" .Values.backup.import.type) }}
+        {{- end -}}
+    {{- end -}}
+    {{- if eq (include "neo4j.backup.maintenanceMode" .) "true" -}}
+        {{- if or (empty (.Values.backup.database | trim)) (regexMatch "[,*?]" .Values.backup.database) -}}
+            {{ fail (printf "backup.mode %s requires the name of the single database whose latest backup is restored. Please set it via backup.database" $mode) }}
+        {{- end -}}
+        {{- if or (not (empty .Values.backup.targets)) (and (not (kindIs "invalid" .Values.backup.aggregate)) .Values.backup.aggregate.enabled) -}}
+            {{ fail (printf "backup.mode %s cannot be used along with backup.targets or backup.aggregate" $mode) }}
+        {{- end -}}
+    {{- end -}}
+    {{- if eq $mode "snapshot" -}}
+        {{- $snapshot := .Values.backup.snapshot | default dict -}}
+        {{- if empty ($snapshot.release | default "" | trim) -}}
+            {{ fail (printf "backup.mode snapshot requires the name of the neo4j release whose volumes are snapshotted. Please set it via backup.snapshot.release") }}
+        {{- end -}}
+        {{- if or (not (empty .Values.backup.targets)) (and (not (kindIs "invalid" .Values.backup.aggregate)) .Values.backup.aggregate.enabled) -}}
+            {{ fail (printf "backup.mode snapshot cannot be used along with backup.targets or backup.aggregate") }}
+        {{- end -}}
+        {{- /* the Role is bound to serviceAccountName , never to the default ServiceAccount shared by every pod of the namespace */ -}}
+        {{- if and (dig "rbac" "create" true $snapshot) (empty .Values.serviceAccountName) -}}
+            {{ fail (printf "backup.snapshot.rbac.create requires a dedicated service account. Please set it via --set serviceAccountName or grant the permissions yourself with --set backup.snapshot.rbac.create=false") }}
+        {{- end -}}
+        {{- if dig "quiesce" "enabled" false $snapshot -}}
+            {{- if empty (dig "quiesce" "passwordFromSecret" "" $snapshot | trim) -}}
+                {{ fail (printf "backup.snapshot.quiesce requires the credentials of the server. Please set the secret containing NEO4J_AUTH via backup.snapshot.quiesce.passwordFromSecret") }}
+            {{- end -}}
+            {{- if or (empty (.Values.backup.database | default "" | trim)) (regexMatch "[*?]" .Values.backup.database) -}}
+                {{ fail (printf "backup.snapshot.quiesce requires the list of databases to quiesce. Please set it via backup.database") }}
+            {{- end -}}
+        {{- end -}}
+    {{- end -}}
+{{- end -}}
+
+{{- define "neo4j.backup.checkTracing" -}}
+    {{- if and (dig "enabled" false (.Values.tracing | default dict)) (empty (trim (.Values.tracing.endpoint | default ""))) -}}
+        {{ fail (printf "tracing.enabled requires the OTLP endpoint. Please set it via --set tracing.endpoint") }}
+    {{- end -}}
+{{- end -}}
+
+{{- define "neo4j.backup.checkLogging" -}}
+    {{- $logging := .Values.logging | default dict -}}
+    {{- if not (has (dig "format" "text" $logging) (list "text" "json")) -}}
+        {{ fail (printf "invalid logging.format %s. Supported values are text and json" (dig "format" "text" $logging)) }}
+    {{- end -}}
+    {{- if not (has (dig "level" "info" $logging) (list "debug" "info" "warn" "error")) -}}
+        {{ fail (printf "invalid logging.level %s. Supported values are debug , info , warn and error" (dig "level" "info" $logging)) }}
+    {{- end -}}
+{{- end -}}
+
+{{/* the Role of the reporting is bound to serviceAccountName , never to the default ServiceAccount shared by every pod of the namespace */}}
+{{- define "neo4j.backup.checkReportingRbac" -}}
+    {{- $reporting := .Values.kubernetesReporting | default dict -}}
+    {{- if and (or (dig "events" false $reporting) (dig "statusConfigMap" false $reporting)) (dig "rbac" "create" true $reporting) (empty .Values.serviceAccountName) -}}
+        {{ fail (printf "kubernetesReporting.rbac.create requires a dedicated service account. Please set it via --set serviceAccountName or grant the permissions yourself with --set kubernetesReporting.rbac.create=false") }}
+    {{- end -}}
+{{- end -}}
+
+{{/* the chains are aggregated from aggregate.fromPath since /backups only holds the artifacts of the current run */}}
+{{- define "neo4j.backup.checkFullBackupPolicy" -}}
+    {{- if gt (dig "aggregateAfterDifferentials" 0 (.Values.backup.fullBackupPolicy | default dict) | int) 0 -}}
+        {{- $fromPath := dig "fromPath" "" (.Values.backup.aggregate | default dict) | trim -}}
+        {{- if empty $fromPath -}}
+            {{ fail (printf "backup.fullBackupPolicy.aggregateAfterDifferentials requires the path holding the whole backup chain. Please set it via backup.aggregate.fromPath (ex: s3://bucket/prefix)") }}
+        {{- end -}}
+        {{- if hasPrefix "s3://" $fromPath -}}
+            {{- if ne .Values.backup.cloudProvider "aws" -}}
+                {{ fail (printf "backup.aggregate.fromPath %s requires backup.cloudProvider aws" $fromPath) }}
+            {{- end -}}
+        {{- else if or (not .Values.backup.keepBackupFiles) (empty .Values.tempVolume) (hasKey (.Values.tempVolume | default dict) "emptyDir") -}}
+            {{ fail (printf "backup.aggregate.fromPath %s is a local path , aggregating a local chain requires backup.keepBackupFiles and a persistent tempVolume (ex: a persistentVolumeClaim)" $fromPath) }}
+        {{- end -}}
+    {{- end -}}
+{{- end -}}
+
+{{/* resumable uploads keep the artifacts and the upload state on /backups which must survive the pod of a failed run */}}
+{{- define "neo4j.backup.checkResumableUploads" -}}
+    {{- if and .Values.backup.resumableUploads (or (empty .Values.tempVolume) (hasKey (.Values.tempVolume | default dict) "emptyDir")) -}}
+        {{ fail (printf "backup.resumableUploads requires a persistent tempVolume (ex: a persistentVolumeClaim) since the artifacts of a failed run are lost with an emptyDir") }}
+    {{- end -}}
+{{- end -}}
+
+{{/* checks that every hook runs on a supported event and contains either a command or a url */}}
+{{- define "neo4j.backup.checkHooks" -}}
+    {{- range $index, $hook := .Values.backup.hooks -}}
+        {{- $name := $hook.name | default (printf "hook-%d" (add1 $index)) -}}
+        {{- if not (has $hook.event (list "preBackup" "postBackup" "postUpload" "onFailure")) -}}
+            {{- fail (printf "Incorrect event %v for backup hook %s. Supported values are preBackup, postBackup, postUpload and onFailure" $hook.event $name) -}}
+        {{- end -}}
+        {{- if eq (empty $hook.command) (empty $hook.url) -}}
+            {{- fail (printf "Backup hook %s must contain either command or url" $name) -}}
+        {{- end -}}
+        {{- if not (has ($hook.failurePolicy | default "fail") (list "fail" "ignore")) -}}
+            {{- fail (printf "Incorrect failurePolicy %s for backup hook %s. Supported values are fail and ignore" $hook.failurePolicy $name) -}}
+        {{- end -}}
+    {{- end -}}
+{{- end -}}
@@ -1,3 +1,7 @@
 module reverse-proxy
 
 go 1.22
+
+require github.com/neo4j/helm-charts/logging v0.0.0-00010101000000-000000000000
+
+replace github.com/neo4j/helm-charts/logging => ../../logging
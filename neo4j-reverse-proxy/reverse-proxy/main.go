@@ -2,7 +2,9 @@ package main
 
 import (
 	"fmt"
+	"github.com/neo4j/helm-charts/logging"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"reverse-proxy/operations"
@@ -11,24 +13,27 @@ import (
 
 func main() {
 
+	if err := logging.Setup("neo4j-reverse-proxy"); err != nil {
+		log.Printf("Warning: the logs are not structured: %v", err)
+	}
 	startup()
 
 	h, err := proxy.NewHandle()
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 	http.Handle("/", h)
 
 	domain := fmt.Sprintf("0.0.0.0:%s", os.Getenv("PORT"))
-	log.Printf("Listening on %s", domain)
+	slog.Info("Listening", "address", domain)
 
-	log.Fatal(http.ListenAndServe(domain, nil))
+	fatal(http.ListenAndServe(domain, nil))
 }
 
 func startup() {
 	errors := operations.CheckEnvVariables()
 	if len(errors) != 0 {
-		log.Fatalf("%v", errors)
+		fatal(fmt.Errorf("%v", errors))
 	}
 
 	hostname := fmt.Sprintf("%s.%s.svc.%s", os.Getenv("SERVICE_NAME"), os.Getenv("NAMESPACE"), os.Getenv("DOMAIN"))
@@ -38,6 +43,12 @@ func startup() {
 
 	err := operations.CheckConnectivity(hostname)
 	if err != nil {
-		log.Fatal(err)
+		fatal(err)
 	}
 }
+
+// fatal logs the error and exits
+func fatal(err error) {
+	slog.Error(err.Error())
+	os.Exit(1)
+}
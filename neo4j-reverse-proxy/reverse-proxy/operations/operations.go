@@ -2,7 +2,7 @@ package operations
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"os"
 	"os/exec"
 	"strings"
@@ -26,7 +26,7 @@ func CheckConnectivity(hostname string) error {
 				hostPort,
 				string(output))
 		}
-		log.Printf("Connectivity established with Service %s!!", hostPort)
+		slog.Info("Connectivity established with Service", "address", hostPort)
 	}
 
 	return nil
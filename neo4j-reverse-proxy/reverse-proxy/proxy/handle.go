@@ -2,7 +2,7 @@ package proxy
 
 import (
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/http/httputil"
 	"os"
@@ -16,7 +16,7 @@ type Handle struct {
 
 func NewHandle() (*Handle, error) {
 	hostname := fmt.Sprintf("%s.%s.svc.%s", os.Getenv("SERVICE_NAME"), os.Getenv("NAMESPACE"), os.Getenv("DOMAIN"))
-	log.Printf("Hostname := %s", hostname)
+	slog.Info("Proxying to service", "hostname", hostname)
 	if ip, present := os.LookupEnv("IP"); present {
 		hostname = ip
 	}
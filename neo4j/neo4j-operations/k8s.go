@@ -6,7 +6,7 @@ import (
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
-	"log"
+	"log/slog"
 	"strings"
 )
 
@@ -29,7 +29,7 @@ func getK8sClient() (*kubernetes.Clientset, error) {
 // getCredsFromSecret get the neo4j authentication details from the k8s secret stored under the key NEO4J_AUTH
 func getCredsFromSecret(secretName string) (string, error) {
 
-	log.Println("Fetching creds from secret", secretName)
+	slog.Info("Fetching creds from secret", "secret", secretName)
 	secret, err := ClientSet.CoreV1().Secrets(Namespace).Get(context.TODO(), secretName, v1.GetOptions{})
 	if err != nil {
 		return "", fmt.Errorf("unable to fetch details about secret %s \n %v", secretName, err)
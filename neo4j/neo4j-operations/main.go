@@ -1,8 +1,10 @@
 package main
 
 import (
+	"github.com/neo4j/helm-charts/logging"
 	"k8s.io/client-go/kubernetes"
 	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -16,9 +18,12 @@ var (
 
 func main() {
 
+	if err := logging.Setup("neo4j-operations"); err != nil {
+		log.Printf("Warning: the logs are not structured: %v", err)
+	}
 	err := checkRequiredVariables()
 	if err != nil {
-		log.Printf("missing required env variables \n %v \n Exiting... :-( ", err)
+		slog.Error("missing required env variables. Exiting... :-(", "error", err)
 		os.Exit(1)
 	}
 	Namespace = os.Getenv("NAMESPACE")
@@ -27,7 +32,7 @@ func main() {
 	// load kubernetes config
 	clientSet, err := getK8sClient()
 	if err != nil {
-		log.Printf("error while setting k8s client \n %v \n Exiting... :-( ", err)
+		slog.Error("error while setting k8s client. Exiting... :-(", "error", err)
 		os.Exit(1)
 	}
 	ClientSet = clientSet
@@ -35,18 +40,18 @@ func main() {
 	// get password from kubernetes secret either specified in values.yaml or the generic k8s secret
 	pass, err := getCredsFromSecret(os.Getenv("SECRETNAME"))
 	if err != nil {
-		log.Printf("error while fetching secret pass \n %v", err)
+		slog.Error("error while fetching secret pass", "error", err)
 		os.Exit(1)
 	}
 
-	log.Printf("Waiting for Neo4j to startup. Sleeping for 60 seconds.")
+	slog.Info("Waiting for Neo4j to startup. Sleeping for 60 seconds.")
 	time.Sleep(60 * time.Second)
 	// connect using the above creds
 	username := strings.Split(pass, "/")[0]
 	password := strings.Split(pass, "/")[1]
 	err = ExecuteEnablement(username, password)
 	if err != nil {
-		log.Printf("error while connecting to neo4j \n %v", err)
+		slog.Error("error while connecting to neo4j", "error", err)
 		os.Exit(1)
 	}
 
@@ -4,7 +4,7 @@ import (
 	"context"
 	"fmt"
 	"github.com/neo4j/neo4j-go-driver/v5/neo4j"
-	"log"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
@@ -24,7 +24,7 @@ func ExecuteEnablement(username, pass string) error {
 		return err
 	}
 	if strings.ToLower(serverState) == "enabled" {
-		log.Println("Server is already enabled !!")
+		slog.Info("Server is already enabled !!", "server", serverId)
 		return nil
 	}
 
@@ -41,12 +41,12 @@ func ExecuteEnablement(username, pass string) error {
 		return fmt.Errorf("Server is NOT ENABLED !!")
 	}
 
-	log.Println("Server is ENABLED !!")
+	slog.Info("Server is ENABLED !!", "server", serverId)
 	return nil
 }
 
 func getNeo4jDriver(ctx context.Context, username, pass string) (neo4j.DriverWithContext, error) {
-	log.Println("Establishing connection with Neo4j")
+	slog.Info("Establishing connection with Neo4j")
 	retries := 5
 	serviceName := fmt.Sprintf("%s.%s.svc.cluster.local:7687", ReleaseName, Namespace)
 	// URI examples: "neo4j://localhost", "neo4j+s://xxx.databases.neo4j.io"
@@ -65,21 +65,20 @@ func getNeo4jDriver(ctx context.Context, username, pass string) (neo4j.DriverWit
 			return nil, err
 		}
 		if err != nil {
-			log.Printf("found error while trying to connect to Neo4j (db uri := %s)\n %s", dbUri, err.Error())
-			log.Printf("sleeping for 30 seconds. Retry (%d/%d)\n", i, retries)
+			slog.Warn("found error while trying to connect to Neo4j , sleeping for 30 seconds", "uri", dbUri, "error", err, "retry", fmt.Sprintf("%d/%d", i, retries))
 			time.Sleep(30 * time.Second)
 			continue
 		}
 		break
 	}
-	log.Println("Connectivity established !!")
+	slog.Info("Connectivity established !!", "uri", dbUri)
 	return driver, nil
 }
 
 // enableNeo4jServer fires the cypher query ENABLE SERVER <server-id>
 func enableNeo4jServer(ctx context.Context, driver neo4j.DriverWithContext, serverId string) error {
 
-	log.Println("Enabling server")
+	slog.Info("Enabling server", "server", serverId)
 	// Enable SERVER 'serverId'
 	query := fmt.Sprintf("ENABLE SERVER \"%s\"", serverId)
 	_, err := neo4j.ExecuteQuery(ctx, driver,
@@ -95,7 +94,7 @@ func enableNeo4jServer(ctx context.Context, driver neo4j.DriverWithContext, serv
 
 // getNeo4jServerIdAndState returns the id and state for the provided serviceName from the list of records
 func getNeo4jServerIdAndState(ctx context.Context, driver neo4j.DriverWithContext, serviceName string) (string, string, error) {
-	log.Println("Fetching Neo4j server id and state")
+	slog.Info("Fetching Neo4j server id and state", "address", serviceName)
 	var serverState, serverId string
 	query := fmt.Sprintf("SHOW SERVERS WHERE address='%s'", serviceName)
 	result, err := neo4j.ExecuteQuery(ctx, driver,